@@ -0,0 +1,266 @@
+package autorun
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/fsx"
+	"github.com/ooni/probe-cli/v3/internal/shellx"
+)
+
+// commandRunner runs external commands. We use this interface
+// so that we can test the manager without running systemctl.
+type commandRunner interface {
+	// Run runs the command connecting its stdout and stderr to ours.
+	Run(name string, arg ...string) error
+
+	// RunQuiet runs the command without showing its stdout and stderr.
+	RunQuiet(name string, arg ...string) error
+
+	// Output runs the command and returns its stdout. Note that the stdout
+	// is returned also when the command exits with a nonzero code.
+	Output(name string, arg ...string) ([]byte, error)
+}
+
+// shellxRunner is the commandRunner using shellx.
+type shellxRunner struct{}
+
+// Run implements commandRunner.
+func (shellxRunner) Run(name string, arg ...string) error {
+	return shellx.Run(log.Log, name, arg...)
+}
+
+// RunQuiet implements commandRunner.
+func (shellxRunner) RunQuiet(name string, arg ...string) error {
+	log.Infof("exec: %s %s", name, strings.Join(arg, " "))
+	return shellx.RunQuiet(name, arg...)
+}
+
+// Output implements commandRunner.
+func (shellxRunner) Output(name string, arg ...string) ([]byte, error) {
+	return shellx.OutputQuiet(name, arg...)
+}
+
+const (
+	// systemdUnitName is the base name of the systemd units.
+	systemdUnitName = "org.ooni.cli"
+
+	// systemdServiceName is the name of the systemd service.
+	systemdServiceName = systemdUnitName + ".service"
+
+	// systemdTimerName is the name of the systemd timer.
+	systemdTimerName = systemdUnitName + ".timer"
+)
+
+var systemdServiceTemplate = `[Unit]
+Description=OONI Probe background measurements
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart="{{ .Executable }}" --log-handler=syslog run unattended
+`
+
+var systemdTimerTemplate = `[Unit]
+Description=Periodically run OONI Probe background measurements
+
+[Timer]
+OnActiveSec=0
+OnUnitActiveSec=1h
+RandomizedDelaySec=5min
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`
+
+// managerLinux is the Manager using systemd user timers.
+type managerLinux struct {
+	// executable returns the path of the ooniprobe executable.
+	executable func() (string, error)
+
+	// runner runs external commands.
+	runner commandRunner
+
+	// unitDir returns the directory containing systemd user units.
+	unitDir func() (string, error)
+}
+
+// newManagerLinux creates a new managerLinux using the default dependencies.
+func newManagerLinux() managerLinux {
+	return managerLinux{
+		executable: os.Executable,
+		runner:     shellxRunner{},
+		unitDir:    systemdUserUnitDir,
+	}
+}
+
+// systemdUserUnitDir returns the directory where we should write user units,
+// which is $XDG_CONFIG_HOME/systemd/user or $HOME/.config/systemd/user.
+func systemdUserUnitDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "systemd", "user"), nil
+}
+
+func (m managerLinux) systemctl(arg ...string) error {
+	return m.runner.RunQuiet("systemctl", append([]string{"--user"}, arg...)...)
+}
+
+func (m managerLinux) LogShow() error {
+	return m.runner.Run("journalctl", "--user", "--unit", systemdServiceName, "--no-pager")
+}
+
+func (m managerLinux) LogStream() error {
+	return m.runner.Run("journalctl", "--user", "--unit", systemdServiceName, "--follow")
+}
+
+func (m managerLinux) unitPaths() (service string, timer string, err error) {
+	dir, err := m.unitDir()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dir, systemdServiceName), filepath.Join(dir, systemdTimerName), nil
+}
+
+func (m managerLinux) mustNotHaveUnits() error {
+	_, timerPath, err := m.unitPaths()
+	if err != nil {
+		return err
+	}
+	log.Infof("exec: test -f %s && already_registered()", timerPath)
+	if fsx.RegularFileExists(timerPath) {
+		// This is not atomic. Do we need atomicity here?
+		return errors.New("autorun: service already registered")
+	}
+	return nil
+}
+
+func writeUnit(path, name, text string, in any) error {
+	var out bytes.Buffer
+	t := template.Must(template.New(name).Parse(text))
+	if err := t.Execute(&out, in); err != nil {
+		return err
+	}
+	log.Infof("exec: writeUnit(%s)", path)
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
+
+func (m managerLinux) writeUnits() error {
+	executable, err := m.executable()
+	if err != nil {
+		return err
+	}
+	servicePath, timerPath, err := m.unitPaths()
+	if err != nil {
+		return err
+	}
+	log.Infof("exec: mkdir -p %s", filepath.Dir(servicePath))
+	if err := os.MkdirAll(filepath.Dir(servicePath), 0755); err != nil {
+		return err
+	}
+	in := struct{ Executable string }{Executable: executable}
+	if err := writeUnit(servicePath, "service", systemdServiceTemplate, in); err != nil {
+		return err
+	}
+	return writeUnit(timerPath, "timer", systemdTimerTemplate, in)
+}
+
+func (m managerLinux) start() error {
+	if err := m.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if err := m.systemctl("enable", "--now", systemdTimerName); err != nil {
+		return err
+	}
+	log.Info("hint: use 'loginctl enable-linger' to run tests when you are not logged in")
+	return nil
+}
+
+func (m managerLinux) Start() error {
+	operations := []func() error{m.mustNotHaveUnits, m.writeUnits, m.start}
+	for _, op := range operations {
+		if err := op(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m managerLinux) stop() error {
+	_, timerPath, err := m.unitPaths()
+	if err != nil {
+		return err
+	}
+	if !fsx.RegularFileExists(timerPath) {
+		return nil // nothing to disable
+	}
+	return m.systemctl("disable", "--now", systemdTimerName)
+}
+
+func (m managerLinux) removeFiles() error {
+	servicePath, timerPath, err := m.unitPaths()
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{timerPath, servicePath} {
+		log.Infof("exec: rm -f %s", path)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return m.systemctl("daemon-reload")
+}
+
+func (m managerLinux) Stop() error {
+	operations := []func() error{m.stop, m.removeFiles}
+	for _, op := range operations {
+		if err := op(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isActive returns the state printed by `systemctl --user is-active`. This command
+// exits with nonzero status when the unit is not active, so we rely on the output.
+func (m managerLinux) isActive(unit string) (string, error) {
+	out, err := m.runner.Output("systemctl", "--user", "is-active", unit)
+	state := strings.TrimSpace(string(out))
+	if state == "" && err != nil {
+		return "", fmt.Errorf("autorun: unexpected error: %w", err)
+	}
+	return state, nil
+}
+
+func (m managerLinux) Status() (string, error) {
+	state, err := m.isActive(systemdServiceName)
+	if err != nil {
+		return "", err
+	}
+	switch state {
+	case "active", "activating", "reloading":
+		return StatusRunning, nil
+	}
+	state, err = m.isActive(systemdTimerName)
+	if err != nil {
+		return "", err
+	}
+	if state == "active" {
+		return StatusScheduled, nil
+	}
+	return StatusStopped, nil
+}
+
+func init() {
+	register("linux", newManagerLinux())
+}
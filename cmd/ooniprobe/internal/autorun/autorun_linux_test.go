@@ -0,0 +1,179 @@
+package autorun
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeRunner is a commandRunner recording the commands it runs.
+type fakeRunner struct {
+	commands []string
+	err      error
+	output   map[string]string
+}
+
+func (r *fakeRunner) Run(name string, arg ...string) error {
+	r.commands = append(r.commands, strings.Join(append([]string{name}, arg...), " "))
+	return r.err
+}
+
+func (r *fakeRunner) RunQuiet(name string, arg ...string) error {
+	return r.Run(name, arg...)
+}
+
+func (r *fakeRunner) Output(name string, arg ...string) ([]byte, error) {
+	cmdline := strings.Join(append([]string{name}, arg...), " ")
+	r.commands = append(r.commands, cmdline)
+	return []byte(r.output[cmdline]), r.err
+}
+
+func newTestManagerLinux(t *testing.T, runner *fakeRunner) (managerLinux, string) {
+	dir := filepath.Join(t.TempDir(), "systemd", "user")
+	m := managerLinux{
+		executable: func() (string, error) {
+			return "/usr/bin/ooniprobe", nil
+		},
+		runner: runner,
+		unitDir: func() (string, error) {
+			return dir, nil
+		},
+	}
+	return m, dir
+}
+
+func TestManagerLinuxStartStop(t *testing.T) {
+	runner := &fakeRunner{}
+	m, dir := newTestManagerLinux(t, runner)
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	service, err := os.ReadFile(filepath.Join(dir, "org.ooni.cli.service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(service), `ExecStart="/usr/bin/ooniprobe" --log-handler=syslog run unattended`) {
+		t.Fatal("unexpected service", string(service))
+	}
+	timer, err := os.ReadFile(filepath.Join(dir, "org.ooni.cli.timer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(timer), "OnUnitActiveSec=1h") {
+		t.Fatal("unexpected timer", string(timer))
+	}
+
+	if err := m.Start(); err == nil || err.Error() != "autorun: service already registered" {
+		t.Fatal("unexpected error", err)
+	}
+
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"org.ooni.cli.service", "org.ooni.cli.timer"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("unexpected error", err)
+		}
+	}
+
+	// stopping again should not invoke systemctl disable
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{
+		"systemctl --user daemon-reload",
+		"systemctl --user enable --now org.ooni.cli.timer",
+		"systemctl --user disable --now org.ooni.cli.timer",
+		"systemctl --user daemon-reload",
+		"systemctl --user daemon-reload",
+	}
+	if diff := cmp.Diff(expect, runner.commands); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestManagerLinuxStartWithSystemctlFailure(t *testing.T) {
+	expected := errors.New("mocked error")
+	m, _ := newTestManagerLinux(t, &fakeRunner{err: expected})
+	if err := m.Start(); !errors.Is(err, expected) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestManagerLinuxStatus(t *testing.T) {
+	const (
+		serviceCmd = "systemctl --user is-active org.ooni.cli.service"
+		timerCmd   = "systemctl --user is-active org.ooni.cli.timer"
+	)
+
+	type testcase struct {
+		name   string
+		output map[string]string
+		err    error
+		expect string
+		fails  bool
+	}
+
+	testcases := []testcase{{
+		name:   "when the service is running",
+		output: map[string]string{serviceCmd: "active\n", timerCmd: "active\n"},
+		expect: StatusRunning,
+	}, {
+		name:   "when the timer is scheduled",
+		output: map[string]string{serviceCmd: "inactive\n", timerCmd: "active\n"},
+		err:    errors.New("exit status 3"),
+		expect: StatusScheduled,
+	}, {
+		name:   "when nothing is running",
+		output: map[string]string{serviceCmd: "inactive\n", timerCmd: "inactive\n"},
+		err:    errors.New("exit status 3"),
+		expect: StatusStopped,
+	}, {
+		name:  "when systemctl fails without output",
+		err:   errors.New("exec: systemctl: not found"),
+		fails: true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _ := newTestManagerLinux(t, &fakeRunner{err: tc.err, output: tc.output})
+			status, err := m.Status()
+			if (err != nil) != tc.fails {
+				t.Fatal("unexpected error", err)
+			}
+			if status != tc.expect {
+				t.Fatal("expected", tc.expect, "got", status)
+			}
+		})
+	}
+}
+
+func TestManagerLinuxLogs(t *testing.T) {
+	runner := &fakeRunner{}
+	m, _ := newTestManagerLinux(t, runner)
+	if err := m.LogShow(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.LogStream(); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"journalctl --user --unit org.ooni.cli.service --no-pager",
+		"journalctl --user --unit org.ooni.cli.service --follow",
+	}
+	if diff := cmp.Diff(expect, runner.commands); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestGetLinux(t *testing.T) {
+	if Get("linux") == nil {
+		t.Fatal("expected a linux manager")
+	}
+}
@@ -0,0 +1,163 @@
+// Package export implements the export subcommand.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("export", "Export results and measurements to a file")
+	format := cmd.Flag("format", "Export format (one of: csv, jsonl, columnar)").Default(
+		"jsonl").Enum("csv", "jsonl", "columnar")
+	since := cmd.Flag("since", "Only export measurements started at or after this date (YYYY-MM-DD or RFC3339)").String()
+	until := cmd.Flag("until", "Only export measurements started before this date (YYYY-MM-DD or RFC3339)").String()
+	testGroup := cmd.Flag("test-group", "Only export measurements belonging to this test group").String()
+	network := cmd.Flag("network", "Only export measurements collected from this ASN (e.g., AS30722)").String()
+	output := cmd.Flag("output", "Write to this file rather than to the standard output").Short('o').Default("-").String()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		config := defaultconfig
+		config.Format = *format
+		config.Since = *since
+		config.Until = *until
+		config.TestGroup = *testGroup
+		config.Network = *network
+		config.Output = *output
+		return doexport(config)
+	})
+}
+
+// doexportconfig contains the config for [doexport].
+type doexportconfig struct {
+	// Format is the export format.
+	Format string
+
+	// Logger is the logger to use.
+	Logger log.Interface
+
+	// Network is the OPTIONAL ASN to filter for.
+	Network string
+
+	// NewProbeCLI is the factory to create a [ooni.ProbeCLI].
+	NewProbeCLI func() (ooni.ProbeCLI, error)
+
+	// Output is the output file where "-" means the standard output.
+	Output string
+
+	// Since is the OPTIONAL date since which to export.
+	Since string
+
+	// Stdout is the standard output.
+	Stdout io.Writer
+
+	// TestGroup is the OPTIONAL test group to filter for.
+	TestGroup string
+
+	// Until is the OPTIONAL date until which to export.
+	Until string
+}
+
+var defaultconfig = doexportconfig{
+	Format:      "jsonl",
+	Logger:      log.Log,
+	NewProbeCLI: root.NewProbeCLI,
+	Output:      "-",
+	Stdout:      os.Stdout,
+}
+
+func doexport(config doexportconfig) error {
+	filter, err := newFilter(&config)
+	if err != nil {
+		return err
+	}
+	probeCLI, err := config.NewProbeCLI()
+	if err != nil {
+		config.Logger.WithError(err).Error("failed to initialize root context")
+		return err
+	}
+	msmts, err := probeCLI.DB().ListMeasurementsWithFilter(filter)
+	if err != nil {
+		config.Logger.WithError(err).Error("failed to list measurements")
+		return err
+	}
+	writer := config.Stdout
+	if config.Output != "-" {
+		filep, err := os.Create(config.Output)
+		if err != nil {
+			config.Logger.WithError(err).Error("failed to create the output file")
+			return err
+		}
+		defer filep.Close()
+		writer = filep
+	}
+	if err := writeMeasurements(writer, config.Format, msmts); err != nil {
+		config.Logger.WithError(err).Error("failed to export measurements")
+		return err
+	}
+	if config.Output != "-" {
+		config.Logger.Infof("Exported %d measurements to %s", len(msmts), config.Output)
+	}
+	return nil
+}
+
+// ErrInvalidDate indicates that the user provided an invalid date.
+var ErrInvalidDate = errors.New("export: invalid date")
+
+// ErrInvalidNetwork indicates that the user provided an invalid network.
+var ErrInvalidNetwork = errors.New("export: invalid network")
+
+// newFilter creates a [*model.DatabaseMeasurementFilter] from the config.
+func newFilter(config *doexportconfig) (*model.DatabaseMeasurementFilter, error) {
+	filter := &model.DatabaseMeasurementFilter{
+		TestGroupName: config.TestGroup,
+	}
+	var err error
+	if filter.Since, err = parseDate(config.Since); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = parseDate(config.Until); err != nil {
+		return nil, err
+	}
+	if filter.ASN, err = parseNetwork(config.Network); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseDate parses either a YYYY-MM-DD date or a RFC3339 date. The
+// empty string maps to the zero [time.Time], i.e., no filtering.
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDate, value)
+}
+
+// parseNetwork parses an ASN using either the AS30722 or the 30722
+// notation. The empty string maps to zero, i.e., no filtering.
+func parseNetwork(value string) (uint, error) {
+	if value == "" {
+		return 0, nil
+	}
+	asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(value), "AS"), 10, 32)
+	if err != nil || asn == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidNetwork, value)
+	}
+	return uint(asn), nil
+}
@@ -0,0 +1,192 @@
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+	"github.com/ooni/probe-cli/v3/internal/database"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestNewFilter(t *testing.T) {
+	t.Run("with empty config", func(t *testing.T) {
+		filter, err := newFilter(&doexportconfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !filter.Since.IsZero() || !filter.Until.IsZero() || filter.ASN != 0 || filter.TestGroupName != "" {
+			t.Fatal("expected empty filter")
+		}
+	})
+
+	t.Run("with valid config", func(t *testing.T) {
+		filter, err := newFilter(&doexportconfig{
+			Network:   "as30722",
+			Since:     "2024-01-01",
+			TestGroup: "websites",
+			Until:     "2024-01-02T12:00:00+01:00",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !filter.Since.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Fatal("unexpected since", filter.Since)
+		}
+		if !filter.Until.Equal(time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC)) {
+			t.Fatal("unexpected until", filter.Until)
+		}
+		if filter.ASN != 30722 || filter.TestGroupName != "websites" {
+			t.Fatal("unexpected filter", filter)
+		}
+	})
+
+	t.Run("with invalid since", func(t *testing.T) {
+		_, err := newFilter(&doexportconfig{Since: "yesterday"})
+		if !errors.Is(err, ErrInvalidDate) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with invalid until", func(t *testing.T) {
+		_, err := newFilter(&doexportconfig{Until: "01/01/2024"})
+		if !errors.Is(err, ErrInvalidDate) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with invalid network", func(t *testing.T) {
+		for _, value := range []string{"Vodafone", "AS0", "AS-1"} {
+			_, err := newFilter(&doexportconfig{Network: value})
+			if !errors.Is(err, ErrInvalidNetwork) {
+				t.Fatal("unexpected error", err)
+			}
+		}
+	})
+}
+
+type locationInfo struct{}
+
+func (*locationInfo) ProbeASN() uint           { return 30722 }
+func (*locationInfo) ProbeASNString() string   { return "AS30722" }
+func (*locationInfo) ProbeCC() string          { return "IT" }
+func (*locationInfo) ProbeIP() string          { return "130.192.91.211" }
+func (*locationInfo) ProbeNetworkName() string { return "Vodafone Italia S.p.A." }
+func (*locationInfo) ResolverIP() string       { return "8.8.8.8" }
+
+var _ model.LocationProvider = &locationInfo{}
+
+func TestDoExport(t *testing.T) {
+	// newDatabase creates a database containing a single measurement
+	newDatabase := func(t *testing.T) *database.Database {
+		tmpdir := t.TempDir()
+		db, err := database.Open(filepath.Join(tmpdir, "main.sqlite3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		network, err := db.CreateNetwork(&locationInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := db.CreateResult(tmpdir, "websites", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.CreateMeasurement(sql.NullString{}, "web_connectivity", tmpdir, 0, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	t.Run("with invalid filter", func(t *testing.T) {
+		err := doexport(doexportconfig{Logger: log.Log, Since: "yesterday"})
+		if !errors.Is(err, ErrInvalidDate) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when NewProbeCLI fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		err := doexport(doexportconfig{
+			Logger: log.Log,
+			NewProbeCLI: func() (ooni.ProbeCLI, error) {
+				return nil, expected
+			},
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when writing to the standard output", func(t *testing.T) {
+		db := newDatabase(t)
+		defer db.Close()
+		stdout := &bytes.Buffer{}
+		err := doexport(doexportconfig{
+			Format: "jsonl",
+			Logger: log.Log,
+			NewProbeCLI: func() (ooni.ProbeCLI, error) {
+				return &oonitest.FakeProbeCLI{FakeDB: db}, nil
+			},
+			Output:    "-",
+			Stdout:    stdout,
+			TestGroup: "websites",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(stdout.String(), `"test_name":"web_connectivity"`) {
+			t.Fatal("unexpected output", stdout.String())
+		}
+	})
+
+	t.Run("when writing to a file", func(t *testing.T) {
+		db := newDatabase(t)
+		defer db.Close()
+		output := filepath.Join(t.TempDir(), "export.csv")
+		err := doexport(doexportconfig{
+			Format: "csv",
+			Logger: log.Log,
+			NewProbeCLI: func() (ooni.ProbeCLI, error) {
+				return &oonitest.FakeProbeCLI{FakeDB: db}, nil
+			},
+			Network: "AS3269", // we should not see any measurement
+			Output:  output,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 1 {
+			t.Fatal("expected just the header", lines)
+		}
+	})
+
+	t.Run("when we cannot create the output file", func(t *testing.T) {
+		db := newDatabase(t)
+		defer db.Close()
+		err := doexport(doexportconfig{
+			Format: "csv",
+			Logger: log.Log,
+			NewProbeCLI: func() (ooni.ProbeCLI, error) {
+				return &oonitest.FakeProbeCLI{FakeDB: db}, nil
+			},
+			Output: filepath.Join(t.TempDir(), "nonexistent", "export.csv"),
+		})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
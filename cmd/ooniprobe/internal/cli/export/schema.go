@@ -0,0 +1,211 @@
+package export
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// exportColumn is a column of the stable export schema.
+type exportColumn struct {
+	// Name is the column name.
+	Name string
+
+	// Type is the column type (one of "int64", "float64", "bool", "string", and "timestamp").
+	Type string
+
+	// Value returns the column value for the given measurement. A nil return
+	// value indicates that the value is NULL in the database.
+	Value func(msmt *model.DatabaseMeasurementURLNetwork) any
+}
+
+// exportSchema is the stable schema we use when exporting data. Each
+// row flattens together a measurement and the corresponding result, URL, and
+// network. We MUST only append new columns to this schema, such that the
+// existing notebooks processing the exported data keep working.
+//
+// Note that we intentionally do not export the probe IP address.
+var exportSchema = []exportColumn{{
+	Name: "result_id",
+	Type: "int64",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseResult.ID
+	},
+}, {
+	Name: "test_group_name",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseResult.TestGroupName
+	},
+}, {
+	Name: "result_start_time",
+	Type: "timestamp",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseResult.StartTime
+	},
+}, {
+	Name: "result_runtime",
+	Type: "float64",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseResult.Runtime
+	},
+}, {
+	Name: "result_is_done",
+	Type: "bool",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseResult.IsDone
+	},
+}, {
+	Name: "result_data_usage_up",
+	Type: "float64",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseResult.DataUsageUp
+	},
+}, {
+	Name: "result_data_usage_down",
+	Type: "float64",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseResult.DataUsageDown
+	},
+}, {
+	Name: "measurement_id",
+	Type: "int64",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.ID
+	},
+}, {
+	Name: "test_name",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.TestName
+	},
+}, {
+	Name: "measurement_start_time",
+	Type: "timestamp",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.StartTime
+	},
+}, {
+	Name: "measurement_runtime",
+	Type: "float64",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.Runtime
+	},
+}, {
+	Name: "measurement_is_done",
+	Type: "bool",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.IsDone
+	},
+}, {
+	Name: "measurement_is_failed",
+	Type: "bool",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.IsFailed
+	},
+}, {
+	Name: "measurement_failure_msg",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return nullString(msmt.DatabaseMeasurement.FailureMsg)
+	},
+}, {
+	Name: "measurement_is_uploaded",
+	Type: "bool",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.IsUploaded
+	},
+}, {
+	Name: "measurement_is_upload_failed",
+	Type: "bool",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.IsUploadFailed
+	},
+}, {
+	Name: "measurement_upload_failure_msg",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return nullString(msmt.DatabaseMeasurement.UploadFailureMsg)
+	},
+}, {
+	Name: "report_id",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return nullString(msmt.DatabaseMeasurement.ReportID)
+	},
+}, {
+	Name: "is_anomaly",
+	Type: "bool",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		if !msmt.DatabaseMeasurement.IsAnomaly.Valid {
+			return nil
+		}
+		return msmt.DatabaseMeasurement.IsAnomaly.Bool
+	},
+}, {
+	Name: "test_keys",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseMeasurement.TestKeys
+	},
+}, {
+	Name: "measurement_file_path",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return nullString(msmt.DatabaseMeasurement.MeasurementFilePath)
+	},
+}, {
+	Name: "url",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return nullString(msmt.DatabaseURL.URL)
+	},
+}, {
+	Name: "category_code",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return nullString(msmt.DatabaseURL.CategoryCode)
+	},
+}, {
+	Name: "url_country_code",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return nullString(msmt.DatabaseURL.CountryCode)
+	},
+}, {
+	Name: "network_name",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseNetwork.NetworkName
+	},
+}, {
+	Name: "network_type",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseNetwork.NetworkType
+	},
+}, {
+	Name: "asn",
+	Type: "int64",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return int64(msmt.DatabaseNetwork.ASN)
+	},
+}, {
+	Name: "network_country_code",
+	Type: "string",
+	Value: func(msmt *model.DatabaseMeasurementURLNetwork) any {
+		return msmt.DatabaseNetwork.CountryCode
+	},
+}}
+
+// nullString maps a NULL string to nil and a valid string to its value.
+func nullString(s sql.NullString) any {
+	if !s.Valid {
+		return nil
+	}
+	return s.String
+}
+
+// exportTimeFormat is the format we use for serializing timestamps.
+const exportTimeFormat = time.RFC3339Nano
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// ErrUnknownFormat indicates that the user requested an unknown export format.
+var ErrUnknownFormat = errors.New("export: unknown format")
+
+// writeMeasurements writes the given measurements to the given writer using
+// the given format (one of "csv", "jsonl", and "columnar").
+func writeMeasurements(w io.Writer, format string, msmts []model.DatabaseMeasurementURLNetwork) error {
+	switch format {
+	case "csv":
+		return writeCSV(w, msmts)
+	case "jsonl":
+		return writeJSONL(w, msmts)
+	case "columnar":
+		return writeColumnar(w, msmts)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// exportJSONValue converts a column value to the value we serialize using JSON.
+func exportJSONValue(value any) any {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC().Format(exportTimeFormat)
+	}
+	return value
+}
+
+// exportCSVValue converts a column value to a CSV field. We represent NULL
+// values using the empty string, which is what most CSV readers expect.
+func exportCSVValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(exportTimeFormat)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// writeCSV writes a CSV file with a header line containing the column names.
+func writeCSV(w io.Writer, msmts []model.DatabaseMeasurementURLNetwork) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, 0, len(exportSchema))
+	for _, column := range exportSchema {
+		header = append(header, column.Name)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for idx := range msmts {
+		record := make([]string, 0, len(exportSchema))
+		for _, column := range exportSchema {
+			record = append(record, exportCSVValue(column.Value(&msmts[idx])))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSONL writes a JSON object per line, where each object maps the column
+// names to the corresponding values and NULL values are mapped to null.
+func writeJSONL(w io.Writer, msmts []model.DatabaseMeasurementURLNetwork) error {
+	encoder := json.NewEncoder(w)
+	for idx := range msmts {
+		row := make(map[string]any, len(exportSchema))
+		for _, column := range exportSchema {
+			row[column.Name] = exportJSONValue(column.Value(&msmts[idx]))
+		}
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// columnarColumn is a column inside a [columnarFile].
+type columnarColumn struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Values []any  `json:"values"`
+}
+
+// columnarFile is the columnar file format. Like Parquet, we store all the
+// values of each column contiguously, which makes loading the data into data
+// frames straightforward (e.g., pandas.DataFrame({c["name"]: c["values"] ...})).
+type columnarFile struct {
+	Format  string           `json:"format"`
+	Version int64            `json:"version"`
+	NumRows int64            `json:"num_rows"`
+	Columns []columnarColumn `json:"columns"`
+}
+
+// columnarFormatName is the name identifying the columnar format.
+const columnarFormatName = "ooniprobe-columnar"
+
+// columnarFormatVersion is the current version of the columnar format.
+const columnarFormatVersion = 1
+
+// writeColumnar writes a single JSON document using the columnar layout.
+func writeColumnar(w io.Writer, msmts []model.DatabaseMeasurementURLNetwork) error {
+	out := &columnarFile{
+		Format:  columnarFormatName,
+		Version: columnarFormatVersion,
+		NumRows: int64(len(msmts)),
+		Columns: make([]columnarColumn, 0, len(exportSchema)),
+	}
+	for _, column := range exportSchema {
+		values := make([]any, 0, len(msmts))
+		for idx := range msmts {
+			values = append(values, exportJSONValue(column.Value(&msmts[idx])))
+		}
+		out.Columns = append(out.Columns, columnarColumn{
+			Name:   column.Name,
+			Type:   column.Type,
+			Values: values,
+		})
+	}
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,180 @@
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// newFakeMeasurements returns fake measurements for testing.
+func newFakeMeasurements() []model.DatabaseMeasurementURLNetwork {
+	startTime := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	return []model.DatabaseMeasurementURLNetwork{{
+		DatabaseMeasurement: model.DatabaseMeasurement{
+			ID:         1,
+			TestName:   "web_connectivity",
+			StartTime:  startTime,
+			Runtime:    1.5,
+			IsDone:     true,
+			IsUploaded: true,
+			ReportID:   sql.NullString{String: "20240101T100000Z_webconnectivity_IT_30722_n1_xx", Valid: true},
+			IsAnomaly:  sql.NullBool{Bool: true, Valid: true},
+			TestKeys:   `{"blocking":"dns","accessible":false}`,
+			ResultID:   1,
+		},
+		DatabaseNetwork: model.DatabaseNetwork{
+			NetworkName: "Vodafone Italia S.p.A.",
+			NetworkType: "wifi",
+			IP:          "130.192.91.211",
+			ASN:         30722,
+			CountryCode: "IT",
+		},
+		DatabaseResult: model.DatabaseResult{
+			ID:            1,
+			TestGroupName: "websites",
+			StartTime:     startTime,
+			IsDone:        true,
+		},
+		DatabaseURL: model.DatabaseURL{
+			URL:          sql.NullString{String: "https://www.example.com/", Valid: true},
+			CategoryCode: sql.NullString{String: "NEWS", Valid: true},
+			CountryCode:  sql.NullString{String: "IT", Valid: true},
+		},
+	}, {
+		DatabaseMeasurement: model.DatabaseMeasurement{
+			ID:         2,
+			TestName:   "signal",
+			StartTime:  startTime.Add(time.Minute),
+			IsFailed:   true,
+			FailureMsg: sql.NullString{String: "generic_timeout_error", Valid: true},
+			ResultID:   2,
+		},
+		DatabaseNetwork: model.DatabaseNetwork{
+			NetworkName: "Vodafone Italia S.p.A.",
+			NetworkType: "wifi",
+			ASN:         30722,
+			CountryCode: "IT",
+		},
+		DatabaseResult: model.DatabaseResult{
+			ID:            2,
+			TestGroupName: "im",
+			StartTime:     startTime.Add(time.Minute),
+		},
+	}}
+}
+
+func TestWriteMeasurements(t *testing.T) {
+	t.Run("with unknown format", func(t *testing.T) {
+		err := writeMeasurements(&bytes.Buffer{}, "parquet", newFakeMeasurements())
+		if !errors.Is(err, ErrUnknownFormat) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we never export the probe IP", func(t *testing.T) {
+		for _, format := range []string{"csv", "jsonl", "columnar"} {
+			buff := &bytes.Buffer{}
+			if err := writeMeasurements(buff, format, newFakeMeasurements()); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(buff.String(), "130.192.91.211") {
+				t.Fatal("the export contains the probe IP using", format)
+			}
+		}
+	})
+
+	t.Run("with csv", func(t *testing.T) {
+		buff := &bytes.Buffer{}
+		if err := writeMeasurements(buff, "csv", newFakeMeasurements()); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(buff).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 {
+			t.Fatal("expected header plus two records")
+		}
+		row := make(map[string]string)
+		for idx, name := range records[0] {
+			row[name] = records[1][idx]
+		}
+		expect := map[string]string{
+			"measurement_start_time": "2024-01-01T10:00:00Z",
+			"measurement_runtime":    "1.5",
+			"is_anomaly":             "true",
+			"url":                    "https://www.example.com/",
+			"asn":                    "30722",
+			"test_keys":              `{"blocking":"dns","accessible":false}`,
+		}
+		for key, value := range expect {
+			if row[key] != value {
+				t.Fatalf("expected %s for %s, got %s", value, key, row[key])
+			}
+		}
+		// NULL values should map to the empty string
+		if records[2][0] != "2" || records[2][len(records[2])-1] != "IT" {
+			t.Fatal("unexpected second record", records[2])
+		}
+	})
+
+	t.Run("with jsonl", func(t *testing.T) {
+		buff := &bytes.Buffer{}
+		if err := writeMeasurements(buff, "jsonl", newFakeMeasurements()); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatal("expected two lines")
+		}
+		var row map[string]any
+		if err := json.Unmarshal([]byte(lines[1]), &row); err != nil {
+			t.Fatal(err)
+		}
+		if len(row) != len(exportSchema) {
+			t.Fatal("unexpected number of columns")
+		}
+		if row["is_anomaly"] != nil || row["url"] != nil {
+			t.Fatal("expected NULL values to map to null")
+		}
+		if row["measurement_failure_msg"] != "generic_timeout_error" {
+			t.Fatal("unexpected measurement_failure_msg")
+		}
+	})
+
+	t.Run("with columnar", func(t *testing.T) {
+		buff := &bytes.Buffer{}
+		if err := writeMeasurements(buff, "columnar", newFakeMeasurements()); err != nil {
+			t.Fatal(err)
+		}
+		var out columnarFile
+		if err := json.Unmarshal(buff.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Format != columnarFormatName || out.Version != columnarFormatVersion || out.NumRows != 2 {
+			t.Fatal("unexpected columnar header")
+		}
+		var names []string
+		for _, column := range out.Columns {
+			if len(column.Values) != 2 {
+				t.Fatal("unexpected number of values for", column.Name)
+			}
+			names = append(names, column.Name)
+		}
+		var expect []string
+		for _, column := range exportSchema {
+			expect = append(expect, column.Name)
+		}
+		if diff := cmp.Diff(expect, names); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package root
 
 import (
+	"os"
+
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/log/handlers/batch"
@@ -8,6 +10,8 @@ import (
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/log/handlers/syslog"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/version"
 )
 
@@ -35,7 +39,7 @@ func init() {
 	isVerbose := Cmd.Flag("verbose", "Enable verbose log output.").Short('v').Bool()
 	isBatch := Cmd.Flag("batch", "Enable batch command line usage.").Bool()
 	logHandler := Cmd.Flag(
-		"log-handler", "Set the desired log handler (one of: batch, cli, syslog)",
+		"log-handler", "Set the desired log handler (one of: batch, cli, json, syslog)",
 	).String()
 
 	softwareName := Cmd.Flag(
@@ -44,6 +48,9 @@ func init() {
 	softwareVersion := Cmd.Flag(
 		"software-version", "Override the application version",
 	).Default(version.Version).String()
+	metricsListen := Cmd.Flag(
+		"metrics-listen", "Serve Prometheus metrics at http://ADDRESS/metrics (e.g., 127.0.0.1:9100)",
+	).String()
 	proxy := Cmd.Flag(
 		"proxy", "specify a proxy address for speaking to the OONI Probe backend (use: --proxy=psiphon:/// for psiphon)",
 	).String()
@@ -62,6 +69,8 @@ func init() {
 			log.SetHandler(batch.Default)
 		case "cli", "":
 			log.SetHandler(cli.Default)
+		case "json":
+			log.SetHandler(logx.NewJSONHandler(os.Stdout))
 		case "syslog":
 			log.SetHandler(syslog.Default)
 		default:
@@ -71,6 +80,14 @@ func init() {
 			log.SetLevel(log.DebugLevel)
 			log.Debugf("ooni version %s", version.Version)
 		}
+		if *metricsListen != "" {
+			// the server runs in the background until ooniprobe exits
+			srv, err := engine.StartMetricsServer(*metricsListen)
+			if err != nil {
+				log.WithError(err).Fatal("cannot start the metrics server")
+			}
+			log.Infof("serving metrics at http://%s/metrics", srv.Addr().String())
+		}
 
 		Init = func() (*ooni.Probe, error) {
 			var err error
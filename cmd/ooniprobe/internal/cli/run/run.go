@@ -1,6 +1,9 @@
 package run
 
 import (
+	"context"
+	"errors"
+
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/apex/log"
 	"github.com/fatih/color"
@@ -8,6 +11,7 @@ import (
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/nettests"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/uploader"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -16,6 +20,7 @@ func init() {
 	noCollector := cmd.Flag("no-collector", "Disable uploading measurements to a collector").Bool()
 
 	var probe *ooni.Probe
+	waitUploader := func() {}
 	cmd.Action(func(_ *kingpin.ParseContext) error {
 		var err error
 		probe, err = root.Init()
@@ -30,11 +35,20 @@ func init() {
 		if *noCollector {
 			probe.Config().Sharing.UploadResults = false
 		}
+		if probe.Config().Sharing.UploadResults {
+			waitUploader = startUploader(probe)
+		}
 		return nil
 	})
 
 	functionalRun := func(runType model.RunType, pred func(name string, gr nettests.Group) bool) error {
-		for name, group := range nettests.All {
+		defer waitUploader()
+		groups, err := nettests.AllGroups(probe.Config())
+		if err != nil {
+			log.WithError(err).Error("failed to load the nettest groups")
+			return err
+		}
+		for name, group := range groups {
 			if !pred(name, group) {
 				continue
 			}
@@ -63,6 +77,7 @@ func init() {
 	inputFile := websitesCmd.Flag("input-file", "File containing input URLs").Strings()
 	input := websitesCmd.Flag("input", "Test the specified URL").Strings()
 	websitesCmd.Action(func(_ *kingpin.ParseContext) error {
+		defer waitUploader()
 		log.Infof("Running %s tests", color.BlueString("websites"))
 		return nettests.RunGroup(nettests.RunGroupConfig{
 			GroupName:  "websites",
@@ -86,10 +101,59 @@ func init() {
 		})
 	})
 
+	// Note: we cannot register a command for each user-defined group because we
+	// read the config file after parsing the command line. Because kingpin passes
+	// unknown commands as arguments to the default command, `ooniprobe run <group>`
+	// ends up here with the group name as the argument.
 	allCmd := cmd.Command("all", "").Default()
+	groupName := allCmd.Arg("group", "Name of the built-in or user-defined test group to run").String()
 	allCmd.Action(func(_ *kingpin.ParseContext) error {
-		return functionalRun(model.RunTypeManual, func(name string, gr nettests.Group) bool {
-			return true
-		})
+		if *groupName == "" {
+			return functionalRun(model.RunTypeManual, func(name string, gr nettests.Group) bool {
+				return true
+			})
+		}
+		groups, err := nettests.AllGroups(probe.Config())
+		if err != nil {
+			log.WithError(err).Error("failed to load the nettest groups")
+			return err
+		}
+		if _, found := groups[*groupName]; !found {
+			log.Errorf("No test group named %s", *groupName)
+			return errors.New("invalid test group name")
+		}
+		return genRunWithGroupName(*groupName)(nil)
+	})
+}
+
+// startUploader starts draining the upload queue in the background and returns
+// a function that waits for the background uploader to finish. We use a separate
+// session such that uploading does not interfere with running nettests.
+func startUploader(probe *ooni.Probe) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	sess, err := probe.NewSession(ctx, model.RunTypeManual)
+	if err != nil {
+		log.WithError(err).Warn("failed to create the uploader session")
+		cancel()
+		return func() {}
+	}
+	u := uploader.Start(ctx, &uploader.Config{
+		Logger:       log.Log,
+		NewSubmitter: sess.NewSubmitter,
+		Queue:        probe.DB(),
 	})
+	return func() {
+		if probe.IsTerminated() {
+			cancel()
+		}
+		uploaded, err := u.Wait()
+		if err != nil {
+			log.WithError(err).Warn("failed to upload the queued measurements")
+		}
+		if uploaded > 0 {
+			log.Infof("Uploaded %d previously queued measurements", uploaded)
+		}
+		cancel()
+		sess.Close()
+	}
 }
@@ -0,0 +1,190 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// statsDimension is a dimension along which we aggregate measurements.
+type statsDimension struct {
+	// name is the name of the dimension.
+	name string
+
+	// title is the human readable title of the dimension.
+	title string
+
+	// keyOf returns the key of a measurement along this dimension
+	// or false if the measurement does not belong to this dimension.
+	keyOf func(msmt *model.DatabaseMeasurementURLNetwork) (string, bool)
+}
+
+// statsDimensions contains the dimensions we aggregate measurements along.
+var statsDimensions = []statsDimension{{
+	name:  "network",
+	title: "Trends per network",
+	keyOf: func(msmt *model.DatabaseMeasurementURLNetwork) (string, bool) {
+		return fmt.Sprintf("AS%d", msmt.DatabaseNetwork.ASN), true
+	},
+}, {
+	name:  "test_group",
+	title: "Trends per test group",
+	keyOf: func(msmt *model.DatabaseMeasurementURLNetwork) (string, bool) {
+		return msmt.DatabaseResult.TestGroupName, true
+	},
+}, {
+	name:  "url_category",
+	title: "Trends per URL category",
+	keyOf: func(msmt *model.DatabaseMeasurementURLNetwork) (string, bool) {
+		category := msmt.DatabaseURL.CategoryCode
+		return category.String, category.Valid && category.String != ""
+	},
+}}
+
+// statsReport is the result of aggregating measurements.
+type statsReport struct {
+	// Trends maps each dimension name to its trend items sorted by key and bucket.
+	Trends map[string][]output.StatsTrendItemData
+
+	// TopURLs contains the most frequently anomalous URLs.
+	TopURLs []output.StatsURLItemData
+
+	// Summary contains the totals.
+	Summary output.StatsSummaryData
+}
+
+// bucketStart returns the start of the bucket containing t, where bucket
+// is one of "day", "week" (starting on Monday), or "month".
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -offset)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// aggregate aggregates the given measurements using the given time bucket and
+// returns at most top URLs in [statsReport.TopURLs].
+func aggregate(msmts []model.DatabaseMeasurementURLNetwork, bucket string, top int) *statsReport {
+	type trendKey struct {
+		key    string
+		bucket time.Time
+	}
+	trends := make(map[string]map[trendKey]*output.StatsTrendItemData)
+	urls := make(map[string]*output.StatsURLItemData)
+	results := make(map[int64]bool)
+	networks := make(map[int64]bool)
+	report := &statsReport{
+		Trends: make(map[string][]output.StatsTrendItemData),
+	}
+
+	for idx := range msmts {
+		msmt := &msmts[idx]
+		isAnomaly := msmt.IsAnomaly.Valid && msmt.IsAnomaly.Bool
+		isFailed := msmt.IsFailed
+		start := bucketStart(msmt.DatabaseMeasurement.StartTime, bucket)
+
+		for _, dimension := range statsDimensions {
+			key, good := dimension.keyOf(msmt)
+			if !good {
+				continue
+			}
+			if trends[dimension.name] == nil {
+				trends[dimension.name] = make(map[trendKey]*output.StatsTrendItemData)
+			}
+			tk := trendKey{key: key, bucket: start}
+			item := trends[dimension.name][tk]
+			if item == nil {
+				item = &output.StatsTrendItemData{
+					Dimension: dimension.name,
+					Key:       key,
+					Bucket:    start,
+				}
+				trends[dimension.name][tk] = item
+			}
+			item.TotalCount++
+			if isAnomaly {
+				item.AnomalyCount++
+			}
+			if isFailed {
+				item.FailureCount++
+			}
+		}
+
+		if msmt.DatabaseURL.URL.Valid {
+			entry := urls[msmt.DatabaseURL.URL.String]
+			if entry == nil {
+				entry = &output.StatsURLItemData{
+					URL:          msmt.DatabaseURL.URL.String,
+					CategoryCode: msmt.DatabaseURL.CategoryCode.String,
+				}
+				urls[msmt.DatabaseURL.URL.String] = entry
+			}
+			entry.TotalCount++
+			if isAnomaly {
+				entry.AnomalyCount++
+			}
+		}
+
+		report.Summary.TotalMeasurements++
+		if isAnomaly {
+			report.Summary.TotalAnomalies++
+		}
+		if isFailed {
+			report.Summary.TotalFailures++
+		}
+		networks[msmt.DatabaseNetwork.ID] = true
+
+		// data usage is accounted per result, so count each result once
+		if !results[msmt.DatabaseResult.ID] {
+			results[msmt.DatabaseResult.ID] = true
+			report.Summary.TotalDataUsageUp += msmt.DatabaseResult.DataUsageUp
+			report.Summary.TotalDataUsageDown += msmt.DatabaseResult.DataUsageDown
+		}
+	}
+	report.Summary.TotalResults = int64(len(results))
+	report.Summary.TotalNetworks = int64(len(networks))
+
+	for name, entries := range trends {
+		items := make([]output.StatsTrendItemData, 0, len(entries))
+		for _, item := range entries {
+			items = append(items, *item)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Key != items[j].Key {
+				return items[i].Key < items[j].Key
+			}
+			return items[i].Bucket.Before(items[j].Bucket)
+		})
+		report.Trends[name] = items
+	}
+
+	for _, entry := range urls {
+		if entry.AnomalyCount > 0 {
+			report.TopURLs = append(report.TopURLs, *entry)
+		}
+	}
+	sort.SliceStable(report.TopURLs, func(i, j int) bool {
+		left, right := report.TopURLs[i], report.TopURLs[j]
+		if left.AnomalyCount != right.AnomalyCount {
+			return left.AnomalyCount > right.AnomalyCount
+		}
+		if left.TotalCount != right.TotalCount {
+			return left.TotalCount > right.TotalCount
+		}
+		return left.URL < right.URL
+	})
+	if top >= 0 && len(report.TopURLs) > top {
+		report.TopURLs = report.TopURLs[:top]
+	}
+	return report
+}
@@ -0,0 +1,174 @@
+package stats
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestBucketStart(t *testing.T) {
+	// Wednesday, 2024-01-17 at 15:04:05 in UTC+1
+	t0 := time.Date(2024, 1, 17, 15, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	type testcase struct {
+		bucket string
+		expect time.Time
+	}
+
+	testcases := []testcase{{
+		bucket: "day",
+		expect: time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC),
+	}, {
+		bucket: "week",
+		expect: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	}, {
+		bucket: "month",
+		expect: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.bucket, func(t *testing.T) {
+			if got := bucketStart(t0, tc.bucket); !got.Equal(tc.expect) {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+
+	t.Run("on a Sunday the week starts on the previous Monday", func(t *testing.T) {
+		sunday := time.Date(2024, 1, 21, 23, 0, 0, 0, time.UTC)
+		expect := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+		if got := bucketStart(sunday, "week"); !got.Equal(expect) {
+			t.Fatal("expected", expect, "got", got)
+		}
+	})
+}
+
+// newMeasurement creates a measurement for testing [aggregate].
+func newMeasurement(resultID int64, asn uint, group string, start time.Time,
+	URL, category string, anomaly, failed bool) model.DatabaseMeasurementURLNetwork {
+	msmt := model.DatabaseMeasurementURLNetwork{}
+	msmt.DatabaseMeasurement.StartTime = start
+	msmt.DatabaseMeasurement.IsAnomaly = sql.NullBool{Bool: anomaly, Valid: true}
+	msmt.DatabaseMeasurement.IsFailed = failed
+	msmt.DatabaseNetwork.ID = int64(asn)
+	msmt.DatabaseNetwork.ASN = asn
+	msmt.DatabaseResult.ID = resultID
+	msmt.DatabaseResult.TestGroupName = group
+	msmt.DatabaseResult.DataUsageUp = 1
+	msmt.DatabaseResult.DataUsageDown = 10
+	if URL != "" {
+		msmt.DatabaseURL.URL = sql.NullString{String: URL, Valid: true}
+		msmt.DatabaseURL.CategoryCode = sql.NullString{String: category, Valid: true}
+	}
+	return msmt
+}
+
+func TestAggregate(t *testing.T) {
+	day1 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	msmts := []model.DatabaseMeasurementURLNetwork{
+		newMeasurement(1, 30722, "websites", day1, "https://a.example/", "NEWS", true, false),
+		newMeasurement(1, 30722, "websites", day1, "https://b.example/", "NEWS", false, false),
+		newMeasurement(1, 30722, "websites", day1, "https://c.example/", "GMB", true, false),
+		newMeasurement(2, 3269, "websites", day2, "https://a.example/", "NEWS", true, false),
+		newMeasurement(3, 3269, "performance", day2, "", "", false, true),
+	}
+
+	t.Run("with day buckets", func(t *testing.T) {
+		report := aggregate(msmts, "day", 10)
+
+		expectNetworks := []output.StatsTrendItemData{{
+			Dimension: "network", Key: "AS30722", Bucket: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalCount: 3, AnomalyCount: 2,
+		}, {
+			Dimension: "network", Key: "AS3269", Bucket: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			TotalCount: 2, AnomalyCount: 1, FailureCount: 1,
+		}}
+		if diff := cmp.Diff(expectNetworks, report.Trends["network"]); diff != "" {
+			t.Fatal(diff)
+		}
+
+		expectGroups := []output.StatsTrendItemData{{
+			Dimension: "test_group", Key: "performance", Bucket: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			TotalCount: 1, FailureCount: 1,
+		}, {
+			Dimension: "test_group", Key: "websites", Bucket: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalCount: 3, AnomalyCount: 2,
+		}, {
+			Dimension: "test_group", Key: "websites", Bucket: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			TotalCount: 1, AnomalyCount: 1,
+		}}
+		if diff := cmp.Diff(expectGroups, report.Trends["test_group"]); diff != "" {
+			t.Fatal(diff)
+		}
+
+		expectCategories := []output.StatsTrendItemData{{
+			Dimension: "url_category", Key: "GMB", Bucket: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalCount: 1, AnomalyCount: 1,
+		}, {
+			Dimension: "url_category", Key: "NEWS", Bucket: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			TotalCount: 2, AnomalyCount: 1,
+		}, {
+			Dimension: "url_category", Key: "NEWS", Bucket: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			TotalCount: 1, AnomalyCount: 1,
+		}}
+		if diff := cmp.Diff(expectCategories, report.Trends["url_category"]); diff != "" {
+			t.Fatal(diff)
+		}
+
+		expectURLs := []output.StatsURLItemData{{
+			URL: "https://a.example/", CategoryCode: "NEWS", TotalCount: 2, AnomalyCount: 2,
+		}, {
+			URL: "https://c.example/", CategoryCode: "GMB", TotalCount: 1, AnomalyCount: 1,
+		}}
+		if diff := cmp.Diff(expectURLs, report.TopURLs); diff != "" {
+			t.Fatal(diff)
+		}
+
+		expectSummary := output.StatsSummaryData{
+			TotalMeasurements:  5,
+			TotalAnomalies:     3,
+			TotalFailures:      1,
+			TotalResults:       3,
+			TotalNetworks:      2,
+			TotalDataUsageUp:   3,
+			TotalDataUsageDown: 30,
+		}
+		if diff := cmp.Diff(expectSummary, report.Summary); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with week buckets", func(t *testing.T) {
+		report := aggregate(msmts, "week", 10)
+		groups := report.Trends["test_group"]
+		if len(groups) != 2 {
+			t.Fatal("expected two test group items", groups)
+		}
+		if groups[1].Key != "websites" || groups[1].TotalCount != 4 || groups[1].AnomalyCount != 3 {
+			t.Fatal("unexpected websites item", groups[1])
+		}
+	})
+
+	t.Run("we limit the number of URLs", func(t *testing.T) {
+		report := aggregate(msmts, "day", 1)
+		if len(report.TopURLs) != 1 || report.TopURLs[0].URL != "https://a.example/" {
+			t.Fatal("unexpected top URLs", report.TopURLs)
+		}
+	})
+
+	t.Run("with no measurements", func(t *testing.T) {
+		report := aggregate(nil, "day", 10)
+		if len(report.Trends) != 0 || len(report.TopURLs) != 0 {
+			t.Fatal("expected empty report")
+		}
+		if report.Summary.TotalMeasurements != 0 {
+			t.Fatal("expected zero measurements")
+		}
+	})
+}
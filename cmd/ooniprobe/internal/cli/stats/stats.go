@@ -0,0 +1,118 @@
+// Package stats implements the stats subcommand.
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("stats", "Show anomaly and failure trends computed from the local results")
+	bucket := cmd.Flag("bucket", "Time bucket used to group measurements (one of: day, week, month)").Default(
+		"week").Enum("day", "week", "month")
+	since := cmd.Flag("since", "Only consider measurements started at or after this date (YYYY-MM-DD or RFC3339)").String()
+	until := cmd.Flag("until", "Only consider measurements started before this date (YYYY-MM-DD or RFC3339)").String()
+	top := cmd.Flag("top", "Number of most frequently anomalous URLs to show").Default("10").Int()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		config := defaultconfig
+		config.Bucket = *bucket
+		config.Since = *since
+		config.Until = *until
+		config.Top = *top
+		return dostats(config)
+	})
+}
+
+// dostatsconfig contains the config for [dostats].
+type dostatsconfig struct {
+	// Bucket is the time bucket (one of "day", "week", "month").
+	Bucket string
+
+	// Logger is the logger to use.
+	Logger log.Interface
+
+	// NewProbeCLI is the factory to create a [ooni.ProbeCLI].
+	NewProbeCLI func() (ooni.ProbeCLI, error)
+
+	// Since is the OPTIONAL date since which to compute stats.
+	Since string
+
+	// Top is the number of most frequently anomalous URLs to show.
+	Top int
+
+	// Until is the OPTIONAL date until which to compute stats.
+	Until string
+}
+
+var defaultconfig = dostatsconfig{
+	Bucket:      "week",
+	Logger:      log.Log,
+	NewProbeCLI: root.NewProbeCLI,
+	Top:         10,
+}
+
+// ErrInvalidDate indicates that the user provided an invalid date.
+var ErrInvalidDate = errors.New("stats: invalid date")
+
+func dostats(config dostatsconfig) error {
+	filter := &model.DatabaseMeasurementFilter{}
+	var err error
+	if filter.Since, err = parseDate(config.Since); err != nil {
+		return err
+	}
+	if filter.Until, err = parseDate(config.Until); err != nil {
+		return err
+	}
+	probeCLI, err := config.NewProbeCLI()
+	if err != nil {
+		config.Logger.WithError(err).Error("failed to initialize root context")
+		return err
+	}
+	msmts, err := probeCLI.DB().ListMeasurementsWithFilter(filter)
+	if err != nil {
+		config.Logger.WithError(err).Error("failed to list measurements")
+		return err
+	}
+	report := aggregate(msmts, config.Bucket, config.Top)
+	for _, dimension := range statsDimensions {
+		items := report.Trends[dimension.name]
+		if len(items) == 0 {
+			continue
+		}
+		output.SectionTitle(dimension.title)
+		for _, item := range items {
+			output.StatsTrendItem(item)
+		}
+	}
+	if len(report.TopURLs) > 0 {
+		output.SectionTitle("Most anomalous URLs")
+		for _, item := range report.TopURLs {
+			output.StatsURLItem(item)
+		}
+	}
+	output.SectionTitle("Summary")
+	output.StatsSummary(report.Summary)
+	return nil
+}
+
+// parseDate parses either a YYYY-MM-DD date or a RFC3339 date. The
+// empty string maps to the zero [time.Time], i.e., no filtering.
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDate, value)
+}
@@ -0,0 +1,134 @@
+package stats
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+	"github.com/ooni/probe-cli/v3/internal/database"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+type locationInfo struct{}
+
+func (*locationInfo) ProbeASN() uint           { return 30722 }
+func (*locationInfo) ProbeASNString() string   { return "AS30722" }
+func (*locationInfo) ProbeCC() string          { return "IT" }
+func (*locationInfo) ProbeIP() string          { return "130.192.91.211" }
+func (*locationInfo) ProbeNetworkName() string { return "Vodafone Italia S.p.A." }
+func (*locationInfo) ResolverIP() string       { return "8.8.8.8" }
+
+var _ model.LocationProvider = &locationInfo{}
+
+type summaryKeys struct {
+	IsAnomaly bool `json:"-"`
+}
+
+func (sk *summaryKeys) Anomaly() bool {
+	return sk.IsAnomaly
+}
+
+var _ model.MeasurementSummaryKeys = &summaryKeys{}
+
+func TestDoStats(t *testing.T) {
+	// newDatabase creates a database containing a single anomalous measurement
+	newDatabase := func(t *testing.T) *database.Database {
+		tmpdir := t.TempDir()
+		db, err := database.Open(filepath.Join(tmpdir, "main.sqlite3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		network, err := db.CreateNetwork(&locationInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := db.CreateResult(tmpdir, "websites", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urlID, err := db.CreateOrUpdateURL("https://www.example.com/", "NEWS", "IT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		msmt, err := db.CreateMeasurement(sql.NullString{}, "web_connectivity", tmpdir, 0,
+			result.ID, sql.NullInt64{Int64: urlID, Valid: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.AddTestKeys(msmt, &summaryKeys{IsAnomaly: true}); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	t.Run("with invalid since", func(t *testing.T) {
+		err := dostats(dostatsconfig{Logger: log.Log, Since: "yesterday"})
+		if !errors.Is(err, ErrInvalidDate) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with invalid until", func(t *testing.T) {
+		err := dostats(dostatsconfig{Logger: log.Log, Until: "01/01/2024"})
+		if !errors.Is(err, ErrInvalidDate) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when NewProbeCLI fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		err := dostats(dostatsconfig{
+			Logger: log.Log,
+			NewProbeCLI: func() (ooni.ProbeCLI, error) {
+				return nil, expected
+			},
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("on success", func(t *testing.T) {
+		db := newDatabase(t)
+		defer db.Close()
+		logger := log.Log.(*log.Logger)
+		savedHandler := logger.Handler
+		handler := memory.New()
+		logger.Handler = handler
+		defer func() {
+			logger.Handler = savedHandler
+		}()
+		err := dostats(dostatsconfig{
+			Bucket: "day",
+			Logger: log.Log,
+			NewProbeCLI: func() (ooni.ProbeCLI, error) {
+				return &oonitest.FakeProbeCLI{FakeDB: db}, nil
+			},
+			Since: "2000-01-01",
+			Top:   10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		types := make(map[string]int)
+		for _, entry := range handler.Entries {
+			if value, good := entry.Fields.Get("type").(string); good {
+				types[value]++
+			}
+		}
+		if types["stats_trend_item"] != 3 {
+			t.Fatal("unexpected number of trend items", types)
+		}
+		if types["stats_url_item"] != 1 {
+			t.Fatal("unexpected number of URL items", types)
+		}
+		if types["stats_summary"] != 1 {
+			t.Fatal("unexpected number of summaries", types)
+		}
+	})
+}
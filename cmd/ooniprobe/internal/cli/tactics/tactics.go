@@ -0,0 +1,80 @@
+package tactics
+
+import (
+	"io"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("tactics", "Manage the statistics about the tactics used to reach the OONI backend")
+
+	dumpCmd := cmd.Command("dump", "Write the tactics statistics to the standard output")
+	dumpCmd.Action(func(_ *kingpin.ParseContext) error {
+		kvStore, err := newKVStore()
+		if err != nil {
+			return err
+		}
+		return dump(kvStore, os.Stdout)
+	})
+
+	pruneCmd := cmd.Command("prune", "Remove old and excess entries from the tactics statistics")
+	pruneCmd.Action(func(_ *kingpin.ParseContext) error {
+		kvStore, err := newKVStore()
+		if err != nil {
+			return err
+		}
+		return enginenetx.PruneStats(kvStore)
+	})
+
+	importCmd := cmd.Command("import", "Merge the tactics statistics generated using dump into the local ones")
+	filename := importCmd.Arg("file", "File containing the tactics statistics to import").Required().String()
+	importCmd.Action(func(_ *kingpin.ParseContext) error {
+		kvStore, err := newKVStore()
+		if err != nil {
+			return err
+		}
+		if err := importFile(kvStore, *filename); err != nil {
+			log.WithError(err).Error("failed to import the tactics statistics")
+			return err
+		}
+		log.Infof("Imported tactics statistics from %s", *filename)
+		return nil
+	})
+}
+
+// newKVStore returns the key-value store used by the engine.
+func newKVStore() (model.KeyValueStore, error) {
+	probe, err := root.Init()
+	if err != nil {
+		log.WithError(err).Error("failed to initialize root context")
+		return nil, err
+	}
+	return kvstore.NewFS(utils.EngineDir(probe.Home()))
+}
+
+// dump writes the tactics statistics stored in kvStore to w.
+func dump(kvStore model.KeyValueStore, w io.Writer) error {
+	data, err := enginenetx.DumpStats(kvStore)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// importFile merges the tactics statistics in filename into the ones stored in kvStore.
+func importFile(kvStore model.KeyValueStore, filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return enginenetx.ImportStats(kvStore, data)
+}
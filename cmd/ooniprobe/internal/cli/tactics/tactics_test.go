@@ -0,0 +1,47 @@
+package tactics
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+)
+
+func TestDumpAndImport(t *testing.T) {
+	// dump the empty stats of a probe
+	source := &kvstore.Memory{}
+	output := &bytes.Buffer{}
+	if err := dump(source, output); err != nil {
+		t.Fatal(err)
+	}
+
+	// import them into another probe
+	filename := filepath.Join(t.TempDir(), "tactics.json")
+	if err := os.WriteFile(filename, output.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dest := &kvstore.Memory{}
+	if err := importFile(dest, filename); err != nil {
+		t.Fatal(err)
+	}
+
+	// make sure that dumping again produces the same output
+	again := &bytes.Buffer{}
+	if err := dump(dest, again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output.Bytes(), again.Bytes()) {
+		t.Fatal("expected", output.String(), "got", again.String())
+	}
+}
+
+func TestImportFileNonexistent(t *testing.T) {
+	err := importFile(&kvstore.Memory{}, filepath.Join(t.TempDir(), "nonexistent.json"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("unexpected error", err)
+	}
+}
@@ -97,3 +97,21 @@ func TestUpdateConfig(t *testing.T) {
 		t.Fatal("the config was migrated again")
 	}
 }
+
+func TestParseConfigWithGroups(t *testing.T) {
+	config, err := ReadConfig("testdata/groups-config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, found := config.Nettests.Groups["regional-im"]
+	if !found {
+		t.Fatal("expected to find the regional-im group")
+	}
+	if group.Label != "Regional Instant Messaging" || !group.UnattendedOK || len(group.Nettests) != 3 {
+		t.Fatal("unexpected group", group)
+	}
+	urlgetter := group.Nettests[2]
+	if urlgetter.TestName != "urlgetter" || len(urlgetter.Inputs) != 1 || urlgetter.Options["HTTPHost"] != "www.example.org" {
+		t.Fatal("unexpected nettest", urlgetter)
+	}
+}
@@ -13,4 +13,22 @@ type Nettests struct {
 	WebsitesMaxRuntime           int64    `json:"websites_max_runtime"`
 	WebsitesURLLimit             int64    `json:"websites_url_limit"`
 	WebsitesEnabledCategoryCodes []string `json:"websites_enabled_category_codes"`
+
+	// Groups contains user-defined nettest groups indexed by name.
+	Groups map[string]NettestGroup `json:"groups,omitempty"`
+}
+
+// NettestGroup is a user-defined nettest group
+type NettestGroup struct {
+	Label        string                `json:"label"`
+	Nettests     []NettestGroupNettest `json:"nettests"`
+	UnattendedOK bool                  `json:"unattended_ok"`
+}
+
+// NettestGroupNettest is a nettest inside a user-defined nettest group
+type NettestGroupNettest struct {
+	TestName   string         `json:"test_name"`
+	InputFiles []string       `json:"input_files,omitempty"`
+	Inputs     []string       `json:"inputs,omitempty"`
+	Options    map[string]any `json:"options,omitempty"`
 }
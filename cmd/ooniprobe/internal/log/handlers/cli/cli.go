@@ -12,6 +12,7 @@ import (
 	"github.com/fatih/color"
 	colorable "github.com/mattn/go-colorable"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/logx"
 )
 
 // Default handler outputting to stdout.
@@ -126,6 +127,8 @@ func (h *Handler) TypedLog(t string, e *log.Entry) error {
 		return logMeasurementItem(h.Writer, e.Fields)
 	case "measurement_json":
 		return logMeasurementJSON(h.Writer, e.Fields)
+	case logx.TypeMeasurementStart:
+		return nil // only meaningful for structured log handlers
 	case "measurement_summary":
 		return logMeasurementSummary(h.Writer, e.Fields)
 	case "result_item":
@@ -134,6 +137,12 @@ func (h *Handler) TypedLog(t string, e *log.Entry) error {
 		return logResultSummary(h.Writer, e.Fields)
 	case "section_title":
 		return logSectionTitle(h.Writer, e.Fields)
+	case "stats_summary":
+		return logStatsSummary(h.Writer, e.Fields)
+	case "stats_trend_item":
+		return logStatsTrendItem(h.Writer, e.Fields)
+	case "stats_url_item":
+		return logStatsURLItem(h.Writer, e.Fields)
 	default:
 		return h.DefaultLog(e)
 	}
@@ -147,7 +156,7 @@ func (h *Handler) DefaultLog(e *log.Entry) error {
 
 	s := color.Sprintf("%s %-25s", bold.Sprintf("%*s", h.Padding+1, level), e.Message)
 	for _, name := range names {
-		if name == "source" {
+		if name == "source" || logx.IsStructuredField(name) {
 			continue
 		}
 		s += fmt.Sprintf(" %s=%v", color.Sprint(name), e.Fields.Get(name))
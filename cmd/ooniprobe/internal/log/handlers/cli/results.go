@@ -85,8 +85,21 @@ var summarizers = map[string]func(uint64, uint64, string) []string{
 	},
 }
 
+// defaultSummarizer is the summarizer for user-defined groups.
+func defaultSummarizer(totalCount uint64, anomalyCount uint64, ss string) []string {
+	return []string{
+		fmt.Sprintf("%d tested", totalCount),
+		fmt.Sprintf("%d blocked", anomalyCount),
+		"",
+	}
+}
+
 func makeSummary(name string, totalCount uint64, anomalyCount uint64, ss string) []string {
-	return summarizers[name](totalCount, anomalyCount, ss)
+	summarizer, found := summarizers[name]
+	if !found {
+		summarizer = defaultSummarizer
+	}
+	return summarizer(totalCount, anomalyCount, ss)
 }
 
 func logResultItem(w io.Writer, f log.Fields) error {
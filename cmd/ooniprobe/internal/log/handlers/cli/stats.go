@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+)
+
+func logStatsTrendItem(w io.Writer, f log.Fields) error {
+	bucket := f.Get("bucket").(time.Time)
+	key := f.Get("key").(string)
+	totalCount := f.Get("total_count").(int64)
+	anomalyCount := f.Get("anomaly_count").(int64)
+	failureCount := f.Get("failure_count").(int64)
+	anomalyRate := f.Get("anomaly_rate").(float64)
+	failureRate := f.Get("failure_rate").(float64)
+	fmt.Fprintf(w, " %s │ %s │ %s │ %s │ %s\n",
+		bucket.Format("2006-01-02"),
+		utils.RightPad(key, 24),
+		utils.RightPad(fmt.Sprintf("%d tested", totalCount), 12),
+		utils.RightPad(fmt.Sprintf("%d anomalies (%.1f%%)", anomalyCount, anomalyRate*100), 22),
+		fmt.Sprintf("%d failed (%.1f%%)", failureCount, failureRate*100))
+	return nil
+}
+
+func logStatsURLItem(w io.Writer, f log.Fields) error {
+	URL := f.Get("url").(string)
+	categoryCode := f.Get("category_code").(string)
+	totalCount := f.Get("total_count").(int64)
+	anomalyCount := f.Get("anomaly_count").(int64)
+	fmt.Fprintf(w, " %s │ %s │ %s\n",
+		utils.RightPad(fmt.Sprintf("%d/%d anomalous", anomalyCount, totalCount), 16),
+		utils.RightPad(categoryCode, 6),
+		URL)
+	return nil
+}
+
+func logStatsSummary(w io.Writer, f log.Fields) error {
+	measurements := f.Get("total_measurements").(int64)
+	anomalies := f.Get("total_anomalies").(int64)
+	failures := f.Get("total_failures").(int64)
+	results := f.Get("total_results").(int64)
+	networks := f.Get("total_networks").(int64)
+	dataUp := f.Get("total_data_usage_up").(float64)
+	dataDown := f.Get("total_data_usage_down").(float64)
+	if measurements == 0 {
+		fmt.Fprintf(w, "No measurements\n")
+		fmt.Fprintf(w, "Try running:\n")
+		fmt.Fprintf(w, "  ooniprobe run websites\n")
+		return nil
+	}
+	fmt.Fprintf(w, " %d measurements in %d results from %d networks\n", measurements, results, networks)
+	fmt.Fprintf(w, " %d anomalies, %d failures\n", anomalies, failures)
+	fmt.Fprintf(w, " ⬆ %s  ⬇ %s\n", formatSize(dataUp), formatSize(dataDown))
+	return nil
+}
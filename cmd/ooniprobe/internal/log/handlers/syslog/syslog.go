@@ -9,6 +9,7 @@ import (
 	"unsafe"
 
 	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/logx"
 )
 
 /*
@@ -34,7 +35,16 @@ func newhandler() handler {
 }
 
 func (h handler) HandleLog(e *log.Entry) error {
-	message := fmt.Sprintf("%s %+v", e.Message, e.Fields)
+	if logx.IsMeasurementStart(e.Fields) {
+		return nil // only meaningful for structured log handlers
+	}
+	fields := log.Fields{}
+	for name, value := range e.Fields {
+		if !logx.IsStructuredField(name) {
+			fields[name] = value
+		}
+	}
+	message := fmt.Sprintf("%s %+v", e.Message, fields)
 	cstr := C.CString(message)
 	defer C.free(unsafe.Pointer(cstr))
 	switch e.Level {
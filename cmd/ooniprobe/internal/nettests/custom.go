@@ -0,0 +1,68 @@
+package nettests
+
+import (
+	"context"
+
+	engine "github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Custom is a nettest defined by the user inside the config file
+// as part of a user-defined nettest group.
+type Custom struct {
+	// TestName is the name of the experiment to run.
+	TestName string
+
+	// InputFiles contains files from which to read inputs.
+	InputFiles []string
+
+	// Inputs contains the inputs to measure.
+	Inputs []string
+
+	// Options contains the experiment options.
+	Options map[string]any
+}
+
+func (n Custom) lookupURLs(ctl *Controller, builder model.ExperimentBuilder) ([]model.OOAPIURLInfo, error) {
+	inputloader := &engine.InputLoader{
+		CheckInConfig: &model.OOAPICheckInConfig{
+			// Setting Charging and OnWiFi to true causes the CheckIn
+			// API to return to us as much URL as possible with the
+			// given RunType hint.
+			Charging: true,
+			OnWiFi:   true,
+			RunType:  ctl.RunType,
+		},
+		ExperimentName: n.TestName,
+		InputPolicy:    builder.InputPolicy(),
+		Session:        ctl.Session,
+		SourceFiles:    n.InputFiles,
+		StaticInputs:   n.Inputs,
+	}
+	return inputloader.Load(context.Background())
+}
+
+// Run starts the nettest.
+func (n Custom) Run(ctl *Controller) error {
+	builder, err := ctl.Session.NewExperimentBuilder(n.TestName)
+	if err != nil {
+		return err
+	}
+	if err := builder.SetOptionsAny(n.Options); err != nil {
+		return err
+	}
+	testlist, err := n.lookupURLs(ctl, builder)
+	if err != nil {
+		return err
+	}
+	// Like the nettests that do not take any input, avoid registering
+	// an empty URL when the experiment is going to run without input.
+	if len(testlist) == 1 && testlist[0].URL == "" {
+		return ctl.Run(builder, []string{""})
+	}
+	urls, err := ctl.BuildAndSetInputIdxMap(testlist)
+	if err != nil {
+		return err
+	}
+	return ctl.Run(builder, urls)
+}
@@ -1,5 +1,14 @@
 package nettests
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/internal/registry"
+)
+
 // Group is a group of nettests
 type Group struct {
 	Label        string
@@ -64,3 +73,55 @@ var All = map[string]Group{
 		UnattendedOK: true,
 	},
 }
+
+// ErrInvalidGroup indicates that a user-defined nettest group is invalid.
+var ErrInvalidGroup = errors.New("invalid nettest group")
+
+// NewCustomGroup creates a [Group] from the given user-defined nettest group
+// or returns an error if the user-defined nettest group is invalid.
+func NewCustomGroup(name string, cg config.NettestGroup) (Group, error) {
+	if _, found := All[name]; found {
+		return Group{}, fmt.Errorf("%w: %s: cannot override a built-in group", ErrInvalidGroup, name)
+	}
+	if len(cg.Nettests) <= 0 {
+		return Group{}, fmt.Errorf("%w: %s: no nettests", ErrInvalidGroup, name)
+	}
+	group := Group{
+		Label:        cg.Label,
+		Nettests:     []Nettest{},
+		UnattendedOK: cg.UnattendedOK,
+	}
+	if group.Label == "" {
+		group.Label = name
+	}
+	experiments := registry.ExperimentNames()
+	for _, nt := range cg.Nettests {
+		if !slices.Contains(experiments, nt.TestName) {
+			return Group{}, fmt.Errorf("%w: %s: unknown nettest: %q", ErrInvalidGroup, name, nt.TestName)
+		}
+		group.Nettests = append(group.Nettests, Custom{
+			TestName:   nt.TestName,
+			InputFiles: nt.InputFiles,
+			Inputs:     nt.Inputs,
+			Options:    nt.Options,
+		})
+	}
+	return group, nil
+}
+
+// AllGroups returns the built-in groups in [All] along with the
+// user-defined nettest groups in the given config.
+func AllGroups(c *config.Config) (map[string]Group, error) {
+	groups := make(map[string]Group)
+	for name, group := range All {
+		groups[name] = group
+	}
+	for name, cg := range c.Nettests.Groups {
+		group, err := NewCustomGroup(name, cg)
+		if err != nil {
+			return nil, err
+		}
+		groups[name] = group
+	}
+	return groups, nil
+}
@@ -0,0 +1,119 @@
+package nettests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+)
+
+func TestNewCustomGroup(t *testing.T) {
+	t.Run("with a valid group", func(t *testing.T) {
+		group, err := NewCustomGroup("regional-im", config.NettestGroup{
+			Nettests: []config.NettestGroupNettest{{
+				TestName: "signal",
+			}, {
+				TestName: "urlgetter",
+				Inputs:   []string{"https://www.example.com/"},
+				Options:  map[string]any{"HTTPHost": "www.example.org"},
+			}},
+			UnattendedOK: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := Group{
+			Label: "regional-im",
+			Nettests: []Nettest{
+				Custom{TestName: "signal"},
+				Custom{
+					TestName: "urlgetter",
+					Inputs:   []string{"https://www.example.com/"},
+					Options:  map[string]any{"HTTPHost": "www.example.org"},
+				},
+			},
+			UnattendedOK: true,
+		}
+		if diff := cmp.Diff(expect, group); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with invalid groups", func(t *testing.T) {
+		cases := map[string]struct {
+			name  string
+			group config.NettestGroup
+		}{
+			"when overriding a built-in group": {
+				name: "im",
+				group: config.NettestGroup{
+					Nettests: []config.NettestGroupNettest{{TestName: "signal"}},
+				},
+			},
+			"without nettests": {
+				name:  "empty",
+				group: config.NettestGroup{},
+			},
+			"with an unknown nettest": {
+				name: "regional-im",
+				group: config.NettestGroup{
+					Nettests: []config.NettestGroupNettest{{TestName: "antani"}},
+				},
+			},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				_, err := NewCustomGroup(tc.name, tc.group)
+				if !errors.Is(err, ErrInvalidGroup) {
+					t.Fatal("unexpected error", err)
+				}
+			})
+		}
+	})
+}
+
+func TestAllGroups(t *testing.T) {
+	t.Run("without user-defined groups", func(t *testing.T) {
+		groups, err := AllGroups(&config.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(groups) != len(All) {
+			t.Fatal("unexpected number of groups", len(groups))
+		}
+	})
+
+	t.Run("with user-defined groups", func(t *testing.T) {
+		c, err := config.ReadConfig("../config/testdata/groups-config.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		groups, err := AllGroups(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		group, found := groups["regional-im"]
+		if !found {
+			t.Fatal("expected to find the regional-im group")
+		}
+		if group.Label != "Regional Instant Messaging" || !group.UnattendedOK || len(group.Nettests) != 3 {
+			t.Fatal("unexpected group", group)
+		}
+		if _, found := groups["websites"]; !found {
+			t.Fatal("expected to find the built-in groups")
+		}
+	})
+
+	t.Run("with an invalid user-defined group", func(t *testing.T) {
+		c := &config.Config{}
+		c.Nettests.Groups = map[string]config.NettestGroup{"websites": {}}
+		groups, err := AllGroups(c)
+		if !errors.Is(err, ErrInvalidGroup) {
+			t.Fatal("unexpected error", err)
+		}
+		if groups != nil {
+			t.Fatal("expected nil groups")
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/uploader"
 	engine "github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/pkg/errors"
@@ -180,6 +181,7 @@ func (c *Controller) Run(builder model.ExperimentBuilder, inputs []string) error
 		c.curInputIdx = idx // allow for precise progress
 		idx64 := int64(idx)
 		log.Debug(color.RedString("status.measurement_start"))
+		output.MeasurementStart(exp.Name(), idx, input)
 		var urlID sql.NullInt64
 		if c.inputIdxMap != nil {
 			urlID = sql.NullInt64{Int64: c.inputIdxMap[idx64], Valid: true}
@@ -222,6 +224,12 @@ func (c *Controller) Run(builder model.ExperimentBuilder, inputs []string) error
 				if err := db.UploadFailed(c.msmts[idx64], err.Error()); err != nil {
 					return errors.Wrap(err, "failed to mark upload as failed")
 				}
+				// Queue the measurement such that the background uploader
+				// retries submitting it the next time ooniprobe runs.
+				nextRetryAt := uploader.NextRetryAt(time.Now(), 1)
+				if err := db.EnqueueUpload(c.msmts[idx64], err.Error(), nextRetryAt); err != nil {
+					return errors.Wrap(err, "failed to enqueue upload")
+				}
 			} else if err := db.UploadSucceeded(c.msmts[idx64]); err != nil {
 				return errors.Wrap(err, "failed to mark upload as succeeded")
 			} else {
@@ -82,7 +82,12 @@ func RunGroup(config RunGroupConfig) error {
 		return err
 	}
 
-	group, ok := All[config.GroupName]
+	groups, err := AllGroups(config.Probe.Config())
+	if err != nil {
+		log.WithError(err).Error("Failed to load the nettest groups")
+		return err
+	}
+	group, ok := groups[config.GroupName]
 	if !ok {
 		log.Errorf("No test group named %s", config.GroupName)
 		return errors.New("invalid test group name")
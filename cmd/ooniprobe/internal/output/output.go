@@ -8,6 +8,7 @@ import (
 
 	"github.com/apex/log"
 	"github.com/mitchellh/go-wordwrap"
+	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -29,6 +30,12 @@ func Progress(key string, perc float64, eta float64, msg string) {
 	}).Info(msg)
 }
 
+// MeasurementStart emits a measurement start event, which structured log
+// handlers use to associate the following log entries with the measurement
+func MeasurementStart(experiment string, idx int, input string) {
+	logx.MeasurementStart(log.Log, experiment, idx, input)
+}
+
 // MeasurementSummaryData contains summary information on the measurement
 type MeasurementSummaryData struct {
 	TotalRuntime       float64
@@ -151,6 +158,85 @@ func ResultSummary(result ResultSummaryData) {
 	}).Info("result summary")
 }
 
+// StatsTrendItemData contains the counters of a dimension (e.g., a
+// network, a test group, or a URL category) within a time bucket
+type StatsTrendItemData struct {
+	Dimension    string
+	Key          string
+	Bucket       time.Time
+	TotalCount   int64
+	AnomalyCount int64
+	FailureCount int64
+}
+
+// StatsTrendItem emits a stats trend item
+func StatsTrendItem(item StatsTrendItemData) {
+	log.WithFields(log.Fields{
+		"type":          "stats_trend_item",
+		"dimension":     item.Dimension,
+		"key":           item.Key,
+		"bucket":        item.Bucket,
+		"total_count":   item.TotalCount,
+		"anomaly_count": item.AnomalyCount,
+		"failure_count": item.FailureCount,
+		"anomaly_rate":  statsRate(item.AnomalyCount, item.TotalCount),
+		"failure_rate":  statsRate(item.FailureCount, item.TotalCount),
+	}).Info("stats trend item")
+}
+
+// StatsURLItemData contains the counters of a frequently anomalous URL
+type StatsURLItemData struct {
+	URL          string
+	CategoryCode string
+	TotalCount   int64
+	AnomalyCount int64
+}
+
+// StatsURLItem emits a stats URL item
+func StatsURLItem(item StatsURLItemData) {
+	log.WithFields(log.Fields{
+		"type":          "stats_url_item",
+		"url":           item.URL,
+		"category_code": item.CategoryCode,
+		"total_count":   item.TotalCount,
+		"anomaly_count": item.AnomalyCount,
+		"anomaly_rate":  statsRate(item.AnomalyCount, item.TotalCount),
+	}).Info("stats url item")
+}
+
+// StatsSummaryData contains the totals of the stats
+type StatsSummaryData struct {
+	TotalMeasurements  int64
+	TotalAnomalies     int64
+	TotalFailures      int64
+	TotalResults       int64
+	TotalNetworks      int64
+	TotalDataUsageUp   float64
+	TotalDataUsageDown float64
+}
+
+// StatsSummary emits the stats summary
+func StatsSummary(summary StatsSummaryData) {
+	log.WithFields(log.Fields{
+		"type":                  "stats_summary",
+		"total_measurements":    summary.TotalMeasurements,
+		"total_anomalies":       summary.TotalAnomalies,
+		"total_failures":        summary.TotalFailures,
+		"total_results":         summary.TotalResults,
+		"total_networks":        summary.TotalNetworks,
+		"total_data_usage_up":   summary.TotalDataUsageUp,
+		"total_data_usage_down": summary.TotalDataUsageDown,
+	}).Info("stats summary")
+}
+
+// statsRate returns count/total or zero when total is zero
+func statsRate(count, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
+
 // SectionTitle is the title of a section
 func SectionTitle(text string) {
 	log.WithFields(log.Fields{
@@ -0,0 +1,167 @@
+// Package uploader implements the background uploader that retries
+// submitting the measurements we previously failed to upload.
+package uploader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Queue is the upload queue drained by the uploader. The
+// [*database.Database] type implements this interface.
+type Queue interface {
+	ListDueUploads(now time.Time, limit int) ([]model.DatabaseUploadQueueEntry, error)
+	UploadRetryFailed(entry *model.DatabaseUploadQueueEntry, failure string, nextRetryAt time.Time) error
+	UploadRetrySucceeded(entry *model.DatabaseUploadQueueEntry, reportID string) error
+}
+
+// Config contains the uploader config.
+type Config struct {
+	// Logger is the MANDATORY logger.
+	Logger model.Logger
+
+	// NewSubmitter is the MANDATORY factory for creating the submitter. We
+	// only call this factory when there is something to upload.
+	NewSubmitter func(ctx context.Context) (model.Submitter, error)
+
+	// Queue is the MANDATORY upload queue.
+	Queue Queue
+
+	// TimeNow is the OPTIONAL function returning the current time. When
+	// not set, we use [time.Now].
+	TimeNow func() time.Time
+}
+
+func (c *Config) timeNow() time.Time {
+	if c.TimeNow != nil {
+		return c.TimeNow()
+	}
+	return time.Now()
+}
+
+const (
+	// initialDelay is the delay before the first retry.
+	initialDelay = time.Minute
+
+	// maxDelay is the maximum delay between two retries.
+	maxDelay = 24 * time.Hour
+
+	// batchSize is the number of entries we read at a time.
+	batchSize = 64
+)
+
+// NextRetryAt returns when we should retry uploading a measurement after
+// the given number of failed attempts. We use exponential backoff with jitter:
+// the delay is uniformly distributed in [d/2, d), where d doubles at each
+// attempt starting from one minute and is capped to one day.
+func NextRetryAt(now time.Time, attempts int64) time.Time {
+	delay := initialDelay
+	for idx := int64(1); idx < attempts && delay < maxDelay; idx++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	half := delay / 2
+	return now.Add(half + time.Duration(rand.Int63n(int64(half))))
+}
+
+// ErrNoMeasurementFile indicates that a queued measurement has no file on disk.
+var ErrNoMeasurementFile = errors.New("uploader: no measurement file")
+
+// Drain uploads all the measurements whose next retry time is not after the time
+// when we started draining and returns the number of uploaded measurements. When
+// an upload fails, we reschedule the measurement using [NextRetryAt] and continue.
+// We stop early on database errors and when the context is done.
+func Drain(ctx context.Context, config *Config) (int, error) {
+	var (
+		submitter model.Submitter
+		uploaded  int
+	)
+	started := config.timeNow()
+	for {
+		entries, err := config.Queue.ListDueUploads(started, batchSize)
+		if err != nil {
+			return uploaded, err
+		}
+		if len(entries) <= 0 {
+			return uploaded, nil
+		}
+		if submitter == nil {
+			if submitter, err = config.NewSubmitter(ctx); err != nil {
+				return uploaded, err
+			}
+		}
+		for idx := range entries {
+			if err := ctx.Err(); err != nil {
+				return uploaded, err
+			}
+			entry := &entries[idx]
+			reportID, err := submit(ctx, submitter, entry)
+			if err != nil {
+				config.Logger.Warnf("uploader: cannot upload measurement %d: %s", entry.MeasurementID, err.Error())
+				nextRetryAt := NextRetryAt(config.timeNow(), entry.Attempts+1)
+				if err := config.Queue.UploadRetryFailed(entry, err.Error(), nextRetryAt); err != nil {
+					return uploaded, err
+				}
+				continue
+			}
+			if err := config.Queue.UploadRetrySucceeded(entry, reportID); err != nil {
+				return uploaded, err
+			}
+			// Like the nettests controller, we only keep on disk the
+			// measurements we have not uploaded yet.
+			os.Remove(entry.MeasurementFilePath.String)
+			uploaded++
+		}
+	}
+}
+
+// submit reads the queued measurement from disk, submits it, and returns the report ID.
+func submit(ctx context.Context, submitter model.Submitter, entry *model.DatabaseUploadQueueEntry) (string, error) {
+	if !entry.MeasurementFilePath.Valid || entry.MeasurementFilePath.String == "" {
+		return "", ErrNoMeasurementFile
+	}
+	data, err := os.ReadFile(entry.MeasurementFilePath.String)
+	if err != nil {
+		return "", err
+	}
+	var measurement model.Measurement
+	if err := json.Unmarshal(data, &measurement); err != nil {
+		return "", err
+	}
+	if err := submitter.Submit(ctx, &measurement); err != nil {
+		return "", err
+	}
+	return measurement.ReportID, nil
+}
+
+// Uploader is an uploader draining the queue in the background.
+type Uploader struct {
+	done     chan any
+	err      error
+	uploaded int
+}
+
+// Start creates an [*Uploader] that calls [Drain] in a background goroutine.
+func Start(ctx context.Context, config *Config) *Uploader {
+	u := &Uploader{done: make(chan any)}
+	go func() {
+		defer close(u.done)
+		u.uploaded, u.err = Drain(ctx, config)
+	}()
+	return u
+}
+
+// Wait waits for the background goroutine to finish and returns
+// the number of uploaded measurements and the error, if any.
+func (u *Uploader) Wait() (int, error) {
+	<-u.done
+	return u.uploaded, u.err
+}
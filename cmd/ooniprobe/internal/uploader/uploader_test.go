@@ -0,0 +1,291 @@
+package uploader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/database"
+	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestNextRetryAt(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	expectDelay := func(attempts int64) time.Duration {
+		switch attempts {
+		case 1:
+			return time.Minute
+		case 2:
+			return 2 * time.Minute
+		case 3:
+			return 4 * time.Minute
+		default:
+			return maxDelay
+		}
+	}
+	for _, attempts := range []int64{1, 2, 3, 20, 1000} {
+		for idx := 0; idx < 64; idx++ {
+			delay := NextRetryAt(now, attempts).Sub(now)
+			expect := expectDelay(attempts)
+			if delay < expect/2 || delay >= expect {
+				t.Fatal("unexpected delay", attempts, delay)
+			}
+		}
+	}
+}
+
+// newQueue creates a database containing a single measurement we failed to
+// upload and returns the database and the corresponding measurement.
+func newQueue(t *testing.T) (*database.Database, *model.DatabaseMeasurement) {
+	tmpdir := t.TempDir()
+	db, err := database.Open(filepath.Join(tmpdir, "main.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	network, err := db.CreateNetwork(&mocks.LocationProvider{
+		MockProbeASN:         func() uint { return 30722 },
+		MockProbeCC:          func() string { return "IT" },
+		MockProbeIP:          func() string { return "127.0.0.1" },
+		MockProbeNetworkName: func() string { return "Vodafone Italia" },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := db.CreateResult(tmpdir, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msmt, err := db.CreateMeasurement(
+		sql.NullString{}, "telegram", result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	measurement := &model.Measurement{TestName: "telegram"}
+	if err := engine.SaveMeasurement(measurement, msmt.MeasurementFilePath.String); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UploadFailed(msmt, "generic_timeout_error"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.EnqueueUpload(msmt, "generic_timeout_error", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	return db, msmt
+}
+
+func TestDrain(t *testing.T) {
+	t.Run("with an empty queue we do not create a submitter", func(t *testing.T) {
+		config := &Config{
+			Logger: model.DiscardLogger,
+			NewSubmitter: func(ctx context.Context) (model.Submitter, error) {
+				t.Fatal("should not be called")
+				return nil, nil
+			},
+			Queue: &mocks.Database{
+				MockListDueUploads: func(now time.Time, limit int) ([]model.DatabaseUploadQueueEntry, error) {
+					return nil, nil
+				},
+			},
+		}
+		uploaded, err := Drain(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uploaded != 0 {
+			t.Fatal("unexpected uploaded", uploaded)
+		}
+	})
+
+	t.Run("when listing the due uploads fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		config := &Config{
+			Logger: model.DiscardLogger,
+			Queue: &mocks.Database{
+				MockListDueUploads: func(now time.Time, limit int) ([]model.DatabaseUploadQueueEntry, error) {
+					return nil, expected
+				},
+			},
+		}
+		if _, err := Drain(context.Background(), config); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when we cannot create a submitter", func(t *testing.T) {
+		db, _ := newQueue(t)
+		expected := errors.New("mocked error")
+		config := &Config{
+			Logger: model.DiscardLogger,
+			NewSubmitter: func(ctx context.Context) (model.Submitter, error) {
+				return nil, expected
+			},
+			Queue: db,
+		}
+		if _, err := Drain(context.Background(), config); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		entries, err := db.ListDueUploads(time.Now(), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Attempts != 1 {
+			t.Fatal("expected the queue to be unchanged", entries)
+		}
+	})
+
+	t.Run("when the context is done", func(t *testing.T) {
+		db, _ := newQueue(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		config := &Config{
+			Logger: model.DiscardLogger,
+			NewSubmitter: func(ctx context.Context) (model.Submitter, error) {
+				return &mocks.Submitter{}, nil
+			},
+			Queue: db,
+		}
+		if _, err := Drain(ctx, config); !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the upload fails we reschedule", func(t *testing.T) {
+		db, msmt := newQueue(t)
+		now := time.Now()
+		config := &Config{
+			Logger: model.DiscardLogger,
+			NewSubmitter: func(ctx context.Context) (model.Submitter, error) {
+				return &mocks.Submitter{
+					MockSubmit: func(ctx context.Context, m *model.Measurement) error {
+						return errors.New("connection_reset")
+					},
+				}, nil
+			},
+			Queue: db,
+			TimeNow: func() time.Time {
+				return now
+			},
+		}
+		uploaded, err := Drain(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uploaded != 0 {
+			t.Fatal("unexpected uploaded", uploaded)
+		}
+		entries, err := db.ListDueUploads(now.Add(maxDelay), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatal("expected a single entry", entries)
+		}
+		entry := entries[0]
+		if entry.Attempts != 2 || entry.LastError.String != "connection_reset" {
+			t.Fatal("unexpected entry", entry)
+		}
+		if entry.NextRetryAt.Before(now.Add(time.Minute)) || !entry.NextRetryAt.Before(now.Add(2*time.Minute)) {
+			t.Fatal("unexpected next retry", entry.NextRetryAt)
+		}
+		if _, err := os.Stat(msmt.MeasurementFilePath.String); err != nil {
+			t.Fatal("expected the measurement file to still exist", err)
+		}
+	})
+
+	t.Run("when the measurement file is missing we reschedule", func(t *testing.T) {
+		db, msmt := newQueue(t)
+		if err := os.Remove(msmt.MeasurementFilePath.String); err != nil {
+			t.Fatal(err)
+		}
+		config := &Config{
+			Logger: model.DiscardLogger,
+			NewSubmitter: func(ctx context.Context) (model.Submitter, error) {
+				return &mocks.Submitter{}, nil
+			},
+			Queue: db,
+		}
+		uploaded, err := Drain(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uploaded != 0 {
+			t.Fatal("unexpected uploaded", uploaded)
+		}
+		entries, err := db.ListDueUploads(time.Now().Add(maxDelay), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Attempts != 2 {
+			t.Fatal("unexpected entries", entries)
+		}
+	})
+
+	t.Run("when the upload succeeds", func(t *testing.T) {
+		db, msmt := newQueue(t)
+		var submitted []*model.Measurement
+		config := &Config{
+			Logger: model.DiscardLogger,
+			NewSubmitter: func(ctx context.Context) (model.Submitter, error) {
+				return &mocks.Submitter{
+					MockSubmit: func(ctx context.Context, m *model.Measurement) error {
+						m.ReportID = "20240101T000000Z_telegram_IT_30722_n1_xx"
+						submitted = append(submitted, m)
+						return nil
+					},
+				}, nil
+			},
+			Queue: db,
+		}
+		uploaded, err := Drain(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uploaded != 1 || len(submitted) != 1 {
+			t.Fatal("unexpected uploaded", uploaded, len(submitted))
+		}
+		if submitted[0].TestName != "telegram" {
+			t.Fatal("unexpected measurement", submitted[0].TestName)
+		}
+		entries, err := db.ListDueUploads(time.Now().Add(maxDelay), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Fatal("expected an empty queue", entries)
+		}
+		if _, err := os.Stat(msmt.MeasurementFilePath.String); !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("expected the measurement file to be removed", err)
+		}
+	})
+}
+
+func TestStart(t *testing.T) {
+	db, _ := newQueue(t)
+	u := Start(context.Background(), &Config{
+		Logger: model.DiscardLogger,
+		NewSubmitter: func(ctx context.Context) (model.Submitter, error) {
+			return &mocks.Submitter{
+				MockSubmit: func(ctx context.Context, m *model.Measurement) error {
+					return nil
+				},
+			}, nil
+		},
+		Queue: db,
+	})
+	uploaded, err := u.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded != 1 {
+		t.Fatal("unexpected uploaded", uploaded)
+	}
+}
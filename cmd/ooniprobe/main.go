@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/app"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/autorun"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/export"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/geoip"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/info"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/list"
@@ -11,6 +12,8 @@ import (
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/rm"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/run"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/show"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/stats"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/tactics"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/upload"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/version"
 )
@@ -32,6 +32,7 @@ require (
 	github.com/ooni/probe-assets v0.22.0
 	github.com/pborman/getopt/v2 v2.1.0
 	github.com/pion/stun v0.6.1
+	github.com/pion/turn/v2 v2.1.4
 	github.com/pkg/errors v0.9.1
 	github.com/quic-go/quic-go v0.42.0
 	github.com/rogpeppe/go-internal v1.12.0
@@ -45,6 +46,7 @@ require (
 	golang.org/x/crypto v0.22.0
 	golang.org/x/net v0.24.0
 	golang.org/x/sys v0.19.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -86,7 +88,6 @@ require (
 	golang.org/x/exp v0.0.0-20231206192017-f3f8817b8deb // indirect
 	golang.org/x/exp/typeparams v0.0.0-20230522175609-2e198f4a06a1 // indirect
 	golang.org/x/time v0.5.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	gvisor.dev/gvisor v0.0.0-20230922204349-b3f36d574a7f // indirect
 )
 
@@ -131,7 +132,6 @@ require (
 	github.com/pion/sctp v1.8.9 // indirect
 	github.com/pion/sdp/v3 v3.0.6 // indirect
 	github.com/pion/srtp/v2 v2.0.18 // indirect
-	github.com/pion/turn/v2 v2.1.4 // indirect
 	github.com/pion/webrtc/v3 v3.2.23 // indirect
 	github.com/prometheus/client_golang v1.17.0
 	github.com/prometheus/client_model v0.5.0 // indirect
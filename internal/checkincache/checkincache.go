@@ -3,6 +3,7 @@ package checkincache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,6 +26,29 @@ type checkInFlagsWrapper struct {
 	Flags map[string]bool
 }
 
+// checkInResponseState is the state containing the full check-in response.
+const checkInResponseState = "checkinresponse.state"
+
+// checkInResponseTTL is the amount of time for which we consider a cached
+// check-in response still usable. URL lists and test helpers do not change
+// frequently, so we can afford a longer expiration than for feature flags.
+const checkInResponseTTL = 7 * 24 * time.Hour
+
+// checkInResponseWrapper is the struct wrapping the full check-in response.
+type checkInResponseWrapper struct {
+	// Config contains the config with which we obtained the response.
+	Config *model.OOAPICheckInConfig
+
+	// Expire contains the expiration date.
+	Expire time.Time
+
+	// Response contains the check-in response.
+	Response *model.OOAPICheckInResult
+
+	// Stored contains the date when we stored the response.
+	Stored time.Time
+}
+
 // Store stores the result of the latest check-in in the given key-value store.
 //
 // We store check-in feature flags in a file called checkinflags.state. These flags
@@ -40,6 +64,74 @@ func Store(kvStore model.KeyValueStore, resp *model.OOAPICheckInResult) error {
 	return kvStore.Set(checkInFlagsState, data)
 }
 
+// StoreResponse stores the full check-in response, including the URL lists for each
+// nettest, the test helpers and the report IDs, in a file called checkinresponse.state,
+// along with the config with which we obtained it. This response is valid for seven
+// days and allows us to keep measuring when the check-in API is not reachable. See
+// [GetResponse] for more details.
+func StoreResponse(kvStore model.KeyValueStore,
+	config *model.OOAPICheckInConfig, resp *model.OOAPICheckInResult) error {
+	now := time.Now()
+	wrapper := &checkInResponseWrapper{
+		Config:   config,
+		Expire:   now.Add(checkInResponseTTL),
+		Response: resp,
+		Stored:   now,
+	}
+	data, err := json.Marshal(wrapper)
+	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+	return kvStore.Set(checkInResponseState, data)
+}
+
+// ErrNoCachedResponse indicates that there is no usable cached check-in response.
+var ErrNoCachedResponse = errors.New("checkincache: no cached check-in response")
+
+// ErrCachedResponseExpired indicates that the cached check-in response is too old.
+var ErrCachedResponseExpired = errors.New("checkincache: cached check-in response expired")
+
+// CachedResponse is a check-in response loaded from the cache.
+type CachedResponse struct {
+	// Config is the config with which we obtained the response.
+	Config *model.OOAPICheckInConfig
+
+	// Response is the cached check-in response.
+	Response *model.OOAPICheckInResult
+
+	// Stored is when we stored the response.
+	Stored time.Time
+}
+
+// Age returns how much time elapsed since we stored the response.
+func (cr *CachedResponse) Age() time.Duration {
+	return time.Since(cr.Stored)
+}
+
+// GetResponse returns the latest check-in response stored by [StoreResponse], which is
+// useful to keep measuring when the check-in API is not reachable. This function
+// returns [ErrNoCachedResponse] if there is no cached response and
+// [ErrCachedResponseExpired] if the cached response is too old.
+func GetResponse(kvStore model.KeyValueStore) (*CachedResponse, error) {
+	data, err := kvStore.Get(checkInResponseState)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoCachedResponse, err.Error())
+	}
+	var wrapper checkInResponseWrapper
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoCachedResponse, err.Error())
+	}
+	if wrapper.Config == nil || wrapper.Response == nil {
+		return nil, ErrNoCachedResponse
+	}
+	if time.Now().After(wrapper.Expire) {
+		return nil, ErrCachedResponseExpired
+	}
+	return &CachedResponse{
+		Config:   wrapper.Config,
+		Response: wrapper.Response,
+		Stored:   wrapper.Stored,
+	}, nil
+}
+
 // GetFeatureFlag returns the value of a check-in feature flag. In case of any
 // error this function will always return a false value.
 func GetFeatureFlag(kvStore model.KeyValueStore, name string) bool {
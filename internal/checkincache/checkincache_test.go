@@ -133,3 +133,119 @@ func TestGetFeatureFlag(t *testing.T) {
 		}
 	})
 }
+
+func TestStoreResponse(t *testing.T) {
+	t.Run("when we can successfully store", func(t *testing.T) {
+		memstore := &kvstore.Memory{}
+		result := &model.OOAPICheckInResult{
+			ProbeASN: "AS30722",
+			ProbeCC:  "IT",
+			Tests: model.OOAPICheckInResultNettests{
+				WebConnectivity: &model.OOAPICheckInInfoWebConnectivity{
+					ReportID: "20240101T000000Z_webconnectivity_IT_30722_n1_xxx",
+					URLs: []model.OOAPIURLInfo{{
+						CategoryCode: "NEWS",
+						CountryCode:  "IT",
+						URL:          "https://www.example.com/",
+					}},
+				},
+			},
+		}
+		config := &model.OOAPICheckInConfig{
+			ProbeASN: "AS30722",
+			ProbeCC:  "IT",
+			WebConnectivity: model.OOAPICheckInConfigWebConnectivity{
+				CategoryCodes: []string{"NEWS"},
+			},
+		}
+		if err := StoreResponse(memstore, config, result); err != nil {
+			t.Fatal(err)
+		}
+		cached, err := GetResponse(memstore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(config, cached.Config); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff(result, cached.Response); diff != "" {
+			t.Fatal(diff)
+		}
+		if age := cached.Age(); age < 0 || age > time.Minute {
+			t.Fatal("unexpected age", age)
+		}
+	})
+
+}
+
+func TestGetResponse(t *testing.T) {
+	t.Run("when we cannot get from the store", func(t *testing.T) {
+		expectedErr := errors.New("mocked error")
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return nil, expectedErr
+			},
+		}
+		cached, err := GetResponse(memstore)
+		if !errors.Is(err, ErrNoCachedResponse) {
+			t.Fatal("unexpected error", err)
+		}
+		if cached != nil {
+			t.Fatal("expected nil cached response")
+		}
+	})
+
+	t.Run("when we cannot unmarshal", func(t *testing.T) {
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return []byte(`{`), nil
+			},
+		}
+		if _, err := GetResponse(memstore); !errors.Is(err, ErrNoCachedResponse) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the response is null", func(t *testing.T) {
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return []byte(`{"Response":null}`), nil
+			},
+		}
+		if _, err := GetResponse(memstore); !errors.Is(err, ErrNoCachedResponse) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the config is null", func(t *testing.T) {
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return []byte(`{"Config":null,"Response":{}}`), nil
+			},
+		}
+		if _, err := GetResponse(memstore); !errors.Is(err, ErrNoCachedResponse) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("if the record was cached too much time ago", func(t *testing.T) {
+		response := &checkInResponseWrapper{
+			Config:   &model.OOAPICheckInConfig{},
+			Expire:   time.Now().Add(-time.Hour),
+			Response: &model.OOAPICheckInResult{},
+			Stored:   time.Now().Add(-8 * 24 * time.Hour),
+		}
+		data, err := json.Marshal(response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		memstore := &mocks.KeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return data, nil
+			},
+		}
+		if _, err := GetResponse(memstore); !errors.Is(err, ErrCachedResponseExpired) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
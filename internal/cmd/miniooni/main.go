@@ -31,9 +31,13 @@ type Options struct {
 	HomeDir             string
 	Inputs              []string
 	InputFilePaths      []string
+	LogHandler          string
 	MaxRuntime          int64
+	MeteredNetwork      bool
+	MetricsListen       string
 	NoJSON              bool
 	NoCollector         bool
+	PinnedAuthorKeys    []string
 	ProbeServicesURL    string
 	Proxy               string
 	Random              bool
@@ -46,6 +50,7 @@ type Options struct {
 	TorBinary           string
 	Tunnel              string
 	Verbose             bool
+	WebTunnelBridge     string
 	Yes                 bool
 }
 
@@ -83,6 +88,20 @@ func main() {
 		"force specific home directory",
 	)
 
+	flags.StringVar(
+		&globalOptions.LogHandler,
+		"log-handler",
+		"text",
+		"set the desired log handler (one of: json, text)",
+	)
+
+	flags.StringVar(
+		&globalOptions.MetricsListen,
+		"metrics-listen",
+		"",
+		"serve Prometheus metrics at http://ADDRESS/metrics (e.g., 127.0.0.1:9100)",
+	)
+
 	flags.BoolVarP(
 		&globalOptions.NoJSON,
 		"no-json",
@@ -167,7 +186,7 @@ func main() {
 		&globalOptions.Tunnel,
 		"tunnel",
 		"",
-		"tunnel to use to communicate with the OONI backend (one of: psiphon, tor, torsf)",
+		"tunnel to use to communicate with the OONI backend (one of: psiphon, tor, torsf, torwebtunnel)",
 	)
 
 	flags.BoolVarP(
@@ -178,6 +197,13 @@ func main() {
 		"increase verbosity level",
 	)
 
+	flags.StringVar(
+		&globalOptions.WebTunnelBridge,
+		"webtunnel-bridge",
+		"",
+		"bridge line for --tunnel=torwebtunnel",
+	)
+
 	flags.BoolVarP(
 		&globalOptions.Yes,
 		"yes",
@@ -191,6 +217,7 @@ func main() {
 	registerAllExperiments(rootCmd, &globalOptions)
 	registerOONIRun(rootCmd, &globalOptions)
 	registerJavaScript(rootCmd, &globalOptions)
+	registerTactics(rootCmd, &globalOptions)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -228,6 +255,18 @@ func registerOONIRun(rootCmd *cobra.Command, globalOptions *Options) {
 		[]string{},
 		"Path to the OONI Run v2 descriptor to run (may be specified multiple times)",
 	)
+	flags.BoolVar(
+		&globalOptions.MeteredNetwork,
+		"metered-network",
+		false,
+		"Skip nettests whose OONI Run v2 descriptor asks not to run on metered networks",
+	)
+	flags.StringSliceVar(
+		&globalOptions.PinnedAuthorKeys,
+		"pin-author-key",
+		[]string{},
+		"Pin AUTHOR=KEY, where KEY is a base64 Ed25519 public key used to verify AUTHOR's descriptors (may be specified multiple times)",
+	)
 }
 
 // registerAllExperiments registers a subcommand for each experiment
@@ -307,8 +346,17 @@ func MainWithConfiguration(experimentName string, currentOptions *Options) {
 		currentOptions.Proxy = fmt.Sprintf("%s:///", currentOptions.Tunnel)
 	}
 
-	logHandler := logx.NewHandlerWithDefaultSettings()
-	logHandler.Emoji = currentOptions.Emoji
+	var logHandler log.Handler
+	switch currentOptions.LogHandler {
+	case "json":
+		logHandler = logx.NewJSONHandlerWithDefaultSettings()
+	case "text", "":
+		textHandler := logx.NewHandlerWithDefaultSettings()
+		textHandler.Emoji = currentOptions.Emoji
+		logHandler = textHandler
+	default:
+		panic(fmt.Sprintf("unknown --log-handler: %s", currentOptions.LogHandler))
+	}
 	logger := &log.Logger{Level: log.InfoLevel, Handler: logHandler}
 	if currentOptions.Verbose {
 		logger.Level = log.DebugLevel
@@ -317,6 +365,12 @@ func MainWithConfiguration(experimentName string, currentOptions *Options) {
 		currentOptions.ReportFile = "report.jsonl"
 	}
 	log.Log = logger
+	if currentOptions.MetricsListen != "" {
+		srv, err := engine.StartMetricsServer(currentOptions.MetricsListen)
+		runtimex.PanicOnError(err, "cannot start the metrics server")
+		defer srv.Close()
+		log.Infof("serving metrics at http://%s/metrics", srv.Addr().String())
+	}
 	for {
 		mainSingleIteration(logger, experimentName, currentOptions)
 		if currentOptions.RepeatEvery <= 0 {
@@ -6,9 +6,12 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"os"
+	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/oonirun"
@@ -20,15 +23,23 @@ func ooniRunMain(ctx context.Context,
 	sess *engine.Session, currentOptions *Options, annotations map[string]string) {
 	logger := sess.Logger()
 	cfg := &oonirun.LinkConfig{
-		AcceptChanges: currentOptions.Yes,
-		Annotations:   annotations,
-		KVStore:       sess.KeyValueStore(),
-		MaxRuntime:    currentOptions.MaxRuntime,
-		NoCollector:   currentOptions.NoCollector,
-		NoJSON:        currentOptions.NoJSON,
-		Random:        currentOptions.Random,
-		ReportFile:    currentOptions.ReportFile,
-		Session:       sess,
+		AcceptChanges:  currentOptions.Yes,
+		Annotations:    annotations,
+		KVStore:        sess.KeyValueStore(),
+		MaxRuntime:     currentOptions.MaxRuntime,
+		MeteredNetwork: currentOptions.MeteredNetwork,
+		NoCollector:    currentOptions.NoCollector,
+		NoJSON:         currentOptions.NoJSON,
+		ProxyActive:    sess.ProxyURL() != nil,
+		Random:         currentOptions.Random,
+		ReportFile:     currentOptions.ReportFile,
+		Session:        sess,
+	}
+	for _, entry := range currentOptions.PinnedAuthorKeys {
+		if err := ooniRunPinAuthorKey(sess, entry); err != nil {
+			logger.Warnf("oonirun: cannot pin author key: %s", err.Error())
+			continue
+		}
 	}
 	for _, URL := range currentOptions.Inputs {
 		r := oonirun.NewLinkRunner(cfg, URL)
@@ -61,3 +72,19 @@ func ooniRunMain(ctx context.Context,
 		}
 	}
 }
+
+// errInvalidPinnedAuthorKey indicates that a --pin-author-key value is not valid.
+var errInvalidPinnedAuthorKey = errors.New("expected AUTHOR=KEY")
+
+// ooniRunPinAuthorKey pins the author key given as AUTHOR=KEY on the command line.
+func ooniRunPinAuthorKey(sess *engine.Session, entry string) error {
+	author, encoded, found := strings.Cut(entry, "=")
+	if !found || author == "" {
+		return errInvalidPinnedAuthorKey
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	return oonirun.V2PinAuthorKey(sess.KeyValueStore(), author, ed25519.PublicKey(key))
+}
@@ -43,6 +43,7 @@ func newSessionOrPanic(ctx context.Context, currentOptions *Options,
 		TorArgs:             currentOptions.TorArgs,
 		TorBinary:           currentOptions.TorBinary,
 		TunnelDir:           tunnelDir,
+		WebTunnelBridge:     currentOptions.WebTunnelBridge,
 	}
 	if currentOptions.ProbeServicesURL != "" {
 		config.AvailableProbeServices = []model.OOAPIService{{
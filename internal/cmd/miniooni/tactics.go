@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/enginenetx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/legacy/kvstore2dir"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/spf13/cobra"
+)
+
+// registerTactics registers the tactics subcommand
+func registerTactics(rootCmd *cobra.Command, globalOptions *Options) {
+	tacticsCmd := &cobra.Command{
+		Use:   "tactics",
+		Short: "Manage the statistics about the tactics used to reach the OONI backend",
+	}
+	rootCmd.AddCommand(tacticsCmd)
+
+	tacticsCmd.AddCommand(&cobra.Command{
+		Use:   "dump",
+		Short: "Write the tactics statistics to the standard output",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			data := runtimex.Try1(enginenetx.DumpStats(tacticsKVStoreOrPanic(globalOptions)))
+			_, err := os.Stdout.Write(append(data, '\n'))
+			runtimex.PanicOnError(err, "cannot write to the standard output")
+		},
+	})
+
+	tacticsCmd.AddCommand(&cobra.Command{
+		Use:   "prune",
+		Short: "Remove old and excess entries from the tactics statistics",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runtimex.Try0(enginenetx.PruneStats(tacticsKVStoreOrPanic(globalOptions)))
+		},
+	})
+
+	tacticsCmd.AddCommand(&cobra.Command{
+		Use:   "import FILE",
+		Short: "Merge the tactics statistics in FILE, generated using dump, into the local ones",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runtimex.Assert(len(args) == 1, "expected exactly one argument")
+			data, err := os.ReadFile(args[0])
+			runtimex.PanicOnError(err, "cannot read the tactics statistics to import")
+			runtimex.Try0(enginenetx.ImportStats(tacticsKVStoreOrPanic(globalOptions), data))
+			log.Infof("imported tactics statistics from %s", args[0])
+		},
+	})
+}
+
+// tacticsKVStoreOrPanic returns the key-value store used by the engine.
+func tacticsKVStoreOrPanic(currentOptions *Options) *kvstore.FS {
+	homeDir := gethomedir(currentOptions.HomeDir)
+	runtimex.Assert(homeDir != "", "home directory is empty")
+	miniooniDir := path.Join(homeDir, ".miniooni")
+
+	// We renamed kvstore2 to engine in the 3.20 development cycle
+	_ = kvstore2dir.Move(miniooniDir)
+
+	kvstore, err := kvstore.NewFS(filepath.Join(miniooniDir, "engine"))
+	runtimex.PanicOnError(err, "cannot create engine directory")
+	return kvstore
+}
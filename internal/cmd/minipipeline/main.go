@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/geoipx"
 	"github.com/ooni/probe-cli/v3/internal/minipipeline"
@@ -14,6 +19,9 @@ import (
 )
 
 var (
+	// batchFlag is the -batch flag
+	batchFlag = flag.String("batch", "", "JSONL file or directory containing measurements to aggregate")
+
 	// destdirFlag is the -destdir flag
 	destdirFlag = flag.String("destdir", ".", "destination directory to use")
 
@@ -26,6 +34,9 @@ var (
 	// mustWriteFileLn allows overwriting must.WriteFile in tests
 	mustWriteFileFn = must.WriteFile
 
+	// parallelismFlag is the -parallelism flag
+	parallelismFlag = flag.Int("parallelism", runtime.NumCPU(), "number of measurements to analyze in parallel")
+
 	// prefixFlag is the -prefix flag
 	prefixFlag = flag.String("prefix", "", "prefix to add to generated files")
 
@@ -35,9 +46,10 @@ var (
 
 func main() {
 	flag.Parse()
-	if *helpFlag || *measurementFlag == "" {
+	if *helpFlag || (*measurementFlag == "" && *batchFlag == "") {
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "usage: %s -measurement <file> [-destdir <dir>] [-prefix <prefix>]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "       %s -batch <file|dir> [-destdir <dir>] [-parallelism <n>] [-prefix <prefix>]\n", filepath.Base(os.Args[0]))
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Mini measurement processing pipeline to reprocess recent probe measurements\n")
 		fmt.Fprintf(os.Stderr, "and align results calculation with ooni/data.\n")
@@ -48,11 +60,22 @@ func main() {
 		fmt.Fprintf(os.Stderr, "analysis like the one in Web Connectivity v0.4 and generate accordingly the\n")
 		fmt.Fprintf(os.Stderr, "observations_classic.json and analysis_classic.json files.\n")
 		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "With -batch <file|dir>, we instead analyze all the measurements contained in\n")
+		fmt.Fprintf(os.Stderr, "the given JSONL file or in the given directory, where we read *.json files\n")
+		fmt.Fprintf(os.Stderr, "containing a single measurement and *.jsonl files containing a measurement\n")
+		fmt.Fprintf(os.Stderr, "per line. We analyze -parallelism <n> measurements in parallel and write\n")
+		fmt.Fprintf(os.Stderr, "per-domain and per-ASN aggregate statistics into the aggregate.json file.\n")
+		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Use -prefix <prefix> to add <prefix> in front of the generated files names.\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		osExitFn(1)
 	}
 
+	if *batchFlag != "" {
+		mainBatch()
+		return
+	}
+
 	// parse the measurement file
 	var parsed minipipeline.WebMeasurement
 	must.UnmarshalJSON(must.ReadFile(*measurementFlag), &parsed)
@@ -78,3 +101,72 @@ func main() {
 	analysisClassic := minipipeline.AnalyzeWebObservationsWithLinearAnalysis(lookupper, containerClassic)
 	mustWriteFileFn(classicAnalysisPath, must.MarshalAndIndentJSON(analysisClassic, "", "  "), 0600)
 }
+
+// mainBatch implements the -batch mode.
+func mainBatch() {
+	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+	aggregator := minipipeline.NewWebAggregator()
+	measurements := make(chan *minipipeline.WebMeasurement)
+	done := make(chan any)
+	go func() {
+		defer close(done)
+		minipipeline.AggregateWebMeasurements(lookupper, *parallelismFlag, aggregator, measurements)
+	}()
+
+	batchReadMeasurements(*batchFlag, aggregator, measurements)
+	close(measurements)
+	<-done
+
+	aggregatePath := filepath.Join(*destdirFlag, *prefixFlag+"aggregate.json")
+	mustWriteFileFn(aggregatePath, must.MarshalAndIndentJSON(aggregator.Aggregate(), "", "  "), 0600)
+}
+
+// batchMaxLineSize is the maximum size of a JSONL line we accept.
+const batchMaxLineSize = 64 << 20
+
+// batchReadMeasurements reads the measurements inside the given file or directory, emits
+// them using the given channel, and accounts parsing errors using the aggregator.
+func batchReadMeasurements(
+	path string, aggregator *minipipeline.WebAggregator, out chan<- *minipipeline.WebMeasurement) {
+	if !runtimex.Try1(os.Stat(path)).IsDir() {
+		batchReadJSONL(path, aggregator, out)
+		return
+	}
+	runtimex.Try0(filepath.WalkDir(path, func(filename string, entry fs.DirEntry, err error) error {
+		runtimex.Try0(err)
+		switch {
+		case entry.IsDir():
+			// nothing
+		case strings.HasSuffix(filename, ".jsonl"):
+			batchReadJSONL(filename, aggregator, out)
+		case strings.HasSuffix(filename, ".json"):
+			batchEmit(must.ReadFile(filename), aggregator, out)
+		}
+		return nil
+	}))
+}
+
+// batchReadJSONL reads a JSONL file where each line contains a measurement.
+func batchReadJSONL(
+	filename string, aggregator *minipipeline.WebAggregator, out chan<- *minipipeline.WebMeasurement) {
+	filep := runtimex.Try1(os.Open(filename))
+	defer filep.Close()
+	scanner := bufio.NewScanner(filep)
+	scanner.Buffer(nil, batchMaxLineSize)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 {
+			batchEmit(line, aggregator, out)
+		}
+	}
+	runtimex.Try0(scanner.Err())
+}
+
+// batchEmit parses a measurement and emits it or accounts the parsing error.
+func batchEmit(data []byte, aggregator *minipipeline.WebAggregator, out chan<- *minipipeline.WebMeasurement) {
+	var meas minipipeline.WebMeasurement
+	if err := json.Unmarshal(data, &meas); err != nil {
+		aggregator.AddFailure(err)
+		return
+	}
+	out <- &meas
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -26,6 +28,7 @@ func mustloaddata(contentmap map[string][]byte, key string) (object map[string]a
 
 func TestMainSuccess(t *testing.T) {
 	// reconfigure the global options for main
+	*batchFlag = ""
 	*destdirFlag = "xo"
 	*measurementFlag = filepath.Join("testdata", "measurement.json")
 	contentmap := make(map[string][]byte)
@@ -69,6 +72,7 @@ func TestMainSuccess(t *testing.T) {
 
 func TestMainUsage(t *testing.T) {
 	// reconfigure the global options for main
+	*batchFlag = ""
 	*destdirFlag = ""
 	*measurementFlag = ""
 	mustWriteFileFn = func(filename string, content []byte, mode fs.FileMode) {
@@ -98,3 +102,64 @@ func TestMainUsage(t *testing.T) {
 		t.Fatal("expected", "os.Exit: 1", "got", err)
 	}
 }
+
+func TestMainBatch(t *testing.T) {
+	// create a directory containing a measurement, a JSONL file
+	// containing two measurements, and an invalid measurement
+	basedir := t.TempDir()
+	measurement := must.ReadFile(filepath.Join("testdata", "measurement.json"))
+	must.WriteFile(filepath.Join(basedir, "measurement.json"), measurement, 0600)
+	compact := &bytes.Buffer{}
+	if err := json.Compact(compact, measurement); err != nil {
+		t.Fatal(err)
+	}
+	compact.WriteString("\n")
+	jsonl := append(bytes.Repeat(compact.Bytes(), 2), []byte("{\n")...)
+	must.WriteFile(filepath.Join(basedir, "measurements.jsonl"), jsonl, 0600)
+	must.WriteFile(filepath.Join(basedir, "README.md"), []byte("ignored\n"), 0600)
+
+	for _, tc := range []struct {
+		name         string
+		batch        string
+		measurements float64
+	}{{
+		name:         "with a directory",
+		batch:        basedir,
+		measurements: 3,
+	}, {
+		name:         "with a JSONL file",
+		batch:        filepath.Join(basedir, "measurements.jsonl"),
+		measurements: 2,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			// reconfigure the global options for main
+			*batchFlag = tc.batch
+			*destdirFlag = "xo"
+			*measurementFlag = ""
+			contentmap := make(map[string][]byte)
+			mustWriteFileFn = func(filename string, content []byte, mode fs.FileMode) {
+				contentmap[filename] = content
+			}
+			osExitFn = os.Exit
+			*parallelismFlag = 2
+			*prefixFlag = "y-"
+
+			// run the main function
+			main()
+
+			// make sure the generated aggregate is good
+			aggregate := mustloaddata(contentmap, filepath.Join("xo", "y-aggregate.json"))
+			if aggregate["measurements"] != tc.measurements || aggregate["failures"] != float64(1) {
+				t.Fatal("unexpected counters", aggregate["measurements"], aggregate["failures"])
+			}
+			byDomain := aggregate["by_domain"].(map[string]any)
+			if entry := byDomain["nexa.polito.it"].(map[string]any); entry["measurements"] != tc.measurements {
+				t.Fatal("unexpected per-domain entry", entry)
+			}
+			byASN := aggregate["by_asn"].(map[string]any)
+			if entry := byASN["AS30722"].(map[string]any); entry["measurements"] != tc.measurements {
+				t.Fatal("unexpected per-ASN entry", entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// http3Listener bundles an [*http3.Server] and the UDP socket it serves.
+type http3Listener struct {
+	// Server is the HTTP/3 server.
+	Server *http3.Server
+
+	// PacketConn is the UDP socket the server should serve.
+	PacketConn net.PacketConn
+}
+
+// mustListenHTTP3 creates a UDP socket listening at the given endpoint and a
+// [*http3.Server] using the given certificate and handler. The caller is responsible
+// for calling Serve in a background goroutine and Close when done.
+func mustListenHTTP3(endpoint string, cert tls.Certificate, handler http.Handler) *http3Listener {
+	addr := runtimex.Try1(net.ResolveUDPAddr("udp", endpoint))
+	pconn := runtimex.Try1(net.ListenUDP("udp", addr))
+	srv := &http3.Server{
+		Handler: handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}),
+	}
+	return &http3Listener{Server: srv, PacketConn: pconn}
+}
+
+// Serve serves requests until the listener is closed.
+func (hl *http3Listener) Serve() error {
+	return hl.Server.Serve(hl.PacketConn)
+}
+
+// Close closes the server and the UDP socket.
+func (hl *http3Listener) Close() error {
+	err := hl.Server.Close()
+	hl.PacketConn.Close()
+	return err
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestHTTP3ListenerWorkingAsIntended(t *testing.T) {
+	// create a certificate for the server
+	ca := netem.MustNewCA()
+	cert := ca.MustNewTLSCertificate("127.0.0.1")
+
+	// create and start the HTTP/3 server
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello OONItarian!"))
+	})
+	listener := mustListenHTTP3("127.0.0.1:0", *cert, handler)
+	go listener.Serve()
+	defer listener.Close()
+
+	// create an HTTP/3 client trusting the CA
+	txp := &http3.RoundTripper{
+		TLSClientConfig: &tls.Config{RootCAs: ca.DefaultCertPool()},
+	}
+	defer txp.Close()
+	client := &http.Client{Transport: txp}
+
+	// issue the request and get the response
+	URL := "https://" + listener.PacketConn.LocalAddr().String() + "/"
+	resp, err := client.Get(URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatal("unexpected status code", resp.StatusCode)
+	}
+	data, err := netxlite.ReadAllContext(context.Background(), resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "Hello OONItarian!" {
+		t.Fatal("unexpected body", string(data))
+	}
+}
@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"net"
@@ -29,6 +30,9 @@ var (
 	// debug controls whether to enable verbose logging
 	debug = flag.Bool("debug", false, "Toggle debug mode")
 
+	// http3Endpoint is the OPTIONAL UDP endpoint where we serve ooniprobe requests using HTTP/3
+	http3Endpoint = flag.String("http3-endpoint", "", "Optional HTTP/3 API endpoint (requires -tls-cert and -tls-key)")
+
 	// pprofEndpoint is the endpoint where we serve pprof info.
 	pprofEndpoint = flag.String("pprof-endpoint", "127.0.0.1:6061", "Pprof endpoint")
 
@@ -44,6 +48,12 @@ var (
 	// srvWg is used by tests to know when the server has shut down
 	srvWg = new(sync.WaitGroup)
 
+	// tlsCert is the certificate file used by the HTTP/3 server
+	tlsCert = flag.String("tls-cert", "", "TLS certificate file for the HTTP/3 API endpoint")
+
+	// tlsKey is the key file used by the HTTP/3 server
+	tlsKey = flag.String("tls-key", "", "TLS key file for the HTTP/3 API endpoint")
+
 	// versionFlag indicates we must print the version on stdout
 	versionFlag = flag.Bool("version", false, "Prints version information on the stdout")
 
@@ -117,6 +127,16 @@ func main() {
 	go srv.Serve(listener)
 	log.Infof("serving ooniprobe requests at http://%s/", listener.Addr().String())
 
+	// optionally create a listening server for serving ooniprobe requests using HTTP/3
+	var http3Srv *http3Listener
+	if *http3Endpoint != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		runtimex.PanicOnError(err, "tls.LoadX509KeyPair failed")
+		http3Srv = mustListenHTTP3(*http3Endpoint, cert, mux)
+		go http3Srv.Serve()
+		log.Infof("serving ooniprobe requests at https://%s/ using HTTP/3", http3Srv.PacketConn.LocalAddr().String())
+	}
+
 	// create another server for serving pprof metrics
 	pprofMux := http.NewServeMux()
 	pprofMux.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
@@ -141,6 +161,11 @@ func main() {
 	go shutdown(pprofSrv, shutdownWg)
 	shutdownWg.Wait()
 
+	// the HTTP/3 server does not support graceful shutdown yet
+	if http3Srv != nil {
+		http3Srv.Close()
+	}
+
 	// notify tests that we are now done
 	srvWg.Done()
 }
@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"flag"
+	"io"
 	"net"
 	"sync"
 	"time"
@@ -14,18 +15,20 @@ import (
 )
 
 var (
-	srvCtx      context.Context
-	srvCancel   context.CancelFunc
-	srvWg       = new(sync.WaitGroup)
-	srvTestChan = make(chan string, len(TestPorts)) // buffered channel for testing
-	srvTest     bool
+	srvCtx         context.Context
+	srvCancel      context.CancelFunc
+	srvWg          = new(sync.WaitGroup)
+	srvTestChan    = make(chan string, len(TestPorts)) // buffered channel for testing
+	srvUDPTestChan = make(chan string, len(TestPorts)) // buffered channel for testing
+	srvTest        bool
+	srvAddress     = "127.0.0.1"
 )
 
 func init() {
 	srvCtx, srvCancel = context.WithCancel(context.Background())
 }
 
-func shutdown(ctx context.Context, l net.Listener) {
+func shutdown(ctx context.Context, l io.Closer) {
 	<-ctx.Done()
 	l.Close()
 }
@@ -38,13 +41,23 @@ func handleConnection(ctx context.Context, conn net.Conn) {
 	<-ctx.Done()
 }
 
+// notifyListening sends to the given channel to imply the server will start listening
+// on port. We do not block when the channel is full, which happens in production because
+// we listen on more ports than the channel buffer holds and nobody reads from it.
+func notifyListening(ch chan<- string, port string) {
+	select {
+	case ch <- port:
+	default:
+	}
+}
+
 func listenTCP(ctx context.Context, port string) {
 	defer srvWg.Done()
-	address := net.JoinHostPort("127.0.0.1", port)
+	address := net.JoinHostPort(srvAddress, port)
 	listener, err := net.Listen("tcp", address)
 	runtimex.PanicOnError(err, "net.Listen failed")
 	go shutdown(ctx, listener)
-	srvTestChan <- port // send to channel to imply server will start listening on port
+	notifyListening(srvTestChan, port)
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
@@ -55,12 +68,36 @@ func listenTCP(ctx context.Context, port string) {
 	}
 }
 
+// listenUDP echoes back the datagrams it receives, which allows the
+// portfiltering experiment to determine whether a UDP port is reachable.
+func listenUDP(ctx context.Context, port string) {
+	defer srvWg.Done()
+	address := net.JoinHostPort(srvAddress, port)
+	pconn, err := net.ListenPacket("udp", address)
+	runtimex.PanicOnError(err, "net.ListenPacket failed")
+	go shutdown(ctx, pconn)
+	notifyListening(srvUDPTestChan, port)
+	buffer := make([]byte, 1<<12)
+	for {
+		count, addr, err := pconn.ReadFrom(buffer)
+		if err != nil {
+			log.Infof("listener unable to read datagrams on port: %s", port)
+			return
+		}
+		if _, err := pconn.WriteTo(buffer[:count], addr); err != nil {
+			log.Debugf("cannot echo datagram to %s: %s", addr, err.Error())
+		}
+	}
+}
+
 func main() {
 	logmap := map[bool]log.Level{
 		true:  log.DebugLevel,
 		false: log.InfoLevel,
 	}
 	debug := flag.Bool("debug", false, "Toggle debug mode")
+	flag.StringVar(&srvAddress, "address", srvAddress, "Address where to listen")
+	udp := flag.Bool("udp", true, "Also run UDP echo listeners")
 	flag.Parse()
 	log.SetLevel(logmap[*debug])
 	defer srvCancel()
@@ -73,6 +110,10 @@ func main() {
 		ctx, cancel := context.WithCancel(srvCtx)
 		defer cancel()
 		go listenTCP(ctx, port)
+		if *udp {
+			srvWg.Add(1)
+			go listenUDP(ctx, port)
+		}
 	}
 	<-srvCtx.Done()
 	srvWg.Wait() // wait for listeners on all ports to close
@@ -4,13 +4,15 @@ import (
 	"context"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 var (
-	portsMap = make(map[string]bool)
+	portsMap    = make(map[string]bool)
+	udpPortsMap = make(map[string]bool)
 )
 
 func TestMainWorkingAsIntended(t *testing.T) {
@@ -35,6 +37,31 @@ func TestMainWorkingAsIntended(t *testing.T) {
 		conn.Close()
 		portsMap[port] = true
 	}
+	for i := 0; i < len(TestPorts); i++ {
+		port := <-srvUDPTestChan
+		addr := net.JoinHostPort("127.0.0.1", port)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		conn, err := dialer.DialContext(ctx, "udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline, _ := ctx.Deadline()
+		conn.SetDeadline(deadline)
+		if _, err := conn.Write([]byte("antani")); err != nil {
+			t.Fatal(err)
+		}
+		buffer := make([]byte, 64)
+		count, err := conn.Read(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buffer[:count]) != "antani" {
+			t.Fatal("unexpected echo", string(buffer[:count]))
+		}
+		conn.Close()
+		cancel()
+		udpPortsMap[port] = true
+	}
 	srvCancel()  // shutdown server
 	srvWg.Wait() // wait for listeners on all ports to close
 	// check if all ports were covered
@@ -42,5 +69,68 @@ func TestMainWorkingAsIntended(t *testing.T) {
 		if !portsMap[port] {
 			t.Fatal("missed port in test", port)
 		}
+		if !udpPortsMap[port] {
+			t.Fatal("missed UDP port in test", port)
+		}
+	}
+}
+
+func TestListenUDPWithMorePortsThanTheTestChannelHolds(t *testing.T) {
+	// find more free ports than the test channel can hold
+	var ports []string
+	for len(ports) < cap(srvUDPTestChan)+3 {
+		pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, port, err := net.SplitHostPort(pconn.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pconn.Close()
+		ports = append(ports, port)
+	}
+
+	// start all the listeners without reading from the test channel
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, port := range ports {
+		srvWg.Add(1)
+		go listenUDP(ctx, port)
+	}
+
+	// make sure each listener echoes what we send
+	for _, port := range ports {
+		addr := net.JoinHostPort("127.0.0.1", port)
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buffer := make([]byte, 64)
+		var count int
+		for attempt := 0; attempt < 10; attempt++ {
+			// retry because the listener may not be ready yet
+			if _, err := conn.Write([]byte("antani")); err != nil {
+				t.Fatal(err)
+			}
+			conn.SetReadDeadline(time.Now().Add(250 * time.Millisecond))
+			if count, err = conn.Read(buffer); err == nil {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatal("no echo from port", port, err)
+		}
+		if string(buffer[:count]) != "antani" {
+			t.Fatal("unexpected echo", string(buffer[:count]))
+		}
+		conn.Close()
+	}
+
+	// shutdown the listeners and drain the test channel
+	cancel()
+	srvWg.Wait()
+	for len(srvUDPTestChan) > 0 {
+		<-srvUDPTestChan
 	}
 }
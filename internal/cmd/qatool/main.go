@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivitylte"
@@ -14,6 +15,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/minipipeline"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/must"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/ooni/probe-cli/v3/internal/webconnectivityqa"
 )
@@ -48,6 +50,9 @@ var (
 
 	// runFlag is the -run flag
 	runFlag = flag.String("run", "", "regexp to select which test cases to run")
+
+	// scenarioFlag is the -scenario flag
+	scenarioFlag = flag.String("scenario", "", "JSON or YAML scenario file to use instead of the builtin test cases")
 )
 
 func mustSerializeMkdirAllAndWriteFile(dirname string, filename string, content any) {
@@ -139,6 +144,17 @@ func runWebConnectivityLTE(tc *webconnectivityqa.TestCase) {
 	}
 }
 
+// mustLoadScenarioTestCase loads a test case from the given scenario file. When the
+// scenario file has no name, we use the file name without extension as the name.
+func mustLoadScenarioTestCase(filename string) *webconnectivityqa.TestCase {
+	sf := runtimex.Try1(netemx.LoadScenarioFile(filename))
+	if sf.Name == "" {
+		sf.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	runtimex.Assert(sf.Input != "", "qatool: the scenario file must contain an input")
+	return webconnectivityqa.NewTestCaseFromScenarioFile(sf)
+}
+
 // override webconnectivitylte algorithm to make it less entropic
 func init() {
 	webconnectivitylte.MaybeSortAddresses = func(entries []webconnectivitylte.DNSEntry) {
@@ -165,6 +181,7 @@ func main() {
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "usage: %s -destdir <destdir> [-run <regexp>] [-disable-measure|-disable-reprocess]]\n", filepath.Base(os.Args[0]))
 		fmt.Fprintf(os.Stderr, "       %s -list [-run <regexp>]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "       %s -destdir <destdir> -scenario <file> [-disable-measure|-disable-reprocess]]\n", filepath.Base(os.Args[0]))
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "The first form of the command runs the QA tests selected by the given\n")
 		fmt.Fprintf(os.Stderr, "<regexp> and creates the corresponding files in <destdir>.\n")
@@ -181,9 +198,19 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Add the -disable-reprocess flag to the first form of the command to\n")
 		fmt.Fprintf(os.Stderr, "avoid reprocessing the measurements using the minipipeline.\n")
 		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "The third form of the command measures the input of the given JSON\n")
+		fmt.Fprintf(os.Stderr, "or YAML scenario file using the domains, addresses, and DPI rules\n")
+		fmt.Fprintf(os.Stderr, "it describes, and creates the corresponding files in <destdir>.\n")
+		fmt.Fprintf(os.Stderr, "\n")
 		osExitFn(1)
 	}
 
+	// handle the case where the user provided a scenario file
+	if *scenarioFlag != "" {
+		runWebConnectivityLTE(mustLoadScenarioTestCase(*scenarioFlag))
+		return
+	}
+
 	// build the regexp
 	selector := regexp.MustCompile(*runFlag)
 
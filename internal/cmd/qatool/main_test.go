@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/must"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
 
@@ -92,6 +94,73 @@ func TestMainSuccess(t *testing.T) {
 	}
 }
 
+func TestMainScenario(t *testing.T) {
+	// write the scenario file
+	filename := filepath.Join(t.TempDir(), "exampleComDNSBlocking.yaml")
+	scenario := []byte(`
+input: https://www.example.com/
+extends_internet_scenario: true
+dpi_rules:
+  - type: spoof_dns_response
+    domain: www.example.com
+`)
+	if err := os.WriteFile(filename, scenario, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// reconfigure the global options for main
+	*destdirFlag = "xo"
+	*listFlag = false
+	contentmap := make(map[string][]byte)
+	mustReadFileFn = func(filename string) []byte {
+		data, found := contentmap[filename]
+		runtimex.Assert(found, fmt.Sprintf("cannot find %s", filename))
+		return data
+	}
+	mustWriteFileFn = func(filename string, content []byte, mode fs.FileMode) {
+		contentmap[filename] = content
+	}
+	osExitFn = os.Exit
+	osMkdirAllFn = func(path string, perm os.FileMode) error {
+		return nil
+	}
+	*runFlag = ""
+	*scenarioFlag = filename
+	defer func() {
+		*scenarioFlag = ""
+	}()
+
+	// run the main function
+	main()
+
+	// make sure we attempted to write the desired files
+	expect := map[string]bool{
+		"xo/exampleComDNSBlocking/measurement.json":          true,
+		"xo/exampleComDNSBlocking/observations.json":         true,
+		"xo/exampleComDNSBlocking/observations_classic.json": true,
+		"xo/exampleComDNSBlocking/analysis.json":             true,
+		"xo/exampleComDNSBlocking/analysis_classic.json":     true,
+	}
+	got := make(map[string]bool)
+	for key := range contentmap {
+		got[key] = true
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// make sure the DPI rule has been applied
+	var measurement struct {
+		TestKeys struct {
+			DNSExperimentFailure *string `json:"dns_experiment_failure"`
+		} `json:"test_keys"`
+	}
+	must.UnmarshalJSON(contentmap["xo/exampleComDNSBlocking/measurement.json"], &measurement)
+	if failure := measurement.TestKeys.DNSExperimentFailure; failure == nil || *failure != "dns_nxdomain_error" {
+		t.Fatal("unexpected DNS experiment failure", failure)
+	}
+}
+
 func TestMainUsage(t *testing.T) {
 	// reconfigure the global options for main
 	*destdirFlag = ""
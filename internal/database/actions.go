@@ -61,6 +61,62 @@ func (d *Database) ListMeasurements(resultID int64) ([]model.DatabaseMeasurement
 	return measurements, nil
 }
 
+// ListMeasurementsWithFilter implements ReadableDatabase.ListMeasurementsWithFilter
+func (d *Database) ListMeasurementsWithFilter(
+	filter *model.DatabaseMeasurementFilter) ([]model.DatabaseMeasurementURLNetwork, error) {
+	measurements := []model.DatabaseMeasurementURLNetwork{}
+	req := d.sess.SQL().Select(
+		db.Raw("networks.*"),
+		db.Raw("urls.*"),
+		db.Raw("measurements.*"),
+		db.Raw("results.*"),
+	).From("measurements").
+		Join("results").On("results.result_id = measurements.result_id").
+		Join("networks").On("results.network_id = networks.network_id").
+		LeftJoin("urls").On("urls.url_id = measurements.url_id").
+		OrderBy("measurements.measurement_start_time", "measurements.measurement_id")
+	conds := db.Cond{}
+	if !filter.Since.IsZero() {
+		conds["measurements.measurement_start_time >="] = filter.Since.UTC()
+	}
+	if !filter.Until.IsZero() {
+		conds["measurements.measurement_start_time <"] = filter.Until.UTC()
+	}
+	if filter.TestGroupName != "" {
+		conds["results.test_group_name"] = filter.TestGroupName
+	}
+	if filter.ASN != 0 {
+		conds["networks.asn"] = filter.ASN
+	}
+	if len(conds) > 0 {
+		req = req.Where(conds)
+	}
+	if err := req.All(&measurements); err != nil {
+		log.Errorf("failed to run query %s: %v", req.String(), err)
+		return measurements, err
+	}
+	return measurements, nil
+}
+
+// ListDueUploads implements ReadableDatabase.ListDueUploads
+func (d *Database) ListDueUploads(now time.Time, limit int) ([]model.DatabaseUploadQueueEntry, error) {
+	entries := []model.DatabaseUploadQueueEntry{}
+	req := d.sess.SQL().Select(
+		db.Raw("upload_queue.*"),
+		db.Raw("measurements.result_id"),
+		db.Raw("measurements.measurement_file_path"),
+	).From("upload_queue").
+		Join("measurements").On("measurements.measurement_id = upload_queue.measurement_id").
+		Where("upload_queue.upload_next_retry_at <= ?", now.UTC()).
+		OrderBy("upload_queue.upload_next_retry_at", "upload_queue.upload_queue_id").
+		Limit(limit)
+	if err := req.All(&entries); err != nil {
+		log.Errorf("failed to run query %s: %v", req.String(), err)
+		return entries, err
+	}
+	return entries, nil
+}
+
 // GetMeasurementJSON implements ReadableDatabase.GetMeasurementJSON
 func (d *Database) GetMeasurementJSON(msmtID int64) (map[string]interface{}, error) {
 	var (
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/engine"
@@ -262,6 +263,323 @@ func TestDeleteResult(t *testing.T) {
 	}
 }
 
+func TestListMeasurementsWithFilter(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	database, err := Open(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := database.Session()
+
+	// createMeasurement creates a measurement with the given start time
+	// inside a new result using the given group and ASN.
+	createMeasurement := func(groupName string, asn uint, startTime time.Time) {
+		network, err := database.CreateNetwork(&locationInfo{
+			asn:         asn,
+			countryCode: "IT",
+			networkName: "Antaninet",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := database.CreateResult(tmpdir, groupName, network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urlID, err := database.CreateOrUpdateURL("https://www.example.com/", "NEWS", "IT")
+		if err != nil {
+			t.Fatal(err)
+		}
+		msmt, err := database.CreateMeasurement(
+			sql.NullString{}, "web_connectivity", tmpdir, 0, result.ID,
+			sql.NullInt64{Int64: urlID, Valid: true},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msmt.StartTime = startTime
+		if err := sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+	createMeasurement("websites", 30722, day(1))
+	createMeasurement("websites", 3269, day(2))
+	createMeasurement("im", 30722, day(3))
+
+	type testcase struct {
+		name   string
+		filter *model.DatabaseMeasurementFilter
+		expect []string
+	}
+
+	cases := []testcase{{
+		name:   "with empty filter",
+		filter: &model.DatabaseMeasurementFilter{},
+		expect: []string{"websites/30722", "websites/3269", "im/30722"},
+	}, {
+		name:   "with since",
+		filter: &model.DatabaseMeasurementFilter{Since: day(2)},
+		expect: []string{"websites/3269", "im/30722"},
+	}, {
+		name:   "with until",
+		filter: &model.DatabaseMeasurementFilter{Until: day(2)},
+		expect: []string{"websites/30722"},
+	}, {
+		name:   "with test group name",
+		filter: &model.DatabaseMeasurementFilter{TestGroupName: "websites"},
+		expect: []string{"websites/30722", "websites/3269"},
+	}, {
+		name:   "with ASN",
+		filter: &model.DatabaseMeasurementFilter{ASN: 30722},
+		expect: []string{"websites/30722", "im/30722"},
+	}, {
+		name: "with all the conditions",
+		filter: &model.DatabaseMeasurementFilter{
+			Since:         day(1),
+			Until:         day(3),
+			TestGroupName: "websites",
+			ASN:           3269,
+		},
+		expect: []string{"websites/3269"},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			msmts, err := database.ListMeasurementsWithFilter(tc.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, msmt := range msmts {
+				if msmt.DatabaseURL.URL.String != "https://www.example.com/" {
+					t.Fatal("unexpected URL", msmt.DatabaseURL.URL.String)
+				}
+				got = append(got, fmt.Sprintf("%s/%d", msmt.TestGroupName, msmt.ASN))
+			}
+			if diff := cmp.Diff(tc.expect, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestUploadQueue(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	database, err := Open(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := database.Session()
+
+	network, err := database.CreateNetwork(&locationInfo{
+		asn:         30722,
+		countryCode: "IT",
+		networkName: "Antaninet",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := database.CreateResult(tmpdir, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msmts []*model.DatabaseMeasurement
+	for idx, testName := range []string{"telegram", "signal"} {
+		msmt, err := database.CreateMeasurement(
+			sql.NullString{}, testName, tmpdir, idx, result.ID, sql.NullInt64{},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := database.UploadFailed(msmt, "generic_timeout_error"); err != nil {
+			t.Fatal(err)
+		}
+		msmts = append(msmts, msmt)
+	}
+	if err := database.UpdateUploadedStatus(result); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := database.EnqueueUpload(msmts[0], "generic_timeout_error", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.EnqueueUpload(msmts[1], "generic_timeout_error", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("ListDueUploads only returns the due entries", func(t *testing.T) {
+		entries, err := database.ListDueUploads(now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatal("expected a single entry, got", len(entries))
+		}
+		entry := entries[0]
+		if entry.MeasurementID != msmts[0].ID {
+			t.Fatal("unexpected measurement ID", entry.MeasurementID)
+		}
+		if entry.Attempts != 1 {
+			t.Fatal("unexpected attempts", entry.Attempts)
+		}
+		if entry.LastError.String != "generic_timeout_error" {
+			t.Fatal("unexpected last error", entry.LastError.String)
+		}
+		if entry.ResultID != result.ID {
+			t.Fatal("unexpected result ID", entry.ResultID)
+		}
+		if entry.MeasurementFilePath.String != msmts[0].MeasurementFilePath.String {
+			t.Fatal("unexpected measurement file path", entry.MeasurementFilePath.String)
+		}
+	})
+
+	t.Run("ListDueUploads honours the limit", func(t *testing.T) {
+		entries, err := database.ListDueUploads(now.Add(time.Hour), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].MeasurementID != msmts[0].ID {
+			t.Fatal("unexpected entries", entries)
+		}
+	})
+
+	t.Run("EnqueueUpload updates existing entries", func(t *testing.T) {
+		if err := database.EnqueueUpload(msmts[1], "connection_refused", now); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := database.ListDueUploads(now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatal("expected two entries, got", len(entries))
+		}
+		entry := entries[1]
+		if entry.MeasurementID != msmts[1].ID || entry.Attempts != 2 {
+			t.Fatal("unexpected entry", entry)
+		}
+		if entry.LastError.String != "connection_refused" {
+			t.Fatal("unexpected last error", entry.LastError.String)
+		}
+	})
+
+	t.Run("UploadRetryFailed reschedules the entry", func(t *testing.T) {
+		entries, err := database.ListDueUploads(now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := &entries[0]
+		if err := database.UploadRetryFailed(entry, "eof_error", now.Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		entries, err = database.ListDueUploads(now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].MeasurementID != msmts[1].ID {
+			t.Fatal("unexpected entries", entries)
+		}
+		entries, err = database.ListDueUploads(now.Add(time.Minute), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatal("expected two entries, got", len(entries))
+		}
+		if entries[1].Attempts != 2 || entries[1].LastError.String != "eof_error" {
+			t.Fatal("unexpected entry", entries[1])
+		}
+	})
+
+	t.Run("UploadRetrySucceeded dequeues and marks as uploaded", func(t *testing.T) {
+		entries, err := database.ListDueUploads(now.Add(time.Minute), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for idx := range entries {
+			if err := database.UploadRetrySucceeded(&entries[idx], "20240101T000000Z_im_IT_30722_n1_xx"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entries, err = database.ListDueUploads(now.Add(24*time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Fatal("expected no entries, got", len(entries))
+		}
+		var msmt model.DatabaseMeasurement
+		if err := sess.Collection("measurements").Find("measurement_id", msmts[0].ID).One(&msmt); err != nil {
+			t.Fatal(err)
+		}
+		if !msmt.IsUploaded || msmt.UploadFailureMsg.Valid {
+			t.Fatal("expected the measurement to be uploaded")
+		}
+		if msmt.ReportID.String != "20240101T000000Z_im_IT_30722_n1_xx" {
+			t.Fatal("unexpected report ID", msmt.ReportID.String)
+		}
+		var res model.DatabaseResult
+		if err := sess.Collection("results").Find("result_id", result.ID).One(&res); err != nil {
+			t.Fatal(err)
+		}
+		if !res.IsUploaded {
+			t.Fatal("expected the result to be uploaded")
+		}
+		if res.TestGroupName != "im" || res.MeasurementDir != result.MeasurementDir {
+			t.Fatal("the result has been modified")
+		}
+	})
+
+	t.Run("deleting the result empties the queue", func(t *testing.T) {
+		msmt, err := database.CreateMeasurement(
+			sql.NullString{}, "whatsapp", tmpdir, 2, result.ID, sql.NullInt64{},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := database.EnqueueUpload(msmt, "generic_timeout_error", now); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.DeleteResult(result.ID); err != nil {
+			t.Fatal(err)
+		}
+		count, err := sess.Collection("upload_queue").Find().Count()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatal("expected empty queue, got", count)
+		}
+	})
+}
+
 func TestNetworkCreate(t *testing.T) {
 	tmpfile, err := ioutil.TempFile("", "dbtest")
 	if err != nil {
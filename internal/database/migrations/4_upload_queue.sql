@@ -0,0 +1,26 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `upload_queue`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The upload queue contains the measurements we failed to submit, such
+-- that we can retry submitting them later using exponential backoff.
+CREATE TABLE `upload_queue` (
+    `upload_queue_id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `measurement_id` INTEGER NOT NULL UNIQUE,
+    `upload_attempts` INTEGER NOT NULL DEFAULT 0,
+    `upload_last_error` TEXT,
+    `upload_next_retry_at` DATETIME NOT NULL,
+    `upload_enqueued_at` DATETIME NOT NULL,
+    CONSTRAINT `fk_measurement_id`
+      FOREIGN KEY (`measurement_id`)
+      REFERENCES `measurements`(`measurement_id`)
+      ON DELETE CASCADE
+);
+
+-- +migrate StatementEnd
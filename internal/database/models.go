@@ -6,6 +6,7 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
 )
 
 // Finished implements WritableDatabase.Finished
@@ -66,3 +67,83 @@ func (d *Database) UploadSucceeded(msmt *model.DatabaseMeasurement) error {
 	}
 	return nil
 }
+
+// EnqueueUpload implements WritableDatabase.EnqueueUpload
+func (d *Database) EnqueueUpload(msmt *model.DatabaseMeasurement, failure string, nextRetryAt time.Time) error {
+	err := d.sess.Tx(func(tx db.Session) error {
+		res := tx.Collection("upload_queue").Find("measurement_id", msmt.ID)
+		count, err := res.Count()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return res.Update(map[string]interface{}{
+				"upload_attempts":      db.Raw("upload_attempts + 1"),
+				"upload_last_error":    failure,
+				"upload_next_retry_at": nextRetryAt.UTC(),
+			})
+		}
+		_, err = tx.Collection("upload_queue").Insert(map[string]interface{}{
+			"measurement_id":       msmt.ID,
+			"upload_attempts":      1,
+			"upload_last_error":    failure,
+			"upload_next_retry_at": nextRetryAt.UTC(),
+			"upload_enqueued_at":   time.Now().UTC(),
+		})
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "enqueuing upload")
+	}
+	return nil
+}
+
+// UploadRetryFailed implements WritableDatabase.UploadRetryFailed
+func (d *Database) UploadRetryFailed(
+	entry *model.DatabaseUploadQueueEntry, failure string, nextRetryAt time.Time) error {
+	entry.Attempts++
+	entry.LastError = sql.NullString{String: failure, Valid: true}
+	entry.NextRetryAt = nextRetryAt.UTC()
+	err := d.sess.Collection("upload_queue").Find("upload_queue_id", entry.ID).Update(map[string]interface{}{
+		"upload_attempts":      entry.Attempts,
+		"upload_last_error":    entry.LastError,
+		"upload_next_retry_at": entry.NextRetryAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "updating upload queue")
+	}
+	return nil
+}
+
+// UploadRetrySucceeded implements WritableDatabase.UploadRetrySucceeded
+func (d *Database) UploadRetrySucceeded(entry *model.DatabaseUploadQueueEntry, reportID string) error {
+	err := d.sess.Tx(func(tx db.Session) error {
+		err := tx.Collection("measurements").Find("measurement_id", entry.MeasurementID).Update(map[string]interface{}{
+			"measurement_is_uploaded":        true,
+			"measurement_is_upload_failed":   false,
+			"measurement_upload_failure_msg": nil,
+			"report_id":                      reportID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.Collection("upload_queue").Find("upload_queue_id", entry.ID).Delete(); err != nil {
+			return err
+		}
+		uploadedTotal := model.UploadedTotalCount{}
+		req := tx.SQL().Select(
+			db.Raw("SUM(measurements.measurement_is_uploaded)"),
+			db.Raw("COUNT(*)"),
+		).From("measurements").Where("measurements.result_id = ?", entry.ResultID)
+		if err := req.One(&uploadedTotal); err != nil {
+			return err
+		}
+		return tx.Collection("results").Find("result_id", entry.ResultID).Update(map[string]interface{}{
+			"result_is_uploaded": uploadedTotal.UploadedCount == uploadedTotal.TotalCount,
+		})
+	})
+	if err != nil {
+		return errors.Wrap(err, "updating uploaded measurement")
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"net/http"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
@@ -139,6 +140,7 @@ func (e *experiment) MeasureAsync(
 	}
 	in, err := async.RunAsync(ctx, e.session, input, e.callbacks)
 	if err != nil {
+		metricMeasurementsFailedCount.WithLabelValues(e.testName, "measure").Inc()
 		return nil, err
 	}
 	out := make(chan *model.Measurement)
@@ -156,8 +158,10 @@ func (e *experiment) MeasureAsync(
 				// submit it. Most likely causes of error here are unlikely,
 				// e.g., the TestKeys being not serializable.
 				e.session.Logger().Warnf("can't scrub measurement: %s", err.Error())
+				metricMeasurementsFailedCount.WithLabelValues(e.testName, "measure").Inc()
 				continue
 			}
+			metricMeasurementsCount.WithLabelValues(e.testName).Inc()
 			out <- measurement
 		}
 	}()
@@ -187,9 +191,15 @@ func (e *experiment) MeasureWithContext(
 func (e *experiment) SubmitAndUpdateMeasurementContext(
 	ctx context.Context, measurement *model.Measurement) error {
 	if e.report == nil {
+		metricMeasurementsFailedCount.WithLabelValues(e.testName, "submit").Inc()
 		return errors.New("report is not open")
 	}
-	return e.report.SubmitMeasurement(ctx, measurement)
+	if err := e.report.SubmitMeasurement(ctx, measurement); err != nil {
+		metricMeasurementsFailedCount.WithLabelValues(e.testName, "submit").Inc()
+		return err
+	}
+	metricMeasurementsSubmittedCount.WithLabelValues(e.testName).Inc()
+	return nil
 }
 
 // newMeasurement creates a new measurement for this experiment with the given input.
@@ -223,6 +233,10 @@ func (e *experiment) newMeasurement(input string) *model.Measurement {
 	m.AddAnnotation("vcs_revision", runtimex.BuildInfo.VcsRevision)
 	m.AddAnnotation("vcs_time", runtimex.BuildInfo.VcsTime)
 	m.AddAnnotation("vcs_tool", runtimex.BuildInfo.VcsTool)
+	// tell whether we're using a cached check-in response and how old it is in seconds
+	if age, good := e.session.CheckInCacheAge(); good {
+		m.AddAnnotation("checkin_cache_age", strconv.FormatInt(int64(age.Seconds()), 10))
+	}
 	return m
 }
 
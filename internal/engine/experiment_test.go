@@ -2,6 +2,7 @@ package engine
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/enginelocate"
 	"github.com/ooni/probe-cli/v3/internal/experiment/example"
@@ -76,6 +77,34 @@ func TestExperimentHonoursSharingDefaults(t *testing.T) {
 	}
 }
 
+func TestExperimentAnnotatesCheckInCacheAge(t *testing.T) {
+	measure := func(sess *Session) *model.Measurement {
+		builder, err := sess.NewExperimentBuilder("example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := builder.NewExperiment().(*experiment)
+		return exp.newMeasurement("")
+	}
+
+	t.Run("when we are not using the cache", func(t *testing.T) {
+		m := measure(&Session{location: &enginelocate.Results{}})
+		if _, found := m.Annotations["checkin_cache_age"]; found {
+			t.Fatal("did not expect to see the annotation")
+		}
+	})
+
+	t.Run("when we are using the cache", func(t *testing.T) {
+		m := measure(&Session{
+			checkInCacheStored: time.Now().Add(-time.Hour),
+			location:           &enginelocate.Results{},
+		})
+		if value := m.Annotations["checkin_cache_age"]; value != "3600" {
+			t.Fatal("unexpected annotation value", value)
+		}
+	})
+}
+
 func TestExperimentMeasurementSummaryKeysNotImplemented(t *testing.T) {
 	t.Run("the .Anomaly method returns false", func(t *testing.T) {
 		sk := &ExperimentMeasurementSummaryKeysNotImplemented{}
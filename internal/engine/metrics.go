@@ -0,0 +1,104 @@
+package engine
+
+//
+// Metrics definitions and server
+//
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsSummaryObjectives returns the summary objectives for promauto.NewSummary.
+func metricsSummaryObjectives() map[float64]float64 {
+	// See https://grafana.com/blog/2022/03/01/how-summary-metrics-work-in-prometheus/
+	return map[float64]float64{
+		0.25: 0.010, // 0.240 <= φ <= 0.260
+		0.5:  0.010, // 0.490 <= φ <= 0.510
+		0.75: 0.010, // 0.740 <= φ <= 0.760
+		0.9:  0.010, // 0.899 <= φ <= 0.901
+		0.99: 0.001, // 0.989 <= φ <= 0.991
+	}
+}
+
+var (
+	// metricMeasurementsCount counts the measurements we run.
+	metricMeasurementsCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ooniprobe_engine_measurements_count",
+		Help: "Total number of measurements run",
+	}, []string{"experiment"})
+
+	// metricMeasurementsSubmittedCount counts the measurements we submitted.
+	metricMeasurementsSubmittedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ooniprobe_engine_measurements_submitted_count",
+		Help: "Total number of measurements submitted to the OONI collector",
+	}, []string{"experiment"})
+
+	// metricMeasurementsFailedCount counts the measurements that failed, where
+	// stage is either "measure" or "submit" depending on what failed.
+	metricMeasurementsFailedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ooniprobe_engine_measurements_failed_count",
+		Help: "Total number of measurements that failed",
+	}, []string{"experiment", "stage"})
+
+	// metricSessionBytesCount counts the bytes sent and received by sessions.
+	metricSessionBytesCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ooniprobe_engine_session_bytes_count",
+		Help: "Total number of bytes sent and received by closed measurement sessions",
+	}, []string{"direction"})
+
+	// metricCheckInDurationSeconds summarizes the duration of the check-in API call.
+	metricCheckInDurationSeconds = promauto.NewSummary(prometheus.SummaryOpts{
+		Name:       "ooniprobe_engine_checkin_duration_seconds",
+		Help:       "Summarizes the time to complete a successful check-in API call (in seconds)",
+		Objectives: metricsSummaryObjectives(),
+	})
+
+	// metricGeolocateDurationSeconds summarizes the duration of the geolocation.
+	metricGeolocateDurationSeconds = promauto.NewSummary(prometheus.SummaryOpts{
+		Name:       "ooniprobe_engine_geolocate_duration_seconds",
+		Help:       "Summarizes the time to complete a successful geolocation (in seconds)",
+		Objectives: metricsSummaryObjectives(),
+	})
+)
+
+// MetricsServer is an HTTP server exporting at /metrics the metrics of the engine,
+// of the packages it depends on, and of the Go runtime using the Prometheus
+// text exposition format. This is useful to monitor long running probes such as
+// `miniooni --repeat-every` and `ooniprobe run unattended`.
+type MetricsServer struct {
+	listener net.Listener
+	srv      *http.Server
+}
+
+// StartMetricsServer creates a [*MetricsServer] listening at the given TCP address
+// and serving metrics in a background goroutine. Because we do not authenticate
+// requests, you should usually listen on a loopback or otherwise private address.
+func StartMetricsServer(address string) (*MetricsServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	ms := &MetricsServer{
+		listener: listener,
+		srv:      &http.Server{Handler: mux},
+	}
+	go ms.srv.Serve(listener)
+	return ms, nil
+}
+
+// Addr returns the address where the server is listening.
+func (ms *MetricsServer) Addr() net.Addr {
+	return ms.listener.Addr()
+}
+
+// Close stops the server.
+func (ms *MetricsServer) Close() error {
+	return ms.srv.Close()
+}
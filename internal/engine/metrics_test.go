@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsServer(t *testing.T) {
+	t.Run("we can fetch the metrics", func(t *testing.T) {
+		srv, err := StartMetricsServer("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer srv.Close()
+		resp, err := http.Get("http://" + srv.Addr().String() + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "ooniprobe_engine_checkin_duration_seconds") {
+			t.Fatal("missing expected metric")
+		}
+	})
+
+	t.Run("with an invalid address", func(t *testing.T) {
+		srv, err := StartMetricsServer("127.0.0.1:antani")
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if srv != nil {
+			t.Fatal("expected nil server")
+		}
+	})
+}
+
+func TestMetricsSubmitFailure(t *testing.T) {
+	exp := &experiment{testName: "antani"}
+	counter := metricMeasurementsFailedCount.WithLabelValues("antani", "submit")
+	before := testutil.ToFloat64(counter)
+	if err := exp.SubmitAndUpdateMeasurementContext(context.Background(), nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if after := testutil.ToFloat64(counter); after != before+1 {
+		t.Fatal("expected the counter to increase", before, after)
+	}
+}
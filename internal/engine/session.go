@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/checkincache"
 	"github.com/ooni/probe-cli/v3/internal/enginelocate"
 	"github.com/ooni/probe-cli/v3/internal/enginenetx"
 	"github.com/ooni/probe-cli/v3/internal/engineresolver"
@@ -44,6 +47,10 @@ type SessionConfig struct {
 	// case, starting a tunnel will fail because there
 	// is no directory where to store state.
 	TunnelDir string
+
+	// WebTunnelBridge is the bridge line to be
+	// used by the torwebtunnel tunnel
+	WebTunnelBridge string
 }
 
 // Session is a measurement session. It contains shared information
@@ -54,6 +61,7 @@ type Session struct {
 	availableProbeServices   []model.OOAPIService
 	availableTestHelpers     map[string][]model.OOAPIService
 	byteCounter              *bytecounter.Counter
+	checkInCacheStored       time.Time
 	network                  *enginenetx.Network
 	kvStore                  model.KeyValueStore
 	location                 *enginelocate.Results
@@ -181,7 +189,7 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 	proxyURL := config.ProxyURL
 	if proxyURL != nil {
 		switch proxyURL.Scheme {
-		case "psiphon", "tor", "torsf", "fake":
+		case "psiphon", "tor", "torsf", "torwebtunnel", "fake":
 			config.Logger.Infof(
 				"starting '%s' tunnel; please be patient...", proxyURL.Scheme)
 			tunnel, _, err := tunnel.Start(ctx, &tunnel.Config{
@@ -192,6 +200,7 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 				TorArgs:             config.TorArgs,
 				TorBinary:           config.TorBinary,
 				TunnelDir:           config.TunnelDir,
+				WebTunnelBridge:     config.WebTunnelBridge,
 			})
 			if err != nil {
 				return nil, err
@@ -268,10 +277,6 @@ func (s *Session) CheckIn(
 	if err := s.maybeLookupLocationContext(ctx); err != nil {
 		return nil, err
 	}
-	client, err := s.newProbeServicesClientForCheckIn(ctx)
-	if err != nil {
-		return nil, err
-	}
 	if config.Platform == "" {
 		config.Platform = s.Platform()
 	}
@@ -293,13 +298,69 @@ func (s *Session) CheckIn(
 	if config.WebConnectivity.CategoryCodes == nil {
 		config.WebConnectivity.CategoryCodes = []string{}
 	}
+	client, err := s.newProbeServicesClientForCheckIn(ctx)
+	if err != nil {
+		return s.maybeCheckInFromCache(ctx, config, err)
+	}
+	t0 := time.Now()
 	resp, err := client.CheckIn(ctx, *config)
 	if err != nil {
-		return nil, err
+		return s.maybeCheckInFromCache(ctx, config, err)
 	}
+	metricCheckInDurationSeconds.Observe(time.Since(t0).Seconds())
 	return resp, nil
 }
 
+// maybeCheckInFromCache is called when the check-in API is not reachable and returns
+// the cached check-in response, if still valid and obtained for the same probe ASN, CC
+// and category codes, or the original error otherwise. Using the cached response means that we can
+// keep measuring, e.g., with Web Connectivity, when we cannot reach the API.
+func (s *Session) maybeCheckInFromCache(ctx context.Context,
+	config *model.OOAPICheckInConfig, err error) (*model.OOAPICheckInResult, error) {
+	if ctx.Err() != nil {
+		return nil, err // the user interrupted us, so don't fallback
+	}
+	cached, cacheErr := checkincache.GetResponse(s.kvStore)
+	if cacheErr != nil {
+		s.logger.Debugf("session: cannot use cached check-in response: %s", cacheErr.Error())
+		return nil, err
+	}
+	if cached.Config.ProbeASN != config.ProbeASN || cached.Config.ProbeCC != config.ProbeCC {
+		s.logger.Debugf("session: cached check-in response is for a different network")
+		return nil, err
+	}
+	if !sameCategoryCodes(cached.Config.WebConnectivity.CategoryCodes, config.WebConnectivity.CategoryCodes) {
+		s.logger.Debugf("session: cached check-in response is for different category codes")
+		return nil, err
+	}
+	s.logger.Warnf("session: check-in failed: %s", err.Error())
+	s.logger.Warnf("session: using the check-in response cached %s ago", cached.Age().Round(time.Second))
+	s.mu.Lock()
+	s.checkInCacheStored = cached.Stored
+	s.mu.Unlock()
+	return cached.Response, nil
+}
+
+// sameCategoryCodes returns whether a and b contain the same category codes
+// regardless of their order.
+func sameCategoryCodes(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// CheckInCacheAge returns the age of the cached check-in response we used because the
+// check-in API was not reachable. The boolean is false if we did not use the cache.
+func (s *Session) CheckInCacheAge() (time.Duration, bool) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	if s.checkInCacheStored.IsZero() {
+		return 0, false
+	}
+	return time.Since(s.checkInCacheStored), true
+}
+
 // maybeLookupLocationContext is a wrapper for MaybeLookupLocationContext that calls
 // the configurable testMaybeLookupLocationContext mock, if configured, and the
 // real MaybeLookupLocationContext API otherwise.
@@ -340,6 +401,10 @@ func (s *Session) doClose() {
 	// make sure we close open connections and persist stats to the key-value store
 	s.network.Close()
 
+	// account for the bytes sent and received during this session
+	metricSessionBytesCount.WithLabelValues("received").Add(s.byteCounter.KibiBytesReceived() * 1024)
+	metricSessionBytesCount.WithLabelValues("sent").Add(s.byteCounter.KibiBytesSent() * 1024)
+
 	s.resolver.CloseIdleConnections()
 	if s.tunnel != nil {
 		s.tunnel.Stop()
@@ -413,6 +478,9 @@ func (s *Session) newProbeServicesClient(ctx context.Context) (*probeservices.Cl
 	if err := s.maybeLookupLocationContext(ctx); err != nil {
 		return nil, err
 	}
+	if s.selectedProbeService == nil {
+		return nil, ErrAllProbeServicesFailed // we're using cached test helpers
+	}
 	if s.selectedProbeServiceHook != nil {
 		s.selectedProbeServiceHook(s.selectedProbeService)
 	}
@@ -641,7 +709,7 @@ func (s *Session) MaybeLookupBackendsContext(ctx context.Context) error {
 	candidates := probeservices.TryAll(ctx, s, s.getAvailableProbeServicesUnlocked())
 	selected := probeservices.SelectBest(candidates)
 	if selected == nil {
-		return ErrAllProbeServicesFailed
+		return s.maybeUseCachedTestHelpersUnlocked()
 	}
 	s.logger.Infof("session: using probe services: %+v", selected.Endpoint)
 	s.selectedProbeService = &selected.Endpoint
@@ -649,6 +717,25 @@ func (s *Session) MaybeLookupBackendsContext(ctx context.Context) error {
 	return nil
 }
 
+// maybeUseCachedTestHelpersUnlocked is called when all probe services failed and
+// configures the test helpers contained by the cached check-in response, if any, such
+// that we can still run experiments requiring test helpers. We return nil when we
+// could use cached test helpers and ErrAllProbeServicesFailed otherwise. Note that
+// we don't select any probe service, so we'll try to select one again the next time
+// we need one. This function WILL NOT acquire the mu mutex, therefore, you MUST
+// ensure you are using it from a locked context.
+func (s *Session) maybeUseCachedTestHelpersUnlocked() error {
+	cached, err := checkincache.GetResponse(s.kvStore)
+	if err != nil || len(cached.Response.Conf.TestHelpers) <= 0 {
+		return ErrAllProbeServicesFailed
+	}
+	s.logger.Warnf("session: %s", ErrAllProbeServicesFailed.Error())
+	s.logger.Warnf("session: using the test helpers cached %s ago", cached.Age().Round(time.Second))
+	s.availableTestHelpers = cached.Response.Conf.TestHelpers
+	s.checkInCacheStored = cached.Stored
+	return nil
+}
+
 // doLookupLocationContext performs a location lookup. If you want memoisation
 // of the results, you should use MaybeLookupLocationContext.
 func (s *Session) doLookupLocationContext(ctx context.Context) (*enginelocate.Results, error) {
@@ -679,10 +766,12 @@ func (s *Session) MaybeLookupLocationContext(ctx context.Context) error {
 	defer s.mu.Unlock()
 	s.mu.Lock()
 	if s.location == nil {
+		t0 := time.Now()
 		location, err := s.lookupLocationContext(ctx)
 		if err != nil {
 			return err
 		}
+		metricGeolocateDurationSeconds.Observe(time.Since(t0).Seconds())
 		s.location = location
 	}
 	return nil
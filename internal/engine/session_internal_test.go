@@ -140,6 +140,8 @@ func TestSessionCheckInNetworkError(t *testing.T) {
 			ASN:         137,
 			CountryCode: "IT",
 		},
+		kvStore:         &kvstore.Memory{},
+		logger:          model.DiscardLogger,
 		softwareName:    "miniooni",
 		softwareVersion: "0.1.0-dev",
 		testMaybeLookupLocationContext: func(ctx context.Context) error {
@@ -182,6 +184,8 @@ func TestSessionCheckInCannotCreateProbeServicesClient(t *testing.T) {
 			ASN:         137,
 			CountryCode: "IT",
 		},
+		kvStore:         &kvstore.Memory{},
+		logger:          model.DiscardLogger,
 		softwareName:    "miniooni",
 		softwareVersion: "0.1.0-dev",
 		testMaybeLookupLocationContext: func(ctx context.Context) error {
@@ -201,6 +205,171 @@ func TestSessionCheckInCannotCreateProbeServicesClient(t *testing.T) {
 	}
 }
 
+func TestSessionCheckInWithCachedResponse(t *testing.T) {
+	// newSession returns a session whose check-in fails with the given error and
+	// whose key-value store contains a cached response for AS137 in IT.
+	newSession := func(t *testing.T, expect error) *Session {
+		kvStore := &kvstore.Memory{}
+		cached := &model.OOAPICheckInResult{
+			ProbeASN: "AS137",
+			ProbeCC:  "IT",
+			Tests: model.OOAPICheckInResultNettests{
+				WebConnectivity: &model.OOAPICheckInInfoWebConnectivity{
+					URLs: []model.OOAPIURLInfo{{
+						CategoryCode: "NEWS",
+						CountryCode:  "IT",
+						URL:          "https://www.repubblica.it/",
+					}},
+				},
+			},
+		}
+		config := &model.OOAPICheckInConfig{
+			ProbeASN: "AS137",
+			ProbeCC:  "IT",
+			WebConnectivity: model.OOAPICheckInConfigWebConnectivity{
+				CategoryCodes: []string{"NEWS", "HUMR"},
+			},
+		}
+		if err := checkincache.StoreResponse(kvStore, config, cached); err != nil {
+			t.Fatal(err)
+		}
+		return &Session{
+			location: &enginelocate.Results{
+				ASN:         137,
+				CountryCode: "IT",
+			},
+			kvStore:         kvStore,
+			logger:          model.DiscardLogger,
+			softwareName:    "miniooni",
+			softwareVersion: "0.1.0-dev",
+			testMaybeLookupLocationContext: func(ctx context.Context) error {
+				return nil
+			},
+			testNewProbeServicesClientForCheckIn: func(
+				ctx context.Context) (sessionProbeServicesClientForCheckIn, error) {
+				return nil, expect
+			},
+		}
+	}
+
+	t.Run("we use the cached response when the API is not reachable", func(t *testing.T) {
+		s := newSession(t, errors.New("mocked error"))
+		if _, good := s.CheckInCacheAge(); good {
+			t.Fatal("expected to not be using the cache")
+		}
+		out, err := s.CheckIn(context.Background(), &model.OOAPICheckInConfig{
+			WebConnectivity: model.OOAPICheckInConfigWebConnectivity{
+				CategoryCodes: []string{"HUMR", "NEWS"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out.Tests.WebConnectivity.URLs) != 1 {
+			t.Fatal("unexpected URLs", out.Tests.WebConnectivity.URLs)
+		}
+		age, good := s.CheckInCacheAge()
+		if !good {
+			t.Fatal("expected to be using the cache")
+		}
+		if age < 0 || age > time.Minute {
+			t.Fatal("unexpected age", age)
+		}
+	})
+
+	t.Run("we don't use the cached response for another network", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		s := newSession(t, expect)
+		out, err := s.CheckIn(context.Background(), &model.OOAPICheckInConfig{ProbeASN: "AS30722"})
+		if !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+		if out != nil {
+			t.Fatal("expected nil out")
+		}
+	})
+
+	t.Run("we don't use the cached response for other category codes", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		s := newSession(t, expect)
+		out, err := s.CheckIn(context.Background(), &model.OOAPICheckInConfig{
+			WebConnectivity: model.OOAPICheckInConfigWebConnectivity{
+				CategoryCodes: []string{"NEWS"},
+			},
+		})
+		if !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+		if out != nil {
+			t.Fatal("expected nil out")
+		}
+	})
+
+	t.Run("we don't use the cached response when interrupted", func(t *testing.T) {
+		s := newSession(t, context.Canceled)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		out, err := s.CheckIn(ctx, &model.OOAPICheckInConfig{})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected err", err)
+		}
+		if out != nil {
+			t.Fatal("expected nil out")
+		}
+	})
+}
+
+func TestSessionMaybeUseCachedTestHelpers(t *testing.T) {
+	t.Run("without a cached response", func(t *testing.T) {
+		s := &Session{kvStore: &kvstore.Memory{}, logger: model.DiscardLogger}
+		if err := s.maybeUseCachedTestHelpersUnlocked(); !errors.Is(err, ErrAllProbeServicesFailed) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
+	t.Run("with cached test helpers", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		helpers := map[string][]model.OOAPIService{
+			"web-connectivity": {{
+				Address: "https://0.th.ooni.org/",
+				Type:    "https",
+			}},
+		}
+		cached := &model.OOAPICheckInResult{
+			Conf: model.OOAPICheckInResultConfig{
+				TestHelpers: helpers,
+			},
+		}
+		if err := checkincache.StoreResponse(kvStore, &model.OOAPICheckInConfig{}, cached); err != nil {
+			t.Fatal(err)
+		}
+		s := &Session{kvStore: kvStore, logger: model.DiscardLogger}
+		if err := s.maybeUseCachedTestHelpersUnlocked(); err != nil {
+			t.Fatal(err)
+		}
+		got, good := s.GetTestHelpersByName("web-connectivity")
+		if !good {
+			t.Fatal("expected to find the test helpers")
+		}
+		if diff := cmp.Diff(helpers["web-connectivity"], got); diff != "" {
+			t.Fatal(diff)
+		}
+		if _, good := s.CheckInCacheAge(); !good {
+			t.Fatal("expected to be using the cache")
+		}
+		// we must not attempt to use a nil probe service
+		s.testMaybeLookupBackendsContext = func(ctx context.Context) error {
+			return nil
+		}
+		s.testMaybeLookupLocationContext = func(ctx context.Context) error {
+			return nil
+		}
+		if _, err := s.newProbeServicesClient(context.Background()); !errors.Is(err, ErrAllProbeServicesFailed) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+}
+
 func TestLowercaseMaybeLookupLocationContextWithCancelledContext(t *testing.T) {
 	s := &Session{}
 	ctx, cancel := context.WithCancel(context.Background())
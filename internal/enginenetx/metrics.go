@@ -0,0 +1,19 @@
+package enginenetx
+
+//
+// Metrics definitions
+//
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricTacticsCount counts the outcome of using tactics for each domain, where the
+// result is one of "started", "success", "interrupted", "tcp_connect_error",
+// "tls_handshake_error", and "tls_verify_error". Dividing the "success" count by
+// the "started" count gives us the success rate of tactics for a domain.
+var metricTacticsCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ooniprobe_enginenetx_tactics_count",
+	Help: "Total number of tactics events by domain and result",
+}, []string{"domain", "result"})
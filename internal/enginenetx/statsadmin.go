@@ -0,0 +1,49 @@
+package enginenetx
+
+//
+// Managing the stats from the command line
+//
+
+import (
+	"encoding/json"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// DumpStats returns the HTTPS dialer tactics stats stored in the given key-value
+// store serialized as indented JSON, after removing old and excess entries. When
+// there are no stored stats, we return an empty stats document.
+func DumpStats(kvStore model.KeyValueStore) ([]byte, error) {
+	container := newStatsContainer()
+	if data, err := kvStore.Get(statsKey); err == nil {
+		parsed, err := parseStatsContainer(data)
+		if err != nil {
+			return nil, err
+		}
+		container = statsContainerPruneEntries(parsed)
+	}
+	return json.MarshalIndent(container, "", "  ")
+}
+
+// PruneStats removes old and excess entries from the HTTPS dialer tactics
+// stats stored in the given key-value store.
+func PruneStats(kvStore model.KeyValueStore) error {
+	return updateStatsContainer(kvStore, func(stored *statsContainer) *statsContainer {
+		return stored
+	})
+}
+
+// ImportStats merges the HTTPS dialer tactics stats serialized inside data, which is
+// typically the output of [DumpStats] on another probe, with the ones stored in the
+// given key-value store. For each tactic, we sum the counters and keep the most recent
+// LastUpdated. Because we prune entries not updated during the last week, importing
+// older stats has no effect. This function fails if data is not a valid stats document.
+func ImportStats(kvStore model.KeyValueStore, data []byte) error {
+	imported, err := parseStatsContainer(data)
+	if err != nil {
+		return err
+	}
+	return updateStatsContainer(kvStore, func(stored *statsContainer) *statsContainer {
+		return statsContainerMerge(stored, imported)
+	})
+}
@@ -0,0 +1,121 @@
+package enginenetx
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestDumpStats(t *testing.T) {
+	t.Run("when there are no stats", func(t *testing.T) {
+		data, err := DumpStats(&kvstore.Memory{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var container statsContainer
+		runtimex.Try0(json.Unmarshal(data, &container))
+		if diff := cmp.Diff(newStatsContainer(), &container); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the stats are invalid", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		runtimex.Try0(kvStore.Set(statsKey, []byte(`{"Version":1}`)))
+		data, err := DumpStats(kvStore)
+		if !errors.Is(err, errStatsContainerWrongVersion) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(data) != 0 {
+			t.Fatal("expected empty data")
+		}
+	})
+}
+
+func TestPruneAndImportStats(t *testing.T) {
+	recent := &httpsDialerTactic{
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "www.example.com",
+		VerifyHostname: "api.ooni.io",
+	}
+	old := &httpsDialerTactic{
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "www.example.org",
+		VerifyHostname: "api.ooni.io",
+	}
+
+	// create stats containing a recent and an old tactic
+	container := newStatsContainer()
+	container.SetStatsTacticLocked(recent, &statsTactic{
+		CountStarted: 4,
+		CountSuccess: 3,
+		LastUpdated:  time.Now().Add(-time.Hour),
+		Tactic:       recent.Clone(),
+	})
+	container.SetStatsTacticLocked(old, &statsTactic{
+		CountStarted: 1,
+		CountSuccess: 1,
+		LastUpdated:  time.Now().Add(-30 * 24 * time.Hour),
+		Tactic:       old.Clone(),
+	})
+	data := runtimex.Try1(json.Marshal(container))
+
+	t.Run("PruneStats removes old entries", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		runtimex.Try0(kvStore.Set(statsKey, data))
+		if err := PruneStats(kvStore); err != nil {
+			t.Fatal(err)
+		}
+		var pruned statsContainer
+		runtimex.Try0(json.Unmarshal(runtimex.Try1(kvStore.Get(statsKey)), &pruned))
+		if _, found := pruned.GetStatsTacticLocked(recent); !found {
+			t.Fatal("expected to find the recent tactic")
+		}
+		if _, found := pruned.GetStatsTacticLocked(old); found {
+			t.Fatal("expected not to find the old tactic")
+		}
+	})
+
+	t.Run("ImportStats merges the stats", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		if err := ImportStats(kvStore, data); err != nil {
+			t.Fatal(err)
+		}
+		if err := ImportStats(kvStore, data); err != nil {
+			t.Fatal(err)
+		}
+		dump, err := DumpStats(kvStore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var imported statsContainer
+		runtimex.Try0(json.Unmarshal(dump, &imported))
+		record, found := imported.GetStatsTacticLocked(recent)
+		if !found {
+			t.Fatal("expected to find the recent tactic")
+		}
+		if record.CountStarted != 8 || record.CountSuccess != 6 {
+			t.Fatal("unexpected record", record)
+		}
+		if _, found := imported.GetStatsTacticLocked(old); found {
+			t.Fatal("expected not to find the old tactic")
+		}
+	})
+
+	t.Run("ImportStats fails with invalid stats", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		if err := ImportStats(kvStore, []byte(`{`)); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := kvStore.Get(statsKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+			t.Fatal("expected the kvstore to be unmodified", err)
+		}
+	})
+}
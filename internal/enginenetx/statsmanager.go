@@ -7,9 +7,7 @@ package enginenetx
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"net"
 	"sort"
 	"sync"
@@ -362,6 +360,10 @@ func newStatsContainer() *statsContainer {
 // The zero value of this structure is not ready to use; please, use the
 // [newStatsManager] factory to create a new instance.
 type statsManager struct {
+	// baseline contains the stats we loaded from the key-value store, which
+	// we use to compute what changed when we merge the stats on close.
+	baseline *statsContainer
+
 	// cancel allows canceling the background stats pruner.
 	cancel context.CancelFunc
 
@@ -402,26 +404,14 @@ func loadStatsContainer(kvStore model.KeyValueStore) (*statsContainer, error) {
 		return nil, err
 	}
 
-	// parse as JSON
-	var container statsContainer
-	if err := json.Unmarshal(data, &container); err != nil {
-		return nil, err
-	}
-
-	// make sure the version is OK
-	if container.Version != statsContainerVersion {
-		err := fmt.Errorf(
-			"%s: %w: expected=%d got=%d",
-			statsKey,
-			errStatsContainerWrongVersion,
-			statsContainerVersion,
-			container.Version,
-		)
+	// parse and validate
+	container, err := parseStatsContainer(data)
+	if err != nil {
 		return nil, err
 	}
 
 	// make sure we prune the data structure
-	pruned := statsContainerPruneEntries(&container)
+	pruned := statsContainerPruneEntries(container)
 	return pruned, nil
 }
 
@@ -437,6 +427,7 @@ func newStatsManager(kvStore model.KeyValueStore, logger model.Logger, trimInter
 	ctx, cancel := context.WithCancel(context.Background())
 
 	mt := &statsManager{
+		baseline:  statsContainerPruneEntries(root), // DEEP COPY!
 		cancel:    cancel,
 		closeOnce: sync.Once{},
 		container: root,
@@ -489,6 +480,7 @@ func (mt *statsManager) OnStarting(tactic *httpsDialerTactic) {
 	// update stats
 	record.CountStarted++
 	record.LastUpdated = time.Now()
+	metricTacticsCount.WithLabelValues(tactic.VerifyHostname, "started").Inc()
 }
 
 func statsSafeIncrementMapStringInt64(input *map[string]int64, value string) {
@@ -516,11 +508,13 @@ func (mt *statsManager) OnTCPConnectError(ctx context.Context, tactic *httpsDial
 	record.LastUpdated = time.Now()
 	if ctx.Err() != nil {
 		record.CountTCPConnectInterrupt++
+		metricTacticsCount.WithLabelValues(tactic.VerifyHostname, "interrupted").Inc()
 		return
 	}
 
 	runtimex.Assert(err != nil, "OnTCPConnectError passed a nil error")
 	record.CountTCPConnectError++
+	metricTacticsCount.WithLabelValues(tactic.VerifyHostname, "tcp_connect_error").Inc()
 	statsSafeIncrementMapStringInt64(&record.HistoTCPConnectError, err.Error())
 }
 
@@ -541,11 +535,13 @@ func (mt *statsManager) OnTLSHandshakeError(ctx context.Context, tactic *httpsDi
 	record.LastUpdated = time.Now()
 	if ctx.Err() != nil {
 		record.CountTLSHandshakeInterrupt++
+		metricTacticsCount.WithLabelValues(tactic.VerifyHostname, "interrupted").Inc()
 		return
 	}
 
 	runtimex.Assert(err != nil, "OnTLSHandshakeError passed a nil error")
 	record.CountTLSHandshakeError++
+	metricTacticsCount.WithLabelValues(tactic.VerifyHostname, "tls_handshake_error").Inc()
 	statsSafeIncrementMapStringInt64(&record.HistoTLSHandshakeError, err.Error())
 }
 
@@ -565,6 +561,7 @@ func (mt *statsManager) OnTLSVerifyError(tactic *httpsDialerTactic, err error) {
 	// update stats
 	runtimex.Assert(err != nil, "OnTLSVerifyError passed a nil error")
 	record.CountTLSVerificationError++
+	metricTacticsCount.WithLabelValues(tactic.VerifyHostname, "tls_verify_error").Inc()
 	statsSafeIncrementMapStringInt64(&record.HistoTLSVerificationError, err.Error())
 	record.LastUpdated = time.Now()
 }
@@ -585,6 +582,7 @@ func (mt *statsManager) OnSuccess(tactic *httpsDialerTactic) {
 	// update stats
 	record.CountSuccess++
 	record.LastUpdated = time.Now()
+	metricTacticsCount.WithLabelValues(tactic.VerifyHostname, "success").Inc()
 }
 
 // Close implements io.Closer
@@ -598,11 +596,15 @@ func (mt *statsManager) Close() (err error) {
 			defer mt.mu.Unlock()
 			mt.mu.Lock()
 
-			// make sure we remove the unneeded entries one last time before saving them
-			container := statsContainerPruneEntries(mt.container)
+			// Other processes sharing the same key-value store may have written their own
+			// stats while we were running, so we merge what changed since we loaded the stats
+			// with the stats currently stored rather than overwriting them.
+			delta := statsContainerDelta(mt.container, mt.baseline)
 
 			// write updated stats into the underlying key-value store
-			err = mt.kvStore.Set(statsKey, runtimex.Try1(json.Marshal(container)))
+			err = updateStatsContainer(mt.kvStore, func(stored *statsContainer) *statsContainer {
+				return statsContainerMerge(stored, delta)
+			})
 		}()
 
 		// wait for background goroutine to join
@@ -0,0 +1,233 @@
+package enginenetx
+
+//
+// Merging stats written by several processes
+//
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// statsMergeMapStringInt64 returns a new map summing the values of left and right. When
+// both maps are nil, this function returns nil to preserve nil versus empty.
+func statsMergeMapStringInt64(left, right map[string]int64) (output map[string]int64) {
+	if left == nil && right == nil {
+		return
+	}
+	output = make(map[string]int64)
+	for key, value := range left {
+		output[key] += value
+	}
+	for key, value := range right {
+		output[key] += value
+	}
+	return
+}
+
+// statsTacticMerge returns a new [*statsTactic] where the counters and the histograms
+// are the sum of the ones in left and right, while LastUpdated and Tactic come from
+// the most recently updated record. We treat nil records as empty records.
+//
+// This function returns nil when both left and right are nil.
+func statsTacticMerge(left, right *statsTactic) *statsTactic {
+	switch {
+	case left == nil && right == nil:
+		return nil
+	case left == nil:
+		return right.Clone()
+	case right == nil:
+		return left.Clone()
+	}
+	newest := left
+	if right.LastUpdated.After(left.LastUpdated) {
+		newest = right
+	}
+	return &statsTactic{
+		CountStarted:               left.CountStarted + right.CountStarted,
+		CountTCPConnectError:       left.CountTCPConnectError + right.CountTCPConnectError,
+		CountTCPConnectInterrupt:   left.CountTCPConnectInterrupt + right.CountTCPConnectInterrupt,
+		CountTLSHandshakeError:     left.CountTLSHandshakeError + right.CountTLSHandshakeError,
+		CountTLSHandshakeInterrupt: left.CountTLSHandshakeInterrupt + right.CountTLSHandshakeInterrupt,
+		CountTLSVerificationError:  left.CountTLSVerificationError + right.CountTLSVerificationError,
+		CountSuccess:               left.CountSuccess + right.CountSuccess,
+		HistoTCPConnectError:       statsMergeMapStringInt64(left.HistoTCPConnectError, right.HistoTCPConnectError),
+		HistoTLSHandshakeError:     statsMergeMapStringInt64(left.HistoTLSHandshakeError, right.HistoTLSHandshakeError),
+		HistoTLSVerificationError:  statsMergeMapStringInt64(left.HistoTLSVerificationError, right.HistoTLSVerificationError),
+		LastUpdated:                newest.LastUpdated,
+		Tactic:                     statsMaybeCloneTactic(newest.Tactic),
+	}
+}
+
+// statsDeltaInt64 returns current minus base or zero if the difference is negative, which
+// happens when the background goroutine has pruned and then re-created a record.
+func statsDeltaInt64(current, base int64) int64 {
+	return max(0, current-base)
+}
+
+// statsDeltaMapStringInt64 returns a new map containing the positive differences
+// between current and base. When current is nil, this function returns nil.
+func statsDeltaMapStringInt64(current, base map[string]int64) (output map[string]int64) {
+	if current == nil {
+		return
+	}
+	output = make(map[string]int64)
+	for key, value := range current {
+		if delta := statsDeltaInt64(value, base[key]); delta > 0 {
+			output[key] = delta
+		}
+	}
+	return
+}
+
+// statsTacticDelta returns a new [*statsTactic] containing what changed in current
+// since base. When base is nil, we return a copy of current. When nothing changed,
+// this function returns nil. The caller must ensure current is not nil.
+func statsTacticDelta(current, base *statsTactic) *statsTactic {
+	if base == nil {
+		return current.Clone()
+	}
+	delta := &statsTactic{
+		CountStarted:               statsDeltaInt64(current.CountStarted, base.CountStarted),
+		CountTCPConnectError:       statsDeltaInt64(current.CountTCPConnectError, base.CountTCPConnectError),
+		CountTCPConnectInterrupt:   statsDeltaInt64(current.CountTCPConnectInterrupt, base.CountTCPConnectInterrupt),
+		CountTLSHandshakeError:     statsDeltaInt64(current.CountTLSHandshakeError, base.CountTLSHandshakeError),
+		CountTLSHandshakeInterrupt: statsDeltaInt64(current.CountTLSHandshakeInterrupt, base.CountTLSHandshakeInterrupt),
+		CountTLSVerificationError:  statsDeltaInt64(current.CountTLSVerificationError, base.CountTLSVerificationError),
+		CountSuccess:               statsDeltaInt64(current.CountSuccess, base.CountSuccess),
+		HistoTCPConnectError:       statsDeltaMapStringInt64(current.HistoTCPConnectError, base.HistoTCPConnectError),
+		HistoTLSHandshakeError:     statsDeltaMapStringInt64(current.HistoTLSHandshakeError, base.HistoTLSHandshakeError),
+		HistoTLSVerificationError:  statsDeltaMapStringInt64(current.HistoTLSVerificationError, base.HistoTLSVerificationError),
+		LastUpdated:                current.LastUpdated,
+		Tactic:                     statsMaybeCloneTactic(current.Tactic),
+	}
+	if !delta.LastUpdated.After(base.LastUpdated) {
+		return nil
+	}
+	return delta
+}
+
+// statsContainerForEachTactic calls fn for each well-formed tactic in the container.
+//
+// We serialize this data to disk, so we need to account for the case where
+// a user has manually edited the JSON to add nil or empty values.
+func statsContainerForEachTactic(c *statsContainer, fn func(domainEpnt, summary string, tactic *statsTactic)) {
+	if c == nil {
+		return
+	}
+	for domainEpnt, record := range c.DomainEndpoints {
+		if domainEpnt == "" || record == nil {
+			continue
+		}
+		for summary, tactic := range record.Tactics {
+			if summary == "" || tactic == nil || tactic.Tactic == nil {
+				continue
+			}
+			fn(domainEpnt, summary, tactic)
+		}
+	}
+}
+
+// statsContainerLookupTactic returns the tactic with the given domain endpoint and summary or nil.
+func statsContainerLookupTactic(c *statsContainer, domainEpnt, summary string) *statsTactic {
+	if c == nil {
+		return nil
+	}
+	record := c.DomainEndpoints[domainEpnt]
+	if record == nil {
+		return nil
+	}
+	return record.Tactics[summary]
+}
+
+// statsContainerSetTactic sets the tactic with the given domain endpoint and summary.
+func statsContainerSetTactic(c *statsContainer, domainEpnt, summary string, tactic *statsTactic) {
+	record := c.DomainEndpoints[domainEpnt]
+	if record == nil {
+		record = &statsDomainEndpoint{
+			Tactics: map[string]*statsTactic{},
+		}
+		c.DomainEndpoints[domainEpnt] = record
+	}
+	record.Tactics[summary] = tactic
+}
+
+// statsContainerMerge returns a new [*statsContainer] obtained by merging each tactic
+// in left with the corresponding tactic in right using [statsTacticMerge].
+func statsContainerMerge(left, right *statsContainer) *statsContainer {
+	output := newStatsContainer()
+	statsContainerForEachTactic(left, func(domainEpnt, summary string, tactic *statsTactic) {
+		statsContainerSetTactic(output, domainEpnt, summary, tactic.Clone())
+	})
+	statsContainerForEachTactic(right, func(domainEpnt, summary string, tactic *statsTactic) {
+		merged := statsTacticMerge(statsContainerLookupTactic(output, domainEpnt, summary), tactic)
+		statsContainerSetTactic(output, domainEpnt, summary, merged)
+	})
+	return output
+}
+
+// statsContainerDelta returns a new [*statsContainer] containing the tactics that
+// changed in current since base, computed using [statsTacticDelta].
+func statsContainerDelta(current, base *statsContainer) *statsContainer {
+	output := newStatsContainer()
+	statsContainerForEachTactic(current, func(domainEpnt, summary string, tactic *statsTactic) {
+		delta := statsTacticDelta(tactic, statsContainerLookupTactic(base, domainEpnt, summary))
+		if delta != nil {
+			statsContainerSetTactic(output, domainEpnt, summary, delta)
+		}
+	})
+	return output
+}
+
+// parseStatsContainer parses and validates a serialized [*statsContainer].
+func parseStatsContainer(data []byte) (*statsContainer, error) {
+	// parse as JSON
+	var container statsContainer
+	if err := json.Unmarshal(data, &container); err != nil {
+		return nil, err
+	}
+
+	// make sure the version is OK
+	if container.Version != statsContainerVersion {
+		err := fmt.Errorf(
+			"%s: %w: expected=%d got=%d",
+			statsKey,
+			errStatsContainerWrongVersion,
+			statsContainerVersion,
+			container.Version,
+		)
+		return nil, err
+	}
+
+	return &container, nil
+}
+
+// updateStatsContainer replaces the stats stored in the key-value store with the
+// pruned result of calling fn with the currently stored stats. We pass to fn an empty
+// container when the stored stats are missing or invalid. When the key-value store
+// implements [model.KeyValueStoreUpdater], the update is atomic, which guarantees
+// that concurrent processes sharing the same stats do not lose updates.
+func updateStatsContainer(kvStore model.KeyValueStore, fn func(stored *statsContainer) *statsContainer) error {
+	transform := func(data []byte) ([]byte, error) {
+		stored, err := parseStatsContainer(data)
+		if err != nil {
+			stored = newStatsContainer()
+		}
+		return json.Marshal(statsContainerPruneEntries(fn(stored)))
+	}
+
+	if updater, ok := kvStore.(model.KeyValueStoreUpdater); ok {
+		return updater.Update(statsKey, transform)
+	}
+
+	// Note: a failure here means that there's no such key, which we
+	// handle exactly like we handle invalid stored stats.
+	data, _ := kvStore.Get(statsKey)
+	value, err := transform(data)
+	if err != nil {
+		return err
+	}
+	return kvStore.Set(statsKey, value)
+}
@@ -0,0 +1,333 @@
+package enginenetx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestStatsTacticMerge(t *testing.T) {
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+
+	left := &statsTactic{
+		CountStarted:         3,
+		CountTCPConnectError: 1,
+		CountSuccess:         2,
+		HistoTCPConnectError: map[string]int64{
+			"connection_refused": 1,
+		},
+		LastUpdated: older,
+		Tactic: &httpsDialerTactic{
+			Address:        "162.55.247.208",
+			Port:           "443",
+			SNI:            "www.example.com",
+			VerifyHostname: "api.ooni.io",
+		},
+	}
+
+	right := &statsTactic{
+		CountStarted:           4,
+		CountTLSHandshakeError: 2,
+		CountSuccess:           2,
+		HistoTCPConnectError: map[string]int64{
+			"connection_refused":    2,
+			"generic_timeout_error": 1,
+		},
+		HistoTLSHandshakeError: map[string]int64{
+			"connection_reset": 2,
+		},
+		LastUpdated: newer,
+		Tactic: &httpsDialerTactic{
+			Address:        "162.55.247.208",
+			InitialDelay:   time.Second,
+			Port:           "443",
+			SNI:            "www.example.com",
+			VerifyHostname: "api.ooni.io",
+		},
+	}
+
+	t.Run("with both nil", func(t *testing.T) {
+		if out := statsTacticMerge(nil, nil); out != nil {
+			t.Fatal("expected nil")
+		}
+	})
+
+	t.Run("with either nil", func(t *testing.T) {
+		if diff := cmp.Diff(left, statsTacticMerge(left, nil)); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff(right, statsTacticMerge(nil, right)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with both non-nil", func(t *testing.T) {
+		expect := &statsTactic{
+			CountStarted:           7,
+			CountTCPConnectError:   1,
+			CountTLSHandshakeError: 2,
+			CountSuccess:           4,
+			HistoTCPConnectError: map[string]int64{
+				"connection_refused":    3,
+				"generic_timeout_error": 1,
+			},
+			HistoTLSHandshakeError: map[string]int64{
+				"connection_reset": 2,
+			},
+			HistoTLSVerificationError: nil,
+			LastUpdated:               newer,
+			Tactic:                    right.Tactic,
+		}
+
+		// the result must not depend on the order of the arguments
+		if diff := cmp.Diff(expect, statsTacticMerge(left, right)); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff(expect, statsTacticMerge(right, left)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestStatsTacticDelta(t *testing.T) {
+	base := &statsTactic{
+		CountStarted: 3,
+		CountSuccess: 2,
+		HistoTCPConnectError: map[string]int64{
+			"connection_refused": 1,
+		},
+		LastUpdated: time.Now().Add(-time.Hour),
+		Tactic: &httpsDialerTactic{
+			Address:        "162.55.247.208",
+			Port:           "443",
+			SNI:            "www.example.com",
+			VerifyHostname: "api.ooni.io",
+		},
+	}
+
+	t.Run("when base is nil", func(t *testing.T) {
+		if diff := cmp.Diff(base, statsTacticDelta(base, nil)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when nothing changed", func(t *testing.T) {
+		if out := statsTacticDelta(base.Clone(), base); out != nil {
+			t.Fatal("expected nil")
+		}
+	})
+
+	t.Run("when something changed", func(t *testing.T) {
+		current := base.Clone()
+		current.CountStarted += 2
+		current.CountTCPConnectError++
+		current.HistoTCPConnectError["connection_refused"]++
+		current.HistoTCPConnectError["generic_timeout_error"]++
+		current.LastUpdated = time.Now()
+
+		expect := &statsTactic{
+			CountStarted:         2,
+			CountTCPConnectError: 1,
+			HistoTCPConnectError: map[string]int64{
+				"connection_refused":    1,
+				"generic_timeout_error": 1,
+			},
+			LastUpdated: current.LastUpdated,
+			Tactic:      base.Tactic,
+		}
+		if diff := cmp.Diff(expect, statsTacticDelta(current, base)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when a counter would become negative", func(t *testing.T) {
+		current := base.Clone()
+		current.CountStarted = 1
+		current.LastUpdated = time.Now()
+		if out := statsTacticDelta(current, base); out.CountStarted != 0 || out.CountSuccess != 0 {
+			t.Fatal("unexpected delta", out)
+		}
+	})
+}
+
+func TestStatsContainerMerge(t *testing.T) {
+	tactic := &statsTactic{
+		CountStarted: 1,
+		CountSuccess: 1,
+		LastUpdated:  time.Now(),
+		Tactic: &httpsDialerTactic{
+			Address:        "162.55.247.208",
+			Port:           "443",
+			SNI:            "www.example.com",
+			VerifyHostname: "api.ooni.io",
+		},
+	}
+	summary := tactic.Tactic.tacticSummaryKey()
+
+	// make sure we include malformed entries that could be present on disk
+	left := &statsContainer{
+		DomainEndpoints: map[string]*statsDomainEndpoint{
+			"": {},
+			"api.ooni.io:443": {
+				Tactics: map[string]*statsTactic{
+					"":      tactic,
+					summary: tactic,
+					"x":     nil,
+					"y":     {},
+				},
+			},
+			"www.example.com:443": nil,
+		},
+		Version: statsContainerVersion,
+	}
+
+	out := statsContainerMerge(left, statsContainerMerge(left, nil))
+	expect := &statsContainer{
+		DomainEndpoints: map[string]*statsDomainEndpoint{
+			"api.ooni.io:443": {
+				Tactics: map[string]*statsTactic{
+					summary: {
+						CountStarted: 2,
+						CountSuccess: 2,
+						LastUpdated:  tactic.LastUpdated,
+						Tactic:       tactic.Tactic,
+					},
+				},
+			},
+		},
+		Version: statsContainerVersion,
+	}
+	if diff := cmp.Diff(expect, out); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestUpdateStatsContainer(t *testing.T) {
+	t.Run("with a key-value store that does not support updates", func(t *testing.T) {
+		var stored []byte
+		kvStore := &mocks.KeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, kvstore.ErrNoSuchKey
+			},
+			MockSet: func(key string, value []byte) error {
+				stored = value
+				return nil
+			},
+		}
+		err := updateStatsContainer(kvStore, func(stored *statsContainer) *statsContainer {
+			return stored
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(`{"DomainEndpoints":{},"Version":5}`, string(stored)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when we cannot set the value", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		kvStore := &mocks.KeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, kvstore.ErrNoSuchKey
+			},
+			MockSet: func(key string, value []byte) error {
+				return expected
+			},
+		}
+		err := updateStatsContainer(kvStore, func(stored *statsContainer) *statsContainer {
+			return stored
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the stored stats are invalid", func(t *testing.T) {
+		kvStore := &kvstore.Memory{}
+		if err := kvStore.Set(statsKey, []byte(`{"Version":1}`)); err != nil {
+			t.Fatal(err)
+		}
+		err := updateStatsContainer(kvStore, func(stored *statsContainer) *statsContainer {
+			if len(stored.DomainEndpoints) != 0 || stored.Version != statsContainerVersion {
+				t.Fatal("expected an empty container", stored)
+			}
+			return stored
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// This test ensures that several stats managers sharing the same key-value store, which
+// is what happens when several processes run at the same time, do not lose updates.
+func TestStatsManagerMergesConcurrentUpdates(t *testing.T) {
+	tactic := &httpsDialerTactic{
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "www.example.com",
+		VerifyHostname: "api.ooni.io",
+	}
+
+	// seed the key-value store with some stats
+	kvStore := runtimex.Try1(kvstore.NewFS(t.TempDir()))
+	seed := newStatsContainer()
+	seed.SetStatsTacticLocked(tactic, &statsTactic{
+		CountStarted: 10,
+		CountSuccess: 10,
+		LastUpdated:  time.Now().Add(-time.Hour),
+		Tactic:       tactic.Clone(),
+	})
+	if err := kvStore.Set(statsKey, runtimex.Try1(json.Marshal(seed))); err != nil {
+		t.Fatal(err)
+	}
+
+	// create all the managers before any of them writes
+	const count = 8
+	var managers []*statsManager
+	for idx := 0; idx < count; idx++ {
+		managers = append(managers, newStatsManager(kvStore, model.DiscardLogger, time.Hour))
+	}
+
+	// simulate a success and a failure for each manager and close concurrently
+	wg := &sync.WaitGroup{}
+	for _, sm := range managers {
+		wg.Add(1)
+		go func(sm *statsManager) {
+			defer wg.Done()
+			sm.OnStarting(tactic)
+			sm.OnSuccess(tactic)
+			sm.OnStarting(tactic)
+			sm.OnTLSHandshakeError(context.Background(), tactic, errors.New(netxlite.FailureConnectionReset))
+			if err := sm.Close(); err != nil {
+				t.Error(err)
+			}
+		}(sm)
+	}
+	wg.Wait()
+
+	// make sure we have accounted for all the updates
+	stored := runtimex.Try1(loadStatsContainer(kvStore))
+	record, found := stored.GetStatsTacticLocked(tactic)
+	if !found {
+		t.Fatal("expected to find the tactic")
+	}
+	if record.CountStarted != 10+2*count || record.CountSuccess != 10+count || record.CountTLSHandshakeError != count {
+		t.Fatal("unexpected record", record)
+	}
+	if record.HistoTLSHandshakeError[netxlite.FailureConnectionReset] != count {
+		t.Fatal("unexpected histogram", record.HistoTLSHandshakeError)
+	}
+}
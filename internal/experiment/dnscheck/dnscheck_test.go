@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -49,7 +50,7 @@ func TestExperimentNameAndVersion(t *testing.T) {
 	if measurer.ExperimentName() != "dnscheck" {
 		t.Error("unexpected experiment name")
 	}
-	if measurer.ExperimentVersion() != "0.9.2" {
+	if measurer.ExperimentVersion() != "0.9.3" {
 		t.Error("unexpected experiment version")
 	}
 }
@@ -111,6 +112,30 @@ func TestWithCancelledContext(t *testing.T) {
 	}
 }
 
+func TestWithCancelledContextAndDNSOverQUIC(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // immediately cancel the context
+	measurer := NewExperimentMeasurer(Config{
+		DefaultAddrs: "94.140.14.14 94.140.15.15",
+	})
+	measurement := &model.Measurement{Input: "quic://dns.adguard-dns.com"}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(log.Log),
+		Measurement: measurement,
+		Session:     newsession(),
+	}
+	err := measurer.Run(ctx, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := measurement.TestKeys.(*TestKeys)
+	for resolverURL := range tk.Lookups {
+		if !strings.HasPrefix(resolverURL, "quic://") {
+			t.Fatal("unexpected resolver URL", resolverURL)
+		}
+	}
+}
+
 func TestMakeResolverURL(t *testing.T) {
 	// test address substitution
 	addr := "255.255.255.0"
@@ -0,0 +1,168 @@
+// Package dsl contains the dsl experiment, which runs a measurement
+// expressed using the JSON representation of the measurement DSL.
+//
+// The document is either provided inline using the "Document" option or
+// fetched from the "DocumentURL" option. This allows researchers to ship new
+// measurement recipes (e.g., using OONI Run v2) without a probe release.
+package dsl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ooni/probe-cli/v3/internal/httpclientx"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/x/dslengine"
+	"github.com/ooni/probe-cli/v3/internal/x/dsljson"
+)
+
+const (
+	testName    = "dsl"
+	testVersion = "0.1.0"
+)
+
+const (
+	// maxActiveDNSLookups is the maximum number of DNS lookups we run in parallel.
+	maxActiveDNSLookups = 4
+
+	// maxActiveConns is the maximum number of endpoint measurements we run in parallel.
+	maxActiveConns = 16
+)
+
+// Config contains the experiment config.
+type Config struct {
+	// Document is the JSON-serialized DSL document to run.
+	Document string `ooni:"JSON-serialized DSL document to run"`
+
+	// DocumentURL is the URL from which to fetch the DSL document.
+	DocumentURL string `ooni:"URL from which to fetch the DSL document to run"`
+}
+
+// TestKeys contains the experiment results.
+type TestKeys struct {
+	// DocumentSHA256 is the SHA256 of the DSL document we executed.
+	DocumentSHA256 string `json:"document_sha256"`
+
+	// DocumentURL is the URL from which we fetched the document or
+	// an empty string if the document was provided inline.
+	DocumentURL string `json:"document_url"`
+
+	// NetworkEvents contains I/O events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// Queries contains the DNS queries results.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// Requests contains HTTP request results.
+	Requests []*model.ArchivalHTTPRequestResult `json:"requests"`
+
+	// TCPConnect contains the TCP connect results.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshakes contains the TLS handshakes results.
+	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+
+	// QUICHandshakes contains the QUIC handshakes results.
+	QUICHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"quic_handshakes"`
+}
+
+// Measurer performs the measurement.
+type Measurer struct {
+	config Config
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer(config Config) model.ExperimentMeasurer {
+	return &Measurer{config: config}
+}
+
+// ExperimentName implements model.ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements model.ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+var (
+	// errNoDocument indicates that neither Document nor DocumentURL are set.
+	errNoDocument = errors.New("dsl: you must set either the Document or the DocumentURL option")
+
+	// errTooManyDocuments indicates that both Document and DocumentURL are set.
+	errTooManyDocuments = errors.New("dsl: you cannot set both the Document and the DocumentURL options")
+
+	// errInvalidDocument indicates that we cannot parse or load the document.
+	errInvalidDocument = errors.New("dsl: invalid document")
+)
+
+// Run implements model.ExperimentMeasurer.Run.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	measurement := args.Measurement
+	sess := args.Session
+
+	// obtain the raw document
+	rawDocument, err := m.document(ctx, sess)
+	if err != nil {
+		return err
+	}
+
+	// parse the raw document into the loadable AST format
+	var root dsljson.RootNode
+	if err := json.Unmarshal(rawDocument, &root); err != nil {
+		return fmt.Errorf("%w: %s", errInvalidDocument, err.Error())
+	}
+
+	// create a runtime for executing the DSL
+	rtx := dslengine.NewRuntimeMeasurexLite(
+		sess.Logger(), measurement.MeasurementStartTimeSaved,
+		dslengine.OptionMaxActiveDNSLookups(maxActiveDNSLookups),
+		dslengine.OptionMaxActiveConns(maxActiveConns),
+	)
+
+	// interpret the JSON representation of the DSL
+	if err := dsljson.Run(ctx, rtx, &root); err != nil {
+		return fmt.Errorf("%w: %s", errInvalidDocument, err.Error())
+	}
+
+	// fill the test keys using the collected observations
+	observations := rtx.Observations()
+	digest := sha256.Sum256(rawDocument)
+	measurement.TestKeys = &TestKeys{
+		DocumentSHA256: hex.EncodeToString(digest[:]),
+		DocumentURL:    m.config.DocumentURL,
+		NetworkEvents:  observations.NetworkEvents,
+		Queries:        observations.Queries,
+		Requests:       observations.Requests,
+		TCPConnect:     observations.TCPConnect,
+		TLSHandshakes:  observations.TLSHandshakes,
+		QUICHandshakes: observations.QUICHandshakes,
+	}
+	return nil
+}
+
+// document returns the inline document or fetches it from DocumentURL.
+func (m *Measurer) document(ctx context.Context, sess model.ExperimentSession) ([]byte, error) {
+	switch {
+	case m.config.Document != "" && m.config.DocumentURL != "":
+		return nil, errTooManyDocuments
+
+	case m.config.Document != "":
+		return []byte(m.config.Document), nil
+
+	case m.config.DocumentURL != "":
+		return httpclientx.GetRaw(ctx, m.config.DocumentURL, &httpclientx.Config{
+			Client:    sess.DefaultHTTPClient(),
+			Logger:    sess.Logger(),
+			UserAgent: sess.UserAgent(),
+		})
+
+	default:
+		return nil, errNoDocument
+	}
+}
@@ -0,0 +1,237 @@
+package dsl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// qaDocument is a DSL document measuring https://www.example.com/.
+const qaDocument = `{
+	"stages": [{
+		"name": "getaddrinfo",
+		"value": {"domain": "www.example.com", "output": "addrs"}
+	}, {
+		"name": "make_endpoints",
+		"value": {"input": "addrs", "output": "endpoints", "port": "443"}
+	}, {
+		"name": "tcp_connect",
+		"value": {"input": "endpoints", "output": "tcp_conns"}
+	}, {
+		"name": "tls_handshake",
+		"value": {"input": "tcp_conns", "output": "tls_conns", "server_name": "www.example.com"}
+	}, {
+		"name": "http_round_trip",
+		"value": {
+			"host": "www.example.com",
+			"input": "tls_conns",
+			"method": "GET",
+			"output": "done",
+			"url_path": "/"
+		}
+	}]
+}`
+
+const (
+	// qaDocumentServerAddress is the address of the server serving the DSL document.
+	qaDocumentServerAddress = "104.16.248.249"
+
+	// qaDocumentURL is the URL from which to fetch the DSL document.
+	qaDocumentURL = "https://recipes.example.net/dsl.json"
+)
+
+// newQAEnv creates a [*netemx.QAEnv] for the internet scenario that
+// additionally serves the [qaDocument] from [qaDocumentURL].
+func newQAEnv() *netemx.QAEnv {
+	scenario := append([]*netemx.ScenarioDomainAddresses{}, netemx.InternetScenario...)
+	scenario = append(scenario, &netemx.ScenarioDomainAddresses{
+		Domains:        []string{"recipes.example.net"},
+		Addresses:      []string{qaDocumentServerAddress},
+		Role:           netemx.ScenarioRoleWebServer,
+		ServerNameMain: "recipes.example.net",
+		WebServerFactory: netemx.HTTPHandlerFactoryFunc(
+			func(env netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/dsl.json" {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.Write([]byte(qaDocument))
+				})
+			}),
+	})
+	return netemx.MustNewScenario(scenario)
+}
+
+func TestMeasurerExperimentNameVersion(t *testing.T) {
+	measurer := NewExperimentMeasurer(Config{})
+	if measurer.ExperimentName() != "dsl" {
+		t.Fatal("unexpected ExperimentName")
+	}
+	if measurer.ExperimentVersion() != "0.1.0" {
+		t.Fatal("unexpected ExperimentVersion")
+	}
+}
+
+func TestMeasurerRun(t *testing.T) {
+	// runMeasurement runs the experiment with the given config inside the env
+	runMeasurement := func(env *netemx.QAEnv, config Config) (*model.Measurement, error) {
+		var (
+			measurement = &model.Measurement{MeasurementStartTimeSaved: time.Now()}
+			err         error
+		)
+		env.Do(func() {
+			args := &model.ExperimentArgs{
+				Callbacks:   model.NewPrinterCallbacks(log.Log),
+				Measurement: measurement,
+				Session: &mockable.Session{
+					MockableHTTPClient: netxlite.NewHTTPClientStdlib(log.Log),
+					MockableLogger:     log.Log,
+					MockableUserAgent:  model.HTTPHeaderUserAgent,
+				},
+			}
+			err = NewExperimentMeasurer(config).Run(context.Background(), args)
+		})
+		return measurement, err
+	}
+
+	// expectedSHA256 is the SHA256 of the qaDocument
+	expectedSHA256 := (func() string {
+		digest := sha256.Sum256([]byte(qaDocument))
+		return hex.EncodeToString(digest[:])
+	}())
+
+	t.Run("we fail when there is no document", func(t *testing.T) {
+		env := newQAEnv()
+		defer env.Close()
+		measurement, err := runMeasurement(env, Config{})
+		if !errors.Is(err, errNoDocument) {
+			t.Fatal("unexpected error", err)
+		}
+		if measurement.TestKeys != nil {
+			t.Fatal("expected nil test keys")
+		}
+	})
+
+	t.Run("we fail when there are two documents", func(t *testing.T) {
+		env := newQAEnv()
+		defer env.Close()
+		_, err := runMeasurement(env, Config{Document: qaDocument, DocumentURL: qaDocumentURL})
+		if !errors.Is(err, errTooManyDocuments) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we fail when the document is not valid JSON", func(t *testing.T) {
+		env := newQAEnv()
+		defer env.Close()
+		_, err := runMeasurement(env, Config{Document: "{"})
+		if !errors.Is(err, errInvalidDocument) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we fail when the document contains an unknown stage", func(t *testing.T) {
+		env := newQAEnv()
+		defer env.Close()
+		_, err := runMeasurement(env, Config{Document: `{"stages":[{"name":"antani","value":{}}]}`})
+		if !errors.Is(err, errInvalidDocument) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we fail when we cannot fetch the document", func(t *testing.T) {
+		env := newQAEnv()
+		defer env.Close()
+		_, err := runMeasurement(env, Config{DocumentURL: "https://recipes.example.net/nonexistent.json"})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	// verifySuccess verifies the test keys of a successful measurement
+	verifySuccess := func(t *testing.T, tk *TestKeys) {
+		if tk.DocumentSHA256 != expectedSHA256 {
+			t.Fatal("unexpected document_sha256", tk.DocumentSHA256)
+		}
+		if len(tk.Queries) <= 0 {
+			t.Fatal("expected at least one query")
+		}
+		if len(tk.TCPConnect) != 1 || tk.TCPConnect[0].Status.Failure != nil {
+			t.Fatal("unexpected tcp_connect", tk.TCPConnect)
+		}
+		if len(tk.TLSHandshakes) != 1 || tk.TLSHandshakes[0].Failure != nil {
+			t.Fatal("unexpected tls_handshakes", tk.TLSHandshakes)
+		}
+		if len(tk.Requests) != 1 || tk.Requests[0].Response.Code != 200 {
+			t.Fatal("unexpected requests", tk.Requests)
+		}
+		if len(tk.QUICHandshakes) != 0 {
+			t.Fatal("unexpected quic_handshakes", tk.QUICHandshakes)
+		}
+	}
+
+	t.Run("we run an inline document", func(t *testing.T) {
+		env := newQAEnv()
+		defer env.Close()
+		measurement, err := runMeasurement(env, Config{Document: qaDocument})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := measurement.TestKeys.(*TestKeys)
+		if tk.DocumentURL != "" {
+			t.Fatal("unexpected document_url", tk.DocumentURL)
+		}
+		verifySuccess(t, tk)
+	})
+
+	t.Run("we run a document fetched from an URL", func(t *testing.T) {
+		env := newQAEnv()
+		defer env.Close()
+		measurement, err := runMeasurement(env, Config{DocumentURL: qaDocumentURL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := measurement.TestKeys.(*TestKeys)
+		if tk.DocumentURL != qaDocumentURL {
+			t.Fatal("unexpected document_url", tk.DocumentURL)
+		}
+		verifySuccess(t, tk)
+	})
+
+	t.Run("we archive failures when the endpoint is blocked", func(t *testing.T) {
+		env := newQAEnv()
+		defer env.Close()
+		env.DPIEngine().AddRule(&netem.DPICloseConnectionForServerEndpoint{
+			Logger:          log.Log,
+			ServerIPAddress: netemx.AddressWwwExampleCom,
+			ServerPort:      443,
+		})
+		measurement, err := runMeasurement(env, Config{Document: qaDocument})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := measurement.TestKeys.(*TestKeys)
+		if len(tk.TCPConnect) != 1 {
+			t.Fatal("unexpected tcp_connect", tk.TCPConnect)
+		}
+		failure := tk.TCPConnect[0].Status.Failure
+		if failure == nil || *failure != netxlite.FailureConnectionRefused {
+			t.Fatal("unexpected failure", failure)
+		}
+		if len(tk.TLSHandshakes) != 0 || len(tk.Requests) != 0 {
+			t.Fatal("expected no TLS handshakes and no requests")
+		}
+	})
+}
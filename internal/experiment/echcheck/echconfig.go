@@ -0,0 +1,86 @@
+package echcheck
+
+// Parses the ECHConfigList carried by the HTTPS/SVCB resource record, as described in
+// ietf.org/archive/id/draft-ietf-tls-esni-18.html#section-4
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// echConfigVersion is the version of the ECHConfig structure we understand.
+const echConfigVersion uint16 = 0xfe0d
+
+var (
+	// errInvalidECHConfigList indicates that we could not parse the ECHConfigList
+	errInvalidECHConfigList = errors.New("invalid ECHConfigList")
+
+	// errNoSupportedECHConfig indicates that the ECHConfigList contains no supported ECHConfig
+	errNoSupportedECHConfig = errors.New("no supported ECHConfig")
+)
+
+// ECHConfig contains the ECHConfig fields that are useful for analysing a measurement.
+type ECHConfig struct {
+	Version    uint16 `json:"version"`
+	ConfigID   uint8  `json:"config_id"`
+	KEMID      uint16 `json:"kem_id"`
+	PublicName string `json:"public_name"`
+}
+
+// parseECHConfigList parses a length-prefixed ECHConfigList and returns the list of
+// ECHConfig entries using a version we support, skipping all the other entries.
+func parseECHConfigList(data []byte) ([]ECHConfig, error) {
+	var list cryptobyte.String
+	input := cryptobyte.String(data)
+	if !input.ReadUint16LengthPrefixed(&list) || !input.Empty() {
+		return nil, errInvalidECHConfigList
+	}
+
+	out := []ECHConfig{}
+	for !list.Empty() {
+		var (
+			version  uint16
+			contents cryptobyte.String
+		)
+		if !list.ReadUint16(&version) || !list.ReadUint16LengthPrefixed(&contents) {
+			return nil, errInvalidECHConfigList
+		}
+		if version != echConfigVersion {
+			continue
+		}
+		config, good := parseECHConfigContents(version, contents)
+		if !good {
+			return nil, errInvalidECHConfigList
+		}
+		out = append(out, config)
+	}
+
+	if len(out) <= 0 {
+		return nil, errNoSupportedECHConfig
+	}
+	return out, nil
+}
+
+// parseECHConfigContents parses the contents of an ECHConfig entry.
+func parseECHConfigContents(version uint16, contents cryptobyte.String) (ECHConfig, bool) {
+	var (
+		config       = ECHConfig{Version: version}
+		publicKey    cryptobyte.String
+		cipherSuites cryptobyte.String
+		maxNameLen   uint8
+		publicName   cryptobyte.String
+	)
+	good := contents.ReadUint8(&config.ConfigID) &&
+		contents.ReadUint16(&config.KEMID) &&
+		contents.ReadUint16LengthPrefixed(&publicKey) &&
+		contents.ReadUint16LengthPrefixed(&cipherSuites) &&
+		contents.ReadUint8(&maxNameLen) &&
+		contents.ReadUint8LengthPrefixed(&publicName)
+	if !good || len(publicKey) <= 0 || len(cipherSuites) <= 0 || len(cipherSuites)%4 != 0 {
+		return ECHConfig{}, false
+	}
+	// note: we ignore the extensions, which follow the public name
+	config.PublicName = string(publicName)
+	return config, true
+}
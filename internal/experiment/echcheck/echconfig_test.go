@@ -0,0 +1,109 @@
+package echcheck
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// newECHConfigList generates an ECHConfigList containing a single ECHConfig
+// using the given version, config ID, and public name.
+func newECHConfigList(t *testing.T, version uint16, configID uint8, publicName string) []byte {
+	publicKey, _, err := hpke.KEM_X25519_HKDF_SHA256.Scheme().GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawPublicKey, err := publicKey.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(version)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(configID)
+			b.AddUint16(uint16(hpke.KEM_X25519_HKDF_SHA256))
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(rawPublicKey)
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(uint16(hpke.KDF_HKDF_SHA256))
+				b.AddUint16(uint16(hpke.AEAD_AES128GCM))
+			})
+			b.AddUint8(0) // max_name_length
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes([]byte(publicName))
+			})
+			b.AddUint16(0) // extensions
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+func TestParseECHConfigList(t *testing.T) {
+	t.Run("with a valid ECHConfigList", func(t *testing.T) {
+		configs, err := parseECHConfigList(newECHConfigList(t, echConfigVersion, 7, "cloudflare-ech.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []ECHConfig{{
+			Version:    echConfigVersion,
+			ConfigID:   7,
+			KEMID:      uint16(hpke.KEM_X25519_HKDF_SHA256),
+			PublicName: "cloudflare-ech.com",
+		}}
+		if diff := cmp.Diff(expect, configs); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with only unsupported versions", func(t *testing.T) {
+		configs, err := parseECHConfigList(newECHConfigList(t, 0xfe0a, 7, "cloudflare-ech.com"))
+		if !errors.Is(err, errNoSupportedECHConfig) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(configs) != 0 {
+			t.Fatal("expected no configs")
+		}
+	})
+
+	t.Run("with empty input", func(t *testing.T) {
+		_, err := parseECHConfigList(nil)
+		if !errors.Is(err, errInvalidECHConfigList) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with truncated input", func(t *testing.T) {
+		data := newECHConfigList(t, echConfigVersion, 7, "cloudflare-ech.com")
+		_, err := parseECHConfigList(data[:len(data)-4])
+		if !errors.Is(err, errInvalidECHConfigList) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with trailing garbage", func(t *testing.T) {
+		data := newECHConfigList(t, echConfigVersion, 7, "cloudflare-ech.com")
+		_, err := parseECHConfigList(append(data, 0))
+		if !errors.Is(err, errInvalidECHConfigList) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with invalid ECHConfig contents", func(t *testing.T) {
+		var b cryptobyte.Builder
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(echConfigVersion)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(make([]byte, 10))
+			})
+		})
+		_, err := parseECHConfigList(b.BytesOrPanic())
+		if !errors.Is(err, errInvalidECHConfigList) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
@@ -16,7 +16,7 @@ import (
 
 const (
 	testName    = "echcheck"
-	testVersion = "0.1.2"
+	testVersion = "0.2.0"
 	defaultURL  = "https://crypto.cloudflare.com/cdn-cgi/trace"
 )
 
@@ -30,8 +30,20 @@ var (
 
 // TestKeys contains echcheck test keys.
 type TestKeys struct {
+	// Control is the handshake without the ECH extension.
 	Control model.ArchivalTLSOrQUICHandshakeResult `json:"control"`
-	Target  model.ArchivalTLSOrQUICHandshakeResult `json:"target"`
+
+	// Target is the handshake using a GREASE ECH extension.
+	Target model.ArchivalTLSOrQUICHandshakeResult `json:"target"`
+
+	// Queries contains the DNS lookups.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// ECHConfigList is the raw ECHConfigList from the HTTPS resource record.
+	ECHConfigList model.ArchivalBinaryData `json:"ech_config_list"`
+
+	// ECHConfigs contains the supported entries of the ECHConfigList.
+	ECHConfigs []ECHConfig `json:"ech_configs"`
 }
 
 // Measurer performs the measurement.
@@ -77,7 +89,20 @@ func (m *Measurer) Run(
 	runtimex.Assert(len(addrs) > 0, "expected at least one entry in addrs")
 	address := net.JoinHostPort(addrs[0], "443")
 
-	// 2. Set up TCP connections
+	// 2. obtain the ECHConfigList from the HTTPS resource record
+	tk := TestKeys{}
+	ol = logx.NewOperationLogger(args.Session.Logger(), "echcheck: LookupHTTPS[%s] %s", m.config.resolverURL(), parsed.Host)
+	https, err := resolver.LookupHTTPS(ctx, parsed.Host)
+	ol.Stop(err)
+	tk.Queries = trace.DNSLookupsFromRoundTrip()
+	if err == nil && len(https.ECHConfigList) > 0 {
+		tk.ECHConfigList = https.ECHConfigList
+		if configs, err := parseECHConfigList(https.ECHConfigList); err == nil {
+			tk.ECHConfigs = configs
+		}
+	}
+
+	// 3. Set up TCP connections
 	ol = logx.NewOperationLogger(args.Session.Logger(), "echcheck: TCPConnect#1 %s", address)
 	var dialer net.Dialer
 	conn, err := dialer.DialContext(ctx, "tcp", address)
@@ -85,6 +110,7 @@ func (m *Measurer) Run(
 	if err != nil {
 		return netxlite.NewErrWrapper(netxlite.ClassifyGenericError, netxlite.ConnectOperation, err)
 	}
+	defer conn.Close()
 
 	ol = logx.NewOperationLogger(args.Session.Logger(), "echcheck: TCPConnect#2 %s", address)
 	conn2, err := dialer.DialContext(ctx, "tcp", address)
@@ -92,8 +118,9 @@ func (m *Measurer) Run(
 	if err != nil {
 		return netxlite.NewErrWrapper(netxlite.ClassifyGenericError, netxlite.ConnectOperation, err)
 	}
+	defer conn2.Close()
 
-	// 3. Conduct and measure control and target TLS handshakes in parallel
+	// 4. Conduct and measure control and target TLS handshakes in parallel
 	controlChannel := make(chan model.ArchivalTLSOrQUICHandshakeResult)
 	targetChannel := make(chan model.ArchivalTLSOrQUICHandshakeResult)
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -121,10 +148,10 @@ func (m *Measurer) Run(
 		)
 	}()
 
-	control := <-controlChannel
-	target := <-targetChannel
+	tk.Control = <-controlChannel
+	tk.Target = <-targetChannel
 
-	args.Measurement.TestKeys = TestKeys{Control: control, Target: target}
+	args.Measurement.TestKeys = tk
 
 	return nil
 }
@@ -14,7 +14,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "echcheck" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.1.2" {
+	if measurer.ExperimentVersion() != "0.2.0" {
 		t.Fatal("unexpected version")
 	}
 }
@@ -121,3 +121,34 @@ func TestMeasurementSuccessRealWorld(t *testing.T) {
 		t.Fatal("unexpected target failure:", *tk.Target.Failure)
 	}
 }
+
+func TestMeasurerMeasureWithoutECHConfigList(t *testing.T) {
+	// create QAEnv
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	env.Do(func() {
+		// create measurer
+		measurer := NewExperimentMeasurer(Config{})
+		msrmnt := &model.Measurement{Input: "https://www.example.com/"}
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+			Measurement: msrmnt,
+			Session:     &mocks.Session{MockLogger: func() model.Logger { return model.DiscardLogger }},
+		}
+
+		// run measurement
+		if err := measurer.Run(context.Background(), args); err != nil {
+			t.Fatal(err)
+		}
+
+		// check results
+		tk := msrmnt.TestKeys.(TestKeys)
+		if len(tk.ECHConfigList) != 0 || len(tk.ECHConfigs) != 0 {
+			t.Fatal("expected no ECHConfigList")
+		}
+		if len(tk.Queries) <= 0 {
+			t.Fatal("expected DNS queries")
+		}
+	})
+}
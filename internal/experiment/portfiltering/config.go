@@ -4,12 +4,30 @@ package portfiltering
 // Config for the port-filtering experiment
 //
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Config contains the experiment configuration.
 type Config struct {
 	// Delay is the delay between each repetition (in milliseconds).
 	Delay int64 `ooni:"number of milliseconds to wait before testing each port"`
+
+	// TestHelper is the URL of the port-filtering test helper.
+	TestHelper string `ooni:"URL of the port-filtering test helper (e.g., http://127.0.0.1)"`
+
+	// TCPPorts is the comma-separated list of TCP ports to measure.
+	TCPPorts string `ooni:"comma-separated list of TCP ports to test (default: all ports in the static list)"`
+
+	// UDPPorts is the comma-separated list of UDP ports to measure.
+	UDPPorts string `ooni:"comma-separated list of UDP ports to test (default: none)"`
+
+	// UDPTimeout is the time to wait for the UDP echo (in milliseconds).
+	UDPTimeout int64 `ooni:"number of milliseconds to wait for the UDP echo before declaring a port filtered"`
 }
 
 func (c *Config) delay() time.Duration {
@@ -18,3 +36,50 @@ func (c *Config) delay() time.Duration {
 	}
 	return 100 * time.Millisecond
 }
+
+func (c *Config) testHelper() string {
+	if c.TestHelper != "" {
+		return c.TestHelper
+	}
+	// TODO(DecFox): Replace the localhost deployment with an OONI testhelper
+	// Ensure that we only do this once we have a deployed testhelper
+	return "http://127.0.0.1"
+}
+
+// errInvalidPort indicates that a port in a list of ports is invalid
+var errInvalidPort = errors.New("invalid port")
+
+// parsePorts parses a comma-separated list of ports.
+func parsePorts(value string) ([]string, error) {
+	out := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		number, err := strconv.Atoi(entry)
+		if err != nil || number <= 0 || number > 65535 {
+			return nil, fmt.Errorf("%w: %s", errInvalidPort, entry)
+		}
+		out = append(out, strconv.Itoa(number))
+	}
+	return out, nil
+}
+
+func (c *Config) tcpPorts() ([]string, error) {
+	if c.TCPPorts != "" {
+		return parsePorts(c.TCPPorts)
+	}
+	return append([]string{}, Ports...), nil
+}
+
+func (c *Config) udpPorts() ([]string, error) {
+	return parsePorts(c.UDPPorts)
+}
+
+func (c *Config) udpTimeout() time.Duration {
+	if c.UDPTimeout > 0 {
+		return time.Duration(c.UDPTimeout) * time.Millisecond
+	}
+	return 3 * time.Second
+}
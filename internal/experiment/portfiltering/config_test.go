@@ -1,8 +1,11 @@
 package portfiltering
 
 import (
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestConfig_delay(t *testing.T) {
@@ -11,3 +14,61 @@ func TestConfig_delay(t *testing.T) {
 		t.Fatal("invalid default delay")
 	}
 }
+
+func TestConfig_testHelper(t *testing.T) {
+	c := Config{}
+	if c.testHelper() != "http://127.0.0.1" {
+		t.Fatal("invalid default test helper")
+	}
+	c.TestHelper = "http://10.0.0.1"
+	if c.testHelper() != "http://10.0.0.1" {
+		t.Fatal("invalid test helper")
+	}
+}
+
+func TestConfig_tcpPorts(t *testing.T) {
+	c := Config{}
+	ports, err := c.tcpPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Ports, ports); diff != "" {
+		t.Fatal(diff)
+	}
+	c.TCPPorts = "80, 443,,8080"
+	ports, err = c.tcpPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"80", "443", "8080"}, ports); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestConfig_udpPorts(t *testing.T) {
+	c := Config{}
+	ports, err := c.udpPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ports) != 0 {
+		t.Fatal("expected no UDP ports by default")
+	}
+	for _, value := range []string{"53,abc", "0", "65536", "-1"} {
+		c.UDPPorts = value
+		if _, err := c.udpPorts(); !errors.Is(err, errInvalidPort) {
+			t.Fatal("unexpected error", err)
+		}
+	}
+}
+
+func TestConfig_udpTimeout(t *testing.T) {
+	c := Config{}
+	if c.udpTimeout() != 3*time.Second {
+		t.Fatal("invalid default UDP timeout")
+	}
+	c.UDPTimeout = 500
+	if c.udpTimeout() != 500*time.Millisecond {
+		t.Fatal("invalid UDP timeout")
+	}
+}
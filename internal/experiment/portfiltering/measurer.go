@@ -14,7 +14,7 @@ import (
 
 const (
 	testName    = "portfiltering"
-	testVersion = "0.1.0"
+	testVersion = "0.2.0"
 )
 
 // Measurer performs the measurement.
@@ -42,20 +42,33 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	_ = args.Callbacks
 	measurement := args.Measurement
 	sess := args.Session
-	// TODO(DecFox): Replace the localhost deployment with an OONI testhelper
-	// Ensure that we only do this once we have a deployed testhelper
-	testhelper := "http://127.0.0.1"
-	parsed, err := url.Parse(testhelper)
-	if err != nil {
+	parsed, err := url.Parse(m.config.testHelper())
+	if err != nil || parsed.Hostname() == "" {
 		return errInvalidTestHelper
 	}
-	tk := new(TestKeys)
+	tcpPorts, err := m.config.tcpPorts()
+	if err != nil {
+		return err
+	}
+	udpPorts, err := m.config.udpPorts()
+	if err != nil {
+		return err
+	}
+	tk := &TestKeys{
+		TCPConnect: []*model.ArchivalTCPConnectResult{},
+		UDPProbe:   []*UDPProbeResult{},
+	}
 	measurement.TestKeys = tk
 	out := make(chan *model.ArchivalTCPConnectResult)
-	go m.tcpConnectLoop(ctx, measurement.MeasurementStartTimeSaved, sess.Logger(), parsed.Host, out)
-	for len(tk.TCPConnect) < len(Ports) {
+	go m.tcpConnectLoop(ctx, measurement.MeasurementStartTimeSaved, sess.Logger(), parsed.Hostname(), tcpPorts, out)
+	for len(tk.TCPConnect) < len(tcpPorts) {
 		tk.TCPConnect = append(tk.TCPConnect, <-out)
 	}
+	udpOut := make(chan *UDPProbeResult)
+	go m.udpProbeLoop(ctx, measurement.MeasurementStartTimeSaved, sess.Logger(), parsed.Hostname(), udpPorts, udpOut)
+	for len(tk.UDPProbe) < len(udpPorts) {
+		tk.UDPProbe = append(tk.UDPProbe, <-udpOut)
+	}
 	return nil // return nil so we always submit the measurement
 }
 
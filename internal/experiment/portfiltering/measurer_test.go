@@ -2,6 +2,8 @@ package portfiltering
 
 import (
 	"context"
+	"errors"
+	"net"
 	"testing"
 
 	"github.com/ooni/probe-cli/v3/internal/mocks"
@@ -13,7 +15,7 @@ func TestMeasurerExperimentNameVersion(t *testing.T) {
 	if measurer.ExperimentName() != "portfiltering" {
 		t.Fatal("unexpected ExperimentName")
 	}
-	if measurer.ExperimentVersion() != "0.1.0" {
+	if measurer.ExperimentVersion() != "0.2.0" {
 		t.Fatal("unexpected ExperimentVersion")
 	}
 }
@@ -42,4 +44,88 @@ func TestMeasurer_run(t *testing.T) {
 	if len(tk.TCPConnect) != len(Ports) {
 		t.Fatal("unexpected number of ports")
 	}
+	if len(tk.UDPProbe) != 0 {
+		t.Fatal("expected no UDP probes by default")
+	}
+}
+
+func TestMeasurer_runWithUDPPorts(t *testing.T) {
+	address := startUDPServer(t, func(payload []byte) []byte {
+		return payload
+	})
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewExperimentMeasurer(Config{
+		Delay:      10,
+		TestHelper: "http://127.0.0.1",
+		TCPPorts:   port,
+		UDPPorts:   port,
+		UDPTimeout: 500,
+	})
+	meas := &model.Measurement{}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session: &mocks.Session{
+			MockLogger: func() model.Logger {
+				return model.DiscardLogger
+			},
+		},
+	}
+	if err := m.Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	tk := meas.TestKeys.(*TestKeys)
+	if len(tk.TCPConnect) != 1 {
+		t.Fatal("unexpected number of TCP connects")
+	}
+	if len(tk.UDPProbe) != 1 || tk.UDPProbe[0].Status != udpStatusOpen {
+		t.Fatal("unexpected UDP probe results", tk.UDPProbe)
+	}
+}
+
+func TestMeasurer_runWithInvalidConfig(t *testing.T) {
+	type testcase struct {
+		name   string
+		config Config
+		expect error
+	}
+
+	testcases := []testcase{{
+		name:   "with a test helper that is not an URL",
+		config: Config{TestHelper: "\t"},
+		expect: errInvalidTestHelper,
+	}, {
+		name:   "with a test helper without host",
+		config: Config{TestHelper: "127.0.0.1"},
+		expect: errInvalidTestHelper,
+	}, {
+		name:   "with invalid TCP ports",
+		config: Config{TCPPorts: "80,xx"},
+		expect: errInvalidPort,
+	}, {
+		name:   "with invalid UDP ports",
+		config: Config{UDPPorts: "70000"},
+		expect: errInvalidPort,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewExperimentMeasurer(tc.config)
+			args := &model.ExperimentArgs{
+				Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+				Measurement: &model.Measurement{},
+				Session: &mocks.Session{
+					MockLogger: func() model.Logger {
+						return model.DiscardLogger
+					},
+				},
+			}
+			if err := m.Run(context.Background(), args); !errors.Is(err, tc.expect) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
 }
@@ -17,13 +17,13 @@ import (
 
 // tcpPingLoop sends the TCP Connect requests to all ports and emits the results onto the out channel
 func (m *Measurer) tcpConnectLoop(ctx context.Context, zeroTime time.Time,
-	logger model.Logger, address string, out chan<- *model.ArchivalTCPConnectResult) {
+	logger model.Logger, address string, ports []string, out chan<- *model.ArchivalTCPConnectResult) {
 	ticker := time.NewTicker(m.config.delay())
 	defer ticker.Stop()
-	rand.Shuffle(len(Ports), func(i, j int) {
-		Ports[i], Ports[j] = Ports[j], Ports[i]
+	rand.Shuffle(len(ports), func(i, j int) {
+		ports[i], ports[j] = ports[j], ports[i]
 	})
-	for i, port := range Ports {
+	for i, port := range ports {
 		addr := net.JoinHostPort(address, port)
 		go m.tcpConnectAsync(ctx, int64(i), zeroTime, logger, addr, out)
 		<-ticker.C
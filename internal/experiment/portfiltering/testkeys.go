@@ -5,4 +5,16 @@ import "github.com/ooni/probe-cli/v3/internal/model"
 // TestKeys contains the experiment results.
 type TestKeys struct {
 	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+	UDPProbe   []*UDPProbeResult                 `json:"udp_probe"`
+}
+
+// UDPProbeResult contains the result of probing a UDP port.
+type UDPProbeResult struct {
+	Address       string                        `json:"address"`
+	Failure       *string                       `json:"failure"`
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+	Status        string                        `json:"status"`
+	T0            float64                       `json:"t0"`
+	T             float64                       `json:"t"`
+	TransactionID int64                         `json:"transaction_id"`
 }
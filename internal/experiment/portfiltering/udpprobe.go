@@ -0,0 +1,126 @@
+package portfiltering
+
+//
+// UDP probing for portfiltering
+//
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/randx"
+)
+
+const (
+	// udpStatusOpen indicates that the test helper echoed our payload.
+	udpStatusOpen = "open"
+
+	// udpStatusClosed indicates that we received an ICMP port unreachable.
+	udpStatusClosed = "closed"
+
+	// udpStatusFiltered indicates that we did not receive any echo before the timeout.
+	udpStatusFiltered = "filtered"
+
+	// udpStatusFailure indicates any other failure, including receiving
+	// a response that is not the echo of our payload.
+	udpStatusFailure = "failure"
+)
+
+// errUDPEchoMismatch indicates that the response is not the echo of our payload
+var errUDPEchoMismatch = errors.New("udp_echo_mismatch")
+
+// udpPayloadSize is the size of the random payload we send.
+const udpPayloadSize = 16
+
+// udpProbeLoop probes all the UDP ports and emits the results onto the out channel
+func (m *Measurer) udpProbeLoop(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, address string, ports []string, out chan<- *UDPProbeResult) {
+	ticker := time.NewTicker(m.config.delay())
+	defer ticker.Stop()
+	rand.Shuffle(len(ports), func(i, j int) {
+		ports[i], ports[j] = ports[j], ports[i]
+	})
+	for i, port := range ports {
+		addr := net.JoinHostPort(address, port)
+		go m.udpProbeAsync(ctx, int64(i), zeroTime, logger, addr, out)
+		<-ticker.C
+	}
+}
+
+// udpProbeAsync probes a UDP port and emits the result onto the out channel.
+func (m *Measurer) udpProbeAsync(ctx context.Context, index int64,
+	zeroTime time.Time, logger model.Logger, address string, out chan<- *UDPProbeResult) {
+	out <- m.udpProbe(ctx, index, zeroTime, logger, address)
+}
+
+// udpProbe sends a random payload to the given UDP endpoint, waits for the test
+// helper to echo it back, and returns the result to the caller.
+func (m *Measurer) udpProbe(ctx context.Context, index int64,
+	zeroTime time.Time, logger model.Logger, address string) *UDPProbeResult {
+	trace := measurexlite.NewTrace(index, zeroTime)
+	ol := logx.NewOperationLogger(logger, "UDPProbe #%d %s", index, address)
+	started := trace.TimeSince(zeroTime)
+	err := m.udpEcho(ctx, trace, logger, address)
+	finished := trace.TimeSince(zeroTime)
+	ol.Stop(err)
+	return &UDPProbeResult{
+		Address:       address,
+		Failure:       measurexlite.NewFailure(err),
+		NetworkEvents: trace.NetworkEvents(),
+		Status:        udpStatusFromError(err),
+		T0:            started.Seconds(),
+		T:             finished.Seconds(),
+		TransactionID: index,
+	}
+}
+
+// udpEcho performs the UDP echo exchange with the test helper.
+func (m *Measurer) udpEcho(ctx context.Context, trace *measurexlite.Trace,
+	logger model.Logger, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.config.udpTimeout())
+	defer cancel()
+	dialer := trace.NewDialerWithoutResolver(logger)
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	payload := []byte(randx.Letters(udpPayloadSize))
+	if _, err := conn.Write(payload); err != nil {
+		return err
+	}
+	buffer := make([]byte, 1<<12)
+	count, err := conn.Read(buffer)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(payload, buffer[:count]) {
+		return errUDPEchoMismatch
+	}
+	return nil
+}
+
+// udpStatusFromError maps the error returned by udpEcho to a status.
+func udpStatusFromError(err error) string {
+	var errWrapper *netxlite.ErrWrapper
+	switch {
+	case err == nil:
+		return udpStatusOpen
+	case errors.As(err, &errWrapper) && errWrapper.Failure == netxlite.FailureGenericTimeoutError:
+		return udpStatusFiltered
+	case errors.As(err, &errWrapper) && errWrapper.Failure == netxlite.FailureConnectionRefused:
+		return udpStatusClosed
+	default:
+		return udpStatusFailure
+	}
+}
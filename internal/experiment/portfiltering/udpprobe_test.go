@@ -0,0 +1,101 @@
+package portfiltering
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// startUDPServer starts a UDP server on the loopback using the given handler to
+// generate the response. When the handler returns nil, the server does not respond.
+func startUDPServer(t *testing.T, handler func(payload []byte) []byte) string {
+	pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pconn.Close() })
+	go func() {
+		buffer := make([]byte, 1<<12)
+		for {
+			count, addr, err := pconn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := handler(buffer[:count]); response != nil {
+				pconn.WriteTo(response, addr)
+			}
+		}
+	}()
+	return pconn.LocalAddr().String()
+}
+
+func TestMeasurer_udpProbe(t *testing.T) {
+	type testcase struct {
+		name          string
+		handler       func(payload []byte) []byte
+		expectStatus  string
+		expectFailure bool
+	}
+
+	testcases := []testcase{{
+		name: "when the test helper echoes the payload",
+		handler: func(payload []byte) []byte {
+			return payload
+		},
+		expectStatus: udpStatusOpen,
+	}, {
+		name: "when the test helper does not respond",
+		handler: func(payload []byte) []byte {
+			return nil
+		},
+		expectStatus:  udpStatusFiltered,
+		expectFailure: true,
+	}, {
+		name: "when the test helper sends an unexpected response",
+		handler: func(payload []byte) []byte {
+			return []byte("antani")
+		},
+		expectStatus:  udpStatusFailure,
+		expectFailure: true,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			address := startUDPServer(t, tc.handler)
+			m := &Measurer{config: Config{UDPTimeout: 250}}
+			result := m.udpProbe(context.Background(), 7, time.Now(), model.DiscardLogger, address)
+			if result.Status != tc.expectStatus {
+				t.Fatal("expected", tc.expectStatus, "got", result.Status)
+			}
+			if (result.Failure != nil) != tc.expectFailure {
+				t.Fatal("unexpected failure", result.Failure)
+			}
+			if result.Address != address || result.TransactionID != 7 {
+				t.Fatal("unexpected result", result)
+			}
+			if len(result.NetworkEvents) <= 0 {
+				t.Fatal("expected network events")
+			}
+		})
+	}
+
+	t.Run("when the port is closed", func(t *testing.T) {
+		// obtain a port that is very likely closed by closing the listener
+		pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		address := pconn.LocalAddr().String()
+		pconn.Close()
+
+		m := &Measurer{config: Config{UDPTimeout: 250}}
+		result := m.udpProbe(context.Background(), 0, time.Now(), model.DiscardLogger, address)
+		// Note: on some systems we may not receive the ICMP port unreachable
+		if result.Status != udpStatusClosed && result.Status != udpStatusFiltered {
+			t.Fatal("unexpected status", result.Status)
+		}
+	})
+}
@@ -0,0 +1,264 @@
+package stunreachability
+
+//
+// RFC 5780 NAT behavior discovery.
+//
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/pion/stun"
+)
+
+const (
+	// natBehaviorEndpointIndependent indicates endpoint-independent mapping or filtering.
+	natBehaviorEndpointIndependent = "endpoint_independent"
+
+	// natBehaviorAddressDependent indicates address-dependent mapping or filtering.
+	natBehaviorAddressDependent = "address_dependent"
+
+	// natBehaviorAddressAndPortDependent indicates address-and-port-dependent mapping or filtering.
+	natBehaviorAddressAndPortDependent = "address_and_port_dependent"
+)
+
+const (
+	// natInitialRTO is the initial retransmission timeout for a NAT discovery transaction.
+	natInitialRTO = 250 * time.Millisecond
+
+	// natMaxAttempts is the maximum number of times we send a NAT discovery request.
+	natMaxAttempts = 3
+)
+
+// NATDiscovery contains the results of the RFC 5780 NAT behavior discovery, which
+// we only run when the server returns the OTHER-ADDRESS attribute.
+//
+// We do not archive the mapped addresses because they contain the probe IP
+// address and we only archive the behaviors we infer from them.
+type NATDiscovery struct {
+	// FilteringBehavior is the NAT filtering behavior (RFC 5780 Sect. 4.4) or an
+	// empty string if we could not determine it.
+	FilteringBehavior string `json:"filtering_behavior"`
+
+	// FilteringFailure is the failure that prevented us from determining the
+	// NAT filtering behavior or nil.
+	FilteringFailure *string `json:"filtering_failure"`
+
+	// MappingBehavior is the NAT mapping behavior (RFC 5780 Sect. 4.3) or an
+	// empty string if we could not determine it.
+	MappingBehavior string `json:"mapping_behavior"`
+
+	// MappingFailure is the failure that prevented us from determining the
+	// NAT mapping behavior or nil.
+	MappingFailure *string `json:"mapping_failure"`
+
+	// OtherAddress is the OTHER-ADDRESS returned by the server.
+	OtherAddress string `json:"other_address"`
+}
+
+// natChangeRequest is the CHANGE-REQUEST attribute defined by RFC 5780 Sect. 7.2.
+type natChangeRequest struct {
+	changeIP   bool
+	changePort bool
+}
+
+var _ stun.Setter = natChangeRequest{}
+
+// AddTo implements stun.Setter.
+func (cr natChangeRequest) AddTo(m *stun.Message) error {
+	var flags uint32
+	if cr.changeIP {
+		flags |= 0x04
+	}
+	if cr.changePort {
+		flags |= 0x02
+	}
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, flags)
+	m.Add(stun.AttrChangeRequest, value)
+	return nil
+}
+
+// natDiscoverer runs the RFC 5780 NAT behavior discovery.
+type natDiscoverer struct {
+	// listener is the listener used to create UDP sockets.
+	listener model.UDPListener
+
+	// other is the alternate address returned by the server.
+	other *net.UDPAddr
+
+	// primary is the primary address of the server.
+	primary *net.UDPAddr
+}
+
+// run runs the mapping and filtering behavior discovery. We use distinct sockets
+// for the two tests such that the mapping test does not create NAT state that
+// would otherwise influence the results of the filtering test.
+func (nd *natDiscoverer) run(ctx context.Context) *NATDiscovery {
+	result := &NATDiscovery{OtherAddress: nd.other.String()}
+	behavior, err := nd.mapping(ctx)
+	if err := wrap(err); err != nil {
+		s := err.Error()
+		result.MappingFailure = &s
+	}
+	result.MappingBehavior = behavior
+	behavior, err = nd.filtering(ctx)
+	if err := wrap(err); err != nil {
+		s := err.Error()
+		result.FilteringFailure = &s
+	}
+	result.FilteringBehavior = behavior
+	return result
+}
+
+// mapping runs the mapping behavior tests defined by RFC 5780 Sect. 4.3.
+func (nd *natDiscoverer) mapping(ctx context.Context) (string, error) {
+	conn, err := nd.listener.Listen(listenAddrFor(nd.primary.IP))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// test I: send to the primary address
+	x1, err := nd.mappedAddress(ctx, conn, nd.primary)
+	if err != nil {
+		return "", err
+	}
+
+	// test II: send to the alternate address and the primary port
+	x2, err := nd.mappedAddress(ctx, conn, &net.UDPAddr{IP: nd.other.IP, Port: nd.primary.Port})
+	if err != nil {
+		return "", err
+	}
+	if x2 == x1 {
+		return natBehaviorEndpointIndependent, nil
+	}
+
+	// test III: send to the alternate address and the alternate port
+	x3, err := nd.mappedAddress(ctx, conn, nd.other)
+	if err != nil {
+		return "", err
+	}
+	if x3 == x2 {
+		return natBehaviorAddressDependent, nil
+	}
+	return natBehaviorAddressAndPortDependent, nil
+}
+
+// filtering runs the filtering behavior tests defined by RFC 5780 Sect. 4.4.
+func (nd *natDiscoverer) filtering(ctx context.Context) (string, error) {
+	conn, err := nd.listener.Listen(listenAddrFor(nd.primary.IP))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// test I: make sure we can communicate with the primary address
+	if _, err := natTransaction(ctx, conn, nd.primary); err != nil {
+		return "", err
+	}
+
+	// test II: ask the server to respond from the alternate address and port
+	_, err = natTransaction(ctx, conn, nd.primary, natChangeRequest{changeIP: true, changePort: true})
+	switch {
+	case err == nil:
+		return natBehaviorEndpointIndependent, nil
+	case !natIsTimeout(err) || ctx.Err() != nil:
+		return "", err
+	}
+
+	// test III: ask the server to respond from the alternate port
+	_, err = natTransaction(ctx, conn, nd.primary, natChangeRequest{changePort: true})
+	switch {
+	case err == nil:
+		return natBehaviorAddressDependent, nil
+	case !natIsTimeout(err) || ctx.Err() != nil:
+		return "", err
+	}
+	return natBehaviorAddressAndPortDependent, nil
+}
+
+// mappedAddress returns the XOR-MAPPED-ADDRESS returned by the server at addr.
+func (nd *natDiscoverer) mappedAddress(
+	ctx context.Context, conn model.UDPLikeConn, addr *net.UDPAddr) (string, error) {
+	resp, err := natTransaction(ctx, conn, addr)
+	if err != nil {
+		return "", err
+	}
+	var mapped stun.XORMappedAddress
+	if err := mapped.GetFrom(resp); err != nil {
+		return "", err
+	}
+	return mapped.String(), nil
+}
+
+// natTransaction sends a binding request to addr, retransmitting it until we
+// receive the corresponding success response or we run out of attempts, in which
+// case we return the timeout error that occurred while reading.
+func natTransaction(ctx context.Context, conn model.UDPLikeConn,
+	addr *net.UDPAddr, setters ...stun.Setter) (*stun.Message, error) {
+	req, err := stun.Build(append([]stun.Setter{stun.TransactionID, stun.BindingRequest}, setters...)...)
+	if err != nil {
+		return nil, err
+	}
+	rto := natInitialRTO
+	for attempt := 0; ; attempt++ {
+		if _, err := conn.WriteTo(req.Raw, addr); err != nil {
+			return nil, err
+		}
+		resp, err := natReadResponse(ctx, conn, req.TransactionID, time.Now().Add(rto))
+		if err == nil || !natIsTimeout(err) || ctx.Err() != nil || attempt+1 >= natMaxAttempts {
+			return resp, err
+		}
+		rto *= 2
+	}
+}
+
+// natReadResponse reads until we receive the binding success response for the
+// given transaction ID, the deadline expires, or the context is done.
+func natReadResponse(ctx context.Context, conn model.UDPLikeConn,
+	tid [stun.TransactionIDSize]byte, deadline time.Time) (*stun.Message, error) {
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	buffer := make([]byte, 1500)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		count, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return nil, err
+		}
+		resp := &stun.Message{Raw: append([]byte{}, buffer[:count]...)}
+		if err := resp.Decode(); err != nil {
+			continue // not a STUN message
+		}
+		if resp.TransactionID != tid || resp.Type != stun.BindingSuccess {
+			continue // stale or unexpected response
+		}
+		return resp, nil
+	}
+}
+
+// listenAddrFor returns the wildcard address with the same family of ip, which
+// we use to create sockets that can communicate with ip.
+func listenAddrFor(ip net.IP) *net.UDPAddr {
+	if ip.To4() != nil {
+		return &net.UDPAddr{IP: net.IPv4zero, Port: 0, Zone: ""}
+	}
+	return &net.UDPAddr{IP: net.IPv6zero, Port: 0, Zone: ""}
+}
+
+// natIsTimeout returns whether the given error is a timeout.
+func natIsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
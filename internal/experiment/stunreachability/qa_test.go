@@ -0,0 +1,493 @@
+package stunreachability
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/google/gopacket/layers"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/pion/stun"
+	"github.com/pion/turn/v2"
+)
+
+const (
+	// qaSTUNDomain is the domain of the emulated STUN server.
+	qaSTUNDomain = "stun.example.org"
+
+	// qaSTUNPrimaryAddress is the primary address of the emulated STUN server.
+	qaSTUNPrimaryAddress = "74.125.250.129"
+
+	// qaSTUNAlternateAddress is the alternate address of the emulated STUN server.
+	qaSTUNAlternateAddress = "74.125.250.130"
+
+	// qaSTUNPrimaryPort is the primary port of the emulated STUN server.
+	qaSTUNPrimaryPort = 3478
+
+	// qaSTUNAlternatePort is the alternate port of the emulated STUN server.
+	qaSTUNAlternatePort = 3479
+
+	// qaTURNDomain is the domain of the emulated TURN server.
+	qaTURNDomain = "turn.example.org"
+
+	// qaTURNAddress is the address of the emulated TURN server.
+	qaTURNAddress = "64.233.165.127"
+
+	// qaTURNPort is the port of the emulated TURN server.
+	qaTURNPort = 3478
+
+	// qaTURNUsername is the username accepted by the emulated TURN server.
+	qaTURNUsername = "ooni"
+
+	// qaTURNPassword is the password accepted by the emulated TURN server.
+	qaTURNPassword = "antani"
+)
+
+// fakeSTUNServer is an RFC 5780 STUN server listening on two addresses and two
+// ports that also emulates the mapping and filtering behavior of the client's NAT.
+type fakeSTUNServer struct {
+	// filtering is the emulated NAT filtering behavior.
+	filtering string
+
+	// mapping is the emulated NAT mapping behavior.
+	mapping string
+
+	// otherAddress indicates whether to return OTHER-ADDRESS.
+	otherAddress bool
+
+	// conns contains the listening sockets indexed by their address.
+	conns map[string]net.PacketConn
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+}
+
+// newFakeSTUNServer creates a new [*fakeSTUNServer].
+func newFakeSTUNServer(mapping, filtering string, otherAddress bool) *fakeSTUNServer {
+	return &fakeSTUNServer{
+		filtering:    filtering,
+		mapping:      mapping,
+		otherAddress: otherAddress,
+		conns:        map[string]net.PacketConn{},
+	}
+}
+
+// MustNewServer implements netemx.NetStackServerFactory.
+func (srv *fakeSTUNServer) MustNewServer(_ netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) netemx.NetStackServer {
+	return &fakeSTUNNetStackServer{server: srv, stack: stack}
+}
+
+var _ netemx.NetStackServerFactory = &fakeSTUNServer{}
+
+// fakeSTUNNetStackServer is the [netemx.NetStackServer] returned by [*fakeSTUNServer].
+type fakeSTUNNetStackServer struct {
+	server *fakeSTUNServer
+	conns  []net.PacketConn
+	stack  *netem.UNetStack
+}
+
+// MustStart implements netemx.NetStackServer.
+func (ns *fakeSTUNNetStackServer) MustStart() {
+	for _, port := range []int{qaSTUNPrimaryPort, qaSTUNAlternatePort} {
+		epnt := &net.UDPAddr{IP: net.ParseIP(ns.stack.IPAddress()), Port: port}
+		conn := runtimex.Try1(ns.stack.ListenUDP("udp", epnt))
+		ns.conns = append(ns.conns, conn)
+		ns.server.mu.Lock()
+		ns.server.conns[epnt.String()] = conn
+		ns.server.mu.Unlock()
+		go ns.server.serve(conn, epnt)
+	}
+}
+
+// Close implements netemx.NetStackServer.
+func (ns *fakeSTUNNetStackServer) Close() error {
+	for _, conn := range ns.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+func (srv *fakeSTUNServer) serve(conn net.PacketConn, local *net.UDPAddr) {
+	buffer := make([]byte, 1500)
+	for {
+		count, from, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		req := &stun.Message{Raw: append([]byte{}, buffer[:count]...)}
+		if err := req.Decode(); err != nil || req.Type != stun.BindingRequest {
+			continue
+		}
+		srv.respond(req, from.(*net.UDPAddr), local)
+	}
+}
+
+func (srv *fakeSTUNServer) respond(req *stun.Message, from, local *net.UDPAddr) {
+	// figure out the address from which we should respond
+	origin := &net.UDPAddr{IP: local.IP, Port: local.Port}
+	if value, err := req.Get(stun.AttrChangeRequest); err == nil && len(value) == 4 {
+		if value[3]&0x04 != 0 {
+			origin.IP = srv.otherIP(local.IP)
+		}
+		if value[3]&0x02 != 0 {
+			origin.Port = srv.otherPort(local.Port)
+		}
+	}
+
+	// emulate the NAT filtering behavior
+	switch srv.filtering {
+	case natBehaviorAddressDependent:
+		if !origin.IP.Equal(local.IP) {
+			return
+		}
+	case natBehaviorAddressAndPortDependent:
+		if !origin.IP.Equal(local.IP) || origin.Port != local.Port {
+			return
+		}
+	}
+
+	// emulate the NAT mapping behavior
+	mapped := &stun.XORMappedAddress{IP: from.IP, Port: from.Port}
+	switch srv.mapping {
+	case natBehaviorAddressDependent:
+		mapped.Port += 100 * srv.index(local.IP.String(), qaSTUNAlternateAddress)
+	case natBehaviorAddressAndPortDependent:
+		mapped.Port += 100*srv.index(local.IP.String(), qaSTUNAlternateAddress) +
+			10*srv.index(strconv.Itoa(local.Port), strconv.Itoa(qaSTUNAlternatePort))
+	}
+
+	setters := []stun.Setter{
+		stun.NewTransactionIDSetter(req.TransactionID),
+		stun.BindingSuccess,
+		mapped,
+		&stun.ResponseOrigin{IP: origin.IP, Port: origin.Port},
+	}
+	if srv.otherAddress {
+		setters = append(setters, &stun.OtherAddress{
+			IP:   srv.otherIP(local.IP),
+			Port: srv.otherPort(local.Port),
+		})
+	}
+	resp := stun.MustBuild(setters...)
+
+	srv.mu.Lock()
+	conn := srv.conns[origin.String()]
+	srv.mu.Unlock()
+	if conn != nil {
+		conn.WriteTo(resp.Raw, from)
+	}
+}
+
+// index returns 1 when value equals alternate and 0 otherwise.
+func (srv *fakeSTUNServer) index(value, alternate string) int {
+	if value == alternate {
+		return 1
+	}
+	return 0
+}
+
+func (srv *fakeSTUNServer) otherIP(ip net.IP) net.IP {
+	if ip.Equal(net.ParseIP(qaSTUNPrimaryAddress)) {
+		return net.ParseIP(qaSTUNAlternateAddress)
+	}
+	return net.ParseIP(qaSTUNPrimaryAddress)
+}
+
+func (srv *fakeSTUNServer) otherPort(port int) int {
+	if port == qaSTUNPrimaryPort {
+		return qaSTUNAlternatePort
+	}
+	return qaSTUNPrimaryPort
+}
+
+// fakeTURNServerFactory is a [netemx.NetStackServerFactory] for a TURN server
+// accepting the [qaTURNUsername] and [qaTURNPassword] credentials.
+type fakeTURNServerFactory struct{}
+
+var _ netemx.NetStackServerFactory = &fakeTURNServerFactory{}
+
+// MustNewServer implements netemx.NetStackServerFactory.
+func (f *fakeTURNServerFactory) MustNewServer(_ netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) netemx.NetStackServer {
+	return &fakeTURNServer{stack: stack}
+}
+
+// fakeTURNServer is the [netemx.NetStackServer] returned by [*fakeTURNServerFactory].
+type fakeTURNServer struct {
+	server *turn.Server
+	stack  *netem.UNetStack
+}
+
+// MustStart implements netemx.NetStackServer.
+func (srv *fakeTURNServer) MustStart() {
+	epnt := &net.UDPAddr{IP: net.ParseIP(srv.stack.IPAddress()), Port: qaTURNPort}
+	conn := runtimex.Try1(srv.stack.ListenUDP("udp", epnt))
+	srv.server = runtimex.Try1(turn.NewServer(turn.ServerConfig{
+		Realm: "example.org",
+		AuthHandler: func(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+			if username != qaTURNUsername {
+				return nil, false
+			}
+			return turn.GenerateAuthKey(username, realm, qaTURNPassword), true
+		},
+		PacketConnConfigs: []turn.PacketConnConfig{{
+			PacketConn:            conn,
+			RelayAddressGenerator: &fakeRelayAddressGenerator{stack: srv.stack},
+		}},
+	}))
+}
+
+// Close implements netemx.NetStackServer.
+func (srv *fakeTURNServer) Close() error {
+	if srv.server != nil {
+		return srv.server.Close()
+	}
+	return nil
+}
+
+// fakeRelayAddressGenerator is a [turn.RelayAddressGenerator] using a [*netem.UNetStack].
+type fakeRelayAddressGenerator struct {
+	stack *netem.UNetStack
+}
+
+var _ turn.RelayAddressGenerator = &fakeRelayAddressGenerator{}
+
+// Validate implements turn.RelayAddressGenerator.
+func (g *fakeRelayAddressGenerator) Validate() error {
+	return nil
+}
+
+// AllocatePacketConn implements turn.RelayAddressGenerator.
+func (g *fakeRelayAddressGenerator) AllocatePacketConn(network string, requestedPort int) (net.PacketConn, net.Addr, error) {
+	epnt := &net.UDPAddr{IP: net.ParseIP(g.stack.IPAddress()), Port: requestedPort}
+	conn, err := g.stack.ListenUDP("udp", epnt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, conn.LocalAddr(), nil
+}
+
+// AllocateConn implements turn.RelayAddressGenerator.
+func (g *fakeRelayAddressGenerator) AllocateConn(network string, requestedPort int) (net.Conn, net.Addr, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+// qaTestCase is a QA test case for this experiment.
+type qaTestCase struct {
+	// name is the test case name.
+	name string
+
+	// server is the emulated STUN server.
+	server *fakeSTUNServer
+
+	// turnServers is the value of the SafeTURNServers config option.
+	turnServers string
+
+	// configure OPTIONALLY configures the environment.
+	configure func(env *netemx.QAEnv)
+
+	// expectFailure is the expected top-level failure.
+	expectFailure *string
+
+	// expectNAT is the expected NAT discovery result.
+	expectNAT *NATDiscovery
+
+	// expectTURN contains whether we expect each TURN allocation to succeed.
+	expectTURN []bool
+}
+
+func TestQA(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	timeout := netxlite.FailureGenericTimeoutError
+	otherAddress := net.JoinHostPort(qaSTUNAlternateAddress, strconv.Itoa(qaSTUNAlternatePort))
+	turnURL := "turn://" + qaTURNUsername + ":" + qaTURNPassword + "@" + net.JoinHostPort(qaTURNDomain, "3478")
+	wrongTURNURL := "turn://" + qaTURNUsername + ":xo@" + net.JoinHostPort(qaTURNDomain, "3478")
+
+	testcases := []*qaTestCase{{
+		name:      "without OTHER-ADDRESS support",
+		server:    newFakeSTUNServer(natBehaviorEndpointIndependent, natBehaviorEndpointIndependent, false),
+		expectNAT: nil,
+	}, {
+		name:   "with endpoint independent mapping and filtering",
+		server: newFakeSTUNServer(natBehaviorEndpointIndependent, natBehaviorEndpointIndependent, true),
+		expectNAT: &NATDiscovery{
+			FilteringBehavior: natBehaviorEndpointIndependent,
+			MappingBehavior:   natBehaviorEndpointIndependent,
+			OtherAddress:      otherAddress,
+		},
+	}, {
+		name:   "with address dependent mapping and filtering",
+		server: newFakeSTUNServer(natBehaviorAddressDependent, natBehaviorAddressDependent, true),
+		expectNAT: &NATDiscovery{
+			FilteringBehavior: natBehaviorAddressDependent,
+			MappingBehavior:   natBehaviorAddressDependent,
+			OtherAddress:      otherAddress,
+		},
+	}, {
+		name:   "with address and port dependent mapping and filtering",
+		server: newFakeSTUNServer(natBehaviorAddressAndPortDependent, natBehaviorAddressAndPortDependent, true),
+		expectNAT: &NATDiscovery{
+			FilteringBehavior: natBehaviorAddressAndPortDependent,
+			MappingBehavior:   natBehaviorAddressAndPortDependent,
+			OtherAddress:      otherAddress,
+		},
+	}, {
+		name:   "with the alternate address blocked",
+		server: newFakeSTUNServer(natBehaviorEndpointIndependent, natBehaviorEndpointIndependent, true),
+		configure: func(env *netemx.QAEnv) {
+			env.DPIEngine().AddRule(&netem.DPIDropTrafficForServerEndpoint{
+				Logger:          log.Log,
+				ServerIPAddress: qaSTUNAlternateAddress,
+				ServerPort:      qaSTUNPrimaryPort,
+				ServerProtocol:  layers.IPProtocolUDP,
+			})
+		},
+		expectNAT: &NATDiscovery{
+			FilteringBehavior: natBehaviorEndpointIndependent,
+			MappingFailure:    &timeout,
+			OtherAddress:      otherAddress,
+		},
+	}, {
+		name:   "with the STUN server blocked",
+		server: newFakeSTUNServer(natBehaviorEndpointIndependent, natBehaviorEndpointIndependent, true),
+		configure: func(env *netemx.QAEnv) {
+			env.DPIEngine().AddRule(&netem.DPIDropTrafficForServerEndpoint{
+				Logger:          log.Log,
+				ServerIPAddress: qaSTUNPrimaryAddress,
+				ServerPort:      qaSTUNPrimaryPort,
+				ServerProtocol:  layers.IPProtocolUDP,
+			})
+		},
+		expectFailure: &timeout,
+		expectNAT:     nil,
+	}, {
+		name:        "with TURN servers",
+		server:      newFakeSTUNServer(natBehaviorEndpointIndependent, natBehaviorEndpointIndependent, false),
+		turnServers: turnURL + " " + wrongTURNURL,
+		expectNAT:   nil,
+		expectTURN:  []bool{true, false},
+	}, {
+		name:        "with the TURN server blocked",
+		server:      newFakeSTUNServer(natBehaviorEndpointIndependent, natBehaviorEndpointIndependent, false),
+		turnServers: turnURL,
+		configure: func(env *netemx.QAEnv) {
+			env.DPIEngine().AddRule(&netem.DPIDropTrafficForServerEndpoint{
+				Logger:          log.Log,
+				ServerIPAddress: qaTURNAddress,
+				ServerPort:      qaTURNPort,
+				ServerProtocol:  layers.IPProtocolUDP,
+			})
+		},
+		expectNAT:  nil,
+		expectTURN: []bool{false},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := netemx.MustNewQAEnv(
+				netemx.QAEnvOptionNetStack(qaSTUNPrimaryAddress, tc.server),
+				netemx.QAEnvOptionNetStack(qaSTUNAlternateAddress, tc.server),
+				netemx.QAEnvOptionNetStack(qaTURNAddress, &fakeTURNServerFactory{}),
+			)
+			defer env.Close()
+			env.AddRecordToAllResolvers(qaSTUNDomain, "", qaSTUNPrimaryAddress)
+			env.AddRecordToAllResolvers(qaTURNDomain, "", qaTURNAddress)
+
+			if tc.configure != nil {
+				tc.configure(env)
+			}
+
+			measurer := NewExperimentMeasurer(Config{SafeTURNServers: tc.turnServers})
+			measurement := &model.Measurement{
+				Input: model.MeasurementTarget("stun://" + net.JoinHostPort(qaSTUNDomain, "3478")),
+			}
+			args := &model.ExperimentArgs{
+				Callbacks:   model.NewPrinterCallbacks(log.Log),
+				Measurement: measurement,
+				Session:     &mockable.Session{MockableLogger: log.Log},
+			}
+
+			env.Do(func() {
+				if err := measurer.Run(context.Background(), args); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			tk := measurement.TestKeys.(*TestKeys)
+			if (tk.Failure == nil) != (tc.expectFailure == nil) ||
+				(tk.Failure != nil && *tk.Failure != *tc.expectFailure) {
+				t.Fatal("unexpected failure", tk.Failure)
+			}
+			verifyNATDiscovery(t, tc.expectNAT, tk.NATDiscovery)
+			if len(tk.TURNAllocations) != len(tc.expectTURN) {
+				t.Fatal("unexpected number of TURN allocations", len(tk.TURNAllocations))
+			}
+			for idx, expectSuccess := range tc.expectTURN {
+				alloc := tk.TURNAllocations[idx]
+				if alloc.Endpoint != net.JoinHostPort(qaTURNDomain, "3478") {
+					t.Fatal("unexpected endpoint", alloc.Endpoint)
+				}
+				if !expectSuccess {
+					if alloc.Failure == nil || alloc.RelayedAddress != nil {
+						t.Fatal("expected failure for allocation", idx)
+					}
+					continue
+				}
+				if alloc.Failure != nil {
+					t.Fatal("unexpected failure", *alloc.Failure)
+				}
+				host, _ := runtimex.Try2(net.SplitHostPort(*alloc.RelayedAddress))
+				if host != qaTURNAddress {
+					t.Fatal("unexpected relayed address", *alloc.RelayedAddress)
+				}
+			}
+			if len(tk.NetworkEvents) <= 0 {
+				t.Fatal("no network events?!")
+			}
+		})
+	}
+}
+
+// verifyNATDiscovery compares the expected and the actual NAT discovery results.
+func verifyNATDiscovery(t *testing.T, expect, got *NATDiscovery) {
+	if expect == nil || got == nil {
+		if expect != got {
+			t.Fatal("expected", expect, "got", got)
+		}
+		return
+	}
+	if got.OtherAddress != expect.OtherAddress {
+		t.Fatal("unexpected other_address", got.OtherAddress)
+	}
+	if got.MappingBehavior != expect.MappingBehavior {
+		t.Fatal("unexpected mapping_behavior", got.MappingBehavior)
+	}
+	if got.FilteringBehavior != expect.FilteringBehavior {
+		t.Fatal("unexpected filtering_behavior", got.FilteringBehavior)
+	}
+	if !sameFailure(expect.MappingFailure, got.MappingFailure) {
+		t.Fatal("unexpected mapping_failure", got.MappingFailure)
+	}
+	if !sameFailure(expect.FilteringFailure, got.FilteringFailure) {
+		t.Fatal("unexpected filtering_failure", got.FilteringFailure)
+	}
+}
+
+// sameFailure returns whether two failures are equal.
+func sameFailure(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
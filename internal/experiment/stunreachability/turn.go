@@ -0,0 +1,146 @@
+package stunreachability
+
+//
+// TURN allocation checks.
+//
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/pion/turn/v2"
+)
+
+// TURNAllocation contains the results of attempting a TURN Allocate.
+type TURNAllocation struct {
+	// Endpoint is the TURN server endpoint.
+	Endpoint string `json:"endpoint"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// RelayedAddress is the relayed address assigned by the server or nil.
+	RelayedAddress *string `json:"relayed_address"`
+}
+
+// turnServer is a TURN server parsed from the SafeTURNServers config option.
+type turnServer struct {
+	// endpoint is the TURN server endpoint.
+	endpoint string
+
+	// password is the password to use.
+	password string
+
+	// username is the username to use.
+	username string
+}
+
+// errInvalidTURNServer means that a TURN server URL is not valid
+var errInvalidTURNServer = errors.New("stun: invalid TURN server URL")
+
+// errNoTURNServerAddress means we could not find an IP address for a TURN server
+var errNoTURNServerAddress = errors.New("stun: no IP address for TURN server")
+
+// parseTURNServers parses the space separated list of TURN server URLs.
+func parseTURNServers(value string) ([]*turnServer, error) {
+	var servers []*turnServer
+	for _, entry := range strings.Fields(value) {
+		URL, err := url.Parse(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidTURNServer, err.Error())
+		}
+		if URL.Scheme != "turn" {
+			return nil, fmt.Errorf("%w: %s", errInvalidTURNServer, "unsupported URL scheme")
+		}
+		if URL.Port() == "" {
+			return nil, fmt.Errorf("%w: %s", errInvalidTURNServer, "missing port in URL")
+		}
+		password, _ := URL.User.Password()
+		servers = append(servers, &turnServer{
+			endpoint: URL.Host,
+			password: password,
+			username: URL.User.Username(),
+		})
+	}
+	return servers, nil
+}
+
+// turnAllocate attempts a TURN Allocate with the given server and, on success,
+// immediately releases the allocation.
+func turnAllocate(ctx context.Context, resolver model.Resolver,
+	listener model.UDPListener, server *turnServer) (string, error) {
+	// the TURN client resolves without using our resolver, so we give it an IP
+	// address, preferring IPv4, and listen using the same address family
+	hostname, port, err := net.SplitHostPort(server.endpoint)
+	if err != nil {
+		return "", err
+	}
+	addrs, err := resolver.LookupHost(ctx, hostname)
+	if err != nil {
+		return "", err
+	}
+	var ip net.IP
+	for _, addr := range addrs {
+		candidate := net.ParseIP(addr)
+		if candidate == nil {
+			continue
+		}
+		if ip == nil || candidate.To4() != nil {
+			ip = candidate
+		}
+		if ip.To4() != nil {
+			break
+		}
+	}
+	if ip == nil {
+		return "", errNoTURNServerAddress
+	}
+
+	conn, err := listener.Listen(listenAddrFor(ip))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	client, err := turn.NewClient(&turn.ClientConfig{
+		TURNServerAddr: net.JoinHostPort(ip.String(), port),
+		Username:       server.username,
+		Password:       server.password,
+		Conn:           conn,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	if err := client.Listen(); err != nil {
+		return "", err
+	}
+
+	// the TURN client does not know about contexts, so we close its pending
+	// transactions when the context is done to interrupt it
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-done:
+		}
+	}()
+
+	relayConn, err := client.Allocate()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	relayedAddress := relayConn.LocalAddr().String()
+	relayConn.Close() // release the allocation
+	return relayedAddress, nil
+}
@@ -0,0 +1,422 @@
+package tor
+
+//
+// Tor link protocol handshake with OR ports
+//
+// See https://spec.torproject.org/tor-spec/negotiating-channels.html
+//
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// orportHandshakeTimeout is the maximum time we wait for the link protocol
+// handshake to complete after the TLS handshake.
+const orportHandshakeTimeout = 10 * time.Second
+
+// Link protocol cell commands we care about.
+const (
+	orportCommandPadding       = 0
+	orportCommandVersions      = 7
+	orportCommandNetinfo       = 8
+	orportCommandVPadding      = 128
+	orportCommandCerts         = 129
+	orportCommandAuthChallenge = 130
+)
+
+// orportCommandNames maps cell commands to the names we use in the JSON.
+var orportCommandNames = map[uint8]string{
+	orportCommandPadding:       "padding",
+	orportCommandVersions:      "versions",
+	orportCommandNetinfo:       "netinfo",
+	orportCommandVPadding:      "vpadding",
+	orportCommandCerts:         "certs",
+	orportCommandAuthChallenge: "auth_challenge",
+}
+
+// orportCommandName returns the name of the given command.
+func orportCommandName(command uint8) string {
+	if name, found := orportCommandNames[command]; found {
+		return name
+	}
+	return "unknown"
+}
+
+// orportSupportedVersions contains the link protocol versions we support, sorted
+// in ascending order. We do not support versions 1 and 2, which predate the
+// in-protocol handshake using VERSIONS and CERTS cells.
+var orportSupportedVersions = []uint16{3, 4, 5}
+
+// orportPayloadLen is the payload length of fixed-length cells.
+const orportPayloadLen = 509
+
+// Certificate types used by CERTS cells.
+const (
+	orportCertTypeLink     = 1
+	orportCertTypeIdentity = 2
+)
+
+var (
+	// errORPortNoCommonVersion indicates that we could not negotiate a link protocol version.
+	errORPortNoCommonVersion = errors.New("tor_no_common_link_protocol_version")
+
+	// errORPortUnexpectedCell indicates that the relay sent a cell we did not expect.
+	errORPortUnexpectedCell = errors.New("tor_unexpected_cell")
+
+	// errORPortInvalidCerts indicates that the CERTS cell is malformed or does not
+	// contain a consistent set of identity and link certificates.
+	errORPortInvalidCerts = errors.New("tor_invalid_certs_cell")
+
+	// errORPortIdentityMismatch indicates that the relay identity does not match
+	// the fingerprint of the target we are measuring.
+	errORPortIdentityMismatch = errors.New("tor_identity_mismatch")
+)
+
+// ORPortHandshake contains the results of the Tor link protocol handshake
+// we perform with or_port and or_port_dirauth targets after TLS.
+type ORPortHandshake struct {
+	// Cells contains the cells we sent and received.
+	Cells []*ORPortCell `json:"cells"`
+
+	// Failure is nil on success or the OONI failure string.
+	Failure *string `json:"failure"`
+
+	// IdentityVerified is true when the relay identity matches
+	// the fingerprint of the target.
+	IdentityVerified bool `json:"identity_verified"`
+
+	// LinkProtocolVersion is the negotiated link protocol version or zero.
+	LinkProtocolVersion int64 `json:"link_protocol_version"`
+}
+
+// ORPortCell is a cell we sent or received or we failed to receive.
+type ORPortCell struct {
+	// Command is the cell command (e.g., "versions"). When reading fails,
+	// this is the command we were expecting to receive.
+	Command string `json:"command"`
+
+	// Direction is either "send" or "recv".
+	Direction string `json:"direction"`
+
+	// Failure is nil on success or the OONI failure string.
+	Failure *string `json:"failure"`
+
+	// Length is the length of the cell in bytes.
+	Length int64 `json:"length"`
+
+	// T0 is when we started sending or receiving the cell.
+	T0 float64 `json:"t0"`
+
+	// T is when we finished sending or receiving the cell.
+	T float64 `json:"t"`
+}
+
+// orportHandshaker performs the Tor link protocol handshake.
+type orportHandshaker struct {
+	// begin is the time relative to which we compute T0 and T.
+	begin time.Time
+
+	// circIDLen is the length of the circuit ID, which depends
+	// on the negotiated link protocol version.
+	circIDLen int
+
+	// conn is the TLS connection with the relay.
+	conn model.TLSConn
+
+	// fingerprint is the OPTIONAL expected relay fingerprint.
+	fingerprint string
+
+	// logger is the logger to use.
+	logger model.Logger
+
+	// result contains the results.
+	result *ORPortHandshake
+}
+
+// orportHandshake performs the Tor link protocol handshake as the initiator
+// over the given TLS connection, which MUST have completed the TLS handshake.
+//
+// We send a VERSIONS cell, read the VERSIONS, CERTS, AUTH_CHALLENGE, and
+// NETINFO cells sent by the relay and, when fingerprint is not empty, validate
+// the relay identity using the certificates in the CERTS cell.
+//
+// This function always returns a non-nil result whose Failure field is nil
+// on success. This function does not close the connection but leaves
+// a deadline set on it, hence the caller should close it when done.
+func orportHandshake(ctx context.Context, conn model.TLSConn, begin time.Time,
+	fingerprint string, logger model.Logger) *ORPortHandshake {
+	oh := &orportHandshaker{
+		begin:       begin,
+		circIDLen:   2,
+		conn:        conn,
+		fingerprint: orportNormalizeFingerprint(fingerprint),
+		logger:      logger,
+		result:      &ORPortHandshake{Cells: []*ORPortCell{}},
+	}
+	ctx, cancel := context.WithTimeout(ctx, orportHandshakeTimeout)
+	defer cancel()
+	if deadline, good := ctx.Deadline(); good {
+		conn.SetDeadline(deadline)
+	}
+	// make sure a canceled context interrupts pending I/O
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+	if err := oh.run(); err != nil {
+		failure := err.Error()
+		oh.result.Failure = &failure
+	}
+	return oh.result
+}
+
+// orportNormalizeFingerprint normalizes a fingerprint, which may be formatted
+// as "$ABCD...", "ABCD ..." or "abcd...", to uppercase hex without spaces.
+func orportNormalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimPrefix(fingerprint, "$")
+	fingerprint = strings.ReplaceAll(fingerprint, " ", "")
+	return strings.ToUpper(fingerprint)
+}
+
+func (oh *orportHandshaker) run() error {
+	// VERSIONS cells always use two-byte circuit IDs
+	versions := &bytes.Buffer{}
+	for _, version := range orportSupportedVersions {
+		binary.Write(versions, binary.BigEndian, version)
+	}
+	if err := oh.writeVarCell(orportCommandVersions, versions.Bytes()); err != nil {
+		return err
+	}
+	payload, err := oh.readCell(orportCommandVersions)
+	if err != nil {
+		return err
+	}
+	version, err := orportNegotiateVersion(payload)
+	if err != nil {
+		return err
+	}
+	oh.result.LinkProtocolVersion = int64(version)
+	if version >= 4 {
+		oh.circIDLen = 4
+	}
+	oh.logger.Debugf("tor: negotiated link protocol version %d", version)
+
+	// the relay sends CERTS, AUTH_CHALLENGE, and NETINFO in this order
+	// possibly interleaved with padding cells that we should ignore
+	if payload, err = oh.readCell(orportCommandCerts); err != nil {
+		return err
+	}
+	if err := oh.verifyCerts(payload); err != nil {
+		return err
+	}
+	if _, err := oh.readCell(orportCommandAuthChallenge); err != nil {
+		return err
+	}
+	_, err = oh.readCell(orportCommandNetinfo)
+	return err
+}
+
+// orportNegotiateVersion returns the highest version listed in the payload
+// of a VERSIONS cell that we also support.
+func orportNegotiateVersion(payload []byte) (uint16, error) {
+	var selected uint16
+	for len(payload) >= 2 {
+		version := binary.BigEndian.Uint16(payload)
+		payload = payload[2:]
+		for _, supported := range orportSupportedVersions {
+			if version == supported && version > selected {
+				selected = version
+			}
+		}
+	}
+	if selected == 0 {
+		return 0, errORPortNoCommonVersion
+	}
+	return selected, nil
+}
+
+// writeVarCell writes a variable-length cell with zero circuit ID.
+func (oh *orportHandshaker) writeVarCell(command uint8, payload []byte) error {
+	cell := make([]byte, oh.circIDLen+3+len(payload))
+	cell[oh.circIDLen] = command
+	binary.BigEndian.PutUint16(cell[oh.circIDLen+1:], uint16(len(payload)))
+	copy(cell[oh.circIDLen+3:], payload)
+	entry := &ORPortCell{
+		Command:   orportCommandName(command),
+		Direction: "send",
+		Length:    int64(len(cell)),
+		T0:        time.Since(oh.begin).Seconds(),
+	}
+	_, err := oh.conn.Write(cell)
+	entry.T = time.Since(oh.begin).Seconds()
+	oh.result.Cells = append(oh.result.Cells, entry)
+	if err != nil {
+		failure := netxlite.NewTopLevelGenericErrWrapper(err).Error()
+		entry.Failure = &failure
+		return errors.New(failure)
+	}
+	return nil
+}
+
+// readCell reads cells until it finds one that is not a padding cell and
+// returns its payload if it is a cell with the expected command.
+func (oh *orportHandshaker) readCell(expected uint8) ([]byte, error) {
+	for {
+		entry := &ORPortCell{
+			Command:   orportCommandName(expected),
+			Direction: "recv",
+			T0:        time.Since(oh.begin).Seconds(),
+		}
+		command, payload, length, err := oh.readOneCell()
+		entry.T = time.Since(oh.begin).Seconds()
+		entry.Length = int64(length)
+		oh.result.Cells = append(oh.result.Cells, entry)
+		if err != nil {
+			failure := netxlite.NewTopLevelGenericErrWrapper(err).Error()
+			entry.Failure = &failure
+			return nil, errors.New(failure)
+		}
+		entry.Command = orportCommandName(command)
+		switch command {
+		case expected:
+			return payload, nil
+		case orportCommandPadding, orportCommandVPadding:
+			continue
+		default:
+			failure := errORPortUnexpectedCell.Error()
+			entry.Failure = &failure
+			return nil, errORPortUnexpectedCell
+		}
+	}
+}
+
+// readOneCell reads a single cell and returns its command, its payload, and
+// the number of bytes we have read.
+func (oh *orportHandshaker) readOneCell() (uint8, []byte, int, error) {
+	header := make([]byte, oh.circIDLen+1)
+	count, err := io.ReadFull(oh.conn, header)
+	if err != nil {
+		return 0, nil, count, err
+	}
+	command := header[oh.circIDLen]
+	var payload []byte
+	if command == orportCommandVersions || command >= 128 {
+		length := make([]byte, 2)
+		n, err := io.ReadFull(oh.conn, length)
+		count += n
+		if err != nil {
+			return 0, nil, count, err
+		}
+		payload = make([]byte, binary.BigEndian.Uint16(length))
+	} else {
+		payload = make([]byte, orportPayloadLen)
+	}
+	n, err := io.ReadFull(oh.conn, payload)
+	count += n
+	if err != nil {
+		return 0, nil, count, err
+	}
+	return command, payload, count, nil
+}
+
+// verifyCerts parses the payload of the CERTS cell and verifies that (1) the
+// identity certificate is self-signed, (2) the link certificate is signed by the
+// identity key, (3) the link certificate is the one used by TLS, and (4) the
+// identity key matches the expected fingerprint, if any.
+func (oh *orportHandshaker) verifyCerts(payload []byte) error {
+	certs, err := orportParseCerts(payload)
+	if err != nil {
+		return err
+	}
+	identity, link := certs[orportCertTypeIdentity], certs[orportCertTypeLink]
+	if identity == nil || link == nil {
+		return errORPortInvalidCerts
+	}
+	// Note: we cannot use CheckSignatureFrom because tor certificates do
+	// not contain the basic constraints extension marking them as CAs.
+	err = identity.CheckSignature(identity.SignatureAlgorithm, identity.RawTBSCertificate, identity.Signature)
+	if err != nil {
+		return errORPortInvalidCerts
+	}
+	err = identity.CheckSignature(link.SignatureAlgorithm, link.RawTBSCertificate, link.Signature)
+	if err != nil {
+		return errORPortInvalidCerts
+	}
+	peerCerts := oh.conn.ConnectionState().PeerCertificates
+	if len(peerCerts) < 1 || !bytes.Equal(peerCerts[0].RawSubjectPublicKeyInfo, link.RawSubjectPublicKeyInfo) {
+		return errORPortInvalidCerts
+	}
+	if oh.fingerprint == "" {
+		return nil
+	}
+	fingerprint, err := orportFingerprint(identity)
+	if err != nil {
+		return errORPortInvalidCerts
+	}
+	if fingerprint != oh.fingerprint {
+		return errORPortIdentityMismatch
+	}
+	oh.result.IdentityVerified = true
+	return nil
+}
+
+// orportParseCerts parses the payload of a CERTS cell and returns the X.509
+// certificates it contains indexed by certificate type. We ignore the certificate
+// types using the tor-specific certificate format (i.e., Ed25519 certificates).
+func orportParseCerts(payload []byte) (map[uint8]*x509.Certificate, error) {
+	if len(payload) < 1 {
+		return nil, errORPortInvalidCerts
+	}
+	count := int(payload[0])
+	payload = payload[1:]
+	out := make(map[uint8]*x509.Certificate)
+	for idx := 0; idx < count; idx++ {
+		if len(payload) < 3 {
+			return nil, errORPortInvalidCerts
+		}
+		certType := payload[0]
+		length := int(binary.BigEndian.Uint16(payload[1:]))
+		payload = payload[3:]
+		if len(payload) < length {
+			return nil, errORPortInvalidCerts
+		}
+		body := payload[:length]
+		payload = payload[length:]
+		if certType != orportCertTypeLink && certType != orportCertTypeIdentity {
+			continue
+		}
+		if out[certType] != nil {
+			return nil, errORPortInvalidCerts // the spec forbids duplicates
+		}
+		cert, err := x509.ParseCertificate(body)
+		if err != nil {
+			return nil, errORPortInvalidCerts
+		}
+		out[certType] = cert
+	}
+	return out, nil
+}
+
+// orportFingerprint computes the relay fingerprint, i.e., the uppercase hex
+// encoding of the SHA1 of the PKCS#1 encoding of the RSA identity key.
+func orportFingerprint(identity *x509.Certificate) (string, error) {
+	key, good := identity.PublicKey.(*rsa.PublicKey)
+	if !good {
+		return "", errORPortInvalidCerts
+	}
+	digest := sha1.Sum(x509.MarshalPKCS1PublicKey(key))
+	return strings.ToUpper(hex.EncodeToString(digest[:])), nil
+}
@@ -0,0 +1,403 @@
+package tor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// fakeRelayBehavior configures the behavior of a [*fakeRelay].
+type fakeRelayBehavior struct {
+	// Padding causes the relay to send padding cells before CERTS.
+	Padding bool
+
+	// SkipCerts causes the relay to send NETINFO instead of CERTS.
+	SkipCerts bool
+
+	// Stall causes the relay to stop responding after the TLS
+	// handshake, like a DPI box that accepts any handshake.
+	Stall bool
+
+	// Versions contains the versions the relay sends in its
+	// VERSIONS cell. When empty, we use 3, 4, and 5. The relay
+	// assumes that we negotiate the highest version.
+	Versions []uint16
+
+	// WrongLinkCert causes the relay to send a link certificate that
+	// does not match the certificate used for TLS.
+	WrongLinkCert bool
+}
+
+// fakeRelay is a local TLS server implementing the responder side of
+// the link protocol handshake, just enough to test [orportHandshake].
+type fakeRelay struct {
+	behavior    fakeRelayBehavior
+	fingerprint string
+	identity    []byte
+	link        []byte
+	otherLink   []byte
+	listener    net.Listener
+	tlsConfig   *tls.Config
+	wg          *sync.WaitGroup
+}
+
+// fakeRelayCert creates a certificate for the given key signed by the given parent.
+func fakeRelayCert(t *testing.T, name string, key *rsa.PrivateKey,
+	parent *x509.Certificate, parentKey *rsa.PrivateKey) ([]byte, *x509.Certificate) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	data, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, cert
+}
+
+// newFakeRelay creates and starts a new [*fakeRelay] listening on the loopback.
+func newFakeRelay(t *testing.T, behavior fakeRelayBehavior) *fakeRelay {
+	fr := newFakeRelayWithoutListener(t, behavior)
+	fr.start(runtimex.Try1(net.Listen("tcp", "127.0.0.1:0")))
+	t.Cleanup(fr.close)
+	return fr
+}
+
+// newFakeRelayWithoutListener creates a new [*fakeRelay] that you
+// should start using the [*fakeRelay] start method.
+func newFakeRelayWithoutListener(t *testing.T, behavior fakeRelayBehavior) *fakeRelay {
+	identityKey := runtimex.Try1(rsa.GenerateKey(rand.Reader, 1024))
+	linkKey := runtimex.Try1(rsa.GenerateKey(rand.Reader, 1024))
+	otherKey := runtimex.Try1(rsa.GenerateKey(rand.Reader, 1024))
+	identityDER, identityCert := fakeRelayCert(t, "www.identity.net", identityKey, nil, nil)
+	linkDER, _ := fakeRelayCert(t, "www.link.net", linkKey, identityCert, identityKey)
+	otherDER, _ := fakeRelayCert(t, "www.other.net", otherKey, identityCert, identityKey)
+	fingerprint := runtimex.Try1(orportFingerprint(identityCert))
+	return &fakeRelay{
+		behavior:    behavior,
+		fingerprint: fingerprint,
+		identity:    identityDER,
+		link:        linkDER,
+		otherLink:   otherDER,
+		tlsConfig: &tls.Config{
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{linkDER},
+				PrivateKey:  linkKey,
+			}},
+		},
+		wg: &sync.WaitGroup{},
+	}
+}
+
+// start starts serving the link protocol over TLS using the given listener.
+func (fr *fakeRelay) start(listener net.Listener) {
+	fr.listener = tls.NewListener(listener, fr.tlsConfig)
+	fr.wg.Add(1)
+	go fr.serve()
+}
+
+// Address returns the relay address.
+func (fr *fakeRelay) Address() string {
+	return fr.listener.Addr().String()
+}
+
+func (fr *fakeRelay) close() {
+	fr.listener.Close()
+	fr.wg.Wait()
+}
+
+func (fr *fakeRelay) serve() {
+	defer fr.wg.Done()
+	for {
+		conn, err := fr.listener.Accept()
+		if err != nil {
+			return
+		}
+		fr.wg.Add(1)
+		go fr.handle(conn.(*tls.Conn))
+	}
+}
+
+func (fr *fakeRelay) handle(conn *tls.Conn) {
+	defer fr.wg.Done()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := conn.Handshake(); err != nil {
+		return
+	}
+	if fr.behavior.Stall {
+		io.Copy(io.Discard, conn) // until the client closes the connection
+		return
+	}
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, binary.BigEndian.Uint16(header[3:]))); err != nil {
+		return
+	}
+	versions := fr.behavior.Versions
+	if len(versions) <= 0 {
+		versions = []uint16{3, 4, 5}
+	}
+	payload := &bytes.Buffer{}
+	circIDLen := 2
+	for _, version := range versions {
+		binary.Write(payload, binary.BigEndian, version)
+		if version >= 4 {
+			circIDLen = 4
+		}
+	}
+	out := &bytes.Buffer{}
+	fakeRelayWriteVarCell(out, 2, orportCommandVersions, payload.Bytes())
+	if fr.behavior.Padding {
+		fakeRelayWriteVarCell(out, circIDLen, orportCommandVPadding, []byte{0, 0, 0, 0})
+		out.Write(make([]byte, circIDLen+1+orportPayloadLen)) // PADDING
+	}
+	if !fr.behavior.SkipCerts {
+		link := fr.link
+		if fr.behavior.WrongLinkCert {
+			link = fr.otherLink
+		}
+		certs := &bytes.Buffer{}
+		certs.WriteByte(3)
+		for _, entry := range []struct {
+			kind uint8
+			data []byte
+		}{{orportCertTypeLink, link}, {orportCertTypeIdentity, fr.identity}, {4, []byte("ed25519")}} {
+			certs.WriteByte(entry.kind)
+			binary.Write(certs, binary.BigEndian, uint16(len(entry.data)))
+			certs.Write(entry.data)
+		}
+		fakeRelayWriteVarCell(out, circIDLen, orportCommandCerts, certs.Bytes())
+		fakeRelayWriteVarCell(out, circIDLen, orportCommandAuthChallenge, make([]byte, 36))
+	}
+	netinfo := make([]byte, circIDLen+1+orportPayloadLen)
+	netinfo[circIDLen] = orportCommandNetinfo
+	out.Write(netinfo)
+	conn.Write(out.Bytes())
+	io.Copy(io.Discard, conn) // until the client closes the connection
+}
+
+func fakeRelayWriteVarCell(w *bytes.Buffer, circIDLen int, command uint8, payload []byte) {
+	w.Write(make([]byte, circIDLen))
+	w.WriteByte(command)
+	binary.Write(w, binary.BigEndian, uint16(len(payload)))
+	w.Write(payload)
+}
+
+// dialFakeRelay dials the given relay and performs the TLS handshake.
+func dialFakeRelay(t *testing.T, fr *fakeRelay) *tls.Conn {
+	conn, err := tls.Dial("tcp", fr.Address(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestORPortHandshake(t *testing.T) {
+	t.Run("with a good relay", func(t *testing.T) {
+		for _, padding := range []bool{false, true} {
+			fr := newFakeRelay(t, fakeRelayBehavior{Padding: padding})
+			conn := dialFakeRelay(t, fr)
+			fingerprint := "$" + strings.ToLower(fr.fingerprint)
+			result := orportHandshake(context.Background(), conn, time.Now(), fingerprint, model.DiscardLogger)
+			if result.Failure != nil {
+				t.Fatal("unexpected failure", *result.Failure)
+			}
+			if !result.IdentityVerified {
+				t.Fatal("expected the identity to be verified")
+			}
+			if result.LinkProtocolVersion != 5 {
+				t.Fatal("unexpected link protocol version", result.LinkProtocolVersion)
+			}
+			var commands []string
+			for _, cell := range result.Cells {
+				if cell.Failure != nil {
+					t.Fatal("unexpected cell failure", *cell.Failure)
+				}
+				if cell.T < cell.T0 || cell.Length <= 0 {
+					t.Fatal("unexpected cell timing or length", cell)
+				}
+				commands = append(commands, cell.Direction+":"+cell.Command)
+			}
+			expected := "send:versions recv:versions recv:certs recv:auth_challenge recv:netinfo"
+			if padding {
+				expected = "send:versions recv:versions recv:vpadding recv:padding recv:certs recv:auth_challenge recv:netinfo"
+			}
+			if got := strings.Join(commands, " "); got != expected {
+				t.Fatal("unexpected cells", got)
+			}
+		}
+	})
+
+	t.Run("without a fingerprint", func(t *testing.T) {
+		fr := newFakeRelay(t, fakeRelayBehavior{})
+		conn := dialFakeRelay(t, fr)
+		result := orportHandshake(context.Background(), conn, time.Now(), "", model.DiscardLogger)
+		if result.Failure != nil {
+			t.Fatal("unexpected failure", *result.Failure)
+		}
+		if result.IdentityVerified {
+			t.Fatal("expected the identity not to be verified")
+		}
+	})
+
+	t.Run("with link protocol version 3", func(t *testing.T) {
+		fr := newFakeRelay(t, fakeRelayBehavior{Versions: []uint16{1, 2, 3}})
+		conn := dialFakeRelay(t, fr)
+		result := orportHandshake(context.Background(), conn, time.Now(), fr.fingerprint, model.DiscardLogger)
+		if result.Failure != nil {
+			t.Fatal("unexpected failure", *result.Failure)
+		}
+		if result.LinkProtocolVersion != 3 {
+			t.Fatal("unexpected link protocol version", result.LinkProtocolVersion)
+		}
+	})
+
+	for _, tc := range []struct {
+		name        string
+		behavior    fakeRelayBehavior
+		fingerprint string
+		expect      string
+	}{{
+		name:     "with a relay that stalls after TLS",
+		behavior: fakeRelayBehavior{Stall: true},
+		expect:   netxlite.FailureGenericTimeoutError,
+	}, {
+		name:     "without a common version",
+		behavior: fakeRelayBehavior{Versions: []uint16{1, 2}},
+		expect:   errORPortNoCommonVersion.Error(),
+	}, {
+		name:     "without CERTS",
+		behavior: fakeRelayBehavior{SkipCerts: true},
+		expect:   errORPortUnexpectedCell.Error(),
+	}, {
+		name:     "with a link certificate not used by TLS",
+		behavior: fakeRelayBehavior{WrongLinkCert: true},
+		expect:   errORPortInvalidCerts.Error(),
+	}, {
+		name:        "with the wrong identity",
+		behavior:    fakeRelayBehavior{},
+		fingerprint: "0000000000000000000000000000000000000000",
+		expect:      errORPortIdentityMismatch.Error(),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			fr := newFakeRelay(t, tc.behavior)
+			conn := dialFakeRelay(t, fr)
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			result := orportHandshake(ctx, conn, time.Now(), tc.fingerprint, model.DiscardLogger)
+			if result.Failure == nil || *result.Failure != tc.expect {
+				t.Fatal("unexpected failure", result.Failure)
+			}
+			if result.IdentityVerified {
+				t.Fatal("expected the identity not to be verified")
+			}
+		})
+	}
+
+	t.Run("when writing fails", func(t *testing.T) {
+		fr := newFakeRelay(t, fakeRelayBehavior{})
+		conn := dialFakeRelay(t, fr)
+		conn.Close()
+		result := orportHandshake(context.Background(), conn, time.Now(), "", model.DiscardLogger)
+		if result.Failure == nil {
+			t.Fatal("expected a failure")
+		}
+		if len(result.Cells) != 1 || result.Cells[0].Failure == nil {
+			t.Fatal("expected a single failed cell")
+		}
+	})
+}
+
+func TestORPortParseCerts(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+	}{{
+		name:    "with an empty payload",
+		payload: []byte{},
+	}, {
+		name:    "with a truncated header",
+		payload: []byte{1, 1, 0},
+	}, {
+		name:    "with a truncated certificate",
+		payload: []byte{1, 1, 0, 4, 0},
+	}, {
+		name:    "with an invalid certificate",
+		payload: []byte{1, 1, 0, 1, 0},
+	}, {
+		name:    "with duplicate certificates",
+		payload: []byte{2, 1, 0, 1, 0, 1, 0, 1, 0},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := orportParseCerts(tc.payload); !errors.Is(err, errORPortInvalidCerts) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
+
+func TestDefaultFlexibleConnectOrPortWithFakeRelay(t *testing.T) {
+	fr := newFakeRelay(t, fakeRelayBehavior{})
+	rc := newResultsCollector(
+		&mockable.Session{
+			MockableLogger: log.Log,
+		},
+		new(model.Measurement),
+		model.NewPrinterCallbacks(log.Log),
+	)
+	kt := keytarget{key: "xx", target: model.OOAPITorTarget{
+		Address:  fr.Address(),
+		Params:   map[string][]string{"fingerprint": {fr.fingerprint}},
+		Protocol: "or_port",
+	}}
+	tk, orport, failure := rc.defaultFlexibleConnect(context.Background(), kt)
+	if failure != nil {
+		t.Fatal("unexpected failure", *failure)
+	}
+	if len(tk.TLSHandshakes) != 1 {
+		t.Fatal("expected a TLS handshake")
+	}
+	if orport == nil || !orport.IdentityVerified {
+		t.Fatal("expected a verified link protocol handshake")
+	}
+	rc.measureSingleTarget(context.Background(), kt, 1)
+	tr := rc.targetresults["xx"]
+	if tr.Failure != nil || tr.ORPortHandshake == nil {
+		t.Fatal("unexpected target results")
+	}
+	if tr.Summary["handshake"].Failure != nil {
+		t.Fatal("unexpected handshake failure in summary")
+	}
+}
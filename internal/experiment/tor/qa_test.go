@@ -0,0 +1,360 @@
+package tor
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"gitlab.com/yawning/obfs4.git/transports/base"
+	"gitlab.com/yawning/obfs4.git/transports/obfs4"
+	pt "gitlab.torproject.org/tpo/anti-censorship/pluggable-transports/goptlib"
+)
+
+const (
+	// qaDirauthAddress is the address of the emulated directory authority
+	// exposing both a dir port and an OR port.
+	qaDirauthAddress = "66.111.2.131"
+
+	// qaDirauthDirPort is the emulated directory authority dir port.
+	qaDirauthDirPort = 9030
+
+	// qaDirauthORPort is the emulated directory authority OR port.
+	qaDirauthORPort = 9001
+
+	// qaBridgeAddress is the address of the emulated obfs4 bridge.
+	qaBridgeAddress = "192.95.36.142"
+
+	// qaBridgePort is the port of the emulated obfs4 bridge.
+	qaBridgePort = 443
+)
+
+// fakeRelayServerFactory is a [netemx.NetStackServerFactory] for a [*fakeRelay].
+type fakeRelayServerFactory struct {
+	port  int
+	relay *fakeRelay
+}
+
+var _ netemx.NetStackServerFactory = &fakeRelayServerFactory{}
+
+// MustNewServer implements netemx.NetStackServerFactory.
+func (f *fakeRelayServerFactory) MustNewServer(_ netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) netemx.NetStackServer {
+	return &fakeRelayServer{port: f.port, relay: f.relay, stack: stack}
+}
+
+// fakeRelayServer is the [netemx.NetStackServer] returned by [*fakeRelayServerFactory].
+type fakeRelayServer struct {
+	port  int
+	relay *fakeRelay
+	stack *netem.UNetStack
+}
+
+// MustStart implements netemx.NetStackServer.
+func (srv *fakeRelayServer) MustStart() {
+	epnt := &net.TCPAddr{IP: net.ParseIP(srv.stack.IPAddress()), Port: srv.port}
+	srv.relay.start(runtimex.Try1(srv.stack.ListenTCP("tcp", epnt)))
+}
+
+// Close implements netemx.NetStackServer.
+func (srv *fakeRelayServer) Close() error {
+	srv.relay.close()
+	return nil
+}
+
+// obfs4ServerFactory is a [netemx.NetStackServerFactory] for an obfs4 server
+// that discards whatever the client sends after the handshake.
+type obfs4ServerFactory struct {
+	factory base.ServerFactory
+	port    int
+}
+
+var _ netemx.NetStackServerFactory = &obfs4ServerFactory{}
+
+// newOBFS4ServerFactory creates a new [*obfs4ServerFactory] with a fresh identity.
+func newOBFS4ServerFactory(t *testing.T, port int) *obfs4ServerFactory {
+	factory := runtimex.Try1((&obfs4.Transport{}).ServerFactory(t.TempDir(), &pt.Args{}))
+	return &obfs4ServerFactory{factory: factory, port: port}
+}
+
+// params returns the params describing this bridge that the OONI API would return.
+func (f *obfs4ServerFactory) params() map[string][]string {
+	out := map[string][]string{}
+	for _, key := range []string{"cert", "iat-mode"} {
+		value, found := f.factory.Args().Get(key)
+		runtimex.Assert(found, "obfs4ServerFactory: missing argument")
+		out[key] = []string{value}
+	}
+	return out
+}
+
+// MustNewServer implements netemx.NetStackServerFactory.
+func (f *obfs4ServerFactory) MustNewServer(_ netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) netemx.NetStackServer {
+	return &obfs4Server{factory: f.factory, port: f.port, stack: stack}
+}
+
+// obfs4Server is the [netemx.NetStackServer] returned by [*obfs4ServerFactory].
+type obfs4Server struct {
+	factory  base.ServerFactory
+	listener net.Listener
+	port     int
+	stack    *netem.UNetStack
+}
+
+// MustStart implements netemx.NetStackServer.
+func (srv *obfs4Server) MustStart() {
+	epnt := &net.TCPAddr{IP: net.ParseIP(srv.stack.IPAddress()), Port: srv.port}
+	srv.listener = runtimex.Try1(srv.stack.ListenTCP("tcp", epnt))
+	go srv.serve()
+}
+
+func (srv *obfs4Server) serve() {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		go srv.handle(conn)
+	}
+}
+
+func (srv *obfs4Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	wrapped, err := srv.factory.WrapConn(conn)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, wrapped) // until the client closes the connection
+}
+
+// Close implements netemx.NetStackServer.
+func (srv *obfs4Server) Close() error {
+	if srv.listener != nil {
+		return srv.listener.Close()
+	}
+	return nil
+}
+
+// qaConsensusHandlerFactory returns a factory for an [http.Handler] serving a
+// consensus larger than the body snapshot we archive for dir_port targets.
+func qaConsensusHandlerFactory() netemx.HTTPHandlerFactory {
+	return netemx.HTTPHandlerFactoryFunc(func(env netemx.NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/tor/status-vote/current/consensus.z" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(strings.Repeat("x", 4096)))
+		})
+	})
+}
+
+// qaTestCase is a QA test case for this experiment.
+type qaTestCase struct {
+	// name is the test case name.
+	name string
+
+	// configure is the OPTIONAL function to configure the environment.
+	configure func(env *netemx.QAEnv)
+
+	// expectFailures maps each target key to the expected failure.
+	expectFailures map[string]*string
+}
+
+func TestQA(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	connectionRefused := netxlite.FailureConnectionRefused
+
+	testcases := []*qaTestCase{{
+		name:      "without censorship",
+		configure: nil,
+		expectFailures: map[string]*string{
+			"dir_port":        nil,
+			"obfs4":           nil,
+			"or_port_dirauth": nil,
+		},
+	}, {
+		name: "with the dir port blocked",
+		configure: func(env *netemx.QAEnv) {
+			env.DPIEngine().AddRule(&netem.DPICloseConnectionForServerEndpoint{
+				Logger:          log.Log,
+				ServerIPAddress: qaDirauthAddress,
+				ServerPort:      qaDirauthDirPort,
+			})
+		},
+		expectFailures: map[string]*string{
+			"dir_port":        &connectionRefused,
+			"obfs4":           nil,
+			"or_port_dirauth": nil,
+		},
+	}, {
+		name: "with the OR port blocked",
+		configure: func(env *netemx.QAEnv) {
+			env.DPIEngine().AddRule(&netem.DPICloseConnectionForServerEndpoint{
+				Logger:          log.Log,
+				ServerIPAddress: qaDirauthAddress,
+				ServerPort:      qaDirauthORPort,
+			})
+		},
+		expectFailures: map[string]*string{
+			"dir_port":        nil,
+			"obfs4":           nil,
+			"or_port_dirauth": &connectionRefused,
+		},
+	}, {
+		name: "with the obfs4 bridge blocked",
+		configure: func(env *netemx.QAEnv) {
+			env.DPIEngine().AddRule(&netem.DPICloseConnectionForServerEndpoint{
+				Logger:          log.Log,
+				ServerIPAddress: qaBridgeAddress,
+				ServerPort:      qaBridgePort,
+			})
+		},
+		expectFailures: map[string]*string{
+			"dir_port":        nil,
+			"obfs4":           &connectionRefused,
+			"or_port_dirauth": nil,
+		},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			relay := newFakeRelayWithoutListener(t, fakeRelayBehavior{})
+			bridge := newOBFS4ServerFactory(t, qaBridgePort)
+
+			env := netemx.MustNewQAEnv(
+				netemx.QAEnvOptionNetStack(
+					qaDirauthAddress,
+					&netemx.HTTPCleartextServerFactory{
+						Factory: qaConsensusHandlerFactory(),
+						Ports:   []int{qaDirauthDirPort},
+					},
+					&fakeRelayServerFactory{port: qaDirauthORPort, relay: relay},
+				),
+				netemx.QAEnvOptionNetStack(qaBridgeAddress, bridge),
+			)
+			defer env.Close()
+
+			if tc.configure != nil {
+				tc.configure(env)
+			}
+
+			targets := map[string]model.OOAPITorTarget{
+				"dir_port": {
+					Address:  net.JoinHostPort(qaDirauthAddress, "9030"),
+					Name:     "dirauth",
+					Protocol: "dir_port",
+				},
+				"obfs4": {
+					Address:  net.JoinHostPort(qaBridgeAddress, "443"),
+					Params:   bridge.params(),
+					Protocol: "obfs4",
+				},
+				"or_port_dirauth": {
+					Address:  net.JoinHostPort(qaDirauthAddress, "9001"),
+					Name:     "dirauth",
+					Params:   map[string][]string{"fingerprint": {relay.fingerprint}},
+					Protocol: "or_port_dirauth",
+				},
+			}
+
+			measurer := NewMeasurer(Config{})
+			measurer.fetchTorTargets = func(ctx context.Context, sess model.ExperimentSession,
+				cc string) (map[string]model.OOAPITorTarget, error) {
+				return targets, nil
+			}
+			measurement := &model.Measurement{MeasurementStartTimeSaved: time.Now()}
+			args := &model.ExperimentArgs{
+				Callbacks:   model.NewPrinterCallbacks(log.Log),
+				Measurement: measurement,
+				Session: &mockable.Session{
+					MockableLogger:  log.Log,
+					MockableTempDir: t.TempDir(),
+				},
+			}
+
+			env.Do(func() {
+				if err := measurer.Run(context.Background(), args); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			tk := measurement.TestKeys.(*TestKeys)
+			var accessible int64
+			for key, expectFailure := range tc.expectFailures {
+				tr, found := tk.Targets[key]
+				if !found {
+					t.Fatal("missing target", key)
+				}
+				if diff := cmp.Diff(expectFailure, tr.Failure); diff != "" {
+					t.Fatal(key, diff)
+				}
+				if len(tr.TCPConnect) != 1 {
+					t.Fatal(key, "expected exactly one TCP connect")
+				}
+				if len(tr.NetworkEvents) <= 0 {
+					t.Fatal(key, "expected network events")
+				}
+				if expectFailure == nil {
+					accessible++
+					tc.checkSuccessfulTarget(t, key, tr)
+				}
+			}
+			if got := tk.DirPortAccessible + tk.OBFS4Accessible + tk.ORPortDirauthAccessible; got != accessible {
+				t.Fatal("unexpected number of accessible targets", got)
+			}
+			if tk.DirPortTotal != 1 || tk.OBFS4Total != 1 || tk.ORPortDirauthTotal != 1 || tk.ORPortTotal != 0 {
+				t.Fatal("unexpected total number of targets")
+			}
+			sk := tk.MeasurementSummaryKeys()
+			if sk.Anomaly() != (accessible != 3) {
+				t.Fatal("unexpected anomaly", sk.Anomaly())
+			}
+		})
+	}
+}
+
+// checkSuccessfulTarget checks the results of a target we could access.
+func (tc *qaTestCase) checkSuccessfulTarget(t *testing.T, key string, tr TargetResults) {
+	if tr.Summary[netxlite.ConnectOperation].Failure != nil {
+		t.Fatal(key, "unexpected connect failure in summary")
+	}
+	switch tr.TargetProtocol {
+	case "dir_port":
+		if len(tr.Requests) != 1 {
+			t.Fatal(key, "expected exactly one request")
+		}
+		resp := tr.Requests[0].Response
+		if resp.Code != 200 || !resp.BodyIsTruncated || len(resp.Body) != 1<<8 {
+			t.Fatal(key, "unexpected response")
+		}
+	case "obfs4":
+		if tr.Summary["handshake"].Failure != nil {
+			t.Fatal(key, "unexpected handshake failure in summary")
+		}
+	case "or_port_dirauth":
+		if len(tr.TLSHandshakes) != 1 || tr.TLSHandshakes[0].Failure != nil {
+			t.Fatal(key, "expected exactly one successful TLS handshake")
+		}
+		if tr.ORPortHandshake == nil || !tr.ORPortHandshake.IdentityVerified {
+			t.Fatal(key, "expected a verified link protocol handshake")
+		}
+		if tr.Summary["handshake"].Failure != nil {
+			t.Fatal(key, "unexpected handshake failure in summary")
+		}
+	}
+}
@@ -7,18 +7,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/ooni/probe-cli/v3/internal/legacy/measurex"
-	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
 	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/ptx"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/ooni/probe-cli/v3/internal/scrubber"
+	"github.com/ooni/probe-cli/v3/internal/x/dslx"
 )
 
 const (
@@ -29,7 +29,7 @@ const (
 	testName = "tor"
 
 	// testVersion is the version of this experiment
-	testVersion = "0.4.0"
+	testVersion = "0.6.0"
 )
 
 // Config contains the experiment config.
@@ -42,18 +42,19 @@ type Summary struct {
 
 // TargetResults contains the results of measuring a target.
 type TargetResults struct {
-	Agent          string                                    `json:"agent"`
-	Failure        *string                                   `json:"failure"`
-	NetworkEvents  []*measurex.ArchivalNetworkEvent          `json:"network_events"`
-	Queries        []*measurex.ArchivalDNSLookupEvent        `json:"queries"`
-	Requests       []*measurex.ArchivalHTTPRoundTripEvent    `json:"requests"`
-	Summary        map[string]Summary                        `json:"summary"`
-	TargetAddress  string                                    `json:"target_address"`
-	TargetName     string                                    `json:"target_name,omitempty"`
-	TargetProtocol string                                    `json:"target_protocol"`
-	TargetSource   string                                    `json:"target_source,omitempty"`
-	TCPConnect     []*measurex.ArchivalTCPConnect            `json:"tcp_connect"`
-	TLSHandshakes  []*measurex.ArchivalQUICTLSHandshakeEvent `json:"tls_handshakes"`
+	Agent           string                                    `json:"agent"`
+	Failure         *string                                   `json:"failure"`
+	NetworkEvents   []*model.ArchivalNetworkEvent             `json:"network_events"`
+	ORPortHandshake *ORPortHandshake                          `json:"or_port_handshake"`
+	Queries         []*model.ArchivalDNSLookupResult          `json:"queries"`
+	Requests        []*model.ArchivalHTTPRequestResult        `json:"requests"`
+	Summary         map[string]Summary                        `json:"summary"`
+	TargetAddress   string                                    `json:"target_address"`
+	TargetName      string                                    `json:"target_name,omitempty"`
+	TargetProtocol  string                                    `json:"target_protocol"`
+	TargetSource    string                                    `json:"target_source,omitempty"`
+	TCPConnect      []*model.ArchivalTCPConnectResult         `json:"tcp_connect"`
+	TLSHandshakes   []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
 
 	// Only for testing. We don't care about this field otherwise. We
 	// cannot make this private because otherwise the IP address sanitizer
@@ -62,11 +63,11 @@ type TargetResults struct {
 }
 
 func registerExtensions(m *model.Measurement) {
-	tracex.ExtHTTP.AddTo(m)
-	tracex.ExtNetevents.AddTo(m)
-	tracex.ExtDNS.AddTo(m)
-	tracex.ExtTCPConnect.AddTo(m)
-	tracex.ExtTLSHandshake.AddTo(m)
+	model.ArchivalExtHTTP.AddTo(m)
+	model.ArchivalExtNetevents.AddTo(m)
+	model.ArchivalExtDNS.AddTo(m)
+	model.ArchivalExtTCPConnect.AddTo(m)
+	model.ArchivalExtTLSHandshake.AddTo(m)
 }
 
 // fillSummary fills the Summary field used by the UI.
@@ -93,8 +94,14 @@ func (tr *TargetResults) fillSummary() {
 		if len(tr.TLSHandshakes) < 1 {
 			return
 		}
+		// The handshake is successful when both the TLS handshake
+		// and the link protocol handshake are successful
+		failure := tr.TLSHandshakes[0].Failure
+		if failure == nil && tr.ORPortHandshake != nil {
+			failure = tr.ORPortHandshake.Failure
+		}
 		tr.Summary["handshake"] = Summary{
-			Failure: tr.TLSHandshakes[0].Failure,
+			Failure: failure,
 		}
 	}
 }
@@ -243,7 +250,7 @@ func (m *Measurer) measureTargets(
 type resultsCollector struct {
 	callbacks       model.ExperimentCallbacks
 	completed       *atomic.Int64
-	flexibleConnect func(context.Context, keytarget) (*measurex.ArchivalMeasurement, *string)
+	flexibleConnect func(context.Context, keytarget) (*dslx.Observations, *ORPortHandshake, *string)
 	measurement     *model.Measurement
 	mu              sync.Mutex
 	sess            model.ExperimentSession
@@ -285,16 +292,17 @@ func maybeSanitize(input TargetResults, kt keytarget) TargetResults {
 func (rc *resultsCollector) measureSingleTarget(
 	ctx context.Context, kt keytarget, total int,
 ) {
-	tk, failure := rc.flexibleConnect(ctx, kt)
-	runtimex.PanicIfNil(tk, "measurex should guarantee non-nil here")
+	obs, orport, failure := rc.flexibleConnect(ctx, kt)
+	runtimex.PanicIfNil(obs, "flexibleConnect should guarantee non-nil here")
 	tr := TargetResults{
-		Agent:         "redirect",
-		Failure:       failure,
-		NetworkEvents: tk.NetworkEvents,
-		Queries:       tk.Queries,
-		Requests:      tk.Requests,
-		TCPConnect:    tk.TCPConnect,
-		TLSHandshakes: tk.TLSHandshakes,
+		Agent:           "redirect",
+		Failure:         failure,
+		NetworkEvents:   obs.NetworkEvents,
+		ORPortHandshake: orport,
+		Queries:         obs.Queries,
+		Requests:        obs.Requests,
+		TCPConnect:      obs.TCPConnect,
+		TLSHandshakes:   obs.TLSHandshakes,
 	}
 	tr.fillSummary()
 	tr = maybeSanitize(tr, kt)
@@ -334,37 +342,192 @@ func maybeScrubbingLogger(input model.Logger, kt keytarget) model.Logger {
 //
 // Returns:
 //
-// - tk is the measurement, which is always non nil because
-// the measurex "easy" API provides this guarantee;
+// - obs contains the observations, which are always non-nil;
+//
+// - orport is the result of the link protocol handshake, which is
+// only non-nil for OR port targets whose TLS handshake succeeded;
 //
 // - failure is nil or an OONI failure string.
 func (rc *resultsCollector) defaultFlexibleConnect(ctx context.Context,
-	kt keytarget) (tk *measurex.ArchivalMeasurement, failure *string) {
-	mx := measurex.NewMeasurerWithDefaultSettings()
-	mx.Begin = rc.measurement.MeasurementStartTimeSaved
-	mx.Logger = maybeScrubbingLogger(rc.sess.Logger(), kt)
+	kt keytarget) (obs *dslx.Observations, orport *ORPortHandshake, failure *string) {
+	rt := dslx.NewRuntimeMeasurexLite(
+		maybeScrubbingLogger(rc.sess.Logger(), kt),
+		rc.measurement.MeasurementStartTimeSaved,
+	)
+	defer rt.Close()
+	endpoint := dslx.NewMaybeWithValue(dslx.NewEndpoint("tcp", dslx.EndpointAddress(kt.target.Address)))
+	var err error
 	switch kt.target.Protocol {
 	case "dir_port":
-		URL := url.URL{
-			Host:   kt.target.Address,
-			Path:   "/tor/status-vote/current/consensus.z",
-			Scheme: "http",
-		}
 		const snapshotsize = 1 << 8 // no need to include all in report
-		mx.HTTPMaxBodySnapshotSize = snapshotsize
-		const timeout = 15 * time.Second
-		return mx.EasyHTTPRoundTripGET(ctx, timeout, URL.String())
+		pipeline := dslx.Compose4(
+			dslx.TCPConnect(rt),
+			dslx.HTTPConnectionTCP(rt),
+			dirportMaxBodySnapshotSize(snapshotsize),
+			dslx.HTTPRequest(rt, dslx.HTTPRequestOptionURLPath("/tor/status-vote/current/consensus.z")),
+		)
+		err = pipeline.Apply(ctx, endpoint).Error
 	case "or_port", "or_port_dirauth":
-		tlsConfig := measurex.NewEasyTLSConfig().InsecureSkipVerify(true)
-		return mx.EasyTLSConnectAndHandshake(ctx, kt.target.Address, tlsConfig)
+		// We do not verify the TLS certificate, because relays use self-signed
+		// certificates, and we instead verify the relay identity using the link protocol.
+		pipeline := dslx.Compose3(
+			dslx.TCPConnect(rt),
+			dslx.TLSHandshake(
+				rt,
+				dslx.TLSHandshakeOptionInsecureSkipVerify(true),
+				dslx.TLSHandshakeOptionNextProto(nil),
+			),
+			orportLinkHandshake(rt, orportTargetFingerprint(kt.target)),
+		)
+		result := pipeline.Apply(ctx, endpoint)
+		err = result.Error
+		if err == nil {
+			orport = result.State
+			failure = orport.Failure
+		}
 	case "obfs4":
-		const timeout = 15 * time.Second
-		return mx.EasyOBFS4ConnectAndHandshake(
-			ctx, timeout, kt.target.Address, rc.sess.TempDir(),
-			kt.target.Params)
+		var params *obfs4Params
+		params, err = newOBFS4Params(rc.sess.TempDir(), kt.target.Params)
+		if err != nil {
+			s := err.Error()
+			return rt.Observations(), nil, &s
+		}
+		pipeline := dslx.Compose2(dslx.TCPConnect(rt), obfs4Handshake(rt, params))
+		err = pipeline.Apply(ctx, endpoint).Error
 	default:
-		return mx.EasyTCPConnect(ctx, kt.target.Address)
+		err = dslx.TCPConnect(rt).Apply(ctx, endpoint).Error
+	}
+	if err != nil {
+		failure = measurexlite.NewFailure(err)
+	}
+	return rt.Observations(), orport, failure
+}
+
+// dirportMaxBodySnapshotSize returns a function limiting the size of the
+// response body snapshot that we read and archive for dir_port targets.
+func dirportMaxBodySnapshotSize(size int64) dslx.Func[*dslx.HTTPConnection, *dslx.HTTPConnection] {
+	return dslx.Operation[*dslx.HTTPConnection, *dslx.HTTPConnection](func(
+		ctx context.Context, input *dslx.HTTPConnection) (*dslx.HTTPConnection, error) {
+		input.MaxBodySnapshotSize = size
+		return input, nil
+	})
+}
+
+// orportLinkHandshake returns a function performing the link protocol
+// handshake (see [orportHandshake]) over an established TLS connection.
+//
+// The returned function does not fail when the link protocol handshake
+// fails, since we want to archive its results anyway. Rather, you should
+// check the Failure field of the returned [*ORPortHandshake].
+func orportLinkHandshake(rt dslx.Runtime, fingerprint string) dslx.Func[*dslx.TLSConnection, *ORPortHandshake] {
+	return dslx.Operation[*dslx.TLSConnection, *ORPortHandshake](func(
+		ctx context.Context, input *dslx.TLSConnection) (*ORPortHandshake, error) {
+		// start the operation logger
+		ol := logx.NewOperationLogger(
+			rt.Logger(),
+			"[#%d] Tor link handshake with %s",
+			input.Trace.Index(),
+			input.Address,
+		)
+
+		// perform the handshake
+		orport := orportHandshake(ctx, input.Conn, input.Trace.ZeroTime(), fingerprint, rt.Logger())
+
+		// stop the operation logger
+		ol.Stop(failureString(orport.Failure))
+
+		// save the I/O events that occurred during the handshake
+		rt.SaveObservations(&dslx.Observations{NetworkEvents: input.Trace.NetworkEvents()})
+
+		return orport, nil
+	})
+}
+
+// obfs4Params contains the params to perform an OBFS4 handshake.
+type obfs4Params struct {
+	Cert        string
+	DataDir     string
+	Fingerprint string
+	IATMode     string
+}
+
+// newOBFS4Params parses the raw params of an obfs4 target as
+// returned by the OONI API, which should contain exactly one
+// value for each of "cert", "fingerprint", and "iat-mode".
+func newOBFS4Params(dataDir string, rawParams map[string][]string) (*obfs4Params, error) {
+	out := &obfs4Params{DataDir: dataDir}
+	for key, values := range rawParams {
+		var field *string
+		switch key {
+		case "cert":
+			field = &out.Cert
+		case "fingerprint":
+			field = &out.Fingerprint
+		case "iat-mode":
+			field = &out.IATMode
+		default:
+			continue // not interested
+		}
+		if len(values) != 1 {
+			return nil, fmt.Errorf("obfs4: expected exactly one value for %s", key)
+		}
+		*field = values[0]
+	}
+	// Assume that the API knows what it's returning, so don't bother
+	// checking whether some fields are missing. If this happens, it
+	// will be the obfs4 library task to tell us about that.
+	return out, nil
+}
+
+// obfs4Handshake returns a function performing an OBFS4 handshake over
+// an established TCP connection, which is closed when done.
+func obfs4Handshake(rt dslx.Runtime, params *obfs4Params) dslx.Func[*dslx.TCPConnection, dslx.Void] {
+	return dslx.Operation[*dslx.TCPConnection, dslx.Void](func(
+		ctx context.Context, input *dslx.TCPConnection) (dslx.Void, error) {
+		// start the operation logger
+		ol := logx.NewOperationLogger(
+			rt.Logger(),
+			"[#%d] OBFS4Handshake with %s",
+			input.Trace.Index(),
+			input.Address,
+		)
+
+		// setup
+		const timeout = 15 * time.Second
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		// handshake
+		dialer := &ptx.OBFS4Dialer{
+			Address:          input.Address,
+			Cert:             params.Cert,
+			DataDir:          params.DataDir,
+			Fingerprint:      params.Fingerprint,
+			IATMode:          params.IATMode,
+			UnderlyingDialer: netxlite.NewSingleUseDialer(input.Conn),
+		}
+		conn, err := dialer.DialContext(ctx)
+		if err == nil {
+			conn.Close()
+		}
+
+		// stop the operation logger
+		ol.Stop(err)
+
+		// save the I/O events that occurred during the handshake
+		rt.SaveObservations(&dslx.Observations{NetworkEvents: input.Trace.NetworkEvents()})
+
+		return dslx.Void{}, err
+	})
+}
+
+// orportTargetFingerprint returns the relay fingerprint of a target, which
+// the API provides using the "fingerprint" param, or an empty string.
+func orportTargetFingerprint(target model.OOAPITorTarget) string {
+	if values := target.Params["fingerprint"]; len(values) > 0 {
+		return values[0]
 	}
+	return ""
 }
 
 // NewExperimentMeasurer creates a new ExperimentMeasurer.
@@ -13,11 +13,11 @@ import (
 
 	"github.com/apex/log"
 	"github.com/google/go-cmp/cmp"
-	"github.com/ooni/probe-cli/v3/internal/legacy/measurex"
 	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
 	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/x/dslx"
 )
 
 func TestNewExperimentMeasurer(t *testing.T) {
@@ -25,7 +25,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "tor" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.4.0" {
+	if measurer.ExperimentVersion() != "0.6.0" {
 		t.Fatal("unexpected version")
 	}
 }
@@ -250,8 +250,8 @@ func TestResultsCollectorMeasureSingleTargetGood(t *testing.T) {
 		new(model.Measurement),
 		model.NewPrinterCallbacks(log.Log),
 	)
-	rc.flexibleConnect = func(context.Context, keytarget) (*measurex.ArchivalMeasurement, *string) {
-		return &measurex.ArchivalMeasurement{}, nil
+	rc.flexibleConnect = func(context.Context, keytarget) (*dslx.Observations, *ORPortHandshake, *string) {
+		return dslx.NewObservations(), nil, nil
 	}
 	rc.measureSingleTarget(
 		context.Background(), wrapTestingTarget(staticTestingTargets[0]),
@@ -284,9 +284,9 @@ func TestResultsCollectorMeasureSingleTargetWithFailure(t *testing.T) {
 		new(model.Measurement),
 		model.NewPrinterCallbacks(log.Log),
 	)
-	rc.flexibleConnect = func(context.Context, keytarget) (*measurex.ArchivalMeasurement, *string) {
+	rc.flexibleConnect = func(context.Context, keytarget) (*dslx.Observations, *ORPortHandshake, *string) {
 		failure := "mocked error"
-		return &measurex.ArchivalMeasurement{}, &failure
+		return dslx.NewObservations(), nil, &failure
 	}
 	rc.measureSingleTarget(
 		context.Background(), keytarget{
@@ -324,7 +324,7 @@ func TestDefautFlexibleConnectDirPort(t *testing.T) {
 	)
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	tk, failure := rc.defaultFlexibleConnect(ctx, wrapTestingTarget(staticTestingTargets[1]))
+	tk, _, failure := rc.defaultFlexibleConnect(ctx, wrapTestingTarget(staticTestingTargets[1]))
 	if failure == nil {
 		t.Fatal("expected a failure here")
 	}
@@ -346,7 +346,7 @@ func TestDefautFlexibleConnectOrPort(t *testing.T) {
 	)
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	tk, failure := rc.defaultFlexibleConnect(ctx, wrapTestingTarget(staticTestingTargets[2]))
+	tk, _, failure := rc.defaultFlexibleConnect(ctx, wrapTestingTarget(staticTestingTargets[2]))
 	if failure == nil {
 		t.Fatal("expected a failure here")
 	}
@@ -356,8 +356,8 @@ func TestDefautFlexibleConnectOrPort(t *testing.T) {
 	if tk.TCPConnect == nil {
 		t.Fatal("expected connects data here")
 	}
-	if tk.NetworkEvents != nil {
-		t.Fatal("expected no network events data here")
+	if len(tk.NetworkEvents) != 1 || tk.NetworkEvents[0].Operation != netxlite.ConnectOperation {
+		t.Fatal("expected only the connect network event here")
 	}
 }
 
@@ -371,7 +371,7 @@ func TestDefautFlexibleConnectOBFS4(t *testing.T) {
 	)
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	tk, failure := rc.defaultFlexibleConnect(ctx, wrapTestingTarget(staticTestingTargets[0]))
+	tk, _, failure := rc.defaultFlexibleConnect(ctx, wrapTestingTarget(staticTestingTargets[0]))
 	if failure == nil {
 		t.Fatal("expected a failure here")
 	}
@@ -381,8 +381,8 @@ func TestDefautFlexibleConnectOBFS4(t *testing.T) {
 	if tk.TCPConnect == nil {
 		t.Fatal("expected connects data here")
 	}
-	if tk.NetworkEvents != nil {
-		t.Fatal("expected no network events data here")
+	if len(tk.NetworkEvents) != 1 || tk.NetworkEvents[0].Operation != netxlite.ConnectOperation {
+		t.Fatal("expected only the connect network event here")
 	}
 }
 
@@ -396,7 +396,7 @@ func TestDefautFlexibleConnectDefault(t *testing.T) {
 	)
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	tk, failure := rc.defaultFlexibleConnect(ctx, wrapTestingTarget(staticTestingTargets[3]))
+	tk, _, failure := rc.defaultFlexibleConnect(ctx, wrapTestingTarget(staticTestingTargets[3]))
 	if failure == nil {
 		t.Fatal("expected a failure here")
 	}
@@ -430,8 +430,8 @@ func TestSummary(t *testing.T) {
 	t.Run("with a TCP connect and nothing else", func(t *testing.T) {
 		tr := new(TargetResults)
 		failure := "mocked_error"
-		tr.TCPConnect = append(tr.TCPConnect, &measurex.ArchivalTCPConnect{
-			Status: &measurex.ArchivalTCPConnectStatus{
+		tr.TCPConnect = append(tr.TCPConnect, &model.ArchivalTCPConnectResult{
+			Status: model.ArchivalTCPConnectStatus{
 				Success: true,
 				Failure: &failure,
 			},
@@ -447,8 +447,8 @@ func TestSummary(t *testing.T) {
 
 	t.Run("for OBFS4", func(t *testing.T) {
 		tr := new(TargetResults)
-		tr.TCPConnect = append(tr.TCPConnect, &measurex.ArchivalTCPConnect{
-			Status: &measurex.ArchivalTCPConnectStatus{
+		tr.TCPConnect = append(tr.TCPConnect, &model.ArchivalTCPConnectResult{
+			Status: model.ArchivalTCPConnectStatus{
 				Success: true,
 			},
 		})
@@ -468,10 +468,10 @@ func TestSummary(t *testing.T) {
 	})
 
 	t.Run("for or_port/or_port_dirauth", func(t *testing.T) {
-		doit := func(targetProtocol string, handshake *measurex.ArchivalQUICTLSHandshakeEvent) {
+		doit := func(targetProtocol string, handshake *model.ArchivalTLSOrQUICHandshakeResult) {
 			tr := new(TargetResults)
-			tr.TCPConnect = append(tr.TCPConnect, &measurex.ArchivalTCPConnect{
-				Status: &measurex.ArchivalTCPConnectStatus{
+			tr.TCPConnect = append(tr.TCPConnect, &model.ArchivalTCPConnectResult{
+				Status: model.ArchivalTCPConnectStatus{
 					Success: true,
 				},
 			})
@@ -501,7 +501,7 @@ func TestSummary(t *testing.T) {
 		}
 		doit("or_port_dirauth", nil)
 		doit("or_port", nil)
-		doit("or_port", &measurex.ArchivalQUICTLSHandshakeEvent{
+		doit("or_port", &model.ArchivalTLSOrQUICHandshakeResult{
 			Failure: (func() *string {
 				s := io.EOF.Error()
 				return &s
@@ -779,10 +779,10 @@ func TestSummaryKeysWorksAsIntended(t *testing.T) {
 func TestTargetResultsFillSummaryDirPort(t *testing.T) {
 	tr := &TargetResults{
 		TargetProtocol: "dir_port",
-		TCPConnect: []*measurex.ArchivalTCPConnect{{
+		TCPConnect: []*model.ArchivalTCPConnectResult{{
 			IP:   "1.2.3.4",
 			Port: 443,
-			Status: &measurex.ArchivalTCPConnectStatus{
+			Status: model.ArchivalTCPConnectStatus{
 				Failure: nil,
 			},
 		}},
@@ -0,0 +1,294 @@
+// Package torwebtunnel contains the torwebtunnel experiment, which
+// measures whether we can bootstrap tor using a WebTunnel bridge.
+package torwebtunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/legacy/tracex"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/ptx"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/torlogs"
+	"github.com/ooni/probe-cli/v3/internal/tunnel"
+)
+
+// Implementation note: this file is written with easy diffing with respect
+// to internal/experiment/torsf/torsf.go in mind.
+
+// testVersion is the experiment version.
+const testVersion = "0.1.0"
+
+// Config contains the experiment config.
+type Config struct {
+	// Bridge is the MANDATORY WebTunnel bridge line.
+	Bridge string `ooni:"WebTunnel bridge line (e.g., 'webtunnel ADDRESS FINGERPRINT url=URL')"`
+
+	// DisablePersistentDatadir disables using a persistent datadir.
+	DisablePersistentDatadir bool `ooni:"Disable using a persistent tor datadir"`
+
+	// DisableProgress disables printing progress messages.
+	DisableProgress bool `ooni:"Disable printing progress messages"`
+}
+
+// ErrMissingBridge indicates that the user did not configure a bridge.
+var ErrMissingBridge = errors.New("torwebtunnel: missing bridge")
+
+// TestKeys contains the experiment's result. Note that we do not include
+// the bridge line, because the URL contains a secret path.
+type TestKeys struct {
+	// BootstrapTime contains the bootstrap time on success.
+	BootstrapTime float64 `json:"bootstrap_time"`
+
+	// Failure contains the failure string or nil.
+	Failure *string `json:"failure"`
+
+	// Success indicates whether we succeded.
+	Success bool `json:"success"`
+
+	// PersistentDatadir indicates whether we're using a persistent tor datadir.
+	PersistentDatadir bool `json:"persistent_datadir"`
+
+	// Timeout contains the default timeout for this experiment
+	Timeout float64 `json:"timeout"`
+
+	// TorLogs contains the bootstrap logs.
+	TorLogs []string `json:"tor_logs"`
+
+	// TorProgress contains the percentage of the maximum progress reached.
+	TorProgress int64 `json:"tor_progress"`
+
+	// TorProgressTag contains the tag of the maximum progress reached.
+	TorProgressTag string `json:"tor_progress_tag"`
+
+	// TorProgressSummary contains the summary of the maximum progress reached.
+	TorProgressSummary string `json:"tor_progress_summary"`
+
+	// TorVersion contains the version of tor (if it's possible to obtain it).
+	TorVersion string `json:"tor_version"`
+
+	// TransportName is always set to "webtunnel" for this experiment.
+	TransportName string `json:"transport_name"`
+
+	// cannotFindTorBinary indicates that we could not find the tor binary.
+	cannotFindTorBinary bool
+}
+
+// Measurer performs the measurement.
+type Measurer struct {
+	// config contains the experiment settings.
+	config Config
+
+	// mockStartListener is an optional function that allows us to override
+	// the function we actually use to start the ptx listener.
+	mockStartListener func() error
+
+	// mockStartTunnel is an optional function that allows us to override the
+	// default tunnel.Start function used to start a tunnel.
+	mockStartTunnel func(
+		ctx context.Context, config *tunnel.Config) (tunnel.Tunnel, tunnel.DebugInfo, error)
+}
+
+// ExperimentName implements model.ExperimentMeasurer.ExperimentName.
+func (m *Measurer) ExperimentName() string {
+	return "torwebtunnel"
+}
+
+// ExperimentVersion implements model.ExperimentMeasurer.ExperimentVersion.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+// maxRuntime is the maximum runtime for this experiment
+const maxRuntime = 600 * time.Second
+
+// Run runs the experiment with the specified context, session,
+// measurement, and experiment calbacks. This method should only
+// return an error in case the experiment could not run (e.g.,
+// a required input is missing). Otherwise, the code should just
+// set the relevant OONI error inside of the measurement and
+// return nil. This is important because the caller may not submit
+// the measurement if this method returns an error.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	callbacks := args.Callbacks
+	measurement := args.Measurement
+	sess := args.Session
+	ptl, wtdialer, err := m.setup(ctx, sess.Logger())
+	if err != nil {
+		// we cannot setup the experiment
+		return err
+	}
+	defer ptl.Stop()
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, maxRuntime)
+	defer cancel()
+	tkch := make(chan *TestKeys)
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	go m.bootstrap(ctx, maxRuntime, sess, tkch, ptl, wtdialer)
+	for {
+		select {
+		case tk := <-tkch:
+			measurement.TestKeys = tk
+			callbacks.OnProgress(1.0, "torwebtunnel experiment is finished")
+			if tk.cannotFindTorBinary {
+				return tunnel.ErrCannotFindTorBinary
+			}
+			return nil
+		case <-ticker.C:
+			if !m.config.DisableProgress {
+				elapsedTime := time.Since(start)
+				progress := elapsedTime.Seconds() / maxRuntime.Seconds()
+				callbacks.OnProgress(progress, fmt.Sprintf(
+					"torwebtunnel: elapsedTime: %.0f s; maxRuntime: %.0f s",
+					elapsedTime.Seconds(), maxRuntime.Seconds()))
+			}
+		}
+	}
+}
+
+// setup prepares for running the torwebtunnel experiment. Returns a valid ptx listener
+// and webtunnel dialer on success. Returns an error on failure. On success,
+// remember to Stop the ptx listener when you're done.
+func (m *Measurer) setup(ctx context.Context,
+	logger model.Logger) (*ptx.Listener, *ptx.WebTunnelDialer, error) {
+	if m.config.Bridge == "" {
+		return nil, nil, ErrMissingBridge
+	}
+	wtdialer, err := ptx.ParseWebTunnelBridgeLine(m.config.Bridge)
+	if err != nil {
+		// cannot run the experiment with an invalid bridge
+		return nil, nil, err
+	}
+	ptl := &ptx.Listener{
+		ExperimentByteCounter: bytecounter.ContextExperimentByteCounter(ctx),
+		Logger:                logger,
+		PTDialer:              wtdialer,
+		SessionByteCounter:    bytecounter.ContextSessionByteCounter(ctx),
+	}
+	if err := m.startListener(ptl.Start); err != nil {
+		// This error condition mostly means "I could not open a local
+		// listening port", which strikes as fundamental failure.
+		return nil, nil, err
+	}
+	return ptl, wtdialer, nil
+}
+
+// bootstrap runs the bootstrap.
+func (m *Measurer) bootstrap(ctx context.Context, timeout time.Duration, sess model.ExperimentSession,
+	out chan<- *TestKeys, ptl *ptx.Listener, wtdialer *ptx.WebTunnelDialer) {
+	tk := &TestKeys{
+		// initialized later
+		BootstrapTime:      0,
+		Failure:            nil,
+		Success:            false,
+		TorLogs:            []string{},
+		TorProgress:        0,
+		TorProgressTag:     "",
+		TorProgressSummary: "",
+		TorVersion:         "",
+		// initialized now
+		PersistentDatadir: !m.config.DisablePersistentDatadir,
+		Timeout:           timeout.Seconds(),
+		TransportName:     wtdialer.Name(),
+	}
+	sess.Logger().Infof(
+		"torwebtunnel: disable persistent datadir: %+v", m.config.DisablePersistentDatadir)
+	defer func() {
+		out <- tk
+	}()
+	tun, debugInfo, err := m.startTunnel()(ctx, &tunnel.Config{
+		Name:      "tor",
+		Session:   sess,
+		TunnelDir: path.Join(m.baseTunnelDir(sess), "torwebtunnel"),
+		Logger:    sess.Logger(),
+		TorArgs: []string{
+			"UseBridges", "1",
+			"ClientTransportPlugin", ptl.AsClientTransportPluginArgument(),
+			"Bridge", wtdialer.AsBridgeArgument(),
+		},
+		TorBinary: sess.TorBinary(),
+	})
+	tk.cannotFindTorBinary = errors.Is(err, tunnel.ErrCannotFindTorBinary)
+	tk.TorVersion = debugInfo.Version
+	m.readTorLogs(sess.Logger(), tk, debugInfo.LogFilePath)
+	if err != nil {
+		// Note: tracex.NewFailure scrubs IP addresses
+		tk.Failure = tracex.NewFailure(err)
+		tk.Success = false
+		return
+	}
+	defer tun.Stop()
+	tk.BootstrapTime = tun.BootstrapTime().Seconds()
+	tk.Success = true
+}
+
+// readTorLogs attempts to read and include the tor logs into
+// the test keys if this operation is possible.
+func (m *Measurer) readTorLogs(logger model.Logger, tk *TestKeys, logFilePath string) {
+	tk.TorLogs = append(tk.TorLogs, torlogs.ReadBootstrapLogsOrWarn(logger, logFilePath)...)
+	if len(tk.TorLogs) <= 0 {
+		return
+	}
+	last := tk.TorLogs[len(tk.TorLogs)-1]
+	bi, err := torlogs.ParseBootstrapLogLine(last)
+	// Implementation note: parsing cannot fail here because we're using the same code
+	// for selecting and for parsing the bootstrap logs, so we panic on error.
+	runtimex.PanicOnError(err, fmt.Sprintf("cannot parse bootstrap line: %s", last))
+	tk.TorProgress = bi.Progress
+	tk.TorProgressTag = bi.Tag
+	tk.TorProgressSummary = bi.Summary
+}
+
+// baseTunnelDir returns the base directory to use for tunnelling
+func (m *Measurer) baseTunnelDir(sess model.ExperimentSession) string {
+	if m.config.DisablePersistentDatadir {
+		return sess.TempDir()
+	}
+	return sess.TunnelDir()
+}
+
+// startListener either calls f or mockStartListener depending
+// on whether mockStartListener is nil or not.
+func (m *Measurer) startListener(f func() error) error {
+	if m.mockStartListener != nil {
+		return m.mockStartListener()
+	}
+	return f()
+}
+
+// startTunnel returns the proper function to start a tunnel.
+func (m *Measurer) startTunnel() func(
+	ctx context.Context, config *tunnel.Config) (tunnel.Tunnel, tunnel.DebugInfo, error) {
+	if m.mockStartTunnel != nil {
+		return m.mockStartTunnel
+	}
+	return tunnel.Start
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer(config Config) model.ExperimentMeasurer {
+	return &Measurer{config: config}
+}
+
+var _ model.MeasurementSummaryKeysProvider = &TestKeys{}
+
+// SummaryKeys contains summary keys for this experiment.
+type SummaryKeys struct {
+	IsAnomaly bool `json:"-"`
+}
+
+// MeasurementSummaryKeys implements model.MeasurementSummaryKeysProvider.
+func (tk *TestKeys) MeasurementSummaryKeys() model.MeasurementSummaryKeys {
+	return &SummaryKeys{IsAnomaly: tk.Failure != nil}
+}
+
+// Anomaly implements model.MeasurementSummaryKeys.
+func (sk *SummaryKeys) Anomaly() bool {
+	return sk.IsAnomaly
+}
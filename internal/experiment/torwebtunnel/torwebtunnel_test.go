@@ -0,0 +1,271 @@
+package torwebtunnel
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/ptx"
+	"github.com/ooni/probe-cli/v3/internal/tunnel"
+	"github.com/ooni/probe-cli/v3/internal/tunnel/mocks"
+)
+
+// testBridge is the bridge line we use for testing.
+const testBridge = "webtunnel [2001:db8::1]:443 0123456789ABCDEF0123456789ABCDEF01234567 url=https://example.com/secret"
+
+// newArgs returns the arguments for running the experiment.
+func newArgs(sess model.ExperimentSession, measurement *model.Measurement) *model.ExperimentArgs {
+	return &model.ExperimentArgs{
+		Callbacks: &model.PrinterCallbacks{
+			Logger: model.DiscardLogger,
+		},
+		Measurement: measurement,
+		Session:     sess,
+	}
+}
+
+func TestExperimentNameAndVersion(t *testing.T) {
+	m := NewExperimentMeasurer(Config{})
+	if m.ExperimentName() != "torwebtunnel" {
+		t.Fatal("invalid experiment name")
+	}
+	if m.ExperimentVersion() != "0.1.0" {
+		t.Fatal("invalid experiment version")
+	}
+}
+
+func TestFailureWithInvalidBridge(t *testing.T) {
+	type testcase struct {
+		bridge string
+		expect error
+	}
+
+	testcases := []testcase{{
+		bridge: "",
+		expect: ErrMissingBridge,
+	}, {
+		bridge: "webtunnel antani",
+		expect: ptx.ErrWebTunnelInvalidBridgeLine,
+	}, {
+		bridge: "webtunnel [2001:db8::1]:443 FP url=http://example.com/secret",
+		expect: ptx.ErrWebTunnelInvalidURL,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.bridge, func(t *testing.T) {
+			m := &Measurer{config: Config{Bridge: tc.bridge}}
+			measurement := &model.Measurement{}
+			sess := &mockable.Session{
+				MockableLogger: model.DiscardLogger,
+			}
+			err := m.Run(context.Background(), newArgs(sess, measurement))
+			if !errors.Is(err, tc.expect) {
+				t.Fatal("unexpected error", err)
+			}
+			if measurement.TestKeys != nil {
+				t.Fatal("expected nil test keys")
+			}
+		})
+	}
+}
+
+func TestFailureToStartPTXListener(t *testing.T) {
+	expected := errors.New("mocked error")
+	m := &Measurer{
+		config: Config{Bridge: testBridge},
+		mockStartListener: func() error {
+			return expected
+		},
+	}
+	measurement := &model.Measurement{}
+	sess := &mockable.Session{}
+	if err := m.Run(context.Background(), newArgs(sess, measurement)); !errors.Is(err, expected) {
+		t.Fatal("not the error we expected", err)
+	}
+	if tk := measurement.TestKeys; tk != nil {
+		t.Fatal("expected nil test keys here")
+	}
+}
+
+func TestSuccessWithMockedTunnelStart(t *testing.T) {
+	bootstrapTime := 3 * time.Second
+	called := &atomic.Int64{}
+	m := &Measurer{
+		config: Config{Bridge: testBridge},
+		mockStartTunnel: func(
+			ctx context.Context, config *tunnel.Config) (tunnel.Tunnel, tunnel.DebugInfo, error) {
+			if !strings.Contains(strings.Join(config.TorArgs, " "), "Bridge "+testBridge) {
+				t.Error("unexpected tor args", config.TorArgs)
+			}
+			// run for some time so we also exercise printing progress.
+			time.Sleep(bootstrapTime)
+			return &mocks.Tunnel{
+				MockBootstrapTime: func() time.Duration {
+					return bootstrapTime
+				},
+				MockStop: func() {
+					called.Add(1)
+				},
+			}, tunnel.DebugInfo{
+				Name:        "tor",
+				LogFilePath: filepath.Join("testdata", "tor.log"),
+			}, nil
+		},
+	}
+	measurement := &model.Measurement{}
+	sess := &mockable.Session{
+		MockableLogger: model.DiscardLogger,
+	}
+	if err := m.Run(context.Background(), newArgs(sess, measurement)); err != nil {
+		t.Fatal(err)
+	}
+	if called.Load() != 1 {
+		t.Fatal("stop was not called")
+	}
+	tk := measurement.TestKeys.(*TestKeys)
+	if tk.BootstrapTime != bootstrapTime.Seconds() {
+		t.Fatal("unexpected bootstrap time")
+	}
+	if tk.Failure != nil {
+		t.Fatal("unexpected failure")
+	}
+	if !tk.PersistentDatadir {
+		t.Fatal("unexpected persistent data dir")
+	}
+	if !tk.Success {
+		t.Fatal("unexpected success value")
+	}
+	if tk.Timeout != maxRuntime.Seconds() {
+		t.Fatal("unexpected timeout")
+	}
+	if count := len(tk.TorLogs); count != 9 {
+		t.Fatal("unexpected length of tor logs", count)
+	}
+	if tk.TorProgress != 100 {
+		t.Fatal("unexpected tor progress")
+	}
+	if tk.TorProgressTag != "done" {
+		t.Fatal("unexpected tor progress tag")
+	}
+	if tk.TransportName != "webtunnel" {
+		t.Fatal("invalid transport name")
+	}
+}
+
+func TestWithCancelledContext(t *testing.T) {
+	// This test calls the real tunnel.Start function so we cover
+	// it but fails immediately because of the cancelled ctx.
+	m := &Measurer{
+		config: Config{Bridge: testBridge},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // fail immediately
+	measurement := &model.Measurement{}
+	sess := &mockable.Session{
+		MockableLogger: model.DiscardLogger,
+	}
+	if err := m.Run(ctx, newArgs(sess, measurement)); err != nil {
+		t.Fatal(err)
+	}
+	tk := measurement.TestKeys.(*TestKeys)
+	if tk.Failure == nil || *tk.Failure != "interrupted" {
+		t.Fatal("unexpected failure")
+	}
+	if tk.Success {
+		t.Fatal("unexpected success value")
+	}
+	if len(tk.TorLogs) != 0 {
+		t.Fatal("unexpected length of tor logs")
+	}
+	if tk.TransportName != "webtunnel" {
+		t.Fatal("invalid transport name")
+	}
+}
+
+func TestFailureToStartTunnel(t *testing.T) {
+	expected := context.DeadlineExceeded // error occurring on bootstrap timeout
+	m := &Measurer{
+		config: Config{Bridge: testBridge},
+		mockStartTunnel: func(
+			ctx context.Context, config *tunnel.Config) (tunnel.Tunnel, tunnel.DebugInfo, error) {
+			return nil,
+				tunnel.DebugInfo{
+					Name:        "tor",
+					LogFilePath: filepath.Join("testdata", "partial.log"),
+				}, expected
+		},
+	}
+	measurement := &model.Measurement{}
+	sess := &mockable.Session{
+		MockableLogger: model.DiscardLogger,
+	}
+	if err := m.Run(context.Background(), newArgs(sess, measurement)); err != nil {
+		t.Fatal(err)
+	}
+	tk := measurement.TestKeys.(*TestKeys)
+	if tk.Failure == nil || *tk.Failure != "generic_timeout_error" {
+		t.Fatal("unexpected failure", tk.Failure)
+	}
+	if tk.Success {
+		t.Fatal("unexpected success value")
+	}
+	if tk.TorProgress != 15 {
+		t.Fatal("unexpected tor progress")
+	}
+	if tk.TorProgressTag != "handshake_done" {
+		t.Fatal("unexpected tor progress tag")
+	}
+}
+
+func TestFailureNoTorBinary(t *testing.T) {
+	expected := tunnel.ErrCannotFindTorBinary
+	m := &Measurer{
+		config: Config{Bridge: testBridge},
+		mockStartTunnel: func(
+			ctx context.Context, config *tunnel.Config) (tunnel.Tunnel, tunnel.DebugInfo, error) {
+			return nil, tunnel.DebugInfo{Name: "tor"}, expected
+		},
+	}
+	measurement := &model.Measurement{}
+	sess := &mockable.Session{
+		MockableLogger: model.DiscardLogger,
+	}
+	if err := m.Run(context.Background(), newArgs(sess, measurement)); !errors.Is(err, expected) {
+		t.Fatal(err)
+	}
+	tk := measurement.TestKeys.(*TestKeys)
+	if !tk.cannotFindTorBinary {
+		t.Fatal("unexpected cannotFindTorBinary values")
+	}
+}
+
+func TestBaseTunnelDir(t *testing.T) {
+	sess := &mockable.Session{
+		MockableTunnelDir: "a",
+		MockableTempDir:   "b",
+	}
+	m := &Measurer{config: Config{DisablePersistentDatadir: true}}
+	if dir := m.baseTunnelDir(sess); dir != "b" {
+		t.Fatal("unexpected base tunnel dir", dir)
+	}
+	m = &Measurer{config: Config{DisablePersistentDatadir: false}}
+	if dir := m.baseTunnelDir(sess); dir != "a" {
+		t.Fatal("unexpected base tunnel dir", dir)
+	}
+}
+
+func TestMeasurementSummaryKeys(t *testing.T) {
+	failure := "generic_timeout_error"
+	for _, tk := range []*TestKeys{{Failure: nil}, {Failure: &failure}} {
+		sk := tk.MeasurementSummaryKeys()
+		if sk.Anomaly() != (tk.Failure != nil) {
+			t.Fatal("invalid Anomaly()")
+		}
+	}
+}
@@ -76,3 +76,13 @@ const (
 	// different addresses from the probe
 	AnalysisDNSFlagUnexpectedAddrs
 )
+
+const (
+	// AnalysisFamilyFlagIPv4SelectiveInterference indicates that we observed unexpected
+	// failures only when using IPv4, while we could successfully use IPv6.
+	AnalysisFamilyFlagIPv4SelectiveInterference = 1 << iota
+
+	// AnalysisFamilyFlagIPv6SelectiveInterference indicates that we observed unexpected
+	// failures only when using IPv6, while we could successfully use IPv4.
+	AnalysisFamilyFlagIPv6SelectiveInterference
+)
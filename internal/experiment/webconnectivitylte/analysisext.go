@@ -45,6 +45,10 @@ func analysisExtMain(
 	// HTTP success analysis (i.e., only if we manage to get an HTTP response)
 	analysisExtHTTPFinalResponse(tk, analysis, &info)
 
+	// split the unexpected failures by address family to detect interference
+	// affecting either IPv4 or IPv6 but not both of them
+	analysisExtAddressFamily(tk, analysis, &info)
+
 	// handle the cases where the probe and the TH both failed, which we can confidently
 	// only evaluate for DNS, TCP, and TLS during the 0-th redirect.
 	analysisExtExpectedFailures(tk, analysis, &info)
@@ -118,6 +122,57 @@ func analysisExtEndpointFailure(tk *TestKeys, analysis *minipipeline.WebAnalysis
 	}
 }
 
+func analysisExtAddressFamily(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
+	tk.BlockingFlagsIPv4 = analysisExtAddressFamilyBlockingFlags(
+		analysis.DNSLookupUnexpectedFailureIPv4,
+		analysis.TCPConnectUnexpectedFailureIPv4,
+		analysis.TLSHandshakeUnexpectedFailureIPv4,
+		analysis.HTTPRoundTripUnexpectedFailureIPv4,
+	)
+	tk.BlockingFlagsIPv6 = analysisExtAddressFamilyBlockingFlags(
+		analysis.DNSLookupUnexpectedFailureIPv6,
+		analysis.TCPConnectUnexpectedFailureIPv6,
+		analysis.TLSHandshakeUnexpectedFailureIPv6,
+		analysis.HTTPRoundTripUnexpectedFailureIPv6,
+	)
+
+	// we need evidence that the other address family works because otherwise we
+	// cannot distinguish between selective interference and, e.g., a website
+	// that is only reachable using one address family
+	ipv4Works := analysis.DNSLookupSuccessIPv4.Len() > 0 ||
+		analysis.TCPConnectSuccessIPv4.Len() > 0 || analysis.TLSHandshakeSuccessIPv4.Len() > 0
+	ipv6Works := analysis.DNSLookupSuccessIPv6.Len() > 0 ||
+		analysis.TCPConnectSuccessIPv6.Len() > 0 || analysis.TLSHandshakeSuccessIPv6.Len() > 0
+
+	switch {
+	case tk.BlockingFlagsIPv4 != 0 && tk.BlockingFlagsIPv6 == 0 && ipv6Works:
+		tk.FamilyFlags |= AnalysisFamilyFlagIPv4SelectiveInterference
+		fmt.Fprintf(info, "- unexpected failures only affect IPv4 (flags: %d)\n", tk.BlockingFlagsIPv4)
+
+	case tk.BlockingFlagsIPv6 != 0 && tk.BlockingFlagsIPv4 == 0 && ipv4Works:
+		tk.FamilyFlags |= AnalysisFamilyFlagIPv6SelectiveInterference
+		fmt.Fprintf(info, "- unexpected failures only affect IPv6 (flags: %d)\n", tk.BlockingFlagsIPv6)
+	}
+}
+
+// analysisExtAddressFamilyBlockingFlags maps the unexpected failures observed
+// for a given address family to the corresponding blocking flags.
+func analysisExtAddressFamilyBlockingFlags(dns, tcp, tls, http minipipeline.Set[int64]) (flags int64) {
+	if dns.Len() > 0 {
+		flags |= AnalysisBlockingFlagDNSBlocking
+	}
+	if tcp.Len() > 0 {
+		flags |= AnalysisBlockingFlagTCPIPBlocking
+	}
+	if tls.Len() > 0 {
+		flags |= AnalysisBlockingFlagTLSBlocking
+	}
+	if http.Len() > 0 {
+		flags |= AnalysisBlockingFlagHTTPBlocking
+	}
+	return
+}
+
 func analysisExtHTTPFinalResponse(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
 	// case #1: HTTP final response without control
 	//
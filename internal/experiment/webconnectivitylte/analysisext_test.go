@@ -0,0 +1,108 @@
+package webconnectivitylte
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
+	"github.com/ooni/probe-cli/v3/internal/minipipeline"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/must"
+)
+
+func TestAnalysisExtAddressFamily(t *testing.T) {
+	type testcase struct {
+		name              string
+		analysis          *minipipeline.WebAnalysis
+		expectIPv4Flags   int64
+		expectIPv6Flags   int64
+		expectFamilyFlags int64
+	}
+
+	testcases := []testcase{{
+		name:     "with no failures",
+		analysis: &minipipeline.WebAnalysis{},
+	}, {
+		name: "with IPv6 endpoints blocked and working IPv4 endpoints",
+		analysis: &minipipeline.WebAnalysis{
+			TCPConnectSuccessIPv4:           minipipeline.NewSet[int64](1),
+			TCPConnectUnexpectedFailureIPv6: minipipeline.NewSet[int64](2),
+		},
+		expectIPv6Flags:   AnalysisBlockingFlagTCPIPBlocking,
+		expectFamilyFlags: AnalysisFamilyFlagIPv6SelectiveInterference,
+	}, {
+		name: "with AAAA lookups and IPv6 TLS handshakes blocked and working A lookups",
+		analysis: &minipipeline.WebAnalysis{
+			DNSLookupSuccessIPv4:              minipipeline.NewSet[int64](1),
+			DNSLookupUnexpectedFailureIPv6:    minipipeline.NewSet[int64](1),
+			TLSHandshakeUnexpectedFailureIPv6: minipipeline.NewSet[int64](3),
+		},
+		expectIPv6Flags:   AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagTLSBlocking,
+		expectFamilyFlags: AnalysisFamilyFlagIPv6SelectiveInterference,
+	}, {
+		name: "with IPv4 HTTP failures and working IPv6 endpoints",
+		analysis: &minipipeline.WebAnalysis{
+			TLSHandshakeSuccessIPv6:            minipipeline.NewSet[int64](1),
+			HTTPRoundTripUnexpectedFailureIPv4: minipipeline.NewSet[int64](2),
+		},
+		expectIPv4Flags:   AnalysisBlockingFlagHTTPBlocking,
+		expectFamilyFlags: AnalysisFamilyFlagIPv4SelectiveInterference,
+	}, {
+		name: "with IPv6 failures and no evidence that IPv4 works",
+		analysis: &minipipeline.WebAnalysis{
+			TCPConnectUnexpectedFailureIPv6: minipipeline.NewSet[int64](2),
+		},
+		expectIPv6Flags: AnalysisBlockingFlagTCPIPBlocking,
+	}, {
+		name: "with failures affecting both address families",
+		analysis: &minipipeline.WebAnalysis{
+			TCPConnectSuccessIPv4:           minipipeline.NewSet[int64](1),
+			TCPConnectSuccessIPv6:           minipipeline.NewSet[int64](2),
+			TCPConnectUnexpectedFailureIPv4: minipipeline.NewSet[int64](3),
+			TCPConnectUnexpectedFailureIPv6: minipipeline.NewSet[int64](4),
+		},
+		expectIPv4Flags: AnalysisBlockingFlagTCPIPBlocking,
+		expectIPv6Flags: AnalysisBlockingFlagTCPIPBlocking,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tk := &TestKeys{}
+			analysisExtAddressFamily(tk, tc.analysis, io.Discard)
+			if tk.BlockingFlagsIPv4 != tc.expectIPv4Flags {
+				t.Fatal("expected", tc.expectIPv4Flags, "got", tk.BlockingFlagsIPv4)
+			}
+			if tk.BlockingFlagsIPv6 != tc.expectIPv6Flags {
+				t.Fatal("expected", tc.expectIPv6Flags, "got", tk.BlockingFlagsIPv6)
+			}
+			if tk.FamilyFlags != tc.expectFamilyFlags {
+				t.Fatal("expected", tc.expectFamilyFlags, "got", tk.FamilyFlags)
+			}
+		})
+	}
+}
+
+// We cannot emulate blocking IPv6 endpoints using netem, which only supports IPv4, so we
+// use a minipipeline test case where connecting to the IPv6 endpoint times out.
+func TestAnalysisExtAddressFamilyWithBlockedIPv6Endpoints(t *testing.T) {
+	measurementFile := filepath.Join(
+		"..", "..", "minipipeline", "testdata", "webconnectivity",
+		"manual", "blockedipv6endpoints", "measurement.json",
+	)
+	var measurement struct {
+		TestKeys *TestKeys `json:"test_keys"`
+	}
+	must.UnmarshalJSON(must.ReadFile(measurementFile), &measurement)
+	tk := measurement.TestKeys
+	tk.analysisClassic(model.GeoIPASNLookupperFunc(geoipx.LookupASN), model.DiscardLogger)
+	if tk.BlockingFlagsIPv4 != 0 {
+		t.Fatal("expected", 0, "got", tk.BlockingFlagsIPv4)
+	}
+	if tk.BlockingFlagsIPv6 != AnalysisBlockingFlagTCPIPBlocking {
+		t.Fatal("expected", AnalysisBlockingFlagTCPIPBlocking, "got", tk.BlockingFlagsIPv6)
+	}
+	if tk.FamilyFlags != AnalysisFamilyFlagIPv6SelectiveInterference {
+		t.Fatal("expected", AnalysisFamilyFlagIPv6SelectiveInterference, "got", tk.FamilyFlags)
+	}
+}
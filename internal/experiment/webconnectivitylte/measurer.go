@@ -47,7 +47,7 @@ func (m *Measurer) ExperimentName() string {
 
 // ExperimentVersion implements model.ExperimentMeasurer.
 func (m *Measurer) ExperimentVersion() string {
-	return "0.5.28"
+	return "0.5.29"
 }
 
 // Run implements model.ExperimentMeasurer.
@@ -92,6 +92,17 @@ type TestKeys struct {
 	// BlockingFlags explains why we think that the website is blocked.
 	BlockingFlags int64 `json:"x_blocking_flags"`
 
+	// BlockingFlagsIPv4 is like BlockingFlags but only considers unexpected failures
+	// of A lookups and of operations using IPv4 endpoints.
+	BlockingFlagsIPv4 int64 `json:"x_blocking_flags_ipv4"`
+
+	// BlockingFlagsIPv6 is like BlockingFlags but only considers unexpected failures
+	// of AAAA lookups and of operations using IPv6 endpoints.
+	BlockingFlagsIPv6 int64 `json:"x_blocking_flags_ipv6"`
+
+	// FamilyFlags describes interference affecting only one address family.
+	FamilyFlags int64 `json:"x_family_flags"`
+
 	// NullNullFlags describes what the algorithm to avoid emitting
 	// blocking = null, accessible = null measurements did
 	NullNullFlags int64 `json:"x_null_null_flags"`
@@ -349,6 +360,9 @@ func NewTestKeys() *TestKeys {
 		DNSConsistency:        optional.None[string](),
 		HTTPExperimentFailure: optional.None[string](),
 		BlockingFlags:         0,
+		BlockingFlagsIPv4:     0,
+		BlockingFlagsIPv6:     0,
+		FamilyFlags:           0,
 		NullNullFlags:         0,
 		BodyProportion:        0,
 		BodyLengthMatch:       optional.None[bool](),
@@ -3,6 +3,7 @@ package kvstore
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -16,7 +17,7 @@ type FS struct {
 	basedir string
 }
 
-var _ model.KeyValueStore = &FS{}
+var _ model.KeyValueStoreUpdater = &FS{}
 
 // NewFS creates a new kvstore.FileSystem.
 func NewFS(basedir string) (kvs *FS, err error) {
@@ -54,3 +55,53 @@ func (kvs *FS) Get(key string) ([]byte, error) {
 func (kvs *FS) Set(key string, value []byte) error {
 	return lockedfile.Write(kvs.filename(key), bytes.NewReader(value), 0600)
 }
+
+// Update atomically updates the value of a specific key. We hold an exclusive
+// lock on the underlying file while calling fn, so concurrent updates performed by
+// other processes using this kvstore are serialized.
+func (kvs *FS) Update(key string, fn func(value []byte) ([]byte, error)) error {
+	for {
+		done, err := kvs.maybeUpdate(key, fn)
+		if done {
+			return err
+		}
+	}
+}
+
+// maybeUpdate is the workhorse of Update. It returns false when the file we have
+// locked has been removed in the meanwhile and the caller should retry.
+func (kvs *FS) maybeUpdate(key string, fn func(value []byte) ([]byte, error)) (bool, error) {
+	filename := kvs.filename(key)
+	filep, err := lockedfile.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return true, err
+	}
+	defer filep.Close()
+	fileinfo, err := filep.Stat()
+	if err != nil {
+		return true, err
+	}
+	if currentinfo, err := os.Stat(filename); err != nil || !os.SameFile(fileinfo, currentinfo) {
+		return false, nil // a failed update removed the file while we were waiting
+	}
+	value, err := io.ReadAll(filep)
+	if err != nil {
+		return true, err
+	}
+	if len(value) <= 0 {
+		value = nil // we have just created the file
+	}
+	if value, err = fn(value); err != nil {
+		if fileinfo.Size() <= 0 {
+			// do not leave behind an empty file that Get would
+			// return as an empty value rather than as ErrNoSuchKey
+			os.Remove(filename)
+		}
+		return true, err
+	}
+	if err := filep.Truncate(0); err != nil {
+		return true, err
+	}
+	_, err = filep.WriteAt(value, 0)
+	return true, err
+}
@@ -65,3 +65,91 @@ func TestFileSystemWithFailure(t *testing.T) {
 		t.Fatal("expected nil here")
 	}
 }
+
+func TestFileSystemUpdate(t *testing.T) {
+	dirpath := filepath.Join("testdata", "kvstore2")
+	if err := os.RemoveAll(dirpath); err != nil {
+		t.Fatal(err)
+	}
+	kvstore, err := NewFS(dirpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("when there is no such key", func(t *testing.T) {
+		err := kvstore.Update("antani", func(value []byte) ([]byte, error) {
+			if value != nil {
+				t.Fatal("expected nil value")
+			}
+			return []byte("mascetti-tarapia-tapioco"), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("when the new value is shorter", func(t *testing.T) {
+		err := kvstore.Update("antani", func(value []byte) ([]byte, error) {
+			if string(value) != "mascetti-tarapia-tapioco" {
+				t.Fatal("unexpected value", string(value))
+			}
+			return []byte("melandri"), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := kvstore.Get("antani")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != "melandri" {
+			t.Fatal("unexpected value", string(value))
+		}
+	})
+
+	t.Run("when fn fails", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		err := kvstore.Update("antani", func(value []byte) ([]byte, error) {
+			return nil, expect
+		})
+		if !errors.Is(err, expect) {
+			t.Fatal("not the error we expected", err)
+		}
+		value, err := kvstore.Get("antani")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != "melandri" {
+			t.Fatal("unexpected value", string(value))
+		}
+	})
+
+	t.Run("when fn fails and there is no such key", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		err := kvstore.Update("mascetti", func(value []byte) ([]byte, error) {
+			if value != nil {
+				t.Fatal("expected nil value")
+			}
+			return nil, expect
+		})
+		if !errors.Is(err, expect) {
+			t.Fatal("not the error we expected", err)
+		}
+		value, err := kvstore.Get("mascetti")
+		if !errors.Is(err, ErrNoSuchKey) {
+			t.Fatal("not the error we expected", err)
+		}
+		if value != nil {
+			t.Fatal("expected nil value")
+		}
+	})
+
+	t.Run("when we cannot open the file", func(t *testing.T) {
+		err := kvstore.Update(filepath.Join("nonexistent", "antani"), func(value []byte) ([]byte, error) {
+			panic("should not be called")
+		})
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
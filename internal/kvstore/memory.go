@@ -21,7 +21,7 @@ type Memory struct {
 	mu sync.Mutex
 }
 
-var _ model.KeyValueStore = &Memory{}
+var _ model.KeyValueStoreUpdater = &Memory{}
 
 // Get returns the specified key's value. In case of error, the
 // error type is such that errors.Is(err, ErrNoSuchKey).
@@ -45,3 +45,18 @@ func (kvs *Memory) Set(key string, value []byte) error {
 	kvs.m[key] = value
 	return nil
 }
+
+// Update atomically updates the value of a key.
+func (kvs *Memory) Update(key string, fn func(value []byte) ([]byte, error)) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	value, err := fn(kvs.m[key])
+	if err != nil {
+		return err
+	}
+	if kvs.m == nil {
+		kvs.m = make(map[string][]byte)
+	}
+	kvs.m[key] = value
+	return nil
+}
@@ -29,3 +29,33 @@ func TestExistingKey(t *testing.T) {
 		t.Fatal("not the result we expected")
 	}
 }
+
+func TestMemoryUpdate(t *testing.T) {
+	kvs := &Memory{}
+	err := kvs.Update("antani", func(value []byte) ([]byte, error) {
+		if value != nil {
+			t.Fatal("expected nil value")
+		}
+		return []byte("mascetti"), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := errors.New("mocked error")
+	err = kvs.Update("antani", func(value []byte) ([]byte, error) {
+		if string(value) != "mascetti" {
+			t.Fatal("unexpected value", string(value))
+		}
+		return nil, expect
+	})
+	if !errors.Is(err, expect) {
+		t.Fatal("not the error we expected", err)
+	}
+	value, err := kvs.Get("antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "mascetti" {
+		t.Fatal("not the result we expected")
+	}
+}
@@ -209,6 +209,73 @@ func TestNewDNSClientDoTDNSSaver(t *testing.T) {
 	dnsclient.CloseIdleConnections()
 }
 
+func TestNewDNSClientDoQ(t *testing.T) {
+	dnsclient, err := NewDNSClient(
+		Config{}, "quic://94.140.14.14:853")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := dnsclient.(*netxlite.SerialResolver)
+	if !ok {
+		t.Fatal("not the resolver we expected")
+	}
+	txp, ok := r.Transport().(*netxlite.DNSOverQUICTransport)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	if txp.Network() != "doq" {
+		t.Fatal("not the Network we expected")
+	}
+	if len(txp.TLSConfig.NextProtos) != 1 || txp.TLSConfig.NextProtos[0] != "doq" {
+		t.Fatal("not the ALPN we expected")
+	}
+	dnsclient.CloseIdleConnections()
+}
+
+func TestNewDNSClientDoQDNSSaver(t *testing.T) {
+	saver := new(tracex.Saver)
+	dnsclient, err := NewDNSClient(
+		Config{Saver: saver}, "quic://94.140.14.14:853")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := dnsclient.(*netxlite.SerialResolver)
+	if !ok {
+		t.Fatal("not the resolver we expected")
+	}
+	txp, ok := r.Transport().(*tracex.DNSTransportSaver)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	doq, ok := txp.DNSTransport.(*netxlite.DNSOverQUICTransport)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	if doq.Network() != "doq" {
+		t.Fatal("not the Network we expected")
+	}
+	dnsclient.CloseIdleConnections()
+}
+
+func TestNewDNSCLientDoQWithoutPort(t *testing.T) {
+	c, err := NewDNSClientWithOverrides(
+		Config{}, "quic://94.140.14.14", "", "dns.adguard-dns.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Address() != "94.140.14.14:853" {
+		t.Fatal("expected default port to be added")
+	}
+}
+
+func TestNewDNSClientBadDoQEndpoint(t *testing.T) {
+	_, err := NewDNSClient(
+		Config{}, "quic://bad:endpoint:853")
+	if err == nil || !strings.Contains(err.Error(), "too many colons in address") {
+		t.Fatal("expected error with bad endpoint")
+	}
+}
+
 func TestNewDNSCLientDoTWithoutPort(t *testing.T) {
 	c, err := NewDNSClientWithOverrides(
 		Config{}, "dot://8.8.8.8", "", "8.8.8.8", "")
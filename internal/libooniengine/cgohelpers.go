@@ -0,0 +1,44 @@
+package main
+
+//
+// Helpers allowing tests, which cannot use cgo, to call the C API.
+//
+
+//#include <stdlib.h>
+//
+//#include "engine.h"
+import "C"
+
+import "unsafe"
+
+// cgoCString converts a Go string to a C string. The caller must free the
+// returned string using [cgoFree].
+func cgoCString(s string) *C.char {
+	return C.CString(s)
+}
+
+// cgoFree frees a C string.
+func cgoFree(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// cgoGoStringAndFree converts a C string allocated by the engine to a Go
+// string and frees it using [OONIEngineFreeMemory]. A NULL pointer maps to
+// the empty string and false.
+func cgoGoStringAndFree(s *C.char) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	defer OONIEngineFreeMemory(unsafe.Pointer(s))
+	return C.GoString(s), true
+}
+
+// cgoTask converts a Go handle to an OONITask.
+func cgoTask(handle uint64) C.OONITask {
+	return C.OONITask(handle)
+}
+
+// cgoInt32 converts a Go int32 to a C int32_t.
+func cgoInt32(v int32) C.int32_t {
+	return C.int32_t(v)
+}
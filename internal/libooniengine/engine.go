@@ -10,6 +10,7 @@ package main
 import "C"
 
 import (
+	"time"
 	"unsafe"
 
 	"github.com/ooni/probe-cli/v3/internal/version"
@@ -21,8 +22,40 @@ func OONIEngineVersion() *C.char {
 }
 
 //export OONIEngineFreeMemory
-func OONIEngineFreeMemory(ptr *C.void) {
-	C.free(unsafe.Pointer(ptr))
+func OONIEngineFreeMemory(ptr unsafe.Pointer) {
+	C.free(ptr)
+}
+
+//export OONIEngineTaskStart
+func OONIEngineTaskStart(settings *C.char) C.OONITask {
+	return C.OONITask(taskStart(C.GoString(settings)))
+}
+
+//export OONIEngineTaskWaitForNextEvent
+func OONIEngineTaskWaitForNextEvent(task C.OONITask, timeout C.int32_t) *C.char {
+	ev, good := taskWaitForNextEvent(uint64(task), time.Duration(timeout)*time.Millisecond)
+	if !good {
+		return nil
+	}
+	return C.CString(ev)
+}
+
+//export OONIEngineTaskIsDone
+func OONIEngineTaskIsDone(task C.OONITask) C.uint8_t {
+	if taskIsDone(uint64(task)) {
+		return 1
+	}
+	return 0
+}
+
+//export OONIEngineTaskInterrupt
+func OONIEngineTaskInterrupt(task C.OONITask) {
+	taskInterrupt(uint64(task))
+}
+
+//export OONIEngineTaskFree
+func OONIEngineTaskFree(task C.OONITask) {
+	taskFree(uint64(task))
 }
 
 func main() {
@@ -7,10 +7,17 @@
 /// C API for using the OONI engine.
 ///
 
+#include <stdint.h>
+
 #ifdef __cplusplus
 extern "C" {
 #endif
 
+/// OONITask is an opaque handle referring to a running task. The zero
+/// value is the invalid handle. The engine detects invalid handles, so
+/// using a handle after OONIEngineTaskFree is safe but useless.
+typedef uint64_t OONITask;
+
 /// OONIEngineVersion return the current engine version.
 ///
 /// @return A char pointer with the current version string.
@@ -19,7 +26,51 @@ char *OONIEngineVersion(void);
 /// OONIEngineFreeMemory frees the memory allocated by the engine.
 ///
 /// @param ptr a void pointer refering to the memory to be freed.
-void OONIENgineFreeMemory(void *ptr);
+void OONIEngineFreeMemory(void *ptr);
+
+/// OONIEngineTaskStart starts a new task in the background.
+///
+/// @param settings A serialized JSON containing the task settings, which
+/// use the same format of pkg/oonimkall's StartTask.
+///
+/// @return A handle referring to the task. This function never fails: if
+/// the settings are invalid, the task will emit a failure.startup event
+/// followed by a task_terminated event. You MUST eventually release the
+/// task using OONIEngineTaskFree.
+OONITask OONIEngineTaskStart(char *settings);
+
+/// OONIEngineTaskWaitForNextEvent waits for the next task event.
+///
+/// @param task The task handle.
+///
+/// @param timeout The number of milliseconds to wait. A negative value means
+/// waiting indefinitely and zero means just polling for an event.
+///
+/// @return A serialized JSON event or NULL if no event arrived within the
+/// timeout or the handle is invalid. After the task is done, this function
+/// keeps returning the task_terminated event. You MUST free the returned
+/// string using OONIEngineFreeMemory.
+char *OONIEngineTaskWaitForNextEvent(OONITask task, int32_t timeout);
+
+/// OONIEngineTaskIsDone returns whether the task is done, i.e., whether
+/// OONIEngineTaskWaitForNextEvent returned the task_terminated event.
+///
+/// @param task The task handle.
+///
+/// @return Nonzero if the task is done or the handle is invalid, zero otherwise.
+uint8_t OONIEngineTaskIsDone(OONITask task);
+
+/// OONIEngineTaskInterrupt interrupts a running task. You still need to
+/// wait for the task_terminated event and to free the task.
+///
+/// @param task The task handle.
+void OONIEngineTaskInterrupt(OONITask task);
+
+/// OONIEngineTaskFree interrupts the task, if needed, and releases the
+/// handle. The engine will discard any pending event.
+///
+/// @param task The task handle.
+void OONIEngineTaskFree(OONITask task);
 
 #ifdef __cplusplus
 }
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/version"
+)
+
+type eventlike struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// startTask starts a task using the C API.
+func startTask(settings string) uint64 {
+	csettings := cgoCString(settings)
+	defer cgoFree(csettings)
+	return uint64(OONIEngineTaskStart(csettings))
+}
+
+// waitForNextEvent waits for the next event using the C API.
+func waitForNextEvent(t *testing.T, handle uint64, timeout int32) (*eventlike, bool) {
+	data, good := cgoGoStringAndFree(OONIEngineTaskWaitForNextEvent(cgoTask(handle), cgoInt32(timeout)))
+	if !good {
+		return nil, false
+	}
+	var ev eventlike
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		t.Fatal(err)
+	}
+	return &ev, true
+}
+
+// isDone calls OONIEngineTaskIsDone.
+func isDone(handle uint64) bool {
+	return OONIEngineTaskIsDone(cgoTask(handle)) != 0
+}
+
+// exampleSettings returns the settings for running the example experiment.
+func exampleSettings(t *testing.T) string {
+	return fmt.Sprintf(`{
+		"assets_dir": %q,
+		"log_level": "DEBUG",
+		"name": "Example",
+		"options": {
+			"no_collector": true,
+			"software_name": "libooniengine-test",
+			"software_version": "0.1.0"
+		},
+		"state_dir": %q,
+		"temp_dir": %q,
+		"tunnel_dir": %q,
+		"version": 1
+	}`, t.TempDir(), t.TempDir(), t.TempDir(), t.TempDir())
+}
+
+func TestOONIEngineVersion(t *testing.T) {
+	v, good := cgoGoStringAndFree(OONIEngineVersion())
+	if !good || v != version.Version {
+		t.Fatal("unexpected version", v)
+	}
+}
+
+func TestOONIEngineTaskWithNetem(t *testing.T) {
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	env.Do(func() {
+		handle := startTask(exampleSettings(t))
+		defer OONIEngineTaskFree(cgoTask(handle))
+
+		keys := map[string]int{}
+		for !isDone(handle) {
+			ev, good := waitForNextEvent(t, handle, -1)
+			if !good {
+				t.Fatal("expected an event")
+			}
+			keys[ev.Key]++
+			if ev.Key == "failure.startup" {
+				t.Fatal("unexpected failure.startup event", ev.Value)
+			}
+		}
+
+		for _, key := range []string{"status.started", "status.geoip_lookup", "measurement", "status.end", "task_terminated"} {
+			if keys[key] <= 0 {
+				t.Fatal("missing event", key, keys)
+			}
+		}
+
+		// make sure we only see task_terminated at this point
+		ev, good := waitForNextEvent(t, handle, 0)
+		if !good || ev.Key != "task_terminated" {
+			t.Fatal("unexpected event", ev)
+		}
+	})
+}
+
+func TestOONIEngineTaskWithInvalidSettings(t *testing.T) {
+	handle := startTask("{")
+	defer OONIEngineTaskFree(cgoTask(handle))
+
+	ev, good := waitForNextEvent(t, handle, 1000)
+	if !good || ev.Key != "failure.startup" {
+		t.Fatal("unexpected event", ev)
+	}
+	if isDone(handle) {
+		t.Fatal("expected the task not to be done")
+	}
+	ev, good = waitForNextEvent(t, handle, 1000)
+	if !good || ev.Key != "task_terminated" {
+		t.Fatal("unexpected event", ev)
+	}
+	if !isDone(handle) {
+		t.Fatal("expected the task to be done")
+	}
+}
+
+func TestOONIEngineTaskInterrupt(t *testing.T) {
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	env.Do(func() {
+		handle := startTask(exampleSettings(t))
+		defer OONIEngineTaskFree(cgoTask(handle))
+
+		OONIEngineTaskInterrupt(cgoTask(handle))
+
+		deadline := time.Now().Add(30 * time.Second)
+		for !isDone(handle) {
+			if time.Now().After(deadline) {
+				t.Fatal("the task did not terminate")
+			}
+			waitForNextEvent(t, handle, 250)
+		}
+	})
+}
+
+func TestOONIEngineTaskFree(t *testing.T) {
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	env.Do(func() {
+		handle := startTask(exampleSettings(t))
+		task := taskGet(handle)
+		if task == nil {
+			t.Fatal("expected a valid task")
+		}
+
+		// free the task without reading any event
+		OONIEngineTaskFree(cgoTask(handle))
+
+		// the pump should drain the events and terminate
+		select {
+		case <-waitForChanClosed(task.events):
+		case <-time.After(30 * time.Second):
+			t.Fatal("the task did not terminate")
+		}
+	})
+}
+
+// waitForChanClosed returns a channel closed once ch has been closed.
+func waitForChanClosed(ch chan string) <-chan any {
+	done := make(chan any)
+	go func() {
+		defer close(done)
+		for range ch {
+			// drain
+		}
+	}()
+	return done
+}
+
+func TestOONIEngineTaskWithInvalidHandle(t *testing.T) {
+	const handle = 0
+	if _, good := waitForNextEvent(t, handle, 0); good {
+		t.Fatal("expected no event")
+	}
+	if !isDone(handle) {
+		t.Fatal("an invalid handle should be done")
+	}
+	OONIEngineTaskInterrupt(handle) // should not crash
+	OONIEngineTaskFree(handle)      // ditto
+}
+
+func TestOONIEngineTaskWaitForNextEventTimeout(t *testing.T) {
+	task := &task{events: make(chan string), isdone: &atomic.Bool{}}
+	started := time.Now()
+	if _, good := task.waitForNextEvent(100 * time.Millisecond); good {
+		t.Fatal("expected no event")
+	}
+	if time.Since(started) < 100*time.Millisecond {
+		t.Fatal("returned too early")
+	}
+	if _, good := task.waitForNextEvent(0); good {
+		t.Fatal("expected no event")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+//
+// Task API
+//
+// This file contains the Go implementation of the task API that we
+// expose to C code. We model tasks after [oonimkall.Task] and we refer
+// to each task using an opaque integer handle, which allows us to
+// avoid passing Go pointers to C code and to detect invalid handles.
+//
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/pkg/oonimkall"
+)
+
+// taskTerminatedEvent is the event emitted when a task is done. We use
+// the same event emitted by [oonimkall.Task.WaitForNextEvent].
+const taskTerminatedEvent = `{"key":"task_terminated","value":{}}`
+
+// task is a running task.
+type task struct {
+	// events is where the pump posts events. The pump closes this
+	// channel after posting the terminated event.
+	events chan string
+
+	// freed is closed when the C code frees the task.
+	freed chan any
+
+	// interrupt interrupts the task.
+	interrupt func()
+
+	// isdone indicates that we delivered the terminated event.
+	isdone *atomic.Bool
+}
+
+// newTask creates a new task using the given settings.
+func newTask(settings string) *task {
+	t := &task{
+		events:    make(chan string),
+		freed:     make(chan any),
+		interrupt: func() {},
+		isdone:    &atomic.Bool{},
+	}
+	kt, err := oonimkall.StartTask(settings)
+	if err != nil {
+		// Like oonimkall, we emit failure.startup when we cannot start
+		// the task, so the caller sees a consistent stream of events.
+		t.events = make(chan string, 2)
+		t.events <- taskFailureStartupEvent(err)
+		t.events <- taskTerminatedEvent
+		close(t.events)
+		return t
+	}
+	t.interrupt = kt.Interrupt
+	go t.pump(kt)
+	return t
+}
+
+// taskFailureStartupEvent returns the serialized failure.startup event.
+func taskFailureStartupEvent(err error) string {
+	data, _ := json.Marshal(map[string]any{
+		"key": "failure.startup",
+		"value": map[string]string{
+			"failure": err.Error(),
+		},
+	})
+	return string(data)
+}
+
+// pump reads events from the underlying task and posts them on the events
+// channel until the task is done. When the task has been freed, the pump
+// keeps reading and discarding events, so the underlying task can finish.
+func (t *task) pump(kt *oonimkall.Task) {
+	defer close(t.events)
+	for {
+		ev := kt.WaitForNextEvent()
+		select {
+		case t.events <- ev:
+		case <-t.freed:
+		}
+		if kt.IsDone() {
+			return
+		}
+	}
+}
+
+// waitForNextEvent returns the next event or false if no event arrived within the
+// given timeout. A negative timeout means that we should wait indefinitely, while
+// a zero timeout means that we should return immediately if there is no event. Like
+// oonimkall, we keep returning the terminated event after the task is done.
+func (t *task) waitForNextEvent(timeout time.Duration) (string, bool) {
+	if t.isDone() {
+		return taskTerminatedEvent, true
+	}
+	var timer <-chan time.Time
+	switch {
+	case timeout == 0:
+		select {
+		case ev, good := <-t.events:
+			return t.maybeTerminated(ev, good), true
+		default:
+			return "", false
+		}
+	case timeout > 0:
+		timer = time.After(timeout)
+	}
+	select {
+	case ev, good := <-t.events:
+		return t.maybeTerminated(ev, good), true
+	case <-timer:
+		return "", false
+	}
+}
+
+// maybeTerminated marks the task as done when it's returning the terminated event.
+func (t *task) maybeTerminated(ev string, good bool) string {
+	if !good || ev == taskTerminatedEvent {
+		t.isdone.Store(true)
+		return taskTerminatedEvent
+	}
+	return ev
+}
+
+// isDone returns whether we delivered the terminated event.
+func (t *task) isDone() bool {
+	return t.isdone.Load()
+}
+
+// free interrupts the task and tells the pump to discard events.
+func (t *task) free() {
+	t.interrupt()
+	close(t.freed)
+}
+
+var (
+	// taskMu protects taskMap and taskNext.
+	taskMu sync.Mutex
+
+	// taskMap maps handles to tasks.
+	taskMap = map[uint64]*task{}
+
+	// taskNext is the next handle to assign. Zero is the invalid handle.
+	taskNext uint64
+)
+
+// taskStart starts a new task and returns its handle.
+func taskStart(settings string) uint64 {
+	t := newTask(settings)
+	defer taskMu.Unlock()
+	taskMu.Lock()
+	taskNext++
+	taskMap[taskNext] = t
+	return taskNext
+}
+
+// taskGet returns the task bound to a handle or nil.
+func taskGet(handle uint64) *task {
+	defer taskMu.Unlock()
+	taskMu.Lock()
+	return taskMap[handle]
+}
+
+// taskWaitForNextEvent implements OONIEngineTaskWaitForNextEvent.
+func taskWaitForNextEvent(handle uint64, timeout time.Duration) (string, bool) {
+	t := taskGet(handle)
+	if t == nil {
+		return "", false
+	}
+	return t.waitForNextEvent(timeout)
+}
+
+// taskIsDone implements OONIEngineTaskIsDone. An invalid handle is always done.
+func taskIsDone(handle uint64) bool {
+	t := taskGet(handle)
+	return t == nil || t.isDone()
+}
+
+// taskInterrupt implements OONIEngineTaskInterrupt.
+func taskInterrupt(handle uint64) {
+	if t := taskGet(handle); t != nil {
+		t.interrupt()
+	}
+}
+
+// taskFree implements OONIEngineTaskFree. Freeing an invalid handle is a no-op.
+func taskFree(handle uint64) {
+	taskMu.Lock()
+	t := taskMap[handle]
+	delete(taskMap, handle)
+	taskMu.Unlock()
+	if t != nil {
+		t.free()
+	}
+}
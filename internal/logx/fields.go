@@ -0,0 +1,87 @@
+package logx
+
+import (
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/logmodel"
+)
+
+// Names of the structured fields understood by [JSONHandler]. The
+// plain-text [Handler] does not print these fields, such that adding
+// them does not change the human readable output.
+const (
+	// FieldExperiment is the name of the experiment being run.
+	FieldExperiment = "experiment"
+
+	// FieldMeasurementIndex is the zero-based index of the measurement.
+	FieldMeasurementIndex = "measurement_index"
+
+	// FieldInput is the input of the measurement.
+	FieldInput = "input"
+
+	// FieldSpanID is the unique ID of an [OperationLogger] span.
+	FieldSpanID = "span_id"
+
+	// FieldSpanEvent is one of "started", "in_progress", and "done".
+	FieldSpanEvent = "span_event"
+
+	// FieldSpanDuration is the duration of a span in seconds.
+	FieldSpanDuration = "span_duration"
+)
+
+// structuredFields contains the fields that [IsStructuredField] recognizes.
+var structuredFields = map[string]bool{
+	FieldExperiment:       true,
+	FieldMeasurementIndex: true,
+	FieldInput:            true,
+	FieldSpanID:           true,
+	FieldSpanEvent:        true,
+	FieldSpanDuration:     true,
+}
+
+// IsStructuredField returns whether name is the name of a structured field
+// meant for machine consumption that text handlers should not print.
+func IsStructuredField(name string) bool {
+	return structuredFields[name]
+}
+
+// fieldsLogger is a logger supporting fields, such as an apex/log logger.
+type fieldsLogger interface {
+	WithFields(fields log.Fielder) *log.Entry
+}
+
+// WithFields returns a logger that attaches the given fields to each log
+// entry, if the given logger supports fields, or the given logger otherwise.
+func WithFields(logger logmodel.Logger, fields log.Fields) logmodel.Logger {
+	if fl, good := logger.(fieldsLogger); good {
+		return fl.WithFields(fields)
+	}
+	return logger
+}
+
+// FieldType is the name of the field containing the type of a typed log entry.
+const FieldType = "type"
+
+// TypeMeasurementStart is the type of the entry emitted by [MeasurementStart].
+const TypeMeasurementStart = "measurement_start"
+
+// IsMeasurementStart returns whether the given fields belong to an entry emitted
+// by [MeasurementStart], which text handlers should not print.
+func IsMeasurementStart(fields log.Fields) bool {
+	return fields[FieldType] == TypeMeasurementStart
+}
+
+// MeasurementStart emits an info entry describing the measurement that is about
+// to start, which [JSONHandler] associates with all the log entries emitted until
+// the next measurement starts. We use the info level such that the entry is not
+// filtered out by default and text handlers do not print it. This function does
+// nothing if the given logger does not support fields.
+func MeasurementStart(logger logmodel.Logger, experiment string, idx int, input string) {
+	if fl, good := logger.(fieldsLogger); good {
+		fl.WithFields(log.Fields{
+			FieldType:             TypeMeasurementStart,
+			FieldExperiment:       experiment,
+			FieldMeasurementIndex: idx,
+			FieldInput:            input,
+		}).Info("Measurement start")
+	}
+}
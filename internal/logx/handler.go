@@ -39,6 +39,9 @@ var _ log.Handler = &Handler{}
 
 // HandleLog implements log.Handler
 func (h *Handler) HandleLog(e *log.Entry) (err error) {
+	if IsMeasurementStart(e.Fields) {
+		return nil // only meaningful for structured log handlers
+	}
 	level := fmt.Sprintf("<%s>", e.Level.String())
 	if h.Emoji {
 		switch e.Level {
@@ -56,10 +59,21 @@ func (h *Handler) HandleLog(e *log.Entry) (err error) {
 	}
 	elapsed := h.Now().Sub(h.StartTime)
 	s := fmt.Sprintf("[%14.6f] %s %s", elapsed.Seconds(), level, e.Message)
-	if len(e.Fields) > 0 {
-		s += fmt.Sprintf(": %+v", e.Fields)
+	if fields := textFields(e.Fields); len(fields) > 0 {
+		s += fmt.Sprintf(": %+v", fields)
 	}
 	s += "\n"
 	_, err = h.Writer.Write([]byte(s))
 	return
 }
+
+// textFields returns the fields that a text handler should print.
+func textFields(fields log.Fields) log.Fields {
+	out := log.Fields{}
+	for name, value := range fields {
+		if !IsStructuredField(name) {
+			out[name] = value
+		}
+	}
+	return out
+}
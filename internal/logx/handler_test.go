@@ -113,3 +113,39 @@ func TestLogHandlerHandleLog(t *testing.T) {
 		})
 	}
 }
+
+func TestLogHandlerHidesStructuredFields(t *testing.T) {
+	expected := "[      1.000000] <info> antani: map[error:EOF]\n"
+	var got string
+	lh := newHandlerForTesting()
+	lh.Writer = &mocks.Writer{
+		MockWrite: func(b []byte) (int, error) {
+			got = string(b)
+			return len(b), nil
+		},
+	}
+	lh.HandleLog(&log.Entry{
+		Fields: map[string]any{
+			"error":              "EOF",
+			logx.FieldExperiment: "example",
+			logx.FieldSpanID:     int64(1),
+		},
+		Level:   log.InfoLevel,
+		Message: "antani",
+	})
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestLogHandlerSkipsMeasurementStart(t *testing.T) {
+	lh := newHandlerForTesting()
+	lh.Writer = &mocks.Writer{
+		MockWrite: func(b []byte) (int, error) {
+			t.Fatal("should not be called")
+			return len(b), nil
+		},
+	}
+	logger := &log.Logger{Level: log.InfoLevel, Handler: lh}
+	logx.MeasurementStart(logger, "dnscheck", 0, "")
+}
@@ -0,0 +1,162 @@
+package logx
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// JSONHandler implements github.com/apex/log.Handler and emits each log
+// entry as a JSON object on its own line using the [JSONLogEntry] schema.
+//
+// The measurement context (i.e., [FieldExperiment], [FieldMeasurementIndex],
+// and [FieldInput]) is sticky: once a log entry contains it, the handler
+// attaches it to all the following entries until the context changes. This
+// allows correlating the logs emitted by experiments, which do not know which
+// measurement they belong to, with the measurement being run.
+type JSONHandler struct {
+	// Now is the MANDATORY function to compute the current time.
+	Now func() time.Time
+
+	// Writer is MANDATORY and is the underlying writer.
+	Writer io.Writer
+
+	// ctx is the current measurement context.
+	ctx jsonMeasurementContext
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+}
+
+// NewJSONHandlerWithDefaultSettings creates a new JSONHandler with default settings.
+func NewJSONHandlerWithDefaultSettings() *JSONHandler {
+	return NewJSONHandler(os.Stderr)
+}
+
+// NewJSONHandler creates a new JSONHandler writing into the given writer.
+func NewJSONHandler(w io.Writer) *JSONHandler {
+	return &JSONHandler{
+		Now:    time.Now,
+		Writer: w,
+	}
+}
+
+var _ log.Handler = &JSONHandler{}
+
+// JSONLogEntry is the schema of the log entries emitted by [JSONHandler]. Fields that are
+// not part of the schema (e.g., the ones added by [log.WithError]) go into Fields.
+type JSONLogEntry struct {
+	// Timestamp is the time when we emitted the entry.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Level is the log level (e.g., "info").
+	Level string `json:"level"`
+
+	// Message is the log message.
+	Message string `json:"message"`
+
+	// Experiment is the name of the experiment or empty.
+	Experiment string `json:"experiment"`
+
+	// MeasurementIndex is the zero-based index of the measurement or nil.
+	MeasurementIndex *int64 `json:"measurement_index"`
+
+	// Input is the measurement input or empty.
+	Input string `json:"input"`
+
+	// SpanID is the ID of the [OperationLogger] span or zero.
+	SpanID int64 `json:"span_id,omitempty"`
+
+	// SpanEvent is the span event (one of "started", "in_progress", and "done") or empty.
+	SpanEvent string `json:"span_event,omitempty"`
+
+	// SpanDuration is the span duration in seconds, only set when the span is done.
+	SpanDuration *float64 `json:"span_duration,omitempty"`
+
+	// Fields contains any other field attached to the entry.
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// jsonMeasurementContext is the sticky measurement context.
+type jsonMeasurementContext struct {
+	experiment string
+	index      *int64
+	input      string
+}
+
+// HandleLog implements log.Handler
+func (h *JSONHandler) HandleLog(e *log.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, found := e.Fields[FieldExperiment]; found {
+		h.ctx = jsonMeasurementContext{
+			experiment: jsonString(e.Fields[FieldExperiment]),
+			index:      jsonInt64(e.Fields[FieldMeasurementIndex]),
+			input:      jsonString(e.Fields[FieldInput]),
+		}
+	}
+	entry := &JSONLogEntry{
+		Timestamp:        h.Now().UTC(),
+		Level:            e.Level.String(),
+		Message:          e.Message,
+		Experiment:       h.ctx.experiment,
+		MeasurementIndex: h.ctx.index,
+		Input:            h.ctx.input,
+		SpanEvent:        jsonString(e.Fields[FieldSpanEvent]),
+		SpanDuration:     jsonFloat64(e.Fields[FieldSpanDuration]),
+	}
+	if spanID := jsonInt64(e.Fields[FieldSpanID]); spanID != nil {
+		entry.SpanID = *spanID
+	}
+	for name, value := range e.Fields {
+		if IsStructuredField(name) {
+			continue
+		}
+		if entry.Fields == nil {
+			entry.Fields = make(map[string]any)
+		}
+		if err, good := value.(error); good {
+			value = err.Error() // otherwise most errors would serialize as `{}`
+		}
+		entry.Fields[name] = value
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.Writer.Write(append(data, '\n'))
+	return err
+}
+
+// jsonString converts value to string or returns the empty string.
+func jsonString(value any) string {
+	s, _ := value.(string)
+	return s
+}
+
+// jsonInt64 converts an integer value to *int64 or returns nil.
+func jsonInt64(value any) *int64 {
+	var v int64
+	switch value := value.(type) {
+	case int:
+		v = int64(value)
+	case int64:
+		v = value
+	default:
+		return nil
+	}
+	return &v
+}
+
+// jsonFloat64 converts value to *float64 or returns nil.
+func jsonFloat64(value any) *float64 {
+	v, good := value.(float64)
+	if !good {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,230 @@
+package logx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestNewJSONHandlerWithDefaultSettings(t *testing.T) {
+	lh := logx.NewJSONHandlerWithDefaultSettings()
+	if lh.Writer != os.Stderr {
+		t.Fatal("expected stderr")
+	}
+	if lh.Now == nil {
+		t.Fatal("expected non-nil Now")
+	}
+}
+
+// runJSONHandler emits the given entries using a [*logx.JSONHandler] and
+// returns the emitted lines parsed as generic JSON objects.
+func runJSONHandler(t *testing.T, entries ...*log.Entry) []map[string]any {
+	buffer := &bytes.Buffer{}
+	lh := logx.NewJSONHandler(buffer)
+	lh.Now = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	for _, entry := range entries {
+		if err := lh.HandleLog(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n") {
+		var value map[string]any
+		runtimex.Try0(json.Unmarshal([]byte(line), &value))
+		out = append(out, value)
+	}
+	return out
+}
+
+func TestJSONHandlerHandleLog(t *testing.T) {
+	t.Run("without measurement context", func(t *testing.T) {
+		got := runJSONHandler(t, &log.Entry{
+			Fields: log.Fields{
+				"error": errors.New("mocked error"),
+			},
+			Level:   log.WarnLevel,
+			Message: "antani",
+		})
+		expected := []map[string]any{{
+			"timestamp":         "2024-01-02T03:04:05Z",
+			"level":             "warn",
+			"message":           "antani",
+			"experiment":        "",
+			"measurement_index": nil,
+			"input":             "",
+			"fields": map[string]any{
+				"error": "mocked error",
+			},
+		}}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with sticky measurement context and spans", func(t *testing.T) {
+		got := runJSONHandler(t, &log.Entry{
+			Fields: log.Fields{
+				logx.FieldExperiment:       "web_connectivity",
+				logx.FieldMeasurementIndex: 3,
+				logx.FieldInput:            "https://www.example.com/",
+			},
+			Level:   log.InfoLevel,
+			Message: "[4/10] running with input: https://www.example.com/",
+		}, &log.Entry{
+			Fields: log.Fields{
+				logx.FieldSpanID:       int64(7),
+				logx.FieldSpanEvent:    "done",
+				logx.FieldSpanDuration: 1.5,
+			},
+			Level:   log.InfoLevel,
+			Message: "dial... ok",
+		}, &log.Entry{
+			Fields: log.Fields{
+				logx.FieldExperiment:       "web_connectivity",
+				logx.FieldMeasurementIndex: int64(4),
+				logx.FieldInput:            "https://www.example.org/",
+			},
+			Level:   log.DebugLevel,
+			Message: "next",
+		})
+		expected := []map[string]any{{
+			"timestamp":         "2024-01-02T03:04:05Z",
+			"level":             "info",
+			"message":           "[4/10] running with input: https://www.example.com/",
+			"experiment":        "web_connectivity",
+			"measurement_index": float64(3),
+			"input":             "https://www.example.com/",
+		}, {
+			"timestamp":         "2024-01-02T03:04:05Z",
+			"level":             "info",
+			"message":           "dial... ok",
+			"experiment":        "web_connectivity",
+			"measurement_index": float64(3),
+			"input":             "https://www.example.com/",
+			"span_id":           float64(7),
+			"span_event":        "done",
+			"span_duration":     1.5,
+		}, {
+			"timestamp":         "2024-01-02T03:04:05Z",
+			"level":             "debug",
+			"message":           "next",
+			"experiment":        "web_connectivity",
+			"measurement_index": float64(4),
+			"input":             "https://www.example.org/",
+		}}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the writer fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		lh := logx.NewJSONHandler(&mocks.Writer{
+			MockWrite: func(b []byte) (int, error) {
+				return 0, expected
+			},
+		})
+		err := lh.HandleLog(&log.Entry{Level: log.InfoLevel, Message: "antani"})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestWithFields(t *testing.T) {
+	t.Run("with a logger supporting fields", func(t *testing.T) {
+		var entry *log.Entry
+		logger := &log.Logger{
+			Level: log.InfoLevel,
+			Handler: log.HandlerFunc(func(e *log.Entry) error {
+				entry = e
+				return nil
+			}),
+		}
+		logx.WithFields(logger, log.Fields{"a": 1}).Info("antani")
+		expected := log.Fields{"a": 1}
+		if diff := cmp.Diff(expected, entry.Fields); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a logger not supporting fields", func(t *testing.T) {
+		logger := &mocks.Logger{}
+		if logx.WithFields(logger, log.Fields{"a": 1}) != logger {
+			t.Fatal("expected to get the original logger")
+		}
+	})
+}
+
+func TestMeasurementStart(t *testing.T) {
+	t.Run("with a logger supporting fields", func(t *testing.T) {
+		var entry *log.Entry
+		logger := &log.Logger{
+			Level: log.InfoLevel,
+			Handler: log.HandlerFunc(func(e *log.Entry) error {
+				entry = e
+				return nil
+			}),
+		}
+		logx.MeasurementStart(logger, "dnscheck", 1, "dot://8.8.8.8")
+		expected := log.Fields{
+			logx.FieldType:             logx.TypeMeasurementStart,
+			logx.FieldExperiment:       "dnscheck",
+			logx.FieldMeasurementIndex: 1,
+			logx.FieldInput:            "dot://8.8.8.8",
+		}
+		if diff := cmp.Diff(expected, entry.Fields); diff != "" {
+			t.Fatal(diff)
+		}
+		if !logx.IsMeasurementStart(entry.Fields) {
+			t.Fatal("expected a measurement start entry")
+		}
+	})
+
+	t.Run("with a logger not supporting fields", func(t *testing.T) {
+		logger := &mocks.Logger{
+			MockInfo: func(message string) {
+				t.Fatal("should not be called")
+			},
+		}
+		logx.MeasurementStart(logger, "dnscheck", 1, "dot://8.8.8.8")
+	})
+
+	t.Run("the JSON handler at info level learns about measurements without input", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		lh := logx.NewJSONHandler(buffer)
+		lh.Now = func() time.Time {
+			return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		}
+		logger := &log.Logger{Level: log.InfoLevel, Handler: lh}
+		logx.MeasurementStart(logger, "web_connectivity", 0, "https://www.example.com/")
+		logx.MeasurementStart(logger, "dnscheck", 1, "")
+		logger.Info("antani")
+		lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+		var got map[string]any
+		runtimex.Try0(json.Unmarshal([]byte(lines[len(lines)-1]), &got))
+		expected := map[string]any{
+			"timestamp":         "2024-01-02T03:04:05Z",
+			"level":             "info",
+			"message":           "antani",
+			"experiment":        "dnscheck",
+			"measurement_index": float64(1),
+			"input":             "",
+		}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
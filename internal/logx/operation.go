@@ -3,16 +3,26 @@ package logx
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/internal/logmodel"
 )
 
+// operationSpanID is the ID of the most recently created [OperationLogger] span.
+var operationSpanID = &atomic.Int64{}
+
 // NewOperationLogger creates a new logger that logs
 // about an in-progress operation. If it takes too much
 // time to emit the result of the operation, the code
 // will emit an interim log message mentioning that the
 // operation is currently in progress.
+//
+// When the logger supports fields (see [WithFields]), each
+// message also carries a unique span ID, the span event and,
+// for the final message, the span duration, thus allowing
+// to correlate the messages of the same operation.
 func NewOperationLogger(logger logmodel.Logger, format string, v ...any) *OperationLogger {
 	return newOperationLogger(500*time.Millisecond, logger, format, v...)
 }
@@ -24,10 +34,12 @@ func newOperationLogger(maxwait time.Duration, logger logmodel.Logger, format st
 		message: fmt.Sprintf(format, v...),
 		once:    &sync.Once{},
 		sighup:  make(chan any),
+		spanID:  operationSpanID.Add(1),
+		t0:      time.Now(),
 		wg:      &sync.WaitGroup{},
 	}
 	ol.wg.Add(1)
-	ol.logger.Infof("%s... started", ol.message)
+	ol.span("started", nil).Infof("%s... started", ol.message)
 	go ol.maybeEmitProgress()
 	return ol
 }
@@ -40,16 +52,30 @@ type OperationLogger struct {
 	message string
 	once    *sync.Once
 	sighup  chan any
+	spanID  int64
+	t0      time.Time
 	wg      *sync.WaitGroup
 }
 
+// span returns a logger that attaches the span fields to the log entries.
+func (ol *OperationLogger) span(event string, duration *time.Duration) logmodel.Logger {
+	fields := log.Fields{
+		FieldSpanID:    ol.spanID,
+		FieldSpanEvent: event,
+	}
+	if duration != nil {
+		fields[FieldSpanDuration] = duration.Seconds()
+	}
+	return WithFields(ol.logger, fields)
+}
+
 func (ol *OperationLogger) maybeEmitProgress() {
 	defer ol.wg.Done()
 	timer := time.NewTimer(ol.maxwait)
 	defer timer.Stop()
 	select {
 	case <-timer.C:
-		ol.logger.Infof("%s... in progress", ol.message)
+		ol.span("in_progress", nil).Infof("%s... in progress", ol.message)
 	case <-ol.sighup:
 		// we'll emit directly in stop
 	}
@@ -62,15 +88,17 @@ func (ol *OperationLogger) Stop(value any) {
 	ol.once.Do(func() {
 		close(ol.sighup)
 		ol.wg.Wait()
+		elapsed := time.Since(ol.t0)
+		logger := ol.span("done", &elapsed)
 		if value != nil {
 			if err, okay := value.(error); okay {
-				ol.logger.Infof("%s... %s", ol.message, err.Error())
+				logger.Infof("%s... %s", ol.message, err.Error())
 				return
 			}
 			// fallthrough
 		} else {
 			value = "ok"
 		}
-		ol.logger.Infof("%s... %+v", ol.message, value)
+		logger.Infof("%s... %+v", ol.message, value)
 	})
 }
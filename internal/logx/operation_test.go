@@ -7,7 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
 	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
 func TestNewOperationLogger(t *testing.T) {
@@ -124,4 +127,42 @@ func TestNewOperationLogger(t *testing.T) {
 			t.Fatal("unexpected first line", lines[0])
 		}
 	})
+
+	t.Run("with a logger supporting fields", func(t *testing.T) {
+		handler := memory.New()
+		logger := &log.Logger{Level: log.InfoLevel, Handler: handler}
+		const maxwait = 100 * time.Microsecond
+		ol := newOperationLogger(maxwait, logger, "antani%d", 0)
+		ol.wg.Wait() // wait for the message to be emitted
+		ol.Stop(nil)
+		if len(handler.Entries) != 3 {
+			t.Fatal("unexpected number of entries")
+		}
+		for idx, event := range []string{"started", "in_progress", "done"} {
+			entry := handler.Entries[idx]
+			if entry.Fields[FieldSpanID] != ol.spanID {
+				t.Fatal("unexpected span ID", entry.Fields[FieldSpanID])
+			}
+			if entry.Fields[FieldSpanEvent] != event {
+				t.Fatal("unexpected span event", entry.Fields[FieldSpanEvent])
+			}
+			_, hasDuration := entry.Fields[FieldSpanDuration].(float64)
+			if hasDuration != (event == "done") {
+				t.Fatal("unexpected span duration", entry.Fields[FieldSpanDuration])
+			}
+		}
+		if handler.Entries[2].Message != "antani0... ok" {
+			t.Fatal("unexpected last message", handler.Entries[2].Message)
+		}
+	})
+
+	t.Run("span IDs are unique", func(t *testing.T) {
+		first := NewOperationLogger(model.DiscardLogger, "antani")
+		second := NewOperationLogger(model.DiscardLogger, "mascetti")
+		first.Stop(nil)
+		second.Stop(nil)
+		if first.spanID == second.spanID {
+			t.Fatal("expected different span IDs")
+		}
+	})
 }
@@ -0,0 +1,323 @@
+package minipipeline
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// WebAggregate contains aggregate statistics computed over many [*WebMeasurement].
+//
+// Use [*WebAggregator] or [AggregateWebMeasurements] to construct this struct.
+type WebAggregate struct {
+	// Measurements is the number of measurements we processed.
+	Measurements int64 `json:"measurements"`
+
+	// Failures is the number of measurements we could not process.
+	Failures int64 `json:"failures"`
+
+	// FailuresByError maps each error that prevented us from
+	// processing a measurement to the number of occurrences.
+	FailuresByError map[string]int64 `json:"failures_by_error"`
+
+	// Total contains statistics for all the processed measurements.
+	Total *WebAggregateEntry `json:"total"`
+
+	// ByDomain contains statistics for each measured domain.
+	ByDomain map[string]*WebAggregateEntry `json:"by_domain"`
+
+	// ByASN contains statistics for each probe ASN. We do not account
+	// measurements where the probe ASN is empty in this table.
+	ByASN map[string]*WebAggregateEntry `json:"by_asn"`
+}
+
+// WebAggregateEntry contains aggregate statistics for a set of [*WebAnalysis].
+type WebAggregateEntry struct {
+	// Measurements is the number of measurements in this set.
+	Measurements int64 `json:"measurements"`
+
+	// DNSExperimentFailure maps the DNS experiment failure to the number of
+	// measurements in which it occurred. A successful lookup maps to "".
+	DNSExperimentFailure map[string]int64 `json:"dns_experiment_failure"`
+
+	// DNSLookupUnexpectedFailure is the number of measurements containing at least
+	// one DNS lookup with an unexpected failure.
+	DNSLookupUnexpectedFailure int64 `json:"dns_lookup_unexpected_failure"`
+
+	// DNSLookupUnexpectedFailureRate is DNSLookupUnexpectedFailure divided by Measurements.
+	DNSLookupUnexpectedFailureRate float64 `json:"dns_lookup_unexpected_failure_rate"`
+
+	// DNSLookupSuccessWithInvalidAddresses is the number of measurements containing at
+	// least one DNS lookup that resolved invalid addresses.
+	DNSLookupSuccessWithInvalidAddresses int64 `json:"dns_lookup_success_with_invalid_addresses"`
+
+	// DNSLookupSuccessWithInvalidAddressesRate is DNSLookupSuccessWithInvalidAddresses
+	// divided by Measurements.
+	DNSLookupSuccessWithInvalidAddressesRate float64 `json:"dns_lookup_success_with_invalid_addresses_rate"`
+
+	// TCPConnectUnexpectedFailure is the number of measurements containing at least
+	// one TCP connect with an unexpected failure.
+	TCPConnectUnexpectedFailure int64 `json:"tcp_connect_unexpected_failure"`
+
+	// TCPConnectUnexpectedFailureRate is TCPConnectUnexpectedFailure divided by Measurements.
+	TCPConnectUnexpectedFailureRate float64 `json:"tcp_connect_unexpected_failure_rate"`
+
+	// TLSHandshakeUnexpectedFailure is the number of measurements containing at least
+	// one TLS handshake with an unexpected failure.
+	TLSHandshakeUnexpectedFailure int64 `json:"tls_handshake_unexpected_failure"`
+
+	// TLSHandshakeUnexpectedFailureRate is TLSHandshakeUnexpectedFailure divided by Measurements.
+	TLSHandshakeUnexpectedFailureRate float64 `json:"tls_handshake_unexpected_failure_rate"`
+
+	// HTTPRoundTripUnexpectedFailure is the number of measurements containing at least
+	// one HTTP round trip with an unexpected failure.
+	HTTPRoundTripUnexpectedFailure int64 `json:"http_round_trip_unexpected_failure"`
+
+	// HTTPRoundTripUnexpectedFailureRate is HTTPRoundTripUnexpectedFailure divided by Measurements.
+	HTTPRoundTripUnexpectedFailureRate float64 `json:"http_round_trip_unexpected_failure_rate"`
+
+	// HTTPFinalResponseDiffBodyProportionFactor is the distribution of the body proportion
+	// factor for the measurements where we could compute it.
+	HTTPFinalResponseDiffBodyProportionFactor *WebAggregateDistribution `json:"http_final_response_diff_body_proportion_factor"`
+
+	// HTTPFinalResponseDiffStatusCodeMismatch is the number of measurements where the
+	// status code of the final response differs from the control's one.
+	HTTPFinalResponseDiffStatusCodeMismatch int64 `json:"http_final_response_diff_status_code_mismatch"`
+
+	// bodyProportions contains the body proportions we have seen so far.
+	bodyProportions []float64
+}
+
+func newWebAggregateEntry() *WebAggregateEntry {
+	return &WebAggregateEntry{
+		DNSExperimentFailure:                      map[string]int64{},
+		HTTPFinalResponseDiffBodyProportionFactor: &WebAggregateDistribution{},
+	}
+}
+
+func (e *WebAggregateEntry) add(analysis *WebAnalysis) {
+	e.Measurements++
+	e.DNSExperimentFailure[analysis.DNSExperimentFailure.UnwrapOr("")]++
+	if analysis.DNSLookupUnexpectedFailure.Len() > 0 {
+		e.DNSLookupUnexpectedFailure++
+	}
+	if analysis.DNSLookupSuccessWithInvalidAddresses.Len() > 0 {
+		e.DNSLookupSuccessWithInvalidAddresses++
+	}
+	if analysis.TCPConnectUnexpectedFailure.Len() > 0 {
+		e.TCPConnectUnexpectedFailure++
+	}
+	if analysis.TLSHandshakeUnexpectedFailure.Len() > 0 {
+		e.TLSHandshakeUnexpectedFailure++
+	}
+	if analysis.HTTPRoundTripUnexpectedFailure.Len() > 0 {
+		e.HTTPRoundTripUnexpectedFailure++
+	}
+	if !analysis.HTTPFinalResponseDiffBodyProportionFactor.IsNone() {
+		e.bodyProportions = append(e.bodyProportions, analysis.HTTPFinalResponseDiffBodyProportionFactor.Unwrap())
+	}
+	if !analysis.HTTPFinalResponseDiffStatusCodeMatch.UnwrapOr(true) {
+		e.HTTPFinalResponseDiffStatusCodeMismatch++
+	}
+}
+
+func (e *WebAggregateEntry) finalize() *WebAggregateEntry {
+	out := *e
+	out.DNSExperimentFailure = make(map[string]int64, len(e.DNSExperimentFailure))
+	for key, value := range e.DNSExperimentFailure {
+		out.DNSExperimentFailure[key] = value
+	}
+	out.DNSLookupUnexpectedFailureRate = aggregateRate(e.DNSLookupUnexpectedFailure, e.Measurements)
+	out.DNSLookupSuccessWithInvalidAddressesRate = aggregateRate(e.DNSLookupSuccessWithInvalidAddresses, e.Measurements)
+	out.TCPConnectUnexpectedFailureRate = aggregateRate(e.TCPConnectUnexpectedFailure, e.Measurements)
+	out.TLSHandshakeUnexpectedFailureRate = aggregateRate(e.TLSHandshakeUnexpectedFailure, e.Measurements)
+	out.HTTPRoundTripUnexpectedFailureRate = aggregateRate(e.HTTPRoundTripUnexpectedFailure, e.Measurements)
+	out.HTTPFinalResponseDiffBodyProportionFactor = NewWebAggregateDistribution(e.bodyProportions...)
+	out.bodyProportions = nil
+	return &out
+}
+
+func aggregateRate(count, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
+
+// WebAggregateDistributionBuckets is the number of buckets of [*WebAggregateDistribution].
+const WebAggregateDistributionBuckets = 10
+
+// WebAggregateDistribution summarizes the distribution of values in the [0, 1] interval.
+type WebAggregateDistribution struct {
+	// Count is the number of values.
+	Count int64 `json:"count"`
+
+	// Min is the minimum value.
+	Min float64 `json:"min"`
+
+	// Max is the maximum value.
+	Max float64 `json:"max"`
+
+	// Mean is the mean value.
+	Mean float64 `json:"mean"`
+
+	// P25 is the 25th percentile.
+	P25 float64 `json:"p25"`
+
+	// P50 is the median.
+	P50 float64 `json:"p50"`
+
+	// P75 is the 75th percentile.
+	P75 float64 `json:"p75"`
+
+	// P90 is the 90th percentile.
+	P90 float64 `json:"p90"`
+
+	// Histogram contains the number of values falling into each of the
+	// [WebAggregateDistributionBuckets] equally sized buckets covering
+	// the [0, 1] interval. The last bucket also includes 1.
+	Histogram [WebAggregateDistributionBuckets]int64 `json:"histogram"`
+}
+
+// NewWebAggregateDistribution computes the [*WebAggregateDistribution] of the given values.
+func NewWebAggregateDistribution(values ...float64) *WebAggregateDistribution {
+	dist := &WebAggregateDistribution{}
+	if len(values) <= 0 {
+		return dist
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, value := range sorted {
+		sum += value
+		bucket := int(value * WebAggregateDistributionBuckets)
+		bucket = max(0, min(bucket, WebAggregateDistributionBuckets-1))
+		dist.Histogram[bucket]++
+	}
+	dist.Count = int64(len(sorted))
+	dist.Min = sorted[0]
+	dist.Max = sorted[len(sorted)-1]
+	dist.Mean = sum / float64(len(sorted))
+	dist.P25 = aggregatePercentile(sorted, 25)
+	dist.P50 = aggregatePercentile(sorted, 50)
+	dist.P75 = aggregatePercentile(sorted, 75)
+	dist.P90 = aggregatePercentile(sorted, 90)
+	return dist
+}
+
+// aggregatePercentile uses the nearest-rank method with non-empty sorted values.
+func aggregatePercentile(sorted []float64, percentile float64) float64 {
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	return sorted[max(0, rank-1)]
+}
+
+// WebAggregator computes a [*WebAggregate]. This struct is safe for concurrent
+// use by multiple goroutines. Use [NewWebAggregator] to construct.
+type WebAggregator struct {
+	byASN           map[string]*WebAggregateEntry
+	byDomain        map[string]*WebAggregateEntry
+	failures        int64
+	failuresByError map[string]int64
+	mu              sync.Mutex
+	total           *WebAggregateEntry
+}
+
+// NewWebAggregator creates a new [*WebAggregator].
+func NewWebAggregator() *WebAggregator {
+	return &WebAggregator{
+		byASN:           map[string]*WebAggregateEntry{},
+		byDomain:        map[string]*WebAggregateEntry{},
+		failures:        0,
+		failuresByError: map[string]int64{},
+		mu:              sync.Mutex{},
+		total:           newWebAggregateEntry(),
+	}
+}
+
+// Add accounts the [*WebAnalysis] of the given [*WebMeasurement]. We use the
+// hostname of the measurement input as the domain and ProbeASN as the ASN.
+func (a *WebAggregator) Add(meas *WebMeasurement, analysis *WebAnalysis) {
+	var domain string
+	if URL, err := url.Parse(meas.Input); err == nil {
+		domain = URL.Hostname()
+	}
+
+	defer a.mu.Unlock()
+	a.mu.Lock()
+
+	a.total.add(analysis)
+	if domain != "" {
+		aggregateEntryFor(a.byDomain, domain).add(analysis)
+	}
+	if meas.ProbeASN != "" {
+		aggregateEntryFor(a.byASN, meas.ProbeASN).add(analysis)
+	}
+}
+
+func aggregateEntryFor(m map[string]*WebAggregateEntry, key string) *WebAggregateEntry {
+	entry := m[key]
+	if entry == nil {
+		entry = newWebAggregateEntry()
+		m[key] = entry
+	}
+	return entry
+}
+
+// AddFailure accounts for a measurement we could not process because of the given error.
+func (a *WebAggregator) AddFailure(err error) {
+	defer a.mu.Unlock()
+	a.mu.Lock()
+	a.failures++
+	a.failuresByError[err.Error()]++
+}
+
+// Aggregate returns the [*WebAggregate] computed so far.
+func (a *WebAggregator) Aggregate() *WebAggregate {
+	defer a.mu.Unlock()
+	a.mu.Lock()
+	out := &WebAggregate{
+		Measurements:    a.total.Measurements,
+		Failures:        a.failures,
+		FailuresByError: make(map[string]int64, len(a.failuresByError)),
+		Total:           a.total.finalize(),
+		ByDomain:        make(map[string]*WebAggregateEntry, len(a.byDomain)),
+		ByASN:           make(map[string]*WebAggregateEntry, len(a.byASN)),
+	}
+	for key, value := range a.failuresByError {
+		out.FailuresByError[key] = value
+	}
+	for key, entry := range a.byDomain {
+		out.ByDomain[key] = entry.finalize()
+	}
+	for key, entry := range a.byASN {
+		out.ByASN[key] = entry.finalize()
+	}
+	return out
+}
+
+// AggregateWebMeasurements reads [*WebMeasurement] from the given channel until it is closed
+// and uses parallelism goroutines to run [IngestWebMeasurement] and [AnalyzeWebObservationsWithLinearAnalysis]
+// and to account the results using the given [*WebAggregator]. This function returns when
+// all the measurements have been processed. A parallelism lower than one means one.
+func AggregateWebMeasurements(lookupper model.GeoIPASNLookupper, parallelism int,
+	aggregator *WebAggregator, measurements <-chan *WebMeasurement) {
+	wg := &sync.WaitGroup{}
+	for idx := 0; idx < max(1, parallelism); idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for meas := range measurements {
+				container, err := IngestWebMeasurement(lookupper, meas)
+				if err != nil {
+					aggregator.AddFailure(err)
+					continue
+				}
+				aggregator.Add(meas, AnalyzeWebObservationsWithLinearAnalysis(lookupper, container))
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,150 @@
+package minipipeline
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/must"
+	"github.com/ooni/probe-cli/v3/internal/optional"
+)
+
+func TestNewWebAggregateDistribution(t *testing.T) {
+	t.Run("with no values", func(t *testing.T) {
+		dist := NewWebAggregateDistribution()
+		if diff := cmp.Diff(&WebAggregateDistribution{}, dist); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with values", func(t *testing.T) {
+		dist := NewWebAggregateDistribution(1, 0.0625, 0.5, 0.9375, 0.25)
+		expect := &WebAggregateDistribution{
+			Count:     5,
+			Min:       0.0625,
+			Max:       1,
+			Mean:      0.55,
+			P25:       0.25,
+			P50:       0.5,
+			P75:       0.9375,
+			P90:       1,
+			Histogram: [WebAggregateDistributionBuckets]int64{1, 0, 1, 0, 0, 1, 0, 0, 0, 2},
+		}
+		if diff := cmp.Diff(expect, dist); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestWebAggregator(t *testing.T) {
+	aggregator := NewWebAggregator()
+
+	aggregator.Add(&WebMeasurement{
+		Input:    "https://www.example.com/",
+		ProbeASN: "AS30722",
+	}, &WebAnalysis{
+		DNSExperimentFailure:                      optional.Some("dns_nxdomain_error"),
+		DNSLookupUnexpectedFailure:                NewSet[int64](1, 2),
+		HTTPFinalResponseDiffBodyProportionFactor: optional.Some(0.25),
+		HTTPFinalResponseDiffStatusCodeMatch:      optional.Some(false),
+	})
+	aggregator.Add(&WebMeasurement{
+		Input:    "https://www.example.com/robots.txt",
+		ProbeASN: "",
+	}, &WebAnalysis{
+		TLSHandshakeUnexpectedFailure:             NewSet[int64](3),
+		HTTPFinalResponseDiffBodyProportionFactor: optional.Some(0.75),
+	})
+	aggregator.Add(&WebMeasurement{
+		Input:    "https://www.example.org/",
+		ProbeASN: "AS30722",
+	}, &WebAnalysis{})
+	aggregator.AddFailure(ErrNoTestKeys)
+
+	aggregate := aggregator.Aggregate()
+	if aggregate.Measurements != 3 || aggregate.Failures != 1 {
+		t.Fatal("unexpected counters", aggregate.Measurements, aggregate.Failures)
+	}
+	if diff := cmp.Diff(map[string]int64{ErrNoTestKeys.Error(): 1}, aggregate.FailuresByError); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if len(aggregate.ByDomain) != 2 || len(aggregate.ByASN) != 1 {
+		t.Fatal("unexpected tables", aggregate.ByDomain, aggregate.ByASN)
+	}
+
+	example := aggregate.ByDomain["www.example.com"]
+	if example.Measurements != 2 || example.DNSLookupUnexpectedFailure != 1 || example.DNSLookupUnexpectedFailureRate != 0.5 {
+		t.Fatal("unexpected DNS statistics", example)
+	}
+	if example.TLSHandshakeUnexpectedFailure != 1 || example.HTTPFinalResponseDiffStatusCodeMismatch != 1 {
+		t.Fatal("unexpected TLS or HTTP statistics", example)
+	}
+	if diff := cmp.Diff(map[string]int64{"dns_nxdomain_error": 1, "": 1}, example.DNSExperimentFailure); diff != "" {
+		t.Fatal(diff)
+	}
+	if dist := example.HTTPFinalResponseDiffBodyProportionFactor; dist.Count != 2 || dist.Mean != 0.5 {
+		t.Fatal("unexpected distribution", dist)
+	}
+
+	asn := aggregate.ByASN["AS30722"]
+	if asn.Measurements != 2 || asn.DNSLookupUnexpectedFailureRate != 0.5 || asn.HTTPFinalResponseDiffBodyProportionFactor.Count != 1 {
+		t.Fatal("unexpected ASN statistics", asn)
+	}
+
+	if aggregate.Total.Measurements != 3 || aggregate.Total.HTTPFinalResponseDiffBodyProportionFactor.Count != 2 {
+		t.Fatal("unexpected total statistics", aggregate.Total)
+	}
+
+	// make sure that adding more data does not change the previous aggregate
+	aggregator.Add(&WebMeasurement{Input: "https://www.example.org/"}, &WebAnalysis{})
+	if aggregate.ByDomain["www.example.org"].Measurements != 1 {
+		t.Fatal("the previous aggregate has changed")
+	}
+}
+
+func TestAggregateWebMeasurements(t *testing.T) {
+	basedir := filepath.Join("testdata", "webconnectivity", "generated")
+	entries, err := os.ReadDir(basedir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	measurements := make(chan *WebMeasurement)
+	aggregator := NewWebAggregator()
+	done := make(chan any)
+	go func() {
+		defer close(done)
+		AggregateWebMeasurements(model.GeoIPASNLookupperFunc(func(ip string) (uint, string, error) {
+			return 0, "", errors.New("mocked error")
+		}), 4, aggregator, measurements)
+	}()
+
+	var expected int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		var meas WebMeasurement
+		must.UnmarshalJSON(must.ReadFile(filepath.Join(basedir, entry.Name(), "measurement.json")), &meas)
+		measurements <- &meas
+		expected++
+	}
+	measurements <- &WebMeasurement{ /* empty */ }
+	close(measurements)
+	<-done
+
+	aggregate := aggregator.Aggregate()
+	if aggregate.Measurements != expected || aggregate.Failures != 1 {
+		t.Fatal("unexpected counters", aggregate.Measurements, aggregate.Failures)
+	}
+	if aggregate.Total.DNSLookupUnexpectedFailure <= 0 || aggregate.Total.HTTPFinalResponseDiffBodyProportionFactor.Count <= 0 {
+		t.Fatal("expected to see unexpected DNS failures and body proportions", aggregate.Total)
+	}
+	if len(aggregate.ByDomain) <= 0 {
+		t.Fatal("expected to see per-domain statistics")
+	}
+}
@@ -108,6 +108,7 @@ func AnalyzeWebObservationsWithoutLinearAnalysis(
 	analysis.dnsComputeSuccessMetrics(lookupper, container)
 	analysis.dnsComputeSuccessMetricsClassic(lookupper, container)
 	analysis.dnsComputeFailureMetrics(container)
+	analysis.dnsComputeFamilyMetrics(container)
 
 	analysis.tcpComputeMetrics(container)
 	analysis.tlsComputeMetrics(container)
@@ -174,6 +175,20 @@ type WebAnalysis struct {
 	// DNSLookupExpectedSuccess contains DNS transactions with expected successes.
 	DNSLookupExpectedSuccess Set[int64]
 
+	// DNSLookupSuccessIPv4 contains DNS transactions where the A query succeeded before
+	// following redirects. Note that the A and AAAA queries of a transaction share its ID.
+	DNSLookupSuccessIPv4 Set[int64]
+
+	// DNSLookupSuccessIPv6 is like DNSLookupSuccessIPv4 but for the AAAA query.
+	DNSLookupSuccessIPv6 Set[int64]
+
+	// DNSLookupUnexpectedFailureIPv4 contains DNS transactions where the A query failed
+	// unexpectedly. Note that the A and AAAA queries of a transaction share its ID.
+	DNSLookupUnexpectedFailureIPv4 Set[int64]
+
+	// DNSLookupUnexpectedFailureIPv6 is like DNSLookupUnexpectedFailureIPv4 but for the AAAA query.
+	DNSLookupUnexpectedFailureIPv6 Set[int64]
+
 	// TCPConnectExpectedFailure contains TCP connect transactions that failed
 	// consistently for the probe and the test helper.
 	TCPConnectExpectedFailure Set[int64]
@@ -201,6 +216,19 @@ type WebAnalysis struct {
 	// while checking for connectivity, as opposed to fetching a webpage.
 	TCPConnectUnexplainedFailureDuringConnectivityCheck Set[int64]
 
+	// TCPConnectSuccessIPv4 contains TCP endpoint transactions using IPv4 that
+	// succeeded before following redirects.
+	TCPConnectSuccessIPv4 Set[int64]
+
+	// TCPConnectSuccessIPv6 is like TCPConnectSuccessIPv4 but for IPv6.
+	TCPConnectSuccessIPv6 Set[int64]
+
+	// TCPConnectUnexpectedFailureIPv4 contains TCP endpoint transactions using IPv4 with unexpected failures.
+	TCPConnectUnexpectedFailureIPv4 Set[int64]
+
+	// TCPConnectUnexpectedFailureIPv6 contains TCP endpoint transactions using IPv6 with unexpected failures.
+	TCPConnectUnexpectedFailureIPv6 Set[int64]
+
 	// TLSHandshakeExpectedFailure contains TLS endpoint transactions that failed
 	// consistently for the probe and the test helper.
 	TLSHandshakeExpectedFailure Set[int64]
@@ -228,6 +256,19 @@ type WebAnalysis struct {
 	// while checking for connectivity, as opposed to fetching a webpage.
 	TLSHandshakeUnexplainedFailureDuringConnectivityCheck Set[int64]
 
+	// TLSHandshakeSuccessIPv4 contains TLS endpoint transactions using IPv4 that
+	// succeeded before following redirects.
+	TLSHandshakeSuccessIPv4 Set[int64]
+
+	// TLSHandshakeSuccessIPv6 is like TLSHandshakeSuccessIPv4 but for IPv6.
+	TLSHandshakeSuccessIPv6 Set[int64]
+
+	// TLSHandshakeUnexpectedFailureIPv4 contains TLS endpoint transactions using IPv4 with unexpected failures.
+	TLSHandshakeUnexpectedFailureIPv4 Set[int64]
+
+	// TLSHandshakeUnexpectedFailureIPv6 contains TLS endpoint transactions using IPv6 with unexpected failures.
+	TLSHandshakeUnexpectedFailureIPv6 Set[int64]
+
 	// HTTPRoundTripUnexpectedFailure contains HTTP endpoint transactions with unexpected failures.
 	HTTPRoundTripUnexpectedFailure Set[int64]
 
@@ -235,6 +276,12 @@ type WebAnalysis struct {
 	// failures for which there's no corresponding control info.
 	HTTPRoundTripUnexplainedFailure Set[int64]
 
+	// HTTPRoundTripUnexpectedFailureIPv4 contains HTTP endpoint transactions using IPv4 with unexpected failures.
+	HTTPRoundTripUnexpectedFailureIPv4 Set[int64]
+
+	// HTTPRoundTripUnexpectedFailureIPv6 contains HTTP endpoint transactions using IPv6 with unexpected failures.
+	HTTPRoundTripUnexpectedFailureIPv6 Set[int64]
+
 	// HTTPFinalResponseSuccessTLSWithoutControl contains the ID of the final response
 	// transaction when the final response succeeded without control and with TLS.
 	HTTPFinalResponseSuccessTLSWithoutControl optional.Value[int64]
@@ -473,6 +520,29 @@ func (wa *WebAnalysis) dnsComputeFailureMetrics(c *WebObservationsContainer) {
 	}
 }
 
+func (wa *WebAnalysis) dnsComputeFamilyMetrics(c *WebObservationsContainer) {
+	// Implementation note: the A and AAAA queries issued by the same resolver share the
+	// transaction ID and the functions above only consider the first observation for each
+	// transaction, so here we walk through all the observations again.
+
+	for _, obs := range c.DNSLookupSuccesses {
+		// lookups once we started following redirects should not be considered
+		if obs.TagDepth.IsNone() || obs.TagDepth.Unwrap() != 0 {
+			continue
+		}
+		utilsAddByDNSQueryFamily(
+			obs, obs.DNSTransactionID.Unwrap(), &wa.DNSLookupSuccessIPv4, &wa.DNSLookupSuccessIPv6)
+	}
+
+	for _, obs := range c.DNSLookupFailures {
+		if !utilsDNSLookupFailureIsUnexpected(obs) {
+			continue
+		}
+		utilsAddByDNSQueryFamily(
+			obs, obs.DNSTransactionID.Unwrap(), &wa.DNSLookupUnexpectedFailureIPv4, &wa.DNSLookupUnexpectedFailureIPv6)
+	}
+}
+
 func (wa *WebAnalysis) tcpComputeMetrics(c *WebObservationsContainer) {
 	for _, obs := range c.KnownTCPEndpoints {
 		// handle the case where there is no measurement
@@ -499,6 +569,13 @@ func (wa *WebAnalysis) tcpComputeMetrics(c *WebObservationsContainer) {
 			continue
 		}
 
+		// track successes by address family, which allows to determine whether
+		// the other address family is the only one failing
+		if obs.TCPConnectFailure.Unwrap() == "" {
+			utilsAddByEndpointFamily(
+				obs, obs.EndpointTransactionID.Unwrap(), &wa.TCPConnectSuccessIPv4, &wa.TCPConnectSuccessIPv6)
+		}
+
 		// handle the case where there is no control information
 		if obs.ControlTCPConnectFailure.IsNone() {
 			continue
@@ -532,6 +609,10 @@ func (wa *WebAnalysis) tcpComputeMetrics(c *WebObservationsContainer) {
 				wa.TCPConnectUnexpectedFailureDuringConnectivityCheck.Add(obs.EndpointTransactionID.Unwrap())
 			}
 			wa.TCPConnectUnexpectedFailure.Add(obs.EndpointTransactionID.Unwrap())
+			utilsAddByEndpointFamily(
+				obs, obs.EndpointTransactionID.Unwrap(),
+				&wa.TCPConnectUnexpectedFailureIPv4, &wa.TCPConnectUnexpectedFailureIPv6,
+			)
 			continue
 		}
 	}
@@ -560,6 +641,13 @@ func (wa *WebAnalysis) tlsComputeMetrics(c *WebObservationsContainer) {
 			continue
 		}
 
+		// track successes by address family, which allows to determine whether
+		// the other address family is the only one failing
+		if obs.TLSHandshakeFailure.Unwrap() == "" {
+			utilsAddByEndpointFamily(
+				obs, obs.EndpointTransactionID.Unwrap(), &wa.TLSHandshakeSuccessIPv4, &wa.TLSHandshakeSuccessIPv6)
+		}
+
 		// handle the case where there is no control information
 		if obs.ControlTLSHandshakeFailure.IsNone() {
 			continue
@@ -587,6 +675,10 @@ func (wa *WebAnalysis) tlsComputeMetrics(c *WebObservationsContainer) {
 				wa.TLSHandshakeUnexpectedFailureDuringConnectivityCheck.Add(obs.EndpointTransactionID.Unwrap())
 			}
 			wa.TLSHandshakeUnexpectedFailure.Add(obs.EndpointTransactionID.Unwrap())
+			utilsAddByEndpointFamily(
+				obs, obs.EndpointTransactionID.Unwrap(),
+				&wa.TLSHandshakeUnexpectedFailureIPv4, &wa.TLSHandshakeUnexpectedFailureIPv6,
+			)
 			continue
 		}
 	}
@@ -624,6 +716,10 @@ func (wa *WebAnalysis) httpComputeFailureMetrics(c *WebObservationsContainer) {
 		// handle the case where only the probe fails
 		if obs.HTTPFailure.Unwrap() != "" {
 			wa.HTTPRoundTripUnexpectedFailure.Add(obs.EndpointTransactionID.Unwrap())
+			utilsAddByEndpointFamily(
+				obs, obs.EndpointTransactionID.Unwrap(),
+				&wa.HTTPRoundTripUnexpectedFailureIPv4, &wa.HTTPRoundTripUnexpectedFailureIPv6,
+			)
 			continue
 		}
 	}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
+	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/optional"
 )
 
@@ -482,3 +484,102 @@ func TestHTTPDiffTitleDifferentLongWords(t *testing.T) {
 		})
 	}
 }
+
+func TestAddressFamilyMetrics(t *testing.T) {
+	container := &WebObservationsContainer{
+		DNSLookupFailures: []*WebObservation{{
+			// AAAA query blocked by the censor
+			DNSTransactionID:        optional.Some[int64](1),
+			DNSQueryType:            optional.Some("AAAA"),
+			DNSEngine:               optional.Some("udp"),
+			DNSLookupFailure:        optional.Some("generic_timeout_error"),
+			TagDepth:                optional.Some[int64](0),
+			ControlDNSLookupFailure: optional.Some(""),
+		}, {
+			// AAAA query failing using DoH, which is about the DoH service
+			DNSTransactionID:        optional.Some[int64](2),
+			DNSQueryType:            optional.Some("AAAA"),
+			DNSEngine:               optional.Some("doh"),
+			DNSLookupFailure:        optional.Some("generic_timeout_error"),
+			TagDepth:                optional.Some[int64](0),
+			ControlDNSLookupFailure: optional.Some(""),
+		}},
+		DNSLookupSuccesses: []*WebObservation{{
+			DNSTransactionID: optional.Some[int64](1),
+			DNSQueryType:     optional.Some("A"),
+			DNSEngine:        optional.Some("udp"),
+			DNSLookupFailure: optional.Some(""),
+			TagDepth:         optional.Some[int64](0),
+		}},
+		KnownTCPEndpoints: map[int64]*WebObservation{
+			3: {
+				EndpointTransactionID:      optional.Some[int64](3),
+				IPAddress:                  optional.Some("93.184.216.34"),
+				TagDepth:                   optional.Some[int64](0),
+				TCPConnectFailure:          optional.Some(""),
+				ControlTCPConnectFailure:   optional.Some(""),
+				TLSHandshakeFailure:        optional.Some(""),
+				ControlTLSHandshakeFailure: optional.Some(""),
+			},
+			4: {
+				EndpointTransactionID:    optional.Some[int64](4),
+				IPAddress:                optional.Some("2606:2800:220:1:248:1893:25c8:1946"),
+				TagDepth:                 optional.Some[int64](0),
+				TCPConnectFailure:        optional.Some("generic_timeout_error"),
+				ControlTCPConnectFailure: optional.Some(""),
+			},
+			5: {
+				// IPv6 not working for the probe, which is not interference
+				EndpointTransactionID:    optional.Some[int64](5),
+				IPAddress:                optional.Some("2606:2800:220:1:248:1893:25c8:1947"),
+				TagDepth:                 optional.Some[int64](0),
+				TCPConnectFailure:        optional.Some("network_unreachable"),
+				ControlTCPConnectFailure: optional.Some(""),
+			},
+			6: {
+				EndpointTransactionID:      optional.Some[int64](6),
+				IPAddress:                  optional.Some("2606:2800:220:1:248:1893:25c8:1948"),
+				TagDepth:                   optional.Some[int64](0),
+				TCPConnectFailure:          optional.Some(""),
+				ControlTCPConnectFailure:   optional.Some(""),
+				TLSHandshakeFailure:        optional.Some("connection_reset"),
+				ControlTLSHandshakeFailure: optional.Some(""),
+			},
+		},
+	}
+
+	analysis := AnalyzeWebObservationsWithoutLinearAnalysis(
+		model.GeoIPASNLookupperFunc(geoipx.LookupASN), container)
+
+	expect := map[string][]int64{
+		"DNSLookupSuccessIPv4":              {1},
+		"DNSLookupSuccessIPv6":              {},
+		"DNSLookupUnexpectedFailureIPv4":    {},
+		"DNSLookupUnexpectedFailureIPv6":    {1},
+		"TCPConnectSuccessIPv4":             {3},
+		"TCPConnectSuccessIPv6":             {6},
+		"TCPConnectUnexpectedFailureIPv4":   {},
+		"TCPConnectUnexpectedFailureIPv6":   {4},
+		"TLSHandshakeSuccessIPv4":           {3},
+		"TLSHandshakeSuccessIPv6":           {},
+		"TLSHandshakeUnexpectedFailureIPv4": {},
+		"TLSHandshakeUnexpectedFailureIPv6": {6},
+	}
+	got := map[string][]int64{
+		"DNSLookupSuccessIPv4":              analysis.DNSLookupSuccessIPv4.Keys(),
+		"DNSLookupSuccessIPv6":              analysis.DNSLookupSuccessIPv6.Keys(),
+		"DNSLookupUnexpectedFailureIPv4":    analysis.DNSLookupUnexpectedFailureIPv4.Keys(),
+		"DNSLookupUnexpectedFailureIPv6":    analysis.DNSLookupUnexpectedFailureIPv6.Keys(),
+		"TCPConnectSuccessIPv4":             analysis.TCPConnectSuccessIPv4.Keys(),
+		"TCPConnectSuccessIPv6":             analysis.TCPConnectSuccessIPv6.Keys(),
+		"TCPConnectUnexpectedFailureIPv4":   analysis.TCPConnectUnexpectedFailureIPv4.Keys(),
+		"TCPConnectUnexpectedFailureIPv6":   analysis.TCPConnectUnexpectedFailureIPv6.Keys(),
+		"TLSHandshakeSuccessIPv4":           analysis.TLSHandshakeSuccessIPv4.Keys(),
+		"TLSHandshakeSuccessIPv6":           analysis.TLSHandshakeSuccessIPv6.Keys(),
+		"TLSHandshakeUnexpectedFailureIPv4": analysis.TLSHandshakeUnexpectedFailureIPv4.Keys(),
+		"TLSHandshakeUnexpectedFailureIPv6": analysis.TLSHandshakeUnexpectedFailureIPv6.Keys(),
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -10,6 +10,9 @@ type WebMeasurement struct {
 	// Input contains the input we measured (a URL).
 	Input string `json:"input"`
 
+	// ProbeASN contains the probe ASN (e.g., "AS30722").
+	ProbeASN string `json:"probe_asn"`
+
 	// TestKeys contains the test-specific measurements.
 	TestKeys optional.Value[*WebMeasurementTestKeys] `json:"test_keys"`
 }
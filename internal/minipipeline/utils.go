@@ -110,6 +110,59 @@ func utilsDNSEngineIsDNSOverHTTPS(obs *WebObservation) bool {
 	return obs.DNSEngine.UnwrapOr("") == "doh"
 }
 
+// utilsDNSLookupFailureIsUnexpected returns whether the probe failed a DNS lookup
+// before following redirects while the control succeeded. This function mirrors the
+// rules used by [*WebAnalysis.dnsComputeFailureMetrics] for each observation.
+func utilsDNSLookupFailureIsUnexpected(obs *WebObservation) bool {
+	// DoH failures are about the DoH service and AAAA lookups returning no
+	// addresses are false positives (see dnsComputeFailureMetrics)
+	if utilsDNSEngineIsDNSOverHTTPS(obs) || utilsDNSLookupFailureIsDNSNoAnswerForAAAA(obs) {
+		return false
+	}
+
+	// we only have control information for the 0-th redirect
+	if obs.TagDepth.IsNone() || obs.TagDepth.Unwrap() != 0 {
+		return false
+	}
+
+	// we need the probe to fail and the control to succeed
+	failure := obs.DNSLookupFailure.UnwrapOr("")
+	if failure == "" || obs.ControlDNSLookupFailure.IsNone() || obs.ControlDNSLookupFailure.Unwrap() != "" {
+		return false
+	}
+
+	// the control returning no addresses is equivalent to dns_no_answer
+	if failure == netxlite.FailureDNSNoAnswer {
+		return obs.ControlDNSResolvedAddrs.IsNone() || obs.ControlDNSResolvedAddrs.Unwrap().Len() > 0
+	}
+	return true
+}
+
+// utilsAddByDNSQueryFamily adds the given ID to v4 or v6 depending on whether the DNS
+// query type is A or AAAA. We ignore other query types (e.g., getaddrinfo's ANY).
+func utilsAddByDNSQueryFamily(obs *WebObservation, id int64, v4, v6 *Set[int64]) {
+	switch obs.DNSQueryType.UnwrapOr("") {
+	case "A":
+		v4.Add(id)
+	case "AAAA":
+		v6.Add(id)
+	}
+}
+
+// utilsAddByEndpointFamily adds the given ID to v4 or v6 depending on the
+// address family of the endpoint IP address, if it is valid.
+func utilsAddByEndpointFamily(obs *WebObservation, id int64, v4, v6 *Set[int64]) {
+	isv6, err := netxlite.IsIPv6(obs.IPAddress.UnwrapOr(""))
+	switch {
+	case err != nil:
+		// nothing
+	case isv6:
+		v6.Add(id)
+	default:
+		v4.Add(id)
+	}
+}
+
 // utilsTCPConnectFailureSeemsMisconfiguredIPv6 returns whether IPv6 seems to be
 // misconfigured for this specific TCP connect attempt.
 //
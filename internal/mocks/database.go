@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -24,6 +25,15 @@ type Database struct {
 	MockListResults        func() ([]model.DatabaseResultNetwork, []model.DatabaseResultNetwork, error)
 	MockListMeasurements   func(resultID int64) ([]model.DatabaseMeasurementURLNetwork, error)
 	MockGetMeasurementJSON func(msmtID int64) (map[string]interface{}, error)
+
+	MockListMeasurementsWithFilter func(
+		filter *model.DatabaseMeasurementFilter) ([]model.DatabaseMeasurementURLNetwork, error)
+
+	MockEnqueueUpload     func(msmt *model.DatabaseMeasurement, failure string, nextRetryAt time.Time) error
+	MockUploadRetryFailed func(
+		entry *model.DatabaseUploadQueueEntry, failure string, nextRetryAt time.Time) error
+	MockUploadRetrySucceeded func(entry *model.DatabaseUploadQueueEntry, reportID string) error
+	MockListDueUploads       func(now time.Time, limit int) ([]model.DatabaseUploadQueueEntry, error)
 }
 
 var _ model.WritableDatabase = &Database{}
@@ -89,6 +99,22 @@ func (d *Database) Failed(msmt *model.DatabaseMeasurement, failure string) error
 	return d.MockFailed(msmt, failure)
 }
 
+// EnqueueUpload calls MockEnqueueUpload
+func (d *Database) EnqueueUpload(msmt *model.DatabaseMeasurement, failure string, nextRetryAt time.Time) error {
+	return d.MockEnqueueUpload(msmt, failure, nextRetryAt)
+}
+
+// UploadRetryFailed calls MockUploadRetryFailed
+func (d *Database) UploadRetryFailed(
+	entry *model.DatabaseUploadQueueEntry, failure string, nextRetryAt time.Time) error {
+	return d.MockUploadRetryFailed(entry, failure, nextRetryAt)
+}
+
+// UploadRetrySucceeded calls MockUploadRetrySucceeded
+func (d *Database) UploadRetrySucceeded(entry *model.DatabaseUploadQueueEntry, reportID string) error {
+	return d.MockUploadRetrySucceeded(entry, reportID)
+}
+
 var _ model.ReadableDatabase = &Database{}
 
 // ListResults calla MockListResults
@@ -105,3 +131,14 @@ func (d *Database) ListMeasurements(resultID int64) ([]model.DatabaseMeasurement
 func (d *Database) GetMeasurementJSON(msmtID int64) (map[string]interface{}, error) {
 	return d.MockGetMeasurementJSON(msmtID)
 }
+
+// ListMeasurementsWithFilter calls MockListMeasurementsWithFilter
+func (d *Database) ListMeasurementsWithFilter(
+	filter *model.DatabaseMeasurementFilter) ([]model.DatabaseMeasurementURLNetwork, error) {
+	return d.MockListMeasurementsWithFilter(filter)
+}
+
+// ListDueUploads calls MockListDueUploads
+func (d *Database) ListDueUploads(now time.Time, limit int) ([]model.DatabaseUploadQueueEntry, error) {
+	return d.MockListDueUploads(now, limit)
+}
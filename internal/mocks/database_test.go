@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -243,4 +244,77 @@ func TestDatabase(t *testing.T) {
 			t.Fatal("not the error we expected")
 		}
 	})
+
+	t.Run("ListMeasurementsWithFilter", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockListMeasurementsWithFilter: func(
+				filter *model.DatabaseMeasurementFilter) ([]model.DatabaseMeasurementURLNetwork, error) {
+				return nil, expected
+			},
+		}
+		msmts, err := db.ListMeasurementsWithFilter(&model.DatabaseMeasurementFilter{})
+		if msmts != nil {
+			t.Fatal("expected nil measurements")
+		}
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
+
+	t.Run("EnqueueUpload", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockEnqueueUpload: func(msmt *model.DatabaseMeasurement, failure string, nextRetryAt time.Time) error {
+				return expected
+			},
+		}
+		err := db.EnqueueUpload(&model.DatabaseMeasurement{}, "", time.Now())
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
+
+	t.Run("UploadRetryFailed", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockUploadRetryFailed: func(
+				entry *model.DatabaseUploadQueueEntry, failure string, nextRetryAt time.Time) error {
+				return expected
+			},
+		}
+		err := db.UploadRetryFailed(&model.DatabaseUploadQueueEntry{}, "", time.Now())
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
+
+	t.Run("UploadRetrySucceeded", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockUploadRetrySucceeded: func(entry *model.DatabaseUploadQueueEntry, reportID string) error {
+				return expected
+			},
+		}
+		err := db.UploadRetrySucceeded(&model.DatabaseUploadQueueEntry{}, "")
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
+
+	t.Run("ListDueUploads", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockListDueUploads: func(now time.Time, limit int) ([]model.DatabaseUploadQueueEntry, error) {
+				return nil, expected
+			},
+		}
+		entries, err := db.ListDueUploads(time.Now(), 10)
+		if entries != nil {
+			t.Fatal("expected nil entries")
+		}
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
 }
@@ -147,6 +147,45 @@ type WritableDatabase interface {
 	//
 	// Returns a non-nil error if the measurement update failed
 	Failed(msmt *DatabaseMeasurement, failure string) error
+
+	// EnqueueUpload adds the measurement to the upload queue or updates the
+	// existing upload queue entry if the measurement is already queued
+	//
+	// Arguments:
+	//
+	// - msmt is the database measurement we failed to upload
+	//
+	// - failure is the error string to use
+	//
+	// - nextRetryAt is the time after which we should retry uploading
+	//
+	// Returns a non-nil error if the upload queue update failed
+	EnqueueUpload(msmt *DatabaseMeasurement, failure string, nextRetryAt time.Time) error
+
+	// UploadRetryFailed records that we failed again to upload a queued measurement
+	//
+	// Arguments:
+	//
+	// - entry is the upload queue entry to update
+	//
+	// - failure is the error string to use
+	//
+	// - nextRetryAt is the time after which we should retry uploading
+	//
+	// Returns a non-nil error if the upload queue update failed
+	UploadRetryFailed(entry *DatabaseUploadQueueEntry, failure string, nextRetryAt time.Time) error
+
+	// UploadRetrySucceeded records that we uploaded a queued measurement, which
+	// removes the measurement from the upload queue
+	//
+	// Arguments:
+	//
+	// - entry is the upload queue entry to remove
+	//
+	// - reportID is the report ID assigned by the collector
+	//
+	// Returns a non-nil error if the database update failed
+	UploadRetrySucceeded(entry *DatabaseUploadQueueEntry, reportID string) error
 }
 
 // ReadableDatabase only supports reading data.
@@ -175,6 +214,43 @@ type ReadableDatabase interface {
 	//
 	// Returns the measurement JSON or an error
 	GetMeasurementJSON(msmtID int64) (map[string]interface{}, error)
+
+	// ListMeasurementsWithFilter returns all the measurements matching a filter
+	//
+	// Arguments:
+	//
+	// - filter contains the conditions the measurements must satisfy
+	//
+	// Returns the measurements sorted by start time or an error
+	ListMeasurementsWithFilter(filter *DatabaseMeasurementFilter) ([]DatabaseMeasurementURLNetwork, error)
+
+	// ListDueUploads returns the queued measurements we should retry uploading
+	//
+	// Arguments:
+	//
+	// - now is the current time
+	//
+	// - limit is the maximum number of entries to return
+	//
+	// Returns the entries whose next retry time is not after now, sorted
+	// by next retry time, or an error
+	ListDueUploads(now time.Time, limit int) ([]DatabaseUploadQueueEntry, error)
+}
+
+// DatabaseMeasurementFilter contains the conditions used to select measurements. The
+// zero value of this struct is a filter that selects all the available measurements.
+type DatabaseMeasurementFilter struct {
+	// Since OPTIONALLY selects measurements started at or after the given time.
+	Since time.Time
+
+	// Until OPTIONALLY selects measurements started before the given time.
+	Until time.Time
+
+	// TestGroupName OPTIONALLY selects measurements belonging to the given group.
+	TestGroupName string
+
+	// ASN OPTIONALLY selects measurements collected using the given ASN.
+	ASN uint
 }
 
 // ResultNetwork is used to represent the structure made from the JOIN
@@ -258,6 +334,22 @@ type DatabaseResult struct {
 	MeasurementDir string    `db:"measurement_dir"`
 }
 
+// DatabaseUploadQueueEntry is an entry of the queue of measurements
+// we failed to upload and we should retry uploading later
+type DatabaseUploadQueueEntry struct {
+	ID            int64          `db:"upload_queue_id,omitempty"`
+	MeasurementID int64          `db:"measurement_id"`
+	Attempts      int64          `db:"upload_attempts"`
+	LastError     sql.NullString `db:"upload_last_error,omitempty"`
+	NextRetryAt   time.Time      `db:"upload_next_retry_at"`
+	EnqueuedAt    time.Time      `db:"upload_enqueued_at"`
+
+	// The following fields come from the measurements table and are
+	// only filled when reading the entries using ListDueUploads
+	ResultID            int64          `db:"result_id,omitempty"`
+	MeasurementFilePath sql.NullString `db:"measurement_file_path,omitempty"`
+}
+
 // PerformanceTestKeys is the result summary for a performance test
 type PerformanceTestKeys struct {
 	Upload   float64 `json:"upload"`
@@ -15,3 +15,16 @@ type KeyValueStore interface {
 	// whether the operation was successful or not.
 	Set(key string, value []byte) (err error)
 }
+
+// KeyValueStoreUpdater is a [KeyValueStore] that can also atomically update the
+// value of a key. Code performing read-modify-write cycles that may run concurrently
+// with other processes should check whether a [KeyValueStore] implements this interface.
+type KeyValueStoreUpdater interface {
+	KeyValueStore
+
+	// Update atomically replaces the value of the given key with the value
+	// returned by fn, which receives the current value or nil if there is
+	// no such key. When fn fails, Update returns its error and does not
+	// modify the value of the given key.
+	Update(key string, fn func(value []byte) ([]byte, error)) (err error)
+}
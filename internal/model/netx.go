@@ -184,6 +184,11 @@ type HTTPSSvc struct {
 
 	// IPv6 contains the IPv6 hints (which may be empty).
 	IPv6 []string
+
+	// ECHConfigList contains the ECHConfigList inside the HTTPS reply, if
+	// any, including its length prefix (which is the format expected by
+	// the [crypto/tls.Config] EncryptedClientHelloConfigList field).
+	ECHConfigList []byte
 }
 
 // MeasuringNetwork defines the constructors required for implementing OONI experiments. All
@@ -17,6 +17,12 @@ type THRequest struct {
 	// v3.17.x release cycle and possibly also for v3.18.x but we
 	// will eventually enable QUIC for all clients.
 	XQUICEnabled bool `json:"x_quic_enabled"`
+
+	// XTimingEnabled is an extension flag that tells the oohelperd
+	// to include into the response the time spent in each step of
+	// the measurement, which allows the probe to distinguish helper
+	// side slowness from censorship-induced slowness.
+	XTimingEnabled bool `json:"x_timing_enabled,omitempty"`
 }
 
 // THTCPConnectResult is the result of the TCP connect
@@ -94,4 +100,31 @@ type THResponse struct {
 	HTTP3Request  *THHTTPRequestResult            `json:"http3_request"` // optional!
 	DNS           THDNSResult                     `json:"dns"`
 	IPInfo        map[string]*THIPInfo            `json:"ip_info,omitempty"`
+	XTiming       *THTiming                       `json:"x_timing,omitempty"` // optional!
+}
+
+// THTiming contains the time spent by the control vantage point in each
+// measurement step. All the durations are expressed in seconds. The test helper
+// only includes this extension field when the request's XTimingEnabled is true.
+type THTiming struct {
+	// DNS is the time spent resolving the domain.
+	DNS float64 `json:"dns"`
+
+	// TCPConnect maps each endpoint to the time spent connecting.
+	TCPConnect map[string]float64 `json:"tcp_connect"`
+
+	// TLSHandshake maps each endpoint to the time spent handshaking.
+	TLSHandshake map[string]float64 `json:"tls_handshake"`
+
+	// QUICHandshake maps each endpoint to the time spent handshaking.
+	QUICHandshake map[string]float64 `json:"quic_handshake"`
+
+	// HTTPRequest is the time spent fetching the webpage.
+	HTTPRequest float64 `json:"http_request"`
+
+	// HTTP3Request is the time spent fetching the webpage using HTTP/3.
+	HTTP3Request float64 `json:"http3_request"`
+
+	// Total is the time spent producing the whole response.
+	Total float64 `json:"total"`
 }
@@ -0,0 +1,88 @@
+package netemx
+
+//
+// DPI rules not provided by netem
+//
+
+import (
+	"github.com/google/gopacket/layers"
+	"github.com/miekg/dns"
+	"github.com/ooni/netem"
+)
+
+// DPIDropDNSQueryForQueryType is a [netem.DPIRule] that drops the DNS-over-UDP
+// queries for a given domain and query type. For example, using [dns.TypeAAAA] allows
+// to emulate networks where only IPv6 lookups are blocked. The zero value is invalid;
+// please fill all the fields marked as MANDATORY.
+type DPIDropDNSQueryForQueryType struct {
+	// Domain is the MANDATORY domain to match.
+	Domain string
+
+	// Logger is the MANDATORY logger.
+	Logger netem.Logger
+
+	// QueryType is the MANDATORY query type to match (e.g., [dns.TypeAAAA]).
+	QueryType uint16
+}
+
+var _ netem.DPIRule = &DPIDropDNSQueryForQueryType{}
+
+// Filter implements netem.DPIRule.
+func (r *DPIDropDNSQueryForQueryType) Filter(
+	direction netem.DPIDirection, packet *netem.DissectedPacket) (*netem.DPIPolicy, bool) {
+	// short circuit for the return path
+	if direction != netem.DPIDirectionClientToServer {
+		return nil, false
+	}
+
+	// short circuit for TCP packets
+	if packet.TransportProtocol() != layers.IPProtocolUDP {
+		return nil, false
+	}
+
+	// short circuit for non-DNS traffic
+	if packet.DestinationPort() != 53 {
+		return nil, false
+	}
+
+	// short circuit in case of misconfiguration
+	if r.Domain == "" {
+		return nil, false
+	}
+
+	// try to parse the DNS request
+	request := &dns.Msg{}
+	if err := request.Unpack(packet.UDP.Payload); err != nil {
+		return nil, false
+	}
+
+	// if the packet is not offending, accept it
+	if len(request.Question) != 1 {
+		return nil, false
+	}
+	question := request.Question[0]
+	if question.Name != dns.CanonicalName(r.Domain) || question.Qtype != r.QueryType {
+		return nil, false
+	}
+
+	r.Logger.Infof(
+		"netem: dpi: dropping traffic for flow %s:%d %s:%d/%s because domain==%s and qtype==%s",
+		packet.SourceIPAddress(),
+		packet.SourcePort(),
+		packet.DestinationIPAddress(),
+		packet.DestinationPort(),
+		packet.TransportProtocol(),
+		question.Name,
+		dns.TypeToString[question.Qtype],
+	)
+
+	// Note: DNS-over-UDP transports use a distinct socket for each query, so
+	// dropping the whole flow only affects the offending query.
+	policy := &netem.DPIPolicy{
+		Delay:   0,
+		Flags:   netem.FrameFlagDrop,
+		PLR:     0,
+		Spoofed: nil,
+	}
+	return policy, true
+}
@@ -0,0 +1,55 @@
+package netemx
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestDPIDropDNSQueryForQueryType(t *testing.T) {
+	env := MustNewScenario(InternetScenario)
+	defer env.Close()
+
+	env.DPIEngine().AddRule(&DPIDropDNSQueryForQueryType{
+		Domain:    "www.example.com",
+		Logger:    log.Log,
+		QueryType: dns.TypeAAAA,
+	})
+
+	env.Do(func() {
+		netx := &netxlite.Netx{}
+		txp := netxlite.NewUnwrappedDNSOverUDPTransport(
+			netx.NewDialerWithoutResolver(log.Log), net.JoinHostPort(AddressDNSGoogle8888, "53"))
+		encoder := &netxlite.DNSEncoderMiekg{}
+
+		t.Run("the A query is not affected", func(t *testing.T) {
+			query := encoder.Encode("www.example.com", dns.TypeA, false)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addrs, err := resp.DecodeLookupHost()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(addrs) != 1 || addrs[0] != AddressWwwExampleCom {
+				t.Fatal("unexpected addrs", addrs)
+			}
+		})
+
+		t.Run("the AAAA query is dropped", func(t *testing.T) {
+			query := encoder.Encode("www.example.com", dns.TypeAAAA, false)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if err == nil || err.Error() != netxlite.FailureGenericTimeoutError {
+				t.Fatal("unexpected error", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response")
+			}
+		})
+	})
+}
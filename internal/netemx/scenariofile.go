@@ -0,0 +1,522 @@
+package netemx
+
+//
+// Declarative scenarios
+//
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/google/gopacket/layers"
+	"github.com/miekg/dns"
+	"github.com/ooni/netem"
+	"gopkg.in/yaml.v3"
+)
+
+// ScenarioFile is the declarative description of a QA environment, which allows
+// reproducing censorship cases without writing Go code. We parse both JSON and YAML
+// files. Here is an example using YAML:
+//
+//	name: tlsBlockingOfExampleCom
+//	input: https://www.example.com/
+//	extends_internet_scenario: true
+//	domains:
+//	  - domains: [www.example.net]
+//	    addresses: [93.184.216.35]
+//	    role: web_server
+//	    server_name_main: www.example.net
+//	    web_server_factory: example
+//	dpi_rules:
+//	  - type: reset_traffic_for_tls_sni
+//	    sni: www.example.com
+//
+// Use [LoadScenarioFile] or [ParseScenarioFile] to obtain a valid instance.
+type ScenarioFile struct {
+	// Name is the OPTIONAL scenario name.
+	Name string `json:"name" yaml:"name"`
+
+	// Description is the OPTIONAL scenario description.
+	Description string `json:"description" yaml:"description"`
+
+	// Input is the OPTIONAL input URL to measure.
+	Input string `json:"input" yaml:"input"`
+
+	// ExtendsInternetScenario OPTIONALLY indicates that we should add the
+	// domains to the ones contained by [InternetScenario].
+	ExtendsInternetScenario bool `json:"extends_internet_scenario" yaml:"extends_internet_scenario"`
+
+	// Domains contains the OPTIONAL domains and addresses to create.
+	Domains []*ScenarioFileDomain `json:"domains" yaml:"domains"`
+
+	// DPIRules contains the OPTIONAL DPI rules to configure.
+	DPIRules []*ScenarioFileDPIRule `json:"dpi_rules" yaml:"dpi_rules"`
+
+	// scenario contains the scenario built by ParseScenarioFile.
+	scenario []*ScenarioDomainAddresses
+
+	// rules contains the DPI rules built by ParseScenarioFile.
+	rules []netem.DPIRule
+}
+
+// ScenarioFileDomain is the declarative version of [ScenarioDomainAddresses].
+type ScenarioFileDomain struct {
+	// Addresses contains the MANDATORY list of addresses belonging to the domain.
+	Addresses []string `json:"addresses" yaml:"addresses"`
+
+	// Domains contains the OPTIONAL list of domains.
+	Domains []string `json:"domains" yaml:"domains"`
+
+	// Role is the MANDATORY role name (e.g., "web_server"). See [ScenarioFileRoles]
+	// for the list of all the available roles.
+	Role string `json:"role" yaml:"role"`
+
+	// ServerNameMain is the MANDATORY server name to use as common name for X.509 certs.
+	ServerNameMain string `json:"server_name_main" yaml:"server_name_main"`
+
+	// ServerNameExtras contains OPTIONAL extra names to also configure into the cert.
+	ServerNameExtras []string `json:"server_name_extras" yaml:"server_name_extras"`
+
+	// WebServerFactory is the name of the factory to use when Role is "web_server". See
+	// [ScenarioFileWebServerFactories] for the list of all the available factories.
+	WebServerFactory string `json:"web_server_factory" yaml:"web_server_factory"`
+}
+
+// ScenarioFileDPIRule is the declarative version of a [netem.DPIRule]. The Type field
+// selects the rule and determines which other fields are MANDATORY.
+type ScenarioFileDPIRule struct {
+	// Type is the MANDATORY rule type (e.g., "spoof_dns_response"). See
+	// [ScenarioFileDPIRuleTypes] for the list of all the available types.
+	Type string `json:"type" yaml:"type"`
+
+	// Addresses contains the addresses to return when spoofing DNS responses.
+	Addresses []string `json:"addresses" yaml:"addresses"`
+
+	// Delay is the extra delay to add when throttling (e.g., "300ms").
+	Delay string `json:"delay" yaml:"delay"`
+
+	// Domain is the domain for which to spoof DNS responses.
+	Domain string `json:"domain" yaml:"domain"`
+
+	// HTTPResponseBody is the body of the blockpage to spoof. When empty, we
+	// use [Blockpage] as the blockpage body.
+	HTTPResponseBody string `json:"http_response_body" yaml:"http_response_body"`
+
+	// PLR is the packet loss rate to use when throttling.
+	PLR float64 `json:"plr" yaml:"plr"`
+
+	// QueryType is the DNS query type to match (e.g., "AAAA").
+	QueryType string `json:"query_type" yaml:"query_type"`
+
+	// ServerIPAddress is the server IP address.
+	ServerIPAddress string `json:"server_ip_address" yaml:"server_ip_address"`
+
+	// ServerPort is the server port.
+	ServerPort uint16 `json:"server_port" yaml:"server_port"`
+
+	// ServerProtocol is the server protocol (either "tcp" or "udp").
+	ServerProtocol string `json:"server_protocol" yaml:"server_protocol"`
+
+	// SNI is the TLS SNI to match.
+	SNI string `json:"sni" yaml:"sni"`
+
+	// String is the string to match in the packet payload.
+	String string `json:"string" yaml:"string"`
+}
+
+// ScenarioFileRoles maps the role names we use in scenario files to roles.
+var ScenarioFileRoles = map[string]uint64{
+	"public_dns":       ScenarioRolePublicDNS,
+	"web_server":       ScenarioRoleWebServer,
+	"ooni_api":         ScenarioRoleOONIAPI,
+	"ubuntu_geoip":     ScenarioRoleUbuntuGeoIP,
+	"ooni_test_helper": ScenarioRoleOONITestHelper,
+	"blockpage_server": ScenarioRoleBlockpageServer,
+	"proxy":            ScenarioRoleProxy,
+	"url_shortener":    ScenarioRoleURLShortener,
+	"bad_ssl":          ScenarioRoleBadSSL,
+}
+
+// ScenarioFileWebServerFactories maps the factory names we use in scenario
+// files to functions creating the corresponding [HTTPHandlerFactory].
+var ScenarioFileWebServerFactories = map[string]func() HTTPHandlerFactory{
+	"blockpage":          BlockpageHandlerFactory,
+	"cloudflare_captcha": CloudflareCAPTCHAHandlerFactory,
+	"example":            ExampleWebPageHandlerFactory,
+	"httpbin":            HTTPBinHandlerFactory,
+	"large_file":         LargeFileHandlerFactory,
+	"yandex":             YandexHandlerFactory,
+}
+
+// ScenarioFileDPIRuleTypes maps the rule types we use in scenario files to
+// functions creating the corresponding [netem.DPIRule].
+var ScenarioFileDPIRuleTypes = map[string]func(rule *ScenarioFileDPIRule) (netem.DPIRule, error){
+	"close_connection_for_server_endpoint": newScenarioFileCloseConnectionForServerEndpoint,
+	"close_connection_for_string":          newScenarioFileCloseConnectionForString,
+	"close_connection_for_tls_sni":         newScenarioFileCloseConnectionForTLSSNI,
+	"drop_dns_query_for_query_type":        newScenarioFileDropDNSQueryForQueryType,
+	"drop_traffic_for_server_endpoint":     newScenarioFileDropTrafficForServerEndpoint,
+	"drop_traffic_for_string":              newScenarioFileDropTrafficForString,
+	"drop_traffic_for_tls_sni":             newScenarioFileDropTrafficForTLSSNI,
+	"reset_traffic_for_string":             newScenarioFileResetTrafficForString,
+	"reset_traffic_for_tls_sni":            newScenarioFileResetTrafficForTLSSNI,
+	"spoof_blockpage_for_string":           newScenarioFileSpoofBlockpageForString,
+	"spoof_dns_response":                   newScenarioFileSpoofDNSResponse,
+	"throttle_traffic_for_tcp_endpoint":    newScenarioFileThrottleTrafficForTCPEndpoint,
+	"throttle_traffic_for_tls_sni":         newScenarioFileThrottleTrafficForTLSSNI,
+}
+
+// ErrInvalidScenarioFile indicates that a scenario file is invalid.
+var ErrInvalidScenarioFile = errors.New("netemx: invalid scenario file")
+
+// LoadScenarioFile reads and parses the scenario file at the given path.
+func LoadScenarioFile(filename string) (*ScenarioFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseScenarioFile(data)
+}
+
+// ParseScenarioFile parses and validates a JSON or YAML scenario file.
+func ParseScenarioFile(data []byte) (*ScenarioFile, error) {
+	var sf ScenarioFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&sf); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidScenarioFile, err.Error())
+	}
+	if sf.ExtendsInternetScenario {
+		sf.scenario = append(sf.scenario, InternetScenario...)
+	}
+	for idx, domain := range sf.Domains {
+		sad, err := domain.newScenarioDomainAddresses()
+		if err != nil {
+			return nil, fmt.Errorf("%w: domains[%d]: %s", ErrInvalidScenarioFile, idx, err.Error())
+		}
+		sf.scenario = append(sf.scenario, sad)
+	}
+	for idx, rule := range sf.DPIRules {
+		factory, found := ScenarioFileDPIRuleTypes[rule.Type]
+		if !found {
+			return nil, fmt.Errorf("%w: dpi_rules[%d]: unknown type: %q", ErrInvalidScenarioFile, idx, rule.Type)
+		}
+		dr, err := factory(rule)
+		if err != nil {
+			return nil, fmt.Errorf("%w: dpi_rules[%d]: %s", ErrInvalidScenarioFile, idx, err.Error())
+		}
+		sf.rules = append(sf.rules, dr)
+	}
+	return &sf, nil
+}
+
+// Scenario returns the scenario to pass to [MustNewScenario].
+func (sf *ScenarioFile) Scenario() []*ScenarioDomainAddresses {
+	return sf.scenario
+}
+
+// Configure adds the DPI rules to the given [*QAEnv].
+func (sf *ScenarioFile) Configure(env *QAEnv) {
+	for _, rule := range sf.rules {
+		env.DPIEngine().AddRule(rule)
+	}
+}
+
+// MustNewScenarioFromFile is like [MustNewScenario] but uses a [*ScenarioFile]
+// to create the scenario and also configures the DPI rules.
+func MustNewScenarioFromFile(sf *ScenarioFile) *QAEnv {
+	env := MustNewScenario(sf.Scenario())
+	sf.Configure(env)
+	return env
+}
+
+func (sfd *ScenarioFileDomain) newScenarioDomainAddresses() (*ScenarioDomainAddresses, error) {
+	role, found := ScenarioFileRoles[sfd.Role]
+	if !found {
+		return nil, fmt.Errorf("unknown role: %q", sfd.Role)
+	}
+	if len(sfd.Addresses) <= 0 {
+		return nil, errors.New("missing addresses")
+	}
+	for _, addr := range sfd.Addresses {
+		if err := scenarioFileValidateAddress(addr); err != nil {
+			return nil, err
+		}
+	}
+	if sfd.ServerNameMain == "" {
+		return nil, errors.New("missing server_name_main")
+	}
+	sad := &ScenarioDomainAddresses{
+		Addresses:        sfd.Addresses,
+		Domains:          sfd.Domains,
+		Role:             role,
+		ServerNameMain:   sfd.ServerNameMain,
+		ServerNameExtras: sfd.ServerNameExtras,
+	}
+	if sad.Domains == nil {
+		sad.Domains = []string{}
+	}
+	if sad.ServerNameExtras == nil {
+		sad.ServerNameExtras = []string{}
+	}
+	switch {
+	case role == ScenarioRoleWebServer:
+		factory, found := ScenarioFileWebServerFactories[sfd.WebServerFactory]
+		if !found {
+			return nil, fmt.Errorf("unknown web_server_factory: %q", sfd.WebServerFactory)
+		}
+		sad.WebServerFactory = factory()
+	case sfd.WebServerFactory != "":
+		return nil, errors.New("web_server_factory requires the web_server role")
+	}
+	return sad, nil
+}
+
+func scenarioFileValidateAddress(addr string) error {
+	if net.ParseIP(addr) == nil {
+		return fmt.Errorf("invalid address: %q", addr)
+	}
+	return nil
+}
+
+func (rule *ScenarioFileDPIRule) validateSNI() error {
+	if rule.SNI == "" {
+		return errors.New("missing sni")
+	}
+	return nil
+}
+
+func (rule *ScenarioFileDPIRule) validateString() error {
+	if rule.String == "" {
+		return errors.New("missing string")
+	}
+	return nil
+}
+
+func (rule *ScenarioFileDPIRule) validateEndpoint() error {
+	if err := scenarioFileValidateAddress(rule.ServerIPAddress); err != nil {
+		return err
+	}
+	if rule.ServerPort == 0 {
+		return errors.New("missing server_port")
+	}
+	return nil
+}
+
+func (rule *ScenarioFileDPIRule) parseDelay() (time.Duration, error) {
+	if rule.Delay == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(rule.Delay)
+}
+
+func (rule *ScenarioFileDPIRule) validatePLR() error {
+	if rule.PLR < 0 || rule.PLR > 1 {
+		return fmt.Errorf("invalid plr: %f", rule.PLR)
+	}
+	return nil
+}
+
+func newScenarioFileCloseConnectionForServerEndpoint(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	return &netem.DPICloseConnectionForServerEndpoint{
+		Logger:          log.Log,
+		ServerIPAddress: rule.ServerIPAddress,
+		ServerPort:      rule.ServerPort,
+	}, nil
+}
+
+func newScenarioFileCloseConnectionForString(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	if err := rule.validateString(); err != nil {
+		return nil, err
+	}
+	return &netem.DPICloseConnectionForString{
+		Logger:          log.Log,
+		ServerIPAddress: rule.ServerIPAddress,
+		ServerPort:      rule.ServerPort,
+		String:          rule.String,
+	}, nil
+}
+
+func newScenarioFileCloseConnectionForTLSSNI(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateSNI(); err != nil {
+		return nil, err
+	}
+	return &netem.DPICloseConnectionForTLSSNI{
+		Logger: log.Log,
+		SNI:    rule.SNI,
+	}, nil
+}
+
+func newScenarioFileDropDNSQueryForQueryType(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if rule.Domain == "" {
+		return nil, errors.New("missing domain")
+	}
+	qtype, found := dns.StringToType[strings.ToUpper(rule.QueryType)]
+	if !found {
+		return nil, fmt.Errorf("invalid query_type: %q", rule.QueryType)
+	}
+	return &DPIDropDNSQueryForQueryType{
+		Domain:    rule.Domain,
+		Logger:    log.Log,
+		QueryType: qtype,
+	}, nil
+}
+
+func newScenarioFileDropTrafficForServerEndpoint(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	var protocol layers.IPProtocol
+	switch rule.ServerProtocol {
+	case "", "tcp":
+		protocol = layers.IPProtocolTCP
+	case "udp":
+		protocol = layers.IPProtocolUDP
+	default:
+		return nil, fmt.Errorf("invalid server_protocol: %q", rule.ServerProtocol)
+	}
+	return &netem.DPIDropTrafficForServerEndpoint{
+		Logger:          log.Log,
+		ServerIPAddress: rule.ServerIPAddress,
+		ServerPort:      rule.ServerPort,
+		ServerProtocol:  protocol,
+	}, nil
+}
+
+func newScenarioFileDropTrafficForString(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	if err := rule.validateString(); err != nil {
+		return nil, err
+	}
+	return &netem.DPIDropTrafficForString{
+		Logger:          log.Log,
+		ServerIPAddress: rule.ServerIPAddress,
+		ServerPort:      rule.ServerPort,
+		String:          rule.String,
+	}, nil
+}
+
+func newScenarioFileDropTrafficForTLSSNI(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateSNI(); err != nil {
+		return nil, err
+	}
+	return &netem.DPIDropTrafficForTLSSNI{
+		Logger: log.Log,
+		SNI:    rule.SNI,
+	}, nil
+}
+
+func newScenarioFileResetTrafficForString(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	if err := rule.validateString(); err != nil {
+		return nil, err
+	}
+	return &netem.DPIResetTrafficForString{
+		Logger:          log.Log,
+		ServerIPAddress: rule.ServerIPAddress,
+		ServerPort:      rule.ServerPort,
+		String:          rule.String,
+	}, nil
+}
+
+func newScenarioFileResetTrafficForTLSSNI(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateSNI(); err != nil {
+		return nil, err
+	}
+	return &netem.DPIResetTrafficForTLSSNI{
+		Logger: log.Log,
+		SNI:    rule.SNI,
+	}, nil
+}
+
+func newScenarioFileSpoofBlockpageForString(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	if err := rule.validateString(); err != nil {
+		return nil, err
+	}
+	body := rule.HTTPResponseBody
+	if body == "" {
+		body = Blockpage
+	}
+	return &netem.DPISpoofBlockpageForString{
+		HTTPResponse:    netem.DPIFormatHTTPResponse([]byte(body)),
+		Logger:          log.Log,
+		ServerIPAddress: rule.ServerIPAddress,
+		ServerPort:      rule.ServerPort,
+		String:          rule.String,
+	}, nil
+}
+
+func newScenarioFileSpoofDNSResponse(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if rule.Domain == "" {
+		return nil, errors.New("missing domain")
+	}
+	// Note: an empty list of addresses means that we should spoof NXDOMAIN.
+	for _, addr := range rule.Addresses {
+		if err := scenarioFileValidateAddress(addr); err != nil {
+			return nil, err
+		}
+	}
+	return &netem.DPISpoofDNSResponse{
+		Addresses: rule.Addresses,
+		Logger:    log.Log,
+		Domain:    rule.Domain,
+	}, nil
+}
+
+func newScenarioFileThrottleTrafficForTCPEndpoint(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateEndpoint(); err != nil {
+		return nil, err
+	}
+	delay, err := rule.parseDelay()
+	if err != nil {
+		return nil, err
+	}
+	if err := rule.validatePLR(); err != nil {
+		return nil, err
+	}
+	return &netem.DPIThrottleTrafficForTCPEndpoint{
+		Delay:           delay,
+		Logger:          log.Log,
+		PLR:             rule.PLR,
+		ServerIPAddress: rule.ServerIPAddress,
+		ServerPort:      rule.ServerPort,
+	}, nil
+}
+
+func newScenarioFileThrottleTrafficForTLSSNI(rule *ScenarioFileDPIRule) (netem.DPIRule, error) {
+	if err := rule.validateSNI(); err != nil {
+		return nil, err
+	}
+	delay, err := rule.parseDelay()
+	if err != nil {
+		return nil, err
+	}
+	if err := rule.validatePLR(); err != nil {
+		return nil, err
+	}
+	return &netem.DPIThrottleTrafficForTLSSNI{
+		Delay:  delay,
+		Logger: log.Log,
+		PLR:    rule.PLR,
+		SNI:    rule.SNI,
+	}, nil
+}
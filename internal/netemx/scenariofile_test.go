@@ -0,0 +1,274 @@
+package netemx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// scenarioFileYAML is a scenario file using YAML.
+const scenarioFileYAML = `
+name: tlsBlocking
+description: resets TLS handshakes for www.example.com
+input: https://www.example.com/
+extends_internet_scenario: true
+domains:
+  - domains: [www.example.net]
+    addresses: [93.184.216.35]
+    role: web_server
+    server_name_main: www.example.net
+    web_server_factory: blockpage
+dpi_rules:
+  - type: reset_traffic_for_tls_sni
+    sni: www.example.com
+  - type: spoof_dns_response
+    domain: www.example.org
+    addresses: [10.10.34.35]
+  - type: throttle_traffic_for_tls_sni
+    sni: largefile.com
+    delay: 300ms
+    plr: 0.1
+`
+
+// scenarioFileJSON is a scenario file using JSON.
+const scenarioFileJSON = `{
+  "name": "tlsBlocking",
+  "domains": [{
+    "domains": ["www.example.com"],
+    "addresses": ["93.184.216.34"],
+    "role": "web_server",
+    "server_name_main": "www.example.com",
+    "web_server_factory": "example"
+  }, {
+    "domains": ["dns.google"],
+    "addresses": ["8.8.8.8"],
+    "role": "public_dns",
+    "server_name_main": "dns.google"
+  }],
+  "dpi_rules": [{
+    "type": "drop_traffic_for_server_endpoint",
+    "server_ip_address": "93.184.216.34",
+    "server_port": 443,
+    "server_protocol": "udp"
+  }]
+}`
+
+func TestParseScenarioFile(t *testing.T) {
+	t.Run("with a valid YAML file", func(t *testing.T) {
+		sf, err := netemx.ParseScenarioFile([]byte(scenarioFileYAML))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sf.Name != "tlsBlocking" || sf.Input != "https://www.example.com/" {
+			t.Fatal("unexpected scenario file", sf.Name, sf.Input)
+		}
+		scenario := sf.Scenario()
+		if len(scenario) != len(netemx.InternetScenario)+1 {
+			t.Fatal("unexpected scenario length", len(scenario))
+		}
+		last := scenario[len(scenario)-1]
+		if last.Role != netemx.ScenarioRoleWebServer || last.WebServerFactory == nil {
+			t.Fatal("unexpected last scenario entry", last)
+		}
+		if diff := cmp.Diff([]string{}, last.ServerNameExtras); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a valid JSON file", func(t *testing.T) {
+		sf, err := netemx.ParseScenarioFile([]byte(scenarioFileJSON))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scenario := sf.Scenario()
+		if len(scenario) != 2 {
+			t.Fatal("unexpected scenario length", len(scenario))
+		}
+		if scenario[1].Role != netemx.ScenarioRolePublicDNS {
+			t.Fatal("unexpected role", scenario[1].Role)
+		}
+	})
+
+	t.Run("with invalid files", func(t *testing.T) {
+		type testcase struct {
+			name   string
+			input  string
+			expect string
+		}
+
+		cases := []testcase{{
+			name:   "with invalid syntax",
+			input:  "{",
+			expect: "netemx: invalid scenario file: yaml:",
+		}, {
+			name:   "with unknown fields",
+			input:  "nonexistent: true\n",
+			expect: "field nonexistent not found",
+		}, {
+			name:   "with unknown role",
+			input:  "domains: [{role: antani, addresses: [1.1.1.1], server_name_main: x}]\n",
+			expect: `domains[0]: unknown role: "antani"`,
+		}, {
+			name:   "with missing addresses",
+			input:  "domains: [{role: proxy, server_name_main: x}]\n",
+			expect: "domains[0]: missing addresses",
+		}, {
+			name:   "with invalid address",
+			input:  "domains: [{role: proxy, addresses: [antani], server_name_main: x}]\n",
+			expect: `domains[0]: invalid address: "antani"`,
+		}, {
+			name:   "with missing server name",
+			input:  "domains: [{role: proxy, addresses: [1.1.1.1]}]\n",
+			expect: "domains[0]: missing server_name_main",
+		}, {
+			name:   "with unknown web server factory",
+			input:  "domains: [{role: web_server, addresses: [1.1.1.1], server_name_main: x, web_server_factory: y}]\n",
+			expect: `domains[0]: unknown web_server_factory: "y"`,
+		}, {
+			name:   "with web server factory and other role",
+			input:  "domains: [{role: proxy, addresses: [1.1.1.1], server_name_main: x, web_server_factory: example}]\n",
+			expect: "domains[0]: web_server_factory requires the web_server role",
+		}, {
+			name:   "with unknown DPI rule type",
+			input:  "dpi_rules: [{type: antani}]\n",
+			expect: `dpi_rules[0]: unknown type: "antani"`,
+		}, {
+			name:   "with missing SNI",
+			input:  "dpi_rules: [{type: reset_traffic_for_tls_sni}]\n",
+			expect: "dpi_rules[0]: missing sni",
+		}, {
+			name:   "with missing string",
+			input:  "dpi_rules: [{type: reset_traffic_for_string, server_ip_address: 1.1.1.1, server_port: 80}]\n",
+			expect: "dpi_rules[0]: missing string",
+		}, {
+			name:   "with missing server port",
+			input:  "dpi_rules: [{type: close_connection_for_server_endpoint, server_ip_address: 1.1.1.1}]\n",
+			expect: "dpi_rules[0]: missing server_port",
+		}, {
+			name:   "with invalid server protocol",
+			input:  "dpi_rules: [{type: drop_traffic_for_server_endpoint, server_ip_address: 1.1.1.1, server_port: 80, server_protocol: sctp}]\n",
+			expect: `dpi_rules[0]: invalid server_protocol: "sctp"`,
+		}, {
+			name:   "with missing domain",
+			input:  "dpi_rules: [{type: spoof_dns_response}]\n",
+			expect: "dpi_rules[0]: missing domain",
+		}, {
+			name:   "with invalid query type",
+			input:  "dpi_rules: [{type: drop_dns_query_for_query_type, domain: x, query_type: antani}]\n",
+			expect: `dpi_rules[0]: invalid query_type: "antani"`,
+		}, {
+			name:   "with invalid delay",
+			input:  "dpi_rules: [{type: throttle_traffic_for_tls_sni, sni: x, delay: antani}]\n",
+			expect: `dpi_rules[0]: time: invalid duration "antani"`,
+		}, {
+			name:   "with invalid PLR",
+			input:  "dpi_rules: [{type: throttle_traffic_for_tls_sni, sni: x, plr: 1.1}]\n",
+			expect: "dpi_rules[0]: invalid plr: 1.100000",
+		}}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				sf, err := netemx.ParseScenarioFile([]byte(tc.input))
+				if !errors.Is(err, netemx.ErrInvalidScenarioFile) {
+					t.Fatal("unexpected error", err)
+				}
+				if !strings.Contains(err.Error(), tc.expect) {
+					t.Fatal("unexpected error string", err.Error())
+				}
+				if sf != nil {
+					t.Fatal("expected nil scenario file")
+				}
+			})
+		}
+	})
+
+	t.Run("we can create all the DPI rules", func(t *testing.T) {
+		for name := range netemx.ScenarioFileDPIRuleTypes {
+			rule := &netemx.ScenarioFileDPIRule{
+				Type:            name,
+				Delay:           "10ms",
+				Domain:          "www.example.com",
+				QueryType:       "AAAA",
+				ServerIPAddress: netemx.AddressWwwExampleCom,
+				ServerPort:      443,
+				SNI:             "www.example.com",
+				String:          "www.example.com",
+			}
+			dr, err := netemx.ScenarioFileDPIRuleTypes[name](rule)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if dr == nil {
+				t.Fatal(name, "expected non-nil rule")
+			}
+		}
+	})
+}
+
+func TestLoadScenarioFile(t *testing.T) {
+	t.Run("when the file does not exist", func(t *testing.T) {
+		sf, err := netemx.LoadScenarioFile(filepath.Join("testdata", "nonexistent.yaml"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("unexpected error", err)
+		}
+		if sf != nil {
+			t.Fatal("expected nil scenario file")
+		}
+	})
+
+	t.Run("we can measure using the loaded scenario", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "scenario.yaml")
+		if err := os.WriteFile(filename, []byte(scenarioFileYAML), 0600); err != nil {
+			t.Fatal(err)
+		}
+		sf, err := netemx.LoadScenarioFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env := netemx.MustNewScenarioFromFile(sf)
+		defer env.Close()
+
+		env.Do(func() {
+			netx := &netxlite.Netx{}
+			client := netxlite.NewHTTPClientStdlib(model.DiscardLogger)
+
+			// the domain added by the scenario file should work
+			req := runtimex.Try1(http.NewRequest("GET", "https://www.example.net/", nil))
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("unexpected status code", resp.StatusCode)
+			}
+
+			// the TLS handshake with www.example.com should be reset
+			req = runtimex.Try1(http.NewRequest("GET", "https://www.example.com/", nil))
+			_, err = client.Do(req)
+			if err == nil || !strings.HasSuffix(err.Error(), netxlite.FailureConnectionReset) {
+				t.Fatal("unexpected error", err)
+			}
+
+			// the DNS response for www.example.org should be spoofed
+			reso := netx.NewStdlibResolver(model.DiscardLogger)
+			addrs, err := reso.LookupHost(context.Background(), "www.example.org")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]string{"10.10.34.35"}, addrs); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+}
@@ -268,6 +268,42 @@ func TestDNSDecoderMiekg(t *testing.T) {
 				if diff := cmp.Diff(v6, reply.IPv6); diff != "" {
 					t.Fatal(diff)
 				}
+				if len(reply.ECHConfigList) != 0 {
+					t.Fatal("expected empty ECHConfigList")
+				}
+			})
+
+			t.Run("with ECHConfigList", func(t *testing.T) {
+				echConfigList := []byte{0x00, 0x02, 0xfe, 0x0d}
+				d := &DNSDecoderMiekg{}
+				queryID := dns.Id()
+				rawQuery := dnsGenQuery(dns.TypeHTTPS, queryID)
+				reply := &dns.Msg{}
+				if err := reply.Unpack(dnsGenHTTPSReplySuccess(rawQuery, nil, []string{"1.1.1.1"}, nil)); err != nil {
+					t.Fatal(err)
+				}
+				answer := reply.Answer[0].(*dns.HTTPS)
+				answer.Value = append(answer.Value, &dns.SVCBECHConfig{ECH: echConfigList})
+				rawResponse, err := reply.Pack()
+				if err != nil {
+					t.Fatal(err)
+				}
+				query := &mocks.DNSQuery{
+					MockID: func() uint16 {
+						return queryID
+					},
+				}
+				resp, err := d.DecodeResponse(rawResponse, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				https, err := resp.DecodeHTTPS()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(echConfigList, https.ECHConfigList); diff != "" {
+					t.Fatal(diff)
+				}
 			})
 		})
 
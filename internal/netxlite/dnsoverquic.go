@@ -0,0 +1,168 @@
+package netxlite
+
+//
+// DNS-over-QUIC transport (RFC 9250)
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/quic-go/quic-go"
+)
+
+// dnsOverQUICALPN is the ALPN used by DNS-over-QUIC according to RFC9250.
+const dnsOverQUICALPN = "doq"
+
+// errDNSMessageTooShort indicates that a DNS message is too short to be valid.
+var errDNSMessageTooShort = errors.New("oodns: DNS message too short")
+
+// DNSOverQUICTransport is a DNS-over-QUIC DNSTransport implementing RFC9250.
+//
+// To construct this type, either manually fill the fields marked as MANDATORY
+// or just use the NewUnwrappedDNSOverQUICTransport factory directly.
+//
+// Note: like DNSOverTCPTransport, this implementation always creates a new
+// connection for each query. This strategy is less efficient but allows us to
+// observe blocking of the QUIC handshake for each query we send.
+type DNSOverQUICTransport struct {
+	// Decoder is the MANDATORY DNSDecoder to use.
+	Decoder model.DNSDecoder
+
+	// Dialer is the MANDATORY QUIC dialer used to create the conn.
+	Dialer model.QUICDialer
+
+	// Endpoint is the MANDATORY server's endpoint (e.g., 94.140.14.14:853).
+	Endpoint string
+
+	// TLSConfig is the OPTIONAL TLS config to use. When the config does not
+	// contain any ALPN, we automatically use "doq". When the config does not
+	// specify a server name, the QUIC dialer will use the endpoint hostname.
+	TLSConfig *tls.Config
+}
+
+// NewUnwrappedDNSOverQUICTransport creates a new DNSOverQUICTransport
+// that has not been wrapped yet.
+//
+// Arguments:
+//
+// - dialer is any type that implements the QUICDialer interface;
+//
+// - address is the endpoint address (e.g., 94.140.14.14:853).
+func NewUnwrappedDNSOverQUICTransport(dialer model.QUICDialer, address string) *DNSOverQUICTransport {
+	return NewUnwrappedDNSOverQUICTransportWithTLSConfig(dialer, address, nil)
+}
+
+// NewUnwrappedDNSOverQUICTransportWithTLSConfig is like NewUnwrappedDNSOverQUICTransport
+// but additionally allows you to specify a custom TLS config (e.g., to override the SNI).
+func NewUnwrappedDNSOverQUICTransportWithTLSConfig(
+	dialer model.QUICDialer, address string, config *tls.Config) *DNSOverQUICTransport {
+	return &DNSOverQUICTransport{
+		Decoder:   &DNSDecoderMiekg{},
+		Dialer:    dialer,
+		Endpoint:  address,
+		TLSConfig: config,
+	}
+}
+
+// RoundTrip sends a query and receives a reply.
+func (t *DNSOverQUICTransport) RoundTrip(
+	ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+	rawQuery, err := query.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(rawQuery) > math.MaxUint16 {
+		return nil, errQueryTooLarge
+	}
+	if len(rawQuery) < 2 {
+		return nil, errDNSMessageTooShort
+	}
+	const iotimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, iotimeout)
+	defer cancel()
+	qconn, err := t.Dialer.DialContext(ctx, t.Endpoint, t.tlsConfig(), &quic.Config{})
+	if err != nil {
+		return nil, err
+	}
+	// The error code zero is DOQ_NO_ERROR according to RFC9250 Sect. 4.3.
+	defer qconn.CloseWithError(0, "")
+	stream, err := qconn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CancelRead(0)
+	stream.SetDeadline(time.Now().Add(iotimeout))
+	// RFC9250 Sect. 4.2.1 says the message ID MUST be zero
+	buf := make([]byte, 2, 2+len(rawQuery))
+	binary.BigEndian.PutUint16(buf, uint16(len(rawQuery)))
+	buf = append(buf, rawQuery...)
+	buf[2], buf[3] = 0, 0
+	if _, err := stream.Write(buf); err != nil {
+		return nil, err
+	}
+	// RFC9250 Sect. 4.2 says the client MUST send the STREAM FIN
+	// after the query to indicate there is no more data to send
+	if err := stream.Close(); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(stream, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header))
+	rawResponse := make([]byte, length)
+	if _, err := io.ReadFull(stream, rawResponse); err != nil {
+		return nil, err
+	}
+	if len(rawResponse) < 2 {
+		return nil, errDNSMessageTooShort
+	}
+	// The server MUST echo back the zero message ID. Once we have checked
+	// that, restore the original ID such that the decoder is happy.
+	if binary.BigEndian.Uint16(rawResponse) != 0 {
+		return nil, dnsDecoderWrapError(ErrDNSReplyWithWrongQueryID)
+	}
+	binary.BigEndian.PutUint16(rawResponse, query.ID())
+	return t.Decoder.DecodeResponse(rawResponse, query)
+}
+
+// tlsConfig returns the TLS config to use for dialing.
+func (t *DNSOverQUICTransport) tlsConfig() *tls.Config {
+	config := &tls.Config{}
+	if t.TLSConfig != nil {
+		config = t.TLSConfig.Clone()
+	}
+	if len(config.NextProtos) <= 0 {
+		config.NextProtos = []string{dnsOverQUICALPN}
+	}
+	return config
+}
+
+// RequiresPadding returns true for DoQ according to RFC9250 Sect. 5.4.
+func (t *DNSOverQUICTransport) RequiresPadding() bool {
+	return true
+}
+
+// Network returns the transport network, i.e., "doq".
+func (t *DNSOverQUICTransport) Network() string {
+	return "doq"
+}
+
+// Address returns the upstream server endpoint (e.g., "94.140.14.14:853").
+func (t *DNSOverQUICTransport) Address() string {
+	return t.Endpoint
+}
+
+// CloseIdleConnections closes idle connections, if any.
+func (t *DNSOverQUICTransport) CloseIdleConnections() {
+	// nothing to do
+}
+
+var _ model.DNSTransport = &DNSOverQUICTransport{}
@@ -0,0 +1,228 @@
+package netxlite
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/miekg/dns"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
+	"github.com/quic-go/quic-go"
+)
+
+func TestDNSOverQUICTransport(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		t.Run("cannot encode query", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			const address = "94.140.14.14:853"
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, address)
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return nil, expected
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("query too large", func(t *testing.T) {
+			const address = "94.140.14.14:853"
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, address)
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, math.MaxUint16+1), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, errQueryTooLarge) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("query too short", func(t *testing.T) {
+			const address = "94.140.14.14:853"
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, address)
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 1), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, errDNSMessageTooShort) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("dial failure", func(t *testing.T) {
+			const address = "94.140.14.14:853"
+			mocked := errors.New("mocked error")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			var gotALPN []string
+			fakedialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					gotALPN = tlsConfig.NextProtos
+					return nil, mocked
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(fakedialer, address)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+			if len(gotALPN) != 1 || gotALPN[0] != "doq" {
+				t.Fatal("unexpected ALPN", gotALPN)
+			}
+		})
+
+		t.Run("open stream failure", func(t *testing.T) {
+			const address = "94.140.14.14:853"
+			mocked := errors.New("mocked error")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			var closed bool
+			fakedialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					return &mocks.QUICEarlyConnection{
+						MockOpenStreamSync: func(ctx context.Context) (quic.Stream, error) {
+							return nil, mocked
+						},
+						MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+							closed = true
+							return nil
+						},
+					}, nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(fakedialer, address)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+			if !closed {
+				t.Fatal("did not close the connection")
+			}
+		})
+
+		t.Run("with a DNS-over-QUIC server", func(t *testing.T) {
+			serverCA := netem.MustNewCA()
+			serverCert := serverCA.MustNewTLSCertificate("dns.example.com")
+
+			newTransport := func(rtx testingx.DNSRoundTripper) (*DNSOverQUICTransport, func()) {
+				listener := testingx.MustNewDNSOverQUICListener(
+					&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+					&testingx.DNSOverUDPListenerStdlib{},
+					&tls.Config{Certificates: []tls.Certificate{*serverCert}},
+					rtx,
+				)
+				netx := &Netx{}
+				dialer := netx.NewQUICDialerWithoutResolver(netx.NewUDPListener(), log.Log)
+				txp := NewUnwrappedDNSOverQUICTransportWithTLSConfig(
+					dialer, listener.LocalAddr().String(), &tls.Config{
+						RootCAs:    serverCA.DefaultCertPool(),
+						ServerName: "dns.example.com",
+					})
+				return txp, func() { listener.Close() }
+			}
+
+			t.Run("success", func(t *testing.T) {
+				dnsConfig := netem.NewDNSConfig()
+				dnsConfig.AddRecord("dns.google", "", "8.8.8.8", "8.8.4.4")
+				txp, cleanup := newTransport(testingx.NewDNSRoundTripperWithDNSConfig(dnsConfig))
+				defer cleanup()
+				encoder := &DNSEncoderMiekg{}
+				query := encoder.Encode("dns.google.", dns.TypeA, txp.RequiresPadding())
+				resp, err := txp.RoundTrip(context.Background(), query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				addrs, err := resp.DecodeLookupHost()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(addrs) != 2 || addrs[0] != "8.8.8.8" || addrs[1] != "8.8.4.4" {
+					t.Fatal("unexpected addrs", addrs)
+				}
+			})
+
+			t.Run("server returns nonzero message ID", func(t *testing.T) {
+				rtx := testingx.DNSRoundTripperFunc(func(ctx context.Context, rawReq []byte) ([]byte, error) {
+					req := &dns.Msg{}
+					if err := req.Unpack(rawReq); err != nil {
+						return nil, err
+					}
+					resp := &dns.Msg{}
+					resp.SetRcode(req, dns.RcodeSuccess)
+					resp.Id = 0xabcd
+					return resp.Pack()
+				})
+				txp, cleanup := newTransport(rtx)
+				defer cleanup()
+				encoder := &DNSEncoderMiekg{}
+				query := encoder.Encode("dns.google.", dns.TypeA, txp.RequiresPadding())
+				resp, err := txp.RoundTrip(context.Background(), query)
+				if !errors.Is(err, ErrDNSReplyWithWrongQueryID) {
+					t.Fatal("unexpected err", err)
+				}
+				if resp != nil {
+					t.Fatal("expected nil resp here")
+				}
+			})
+		})
+	})
+
+	t.Run("other functions okay", func(t *testing.T) {
+		const address = "94.140.14.14:853"
+		txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, address)
+		if txp.RequiresPadding() != true {
+			t.Fatal("invalid RequiresPadding")
+		}
+		if txp.Network() != "doq" {
+			t.Fatal("invalid Network")
+		}
+		if txp.Address() != address {
+			t.Fatal("invalid Address")
+		}
+		txp.CloseIdleConnections()
+	})
+
+	t.Run("tlsConfig does not override the user-provided ALPN", func(t *testing.T) {
+		txp := NewUnwrappedDNSOverQUICTransportWithTLSConfig(
+			&mocks.QUICDialer{}, "94.140.14.14:853", &tls.Config{NextProtos: []string{"dq"}})
+		config := txp.tlsConfig()
+		if len(config.NextProtos) != 1 || config.NextProtos[0] != "dq" {
+			t.Fatal("unexpected ALPN", config.NextProtos)
+		}
+	})
+}
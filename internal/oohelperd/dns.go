@@ -36,6 +36,9 @@ type dnsConfig struct {
 	// Out is the MANDATORY channel where we publish the results.
 	Out chan ctrlDNSResult
 
+	// Timing is the OPTIONAL collector for the per-step timing.
+	Timing *timingCollector
+
 	// Wg is MANDATORY and allows [dnsDo] to synchronize with the caller.
 	Wg *sync.WaitGroup
 }
@@ -65,6 +68,7 @@ func dnsDo(ctx context.Context, config *dnsConfig) {
 	// publish the time required for running this micro-measurement
 	elapsed := time.Since(started)
 	metricDNSTaskDurationSeconds.Observe(elapsed.Seconds())
+	config.Timing.observeDNS(elapsed)
 
 	// make sure we return an empty slice on failure because this
 	// is what the legacy TH would have done.
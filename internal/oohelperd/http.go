@@ -45,12 +45,18 @@ type httpConfig struct {
 	// Out is the MANDATORY channel where we'll post results.
 	Out chan ctrlHTTPResponse
 
+	// Timing is the OPTIONAL collector for the per-step timing.
+	Timing *timingCollector
+
 	// URL is the MANDATORY URL to measure.
 	URL string
 
 	// Wg is MANDATORY and allows synchronizing with parent.
 	Wg *sync.WaitGroup
 
+	// http3 is the OPTIONAL flag indicating we're measuring using HTTP/3
+	http3 bool
+
 	// searchForH3 is the OPTIONAL flag to decide whether to inspect Alt-Svc for HTTP/3 discovery
 	searchForH3 bool
 }
@@ -107,6 +113,7 @@ func httpDo(ctx context.Context, config *httpConfig) {
 	// publish the elapsed time required for measuring HTTP
 	elapsed := time.Since(t0)
 	metricHTTPTaskDurationSeconds.Observe(elapsed.Seconds())
+	config.Timing.observeHTTPRequest(config.http3, elapsed)
 
 	// handle the case of failure
 	if err != nil {
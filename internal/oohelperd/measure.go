@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -32,6 +33,12 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 		Logger: config.baseLogger,
 	}
 
+	// take the time before starting to measure
+	started := time.Now()
+
+	// optionally collect the time spent in each step
+	timing := newTimingCollector(creq.XTimingEnabled)
+
 	// parse input for correctness
 	URL, err := url.Parse(creq.HTTPRequest)
 	if err != nil {
@@ -49,6 +56,7 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 			Logger:      logger,
 			NewResolver: config.newResolver,
 			Out:         dnsch,
+			Timing:      timing,
 			Wg:          wg,
 		})
 	}
@@ -95,6 +103,7 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 			NewTSLHandshaker: config.newTLSHandshaker,
 			URLHostname:      URL.Hostname(),
 			Out:              tcpconnch,
+			Timing:           timing,
 			Wg:               wg,
 		})
 	}
@@ -108,8 +117,10 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 		MaxAcceptableBody: config.maxAcceptableBody,
 		NewClient:         config.newHTTPClient,
 		Out:               httpch,
+		Timing:            timing,
 		URL:               creq.HTTPRequest,
 		Wg:                wg,
+		http3:             false,
 		searchForH3:       true,
 	})
 
@@ -136,6 +147,7 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 				NewQUICDialer: config.newQUICDialer,
 				URLHostname:   URL.Hostname(),
 				Out:           quicconnch,
+				Timing:        timing,
 				Wg:            wg,
 			})
 		}
@@ -150,8 +162,10 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 			MaxAcceptableBody: config.maxAcceptableBody,
 			NewClient:         config.newHTTP3Client,
 			Out:               http3ch,
+			Timing:            timing,
 			URL:               "https://" + cresp.HTTPRequest.DiscoveredH3Endpoint,
 			Wg:                wg,
+			http3:             true,
 			searchForH3:       false,
 		})
 		wg.Wait()
@@ -178,5 +192,8 @@ Loop:
 		}
 	}
 
+	// optionally include the per-step timing
+	cresp.XTiming = timing.finish(time.Since(started))
+
 	return cresp, nil
 }
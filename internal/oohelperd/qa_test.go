@@ -3,6 +3,8 @@ package oohelperd_test
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -135,3 +137,90 @@ func TestQAEnableDisableQUIC(t *testing.T) {
 		})
 	}
 }
+
+// TestQATimingExtension ensures that we include the per-step timing only when requested.
+func TestQATimingExtension(t *testing.T) {
+	for _, enableTiming := range []bool{false, true} {
+		t.Run(fmt.Sprintf("with XTimingEnabled=%v", enableTiming), func(t *testing.T) {
+			// create a new testing scenario
+			env := netemx.MustNewScenario(netemx.InternetScenario)
+			defer env.Close()
+
+			// create a new handler with QUIC enabled
+			handler := oohelperd.NewHandler(
+				log.Log,
+				&netxlite.Netx{Underlying: &netxlite.NetemUnderlyingNetworkAdapter{UNet: env.ClientStack}},
+			)
+			handler.EnableQUIC = true
+
+			// create request body
+			reqbody := &model.THRequest{
+				HTTPRequest: "https://www.example.com/",
+				HTTPRequestHeaders: map[string][]string{
+					"Accept-Language": {model.HTTPHeaderAcceptLanguage},
+					"Accept":          {model.HTTPHeaderAccept},
+					"User-Agent":      {model.HTTPHeaderUserAgent},
+				},
+				TCPConnect:     []string{netemx.AddressWwwExampleCom},
+				XQUICEnabled:   true,
+				XTimingEnabled: enableTiming,
+			}
+
+			// create request
+			req := runtimex.Try1(http.NewRequest(
+				"POST",
+				"http://127.0.0.1:8080/",
+				bytes.NewReader(must.MarshalJSON(reqbody)),
+			))
+
+			// invoke the handler
+			resprec := httptest.NewRecorder()
+			handler.ServeHTTP(resprec, req)
+
+			// get the response
+			resp := resprec.Result()
+			defer resp.Body.Close()
+			if resp.StatusCode != 200 {
+				t.Fatal("expected 200 Ok")
+			}
+
+			// parse the response body
+			respbody := runtimex.Try1(netxlite.ReadAllContext(context.Background(), resp.Body))
+			var jsonresp model.THResponse
+			must.UnmarshalJSON(respbody, &jsonresp)
+
+			// when timing is disabled, we should not see the extension field
+			if !enableTiming {
+				if jsonresp.XTiming != nil {
+					t.Fatal("expected nil XTiming")
+				}
+				return
+			}
+
+			// otherwise make sure we have timing for each step
+			timing := jsonresp.XTiming
+			if timing == nil {
+				t.Fatal("expected non-nil XTiming")
+			}
+			if timing.DNS <= 0 {
+				t.Fatal("expected positive DNS timing")
+			}
+			endpoint := net.JoinHostPort(netemx.AddressWwwExampleCom, "443")
+			if timing.TCPConnect[endpoint] <= 0 {
+				t.Fatal("expected positive TCP connect timing")
+			}
+			if timing.TLSHandshake[endpoint] <= 0 {
+				t.Fatal("expected positive TLS handshake timing")
+			}
+			if timing.QUICHandshake[endpoint] <= 0 {
+				t.Fatal("expected positive QUIC handshake timing")
+			}
+			if timing.HTTPRequest <= 0 || timing.HTTP3Request <= 0 {
+				t.Fatal("expected positive HTTP timing")
+			}
+			if timing.Total < timing.DNS+timing.HTTPRequest {
+				t.Fatal("expected total to include DNS and HTTP timing")
+			}
+		})
+	}
+}
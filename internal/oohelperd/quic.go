@@ -48,6 +48,9 @@ type quicConfig struct {
 	// Out is the MANDATORY chan where we'll post the QUIC measurement results.
 	Out chan *quicResult
 
+	// Timing is the OPTIONAL collector for the per-step timing.
+	Timing *timingCollector
+
 	// URLHostname is the MANDATORY URL.Hostname() to use.
 	URLHostname string
 
@@ -86,7 +89,9 @@ func quicDo(ctx context.Context, config *quicConfig) {
 		RootCAs:    nil,
 		ServerName: config.URLHostname,
 	}
+	t0 := time.Now()
 	quicConn, err := dialer.DialContext(ctx, config.Endpoint, tlsConfig, &quic.Config{})
+	config.Timing.observeQUICHandshake(config.Endpoint, time.Since(t0))
 	defer measurexlite.MaybeCloseQUICConn(quicConn)
 	ol.Stop(err)
 
@@ -60,6 +60,9 @@ type tcpTLSConfig struct {
 	// Out is the MANDATORY where we'll post the TCP measurement results.
 	Out chan *tcpResultPair
 
+	// Timing is the OPTIONAL collector for the per-step timing.
+	Timing *timingCollector
+
 	// URLHostname is the MANDATORY URL.Hostname() to use.
 	URLHostname string
 
@@ -111,6 +114,7 @@ func tcpTLSDo(ctx context.Context, config *tcpTLSConfig) {
 	// publish the time required to connect
 	tcpElapsed := time.Since(tcpT0)
 	metricTCPTaskDurationSeconds.Observe(tcpElapsed.Seconds())
+	config.Timing.observeTCPConnect(config.Endpoint, tcpElapsed)
 
 	// make sure we fill the TCP stanza
 	out.TCP.Failure = tcpMapFailure(newfailure(err))
@@ -145,6 +149,7 @@ func tcpTLSDo(ctx context.Context, config *tcpTLSConfig) {
 	// publish time required to handshake
 	tlsElapsed := time.Since(tlsT0)
 	metricTLSTaskDurationSeconds.Observe(tlsElapsed.Seconds())
+	config.Timing.observeTLSHandshake(config.Endpoint, tlsElapsed)
 
 	ol.Stop(err)
 
@@ -0,0 +1,109 @@
+package oohelperd
+
+//
+// Per-step timing breakdown
+//
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// timingCollector collects the time spent in each measurement step. The nil
+// pointer is valid and means that we are not collecting timing information.
+type timingCollector struct {
+	mu sync.Mutex
+	t  model.THTiming
+}
+
+// newTimingCollector returns a new [*timingCollector] when enabled is true
+// and a nil [*timingCollector] (i.e., a no-op collector) otherwise.
+func newTimingCollector(enabled bool) *timingCollector {
+	if !enabled {
+		return nil
+	}
+	return &timingCollector{
+		mu: sync.Mutex{},
+		t: model.THTiming{
+			TCPConnect:    map[string]float64{},
+			TLSHandshake:  map[string]float64{},
+			QUICHandshake: map[string]float64{},
+		},
+	}
+}
+
+// observeDNS records the time spent resolving the domain.
+func (tc *timingCollector) observeDNS(elapsed time.Duration) {
+	if tc != nil {
+		defer tc.mu.Unlock()
+		tc.mu.Lock()
+		tc.t.DNS = elapsed.Seconds()
+	}
+}
+
+// observeTCPConnect records the time spent connecting to the given endpoint.
+func (tc *timingCollector) observeTCPConnect(endpoint string, elapsed time.Duration) {
+	if tc != nil {
+		defer tc.mu.Unlock()
+		tc.mu.Lock()
+		tc.t.TCPConnect[endpoint] = elapsed.Seconds()
+	}
+}
+
+// observeTLSHandshake records the time spent handshaking with the given endpoint.
+func (tc *timingCollector) observeTLSHandshake(endpoint string, elapsed time.Duration) {
+	if tc != nil {
+		defer tc.mu.Unlock()
+		tc.mu.Lock()
+		tc.t.TLSHandshake[endpoint] = elapsed.Seconds()
+	}
+}
+
+// observeQUICHandshake records the time spent handshaking with the given endpoint.
+func (tc *timingCollector) observeQUICHandshake(endpoint string, elapsed time.Duration) {
+	if tc != nil {
+		defer tc.mu.Unlock()
+		tc.mu.Lock()
+		tc.t.QUICHandshake[endpoint] = elapsed.Seconds()
+	}
+}
+
+// observeHTTPRequest records the time spent fetching the webpage.
+func (tc *timingCollector) observeHTTPRequest(http3 bool, elapsed time.Duration) {
+	if tc != nil {
+		defer tc.mu.Unlock()
+		tc.mu.Lock()
+		if http3 {
+			tc.t.HTTP3Request = elapsed.Seconds()
+			return
+		}
+		tc.t.HTTPRequest = elapsed.Seconds()
+	}
+}
+
+// finish returns a copy of the collected timing information using the given
+// total elapsed time or nil if we are not collecting timing information.
+func (tc *timingCollector) finish(total time.Duration) *model.THTiming {
+	if tc == nil {
+		return nil
+	}
+	defer tc.mu.Unlock()
+	tc.mu.Lock()
+	out := tc.t
+	out.TCPConnect = copyTimingMap(tc.t.TCPConnect)
+	out.TLSHandshake = copyTimingMap(tc.t.TLSHandshake)
+	out.QUICHandshake = copyTimingMap(tc.t.QUICHandshake)
+	out.Total = total.Seconds()
+	return &out
+}
+
+// copyTimingMap returns a copy of the given map.
+func copyTimingMap(in map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(in))
+	for key, value := range in {
+		out[key] = value
+	}
+	return out
+}
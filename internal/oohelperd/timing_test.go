@@ -0,0 +1,58 @@
+package oohelperd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestTimingCollector(t *testing.T) {
+	t.Run("when disabled", func(t *testing.T) {
+		tc := newTimingCollector(false)
+		if tc != nil {
+			t.Fatal("expected nil collector")
+		}
+
+		// make sure all the methods are nil safe
+		tc.observeDNS(time.Second)
+		tc.observeTCPConnect("8.8.8.8:443", time.Second)
+		tc.observeTLSHandshake("8.8.8.8:443", time.Second)
+		tc.observeQUICHandshake("8.8.8.8:443", time.Second)
+		tc.observeHTTPRequest(false, time.Second)
+		tc.observeHTTPRequest(true, time.Second)
+		if out := tc.finish(time.Second); out != nil {
+			t.Fatal("expected nil timing")
+		}
+	})
+
+	t.Run("when enabled", func(t *testing.T) {
+		tc := newTimingCollector(true)
+		tc.observeDNS(100 * time.Millisecond)
+		tc.observeTCPConnect("8.8.8.8:443", 200*time.Millisecond)
+		tc.observeTLSHandshake("8.8.8.8:443", 300*time.Millisecond)
+		tc.observeQUICHandshake("8.8.8.8:443", 400*time.Millisecond)
+		tc.observeHTTPRequest(false, 500*time.Millisecond)
+		tc.observeHTTPRequest(true, 600*time.Millisecond)
+		expect := &model.THTiming{
+			DNS:           0.1,
+			TCPConnect:    map[string]float64{"8.8.8.8:443": 0.2},
+			TLSHandshake:  map[string]float64{"8.8.8.8:443": 0.3},
+			QUICHandshake: map[string]float64{"8.8.8.8:443": 0.4},
+			HTTPRequest:   0.5,
+			HTTP3Request:  0.6,
+			Total:         1,
+		}
+		got := tc.finish(time.Second)
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// make sure that finish returns a copy
+		tc.observeTCPConnect("8.8.4.4:443", time.Second)
+		if len(got.TCPConnect) != 1 {
+			t.Fatal("expected finish to return a copy")
+		}
+	})
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/humanize"
+	"github.com/ooni/probe-cli/v3/internal/logx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -147,6 +148,7 @@ func (ed *Experiment) newInputProcessor(experiment model.Experiment,
 		Experiment: &experimentWrapper{
 			child:  NewInputProcessorExperimentWrapper(experiment),
 			logger: ed.Session.Logger(),
+			name:   ed.Name,
 			total:  len(inputList),
 		},
 		Inputs:     inputList,
@@ -238,12 +240,18 @@ type experimentWrapper struct {
 	// logger is the logger to use
 	logger model.Logger
 
+	// name is the experiment name
+	name string
+
 	// total is the total number of inputs
 	total int
 }
 
 func (ew *experimentWrapper) MeasureAsync(
 	ctx context.Context, input string, idx int) (<-chan *model.Measurement, error) {
+	// Note: we always announce the measurement such that structured log handlers
+	// learn which measurement the following log entries belong to
+	logx.MeasurementStart(ew.logger, ew.name, idx, input)
 	if input != "" {
 		ew.logger.Infof("[%d/%d] running with input: %s", idx+1, ew.total, input)
 	}
@@ -29,12 +29,20 @@ type LinkConfig struct {
 	// MaxRuntime is the OPTIONAL maximum runtime in seconds.
 	MaxRuntime int64
 
+	// MeteredNetwork OPTIONALLY indicates we're using a metered (e.g., mobile)
+	// network, which causes us to skip OONI Run v2 nettests asking for that.
+	MeteredNetwork bool
+
 	// NoCollector OPTIONALLY indicates we should not be using any collector.
 	NoCollector bool
 
 	// NoJSON OPTIONALLY indicates we don't want to save measurements to a JSON file.
 	NoJSON bool
 
+	// ProxyActive OPTIONALLY indicates we're using a proxy or a tunnel, which
+	// causes us to skip OONI Run v2 nettests asking for that.
+	ProxyActive bool
+
 	// Random OPTIONALLY indicates we should randomize inputs.
 	Random bool
 
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"sync/atomic"
+	"time"
 
 	"github.com/hexops/gotextdiff"
 	"github.com/hexops/gotextdiff/myers"
@@ -28,6 +29,10 @@ var (
 	// v2CountFailedExperiments countes the number of failed experiments
 	// and is useful when testing this package
 	v2CountFailedExperiments = &atomic.Int64{}
+
+	// v2CountSkippedNettests counts the number of nettests we skipped
+	// because of their schedule or of the network conditions.
+	v2CountSkippedNettests = &atomic.Int64{}
 )
 
 // V2Descriptor describes a list of nettests to run together.
@@ -50,26 +55,57 @@ type V2Nettest struct {
 	// Inputs contains inputs for the experiment.
 	Inputs []string `json:"inputs"`
 
+	// MaxRuntime is the OPTIONAL maximum runtime in seconds. When both this
+	// field and LinkConfig.MaxRuntime are positive, we use the smaller value.
+	MaxRuntime int64 `json:"max_runtime,omitempty"`
+
 	// Options contains the experiment options. Any option name starting with
 	// `Safe` will be available for the experiment run, but omitted from
 	// the serialized Measurement that the experiment builder will submit
 	// to the OONI backend.
 	Options map[string]any `json:"options"`
 
+	// RepeatEvery is the OPTIONAL minimum interval in seconds between two
+	// runs of this nettest. When positive, we skip the nettest if it ran
+	// successfully less than RepeatEvery seconds ago, such that running
+	// the descriptor periodically (e.g., using autorun) honours it.
+	RepeatEvery int64 `json:"repeat_every,omitempty"`
+
+	// SkipOnMeteredNetwork OPTIONALLY indicates that we should not run
+	// this nettest when we're using a metered (e.g., mobile) network.
+	SkipOnMeteredNetwork bool `json:"skip_on_metered_network,omitempty"`
+
+	// SkipWithProxy OPTIONALLY indicates that we should not run this
+	// nettest when we're using a proxy or a tunnel.
+	SkipWithProxy bool `json:"skip_with_proxy,omitempty"`
+
 	// TestName contains the nettest name.
 	TestName string `json:"test_name"`
 }
 
 // getV2DescriptorFromHTTPSURL GETs a v2Descriptor instance from
-// a static URL (e.g., from a GitHub repo or from a Gist).
+// a static URL (e.g., from a GitHub repo or from a Gist). This function
+// also returns the raw bytes, which we need to verify signatures.
 func getV2DescriptorFromHTTPSURL(ctx context.Context, client model.HTTPClient,
-	logger model.Logger, URL string) (*V2Descriptor, error) {
-	return httpclientx.GetJSON[*V2Descriptor](ctx, URL, &httpclientx.Config{
+	logger model.Logger, URL string) (*V2Descriptor, []byte, error) {
+	raw, err := httpclientx.GetRaw(ctx, URL, &httpclientx.Config{
 		Authorization: "", // not needed
 		Client:        client,
 		Logger:        logger,
 		UserAgent:     model.HTTPHeaderUserAgent,
 	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var desc *V2Descriptor
+	if err := json.Unmarshal(raw, &desc); err != nil {
+		return nil, nil, err
+	}
+	desc, err = httpclientx.NilSafetyErrorIfNil(desc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return desc, raw, nil
 }
 
 // v2DescriptorCache contains all the known v2Descriptor entries.
@@ -134,12 +170,14 @@ func v2DescriptorCacheLoad(fsstore model.KeyValueStore) (*v2DescriptorCache, err
 //
 // - newValue is the new v2Descriptor, which may be nil;
 //
+// - newRaw contains the raw bytes of the new v2Descriptor;
+//
 // - err is the error that occurred, or nil in case of success.
 func (cache *v2DescriptorCache) PullChangesWithoutSideEffects(
 	ctx context.Context, client model.HTTPClient, logger model.Logger,
-	URL string) (oldValue, newValue *V2Descriptor, err error) {
+	URL string) (oldValue, newValue *V2Descriptor, newRaw []byte, err error) {
 	oldValue = cache.Entries[URL]
-	newValue, err = getV2DescriptorFromHTTPSURL(ctx, client, logger, URL)
+	newValue, newRaw, err = getV2DescriptorFromHTTPSURL(ctx, client, logger, URL)
 	return
 }
 
@@ -169,7 +207,13 @@ func V2MeasureDescriptor(ctx context.Context, config *LinkConfig, desc *V2Descri
 
 	logger := config.Session.Logger()
 
-	for _, nettest := range desc.Nettests {
+	// load the schedule, which we only need when some nettest has a repeat interval
+	schedule, err := v2ScheduleLoadIfNeeded(config.KVStore, desc)
+	if err != nil {
+		return err
+	}
+
+	for idx, nettest := range desc.Nettests {
 		// early handling of the case where the test name is empty
 		if nettest.TestName == "" {
 			logger.Warn("oonirun: nettest name cannot be empty")
@@ -177,13 +221,21 @@ func V2MeasureDescriptor(ctx context.Context, config *LinkConfig, desc *V2Descri
 			continue
 		}
 
+		// skip the nettest if the schedule or the network conditions say so
+		scheduleKey := v2ScheduleEntryKey(desc, idx, nettest)
+		if reason := v2NettestSkipReason(config, schedule, scheduleKey, nettest); reason != "" {
+			logger.Infof("oonirun: skipping %s: %s", nettest.TestName, reason)
+			v2CountSkippedNettests.Add(1)
+			continue
+		}
+
 		// construct an experiment from the current nettest
 		exp := &Experiment{
 			Annotations:            config.Annotations,
 			ExtraOptions:           nettest.Options,
 			Inputs:                 nettest.Inputs,
 			InputFilePaths:         nil,
-			MaxRuntime:             config.MaxRuntime,
+			MaxRuntime:             v2MaxRuntime(config.MaxRuntime, nettest.MaxRuntime),
 			Name:                   nettest.TestName,
 			NoCollector:            config.NoCollector,
 			NoJSON:                 config.NoJSON,
@@ -203,11 +255,102 @@ func V2MeasureDescriptor(ctx context.Context, config *LinkConfig, desc *V2Descri
 			v2CountFailedExperiments.Add(1)
 			continue
 		}
+
+		// remember when we ran nettests having a repeat interval
+		if nettest.RepeatEvery > 0 {
+			if err := schedule.Update(config.KVStore, scheduleKey, time.Now()); err != nil {
+				logger.Warnf("oonirun: cannot update schedule: %s", err.Error())
+			}
+		}
 	}
 
 	return nil
 }
 
+// v2MaxRuntime returns the max runtime to use given the one configured
+// by the user and the one configured by the nettest.
+func v2MaxRuntime(configured, nettest int64) int64 {
+	if nettest > 0 && (configured <= 0 || nettest < configured) {
+		return nettest
+	}
+	return configured
+}
+
+// v2NettestSkipReason returns a nonempty string explaining why we should
+// skip the given nettest, or an empty string if we should run it.
+func v2NettestSkipReason(
+	config *LinkConfig, schedule *v2Schedule, key string, nettest V2Nettest) string {
+	if nettest.SkipOnMeteredNetwork && config.MeteredNetwork {
+		return "we're using a metered network"
+	}
+	if nettest.SkipWithProxy && config.ProxyActive {
+		return "we're using a proxy or a tunnel"
+	}
+	if nettest.RepeatEvery > 0 {
+		interval := time.Duration(nettest.RepeatEvery) * time.Second
+		if lastRun, found := schedule.LastRun[key]; found && time.Since(lastRun) < interval {
+			return fmt.Sprintf("it already ran at %s", lastRun.Format(time.RFC3339))
+		}
+	}
+	return ""
+}
+
+// v2Schedule tracks when we last ran nettests having a repeat interval.
+type v2Schedule struct {
+	// LastRun maps a schedule entry key to the time of the last run.
+	LastRun map[string]time.Time
+}
+
+// v2ScheduleKey is the name of the kvstore2 entry keeping
+// information about the v2Schedule.
+const v2ScheduleKey = "oonirun-v2.schedule"
+
+// v2ScheduleEntryKey returns the key identifying a nettest inside the schedule.
+func v2ScheduleEntryKey(desc *V2Descriptor, idx int, nettest V2Nettest) string {
+	return fmt.Sprintf("%s/%d/%s", desc.Name, idx, nettest.TestName)
+}
+
+// v2ScheduleLoadIfNeeded loads the v2Schedule if any nettest inside the
+// descriptor has a repeat interval and otherwise returns an empty schedule.
+func v2ScheduleLoadIfNeeded(fsstore model.KeyValueStore, desc *V2Descriptor) (*v2Schedule, error) {
+	schedule := &v2Schedule{
+		LastRun: make(map[string]time.Time),
+	}
+	needed := false
+	for _, nettest := range desc.Nettests {
+		needed = needed || nettest.RepeatEvery > 0
+	}
+	if !needed {
+		return schedule, nil
+	}
+
+	// attempt to access the schedule and handle a miss like an empty schedule
+	data, err := fsstore.Get(v2ScheduleKey)
+	if err != nil {
+		if errors.Is(err, kvstore.ErrNoSuchKey) {
+			return schedule, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, schedule); err != nil {
+		return nil, err
+	}
+	if schedule.LastRun == nil {
+		schedule.LastRun = make(map[string]time.Time)
+	}
+	return schedule, nil
+}
+
+// Update updates the given schedule entry and writes back onto the disk.
+//
+// Note: this method modifies schedule and is not safe for concurrent usage.
+func (schedule *v2Schedule) Update(fsstore model.KeyValueStore, key string, lastRun time.Time) error {
+	schedule.LastRun[key] = lastRun
+	data, err := json.Marshal(schedule)
+	runtimex.PanicOnError(err, "json.Marshal failed")
+	return fsstore.Set(v2ScheduleKey, data)
+}
+
 // ErrNeedToAcceptChanges indicates that the user needs to accept
 // changes (i.e., a new or modified set of descriptors) before
 // we can actually run this set of descriptors.
@@ -245,6 +388,12 @@ func v2DescriptorDiff(oldValue, newValue *V2Descriptor, URL string) string {
 // provided config.AcceptChanges, this function will log what has changed
 // and will return with an ErrNeedToAcceptChanges error.
 //
+// When the old or the new author has a key pinned using V2PinAuthorKey,
+// we automatically accept changes whose detached signature verifies and
+// otherwise log what has changed and return an error wrapping either
+// ErrUnsignedDescriptor or ErrInvalidSignature, regardless of the
+// value of config.AcceptChanges.
+//
 // In such a case, the caller SHOULD print additional information
 // explaining how to accept changes and then SHOULD exit 1 or similar.
 func v2MeasureHTTPS(ctx context.Context, config *LinkConfig, URL string) error {
@@ -259,7 +408,7 @@ func v2MeasureHTTPS(ctx context.Context, config *LinkConfig, URL string) error {
 
 	// pull a possibly new descriptor without updating the old descriptor
 	clnt := config.Session.DefaultHTTPClient()
-	oldValue, newValue, err := cache.PullChangesWithoutSideEffects(ctx, clnt, logger, URL)
+	oldValue, newValue, newRaw, err := cache.PullChangesWithoutSideEffects(ctx, clnt, logger, URL)
 	if err != nil {
 		return err
 	}
@@ -267,11 +416,27 @@ func v2MeasureHTTPS(ctx context.Context, config *LinkConfig, URL string) error {
 	// compare the new descriptor to the old descriptor
 	diff := v2DescriptorDiff(oldValue, newValue, URL)
 
-	// possibly stop if configured to ask for permission when accepting changes
-	if !config.AcceptChanges && diff != "" {
-		logger.Warnf("oonirun: %s changed as follows:\n\n%s", URL, diff)
-		logger.Warnf("oonirun: we are not going to run this link until you accept changes")
-		return ErrNeedToAcceptChanges
+	// decide whether to accept the changes, if any
+	if diff != "" {
+		err := v2VerifyChanges(ctx, config, URL, oldValue, newValue, newRaw)
+		switch {
+		case err == nil:
+			logger.Infof("oonirun: %s changed as follows:\n\n%s", URL, diff)
+			logger.Infof("oonirun: accepting changes because the signature is valid")
+
+		case errors.Is(err, errNoPinnedKey):
+			// possibly stop if configured to ask for permission when accepting changes
+			if !config.AcceptChanges {
+				logger.Warnf("oonirun: %s changed as follows:\n\n%s", URL, diff)
+				logger.Warnf("oonirun: we are not going to run this link until you accept changes")
+				return ErrNeedToAcceptChanges
+			}
+
+		default:
+			logger.Warnf("oonirun: %s changed as follows:\n\n%s", URL, diff)
+			logger.Warnf("oonirun: rejecting these changes: %s", err.Error())
+			return err
+		}
 	}
 
 	// in case there are changes, update the descriptor
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -523,3 +524,176 @@ func TestV2DescriptorCacheLoad(t *testing.T) {
 	})
 
 }
+
+func TestV2MaxRuntime(t *testing.T) {
+	type testcase struct {
+		configured int64
+		nettest    int64
+		expect     int64
+	}
+
+	testcases := []testcase{
+		{configured: 0, nettest: 0, expect: 0},
+		{configured: 90, nettest: 0, expect: 90},
+		{configured: 0, nettest: 30, expect: 30},
+		{configured: 90, nettest: 30, expect: 30},
+		{configured: 30, nettest: 90, expect: 30},
+	}
+
+	for _, tc := range testcases {
+		if got := v2MaxRuntime(tc.configured, tc.nettest); got != tc.expect {
+			t.Fatal("for", tc.configured, tc.nettest, "expected", tc.expect, "got", got)
+		}
+	}
+}
+
+func TestV2MeasureDescriptorSkipsNettests(t *testing.T) {
+	// newConfig creates the config for running the example nettest
+	newConfig := func(kvs model.KeyValueStore) *LinkConfig {
+		return &LinkConfig{
+			KVStore:     kvs,
+			NoCollector: true,
+			NoJSON:      true,
+			Session:     newMinimalFakeSession(),
+		}
+	}
+
+	// measure runs the descriptor and returns how many nettests we skipped
+	measure := func(t *testing.T, config *LinkConfig, descr *V2Descriptor) int64 {
+		previous := v2CountSkippedNettests.Load()
+		if err := V2MeasureDescriptor(context.Background(), config, descr); err != nil {
+			t.Fatal(err)
+		}
+		return v2CountSkippedNettests.Load() - previous
+	}
+
+	t.Run("on metered networks", func(t *testing.T) {
+		descr := &V2Descriptor{
+			Nettests: []V2Nettest{{
+				SkipOnMeteredNetwork: true,
+				TestName:             "example",
+			}, {
+				TestName: "example",
+			}},
+		}
+		config := newConfig(&kvstore.Memory{})
+		if count := measure(t, config, descr); count != 0 {
+			t.Fatal("expected to skip no nettests", count)
+		}
+		config.MeteredNetwork = true
+		if count := measure(t, config, descr); count != 1 {
+			t.Fatal("expected to skip one nettest", count)
+		}
+	})
+
+	t.Run("with a proxy", func(t *testing.T) {
+		descr := &V2Descriptor{
+			Nettests: []V2Nettest{{
+				SkipWithProxy: true,
+				TestName:      "example",
+			}},
+		}
+		config := newConfig(&kvstore.Memory{})
+		config.ProxyActive = true
+		if count := measure(t, config, descr); count != 1 {
+			t.Fatal("expected to skip one nettest", count)
+		}
+	})
+
+	t.Run("with a repeat interval", func(t *testing.T) {
+		descr := &V2Descriptor{
+			Name: "antani",
+			Nettests: []V2Nettest{{
+				RepeatEvery: 3600,
+				TestName:    "example",
+			}, {
+				TestName: "example",
+			}},
+		}
+		kvs := &kvstore.Memory{}
+		config := newConfig(kvs)
+		if count := measure(t, config, descr); count != 0 {
+			t.Fatal("expected to skip no nettests", count)
+		}
+		if count := measure(t, config, descr); count != 1 {
+			t.Fatal("expected to skip one nettest", count)
+		}
+
+		// pretend the nettest ran long ago and make sure we run it again
+		schedule := &v2Schedule{LastRun: map[string]time.Time{}}
+		if err := schedule.Update(kvs, "antani/0/example", time.Now().Add(-2*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if count := measure(t, config, descr); count != 0 {
+			t.Fatal("expected to skip no nettests", count)
+		}
+	})
+
+	t.Run("when we cannot load the schedule", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		descr := &V2Descriptor{
+			Nettests: []V2Nettest{{
+				RepeatEvery: 3600,
+				TestName:    "example",
+			}},
+		}
+		config := newConfig(&mocks.KeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, expected
+			},
+		})
+		if err := V2MeasureDescriptor(context.Background(), config, descr); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the schedule is not valid JSON", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		if err := kvs.Set(v2ScheduleKey, []byte("{")); err != nil {
+			t.Fatal(err)
+		}
+		descr := &V2Descriptor{
+			Nettests: []V2Nettest{{
+				RepeatEvery: 3600,
+				TestName:    "example",
+			}},
+		}
+		err := V2MeasureDescriptor(context.Background(), newConfig(kvs), descr)
+		if err == nil || err.Error() != "unexpected end of JSON input" {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestV2DescriptorDiffShowsBudgetsAndConditions(t *testing.T) {
+	oldValue := &V2Descriptor{
+		Nettests: []V2Nettest{{
+			TestName: "example",
+		}},
+	}
+	newValue := &V2Descriptor{
+		Nettests: []V2Nettest{{
+			MaxRuntime:           60,
+			RepeatEvery:          3600,
+			SkipOnMeteredNetwork: true,
+			SkipWithProxy:        true,
+			TestName:             "example",
+		}},
+	}
+	diff := v2DescriptorDiff(oldValue, newValue, "https://example.com/")
+	for _, entry := range []string{
+		`+      "max_runtime": 60,`,
+		`+      "repeat_every": 3600,`,
+		`+      "skip_on_metered_network": true,`,
+		`+      "skip_with_proxy": true,`,
+	} {
+		if !strings.Contains(diff, entry) {
+			t.Fatal("diff does not contain", entry, "\n", diff)
+		}
+	}
+
+	// make sure that descriptors without these fields do not produce a diff
+	if diff := v2DescriptorDiff(oldValue, oldValue, "https://example.com/"); diff != "" {
+		t.Fatal("expected no diff", diff)
+	}
+}
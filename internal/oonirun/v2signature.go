@@ -0,0 +1,183 @@
+package oonirun
+
+//
+// OONI Run v2 descriptor signatures
+//
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/httpclientx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// The signature of a descriptor is detached and lives at the descriptor URL
+// with the `.sig` suffix appended to the path. It contains the base64 encoding
+// of the Ed25519 signature of the raw bytes of the descriptor. We verify it
+// using the key pinned for the descriptor author inside the trust store.
+
+var (
+	// ErrUnsignedDescriptor indicates that a descriptor whose author has a pinned
+	// key changed but we could not fetch its signature.
+	ErrUnsignedDescriptor = errors.New("oonirun: descriptor is not signed")
+
+	// ErrInvalidSignature indicates that a descriptor whose author has a pinned
+	// key changed but its signature does not verify.
+	ErrInvalidSignature = errors.New("oonirun: invalid descriptor signature")
+
+	// errNoPinnedKey indicates that no author involved in a change has a pinned
+	// key, such that we need to fallback to asking the user to accept changes.
+	errNoPinnedKey = errors.New("oonirun: no pinned key")
+)
+
+// v2TrustStore contains the pinned Ed25519 public keys of the authors
+// of OONI Run v2 descriptors, indexed by author.
+type v2TrustStore struct {
+	// Keys maps each author to its pinned public key.
+	Keys map[string][]byte
+}
+
+// v2TrustStoreKey is the name of the kvstore2 entry containing the v2TrustStore.
+const v2TrustStoreKey = "oonirun-v2.trust"
+
+// v2TrustStoreLoad loads the v2TrustStore.
+func v2TrustStoreLoad(fsstore model.KeyValueStore) (*v2TrustStore, error) {
+	store := &v2TrustStore{
+		Keys: make(map[string][]byte),
+	}
+	data, err := fsstore.Get(v2TrustStoreKey)
+	if err != nil {
+		if errors.Is(err, kvstore.ErrNoSuchKey) {
+			return store, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, err
+	}
+	if store.Keys == nil {
+		store.Keys = make(map[string][]byte)
+	}
+	return store, nil
+}
+
+// write writes the v2TrustStore back onto the disk.
+func (store *v2TrustStore) write(fsstore model.KeyValueStore) error {
+	data, err := json.Marshal(store)
+	runtimex.PanicOnError(err, "json.Marshal failed")
+	return fsstore.Set(v2TrustStoreKey, data)
+}
+
+// ErrInvalidAuthorKey indicates that an author key is not a valid Ed25519 public key.
+var ErrInvalidAuthorKey = errors.New("oonirun: invalid author key")
+
+// V2PinAuthorKey pins the given Ed25519 public key for the given author. From now
+// on, we automatically accept changes to descriptors from this author when they
+// are correctly signed and we reject changes that are not signed or whose
+// signature does not verify, even when LinkConfig.AcceptChanges is true.
+func V2PinAuthorKey(fsstore model.KeyValueStore, author string, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return ErrInvalidAuthorKey
+	}
+	store, err := v2TrustStoreLoad(fsstore)
+	if err != nil {
+		return err
+	}
+	store.Keys[author] = key
+	return store.write(fsstore)
+}
+
+// V2UnpinAuthorKey removes the pinned key of the given author, if any.
+func V2UnpinAuthorKey(fsstore model.KeyValueStore, author string) error {
+	store, err := v2TrustStoreLoad(fsstore)
+	if err != nil {
+		return err
+	}
+	delete(store.Keys, author)
+	return store.write(fsstore)
+}
+
+// v2SignatureURL returns the URL of the detached signature of the descriptor at URL.
+func v2SignatureURL(URL string) (string, error) {
+	parsed, err := url.Parse(URL)
+	if err != nil {
+		return "", err
+	}
+	parsed.Path += ".sig"
+	parsed.RawPath = ""
+	return parsed.String(), nil
+}
+
+// getV2DescriptorSignature GETs the detached signature of the descriptor at URL.
+func getV2DescriptorSignature(ctx context.Context, client model.HTTPClient,
+	logger model.Logger, URL string) ([]byte, error) {
+	sigURL, err := v2SignatureURL(URL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := httpclientx.GetRaw(ctx, sigURL, &httpclientx.Config{
+		Authorization: "", // not needed
+		Client:        client,
+		Logger:        logger,
+		UserAgent:     model.HTTPHeaderUserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+}
+
+// v2VerifyChanges verifies the changes from oldValue to newValue, where raw contains
+// the raw bytes of newValue. This function returns nil when the signature verifies,
+// errNoPinnedKey when no involved author has a pinned key, and an error wrapping
+// either ErrUnsignedDescriptor or ErrInvalidSignature otherwise.
+func v2VerifyChanges(ctx context.Context, config *LinkConfig, URL string,
+	oldValue, newValue *V2Descriptor, raw []byte) error {
+	store, err := v2TrustStoreLoad(config.KVStore)
+	if err != nil {
+		return err
+	}
+
+	// figure out whether the old and the new authors have pinned keys
+	var oldKey, newKey []byte
+	if oldValue != nil {
+		oldKey = store.Keys[oldValue.Author]
+	}
+	if newValue != nil {
+		newKey = store.Keys[newValue.Author]
+	}
+	if oldKey == nil && newKey == nil {
+		return errNoPinnedKey
+	}
+
+	// refuse to let a pinned author be replaced by an author without a pinned key
+	if newKey == nil {
+		return fmt.Errorf("%w: the new author has no pinned key", ErrInvalidSignature)
+	}
+
+	// make sure we're not going to panic because of a corrupted trust store
+	if len(newKey) != ed25519.PublicKeySize {
+		return ErrInvalidAuthorKey
+	}
+
+	// fetch and verify the detached signature
+	logger := config.Session.Logger()
+	clnt := config.Session.DefaultHTTPClient()
+	signature, err := getV2DescriptorSignature(ctx, clnt, logger, URL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsignedDescriptor, err.Error())
+	}
+	if !ed25519.Verify(ed25519.PublicKey(newKey), raw, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package oonirun
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// newSignedDescriptorServer returns a server serving the given descriptor at /descr
+// and the given signature at /descr.sig, or 404 if the signature is nil.
+func newSignedDescriptorServer(t *testing.T, descr *V2Descriptor, signer func([]byte) []byte) string {
+	data, err := json.Marshal(descr)
+	runtimex.PanicOnError(err, "json.Marshal failed")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/descr":
+			w.Write(data)
+		case "/descr.sig":
+			if signer == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(base64.StdEncoding.EncodeToString(signer(data)) + "\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/descr"
+}
+
+// newSignedDescriptorConfig returns a config using the given key-value store.
+func newSignedDescriptorConfig(kvs *kvstore.Memory, acceptChanges bool) *LinkConfig {
+	return &LinkConfig{
+		AcceptChanges: acceptChanges,
+		KVStore:       kvs,
+		NoCollector:   true,
+		NoJSON:        true,
+		Session:       newMinimalFakeSession(),
+	}
+}
+
+func TestV2MeasureHTTPSWithSignatures(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(data []byte) []byte {
+		return ed25519.Sign(privateKey, data)
+	}
+	signWithOtherKey := func(data []byte) []byte {
+		return ed25519.Sign(otherPrivateKey, data)
+	}
+
+	newDescriptor := func(author string) *V2Descriptor {
+		return &V2Descriptor{
+			Name:   "antani",
+			Author: author,
+			Nettests: []V2Nettest{{
+				TestName: "example",
+			}},
+		}
+	}
+
+	// newPinnedStore returns a key-value store where "ooni" has a pinned key.
+	newPinnedStore := func(t *testing.T) *kvstore.Memory {
+		kvs := &kvstore.Memory{}
+		if err := V2PinAuthorKey(kvs, "ooni", publicKey); err != nil {
+			t.Fatal(err)
+		}
+		return kvs
+	}
+
+	t.Run("without pinned keys we ask to accept changes", func(t *testing.T) {
+		URL := newSignedDescriptorServer(t, newDescriptor("ooni"), sign)
+		config := newSignedDescriptorConfig(&kvstore.Memory{}, false)
+		if err := v2MeasureHTTPS(context.Background(), config, URL); !errors.Is(err, ErrNeedToAcceptChanges) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with a valid signature we accept changes", func(t *testing.T) {
+		URL := newSignedDescriptorServer(t, newDescriptor("ooni"), sign)
+		kvs := newPinnedStore(t)
+		config := newSignedDescriptorConfig(kvs, false)
+		if err := v2MeasureHTTPS(context.Background(), config, URL); err != nil {
+			t.Fatal(err)
+		}
+		cache, err := v2DescriptorCacheLoad(kvs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cache.Entries[URL] == nil {
+			t.Fatal("expected the cache to contain the descriptor")
+		}
+	})
+
+	t.Run("without a signature we reject changes", func(t *testing.T) {
+		URL := newSignedDescriptorServer(t, newDescriptor("ooni"), nil)
+		config := newSignedDescriptorConfig(newPinnedStore(t), true)
+		if err := v2MeasureHTTPS(context.Background(), config, URL); !errors.Is(err, ErrUnsignedDescriptor) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with an invalid signature we reject changes", func(t *testing.T) {
+		URL := newSignedDescriptorServer(t, newDescriptor("ooni"), signWithOtherKey)
+		kvs := newPinnedStore(t)
+		config := newSignedDescriptorConfig(kvs, true)
+		if err := v2MeasureHTTPS(context.Background(), config, URL); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatal("unexpected error", err)
+		}
+		cache, err := v2DescriptorCacheLoad(kvs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cache.Entries) != 0 {
+			t.Fatal("expected the cache to be empty")
+		}
+	})
+
+	t.Run("we reject changes replacing a pinned author", func(t *testing.T) {
+		URL := newSignedDescriptorServer(t, newDescriptor("mallory"), signWithOtherKey)
+		kvs := newPinnedStore(t)
+		cache, err := v2DescriptorCacheLoad(kvs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.Update(kvs, URL, newDescriptor("ooni")); err != nil {
+			t.Fatal(err)
+		}
+		config := newSignedDescriptorConfig(kvs, true)
+		if err := v2MeasureHTTPS(context.Background(), config, URL); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with a corrupted trust store", func(t *testing.T) {
+		URL := newSignedDescriptorServer(t, newDescriptor("ooni"), sign)
+		kvs := &kvstore.Memory{}
+		if err := kvs.Set(v2TrustStoreKey, []byte(`{"Keys":{"ooni":"AAAA"}}`)); err != nil {
+			t.Fatal(err)
+		}
+		config := newSignedDescriptorConfig(kvs, true)
+		if err := v2MeasureHTTPS(context.Background(), config, URL); !errors.Is(err, ErrInvalidAuthorKey) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestV2PinAuthorKey(t *testing.T) {
+	t.Run("with an invalid key", func(t *testing.T) {
+		err := V2PinAuthorKey(&kvstore.Memory{}, "ooni", ed25519.PublicKey("antani"))
+		if !errors.Is(err, ErrInvalidAuthorKey) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("pin and unpin", func(t *testing.T) {
+		publicKey, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kvs := &kvstore.Memory{}
+		if err := V2PinAuthorKey(kvs, "ooni", publicKey); err != nil {
+			t.Fatal(err)
+		}
+		store, err := v2TrustStoreLoad(kvs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !publicKey.Equal(ed25519.PublicKey(store.Keys["ooni"])) {
+			t.Fatal("the key was not pinned")
+		}
+		if err := V2UnpinAuthorKey(kvs, "ooni"); err != nil {
+			t.Fatal(err)
+		}
+		store, err = v2TrustStoreLoad(kvs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(store.Keys) != 0 {
+			t.Fatal("the key was not unpinned")
+		}
+	})
+
+	t.Run("when we cannot load the trust store", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		kvs := &mocks.KeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, expected
+			},
+		}
+		publicKey, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := V2PinAuthorKey(kvs, "ooni", publicKey); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if err := V2UnpinAuthorKey(kvs, "ooni"); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the trust store is not valid JSON", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		if err := kvs.Set(v2TrustStoreKey, []byte("{")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := v2TrustStoreLoad(kvs); err == nil || err.Error() != "unexpected end of JSON input" {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestV2SignatureURL(t *testing.T) {
+	URL, err := v2SignatureURL("https://example.com/descr.json?x=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if URL != "https://example.com/descr.json.sig?x=1" {
+		t.Fatal("unexpected URL", URL)
+	}
+	if _, err := v2SignatureURL("\t"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
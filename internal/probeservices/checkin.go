@@ -29,5 +29,6 @@ func (c Client) CheckIn(
 
 	// make sure we track selected parts of the response
 	_ = checkincache.Store(c.KVStore, resp)
+	_ = checkincache.StoreResponse(c.KVStore, &config, resp)
 	return resp, nil
 }
@@ -0,0 +1,247 @@
+package ptx
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// WebTunnelDialer is a dialer for WebTunnel. A WebTunnel bridge hides behind
+// a web server and we reach it by performing an HTTP/1.1 upgrade over TLS
+// using a secret path. Once the server accepts the upgrade, the connection
+// carries the tor stream. Make sure you fill all the fields marked as
+// mandatory before using.
+type WebTunnelDialer struct {
+	// Address contains the MANDATORY bridge address. With WebTunnel this
+	// address is a placeholder that tor uses to identify the bridge, since
+	// we actually connect to the host contained in the URL.
+	Address string
+
+	// Fingerprint is the MANDATORY bridge fingerprint.
+	Fingerprint string
+
+	// RootCAs is the OPTIONAL pool of root CAs to use when verifying
+	// the server certificate. If not set, we use the default pool.
+	RootCAs *x509.CertPool
+
+	// ServerName is the OPTIONAL SNI to use. If not set, we use the
+	// hostname contained in the URL.
+	ServerName string
+
+	// URL is the MANDATORY https URL containing the secret path.
+	URL string
+
+	// UnderlyingDialer is the optional underlying dialer to use. If not
+	// set, we will use a dialer using the system resolver.
+	UnderlyingDialer model.SimpleDialer
+
+	// Version is the OPTIONAL version parameter of the bridge line.
+	Version string
+}
+
+var _ PTDialer = &WebTunnelDialer{}
+
+// ErrWebTunnelInvalidBridgeLine indicates that we cannot parse a bridge line.
+var ErrWebTunnelInvalidBridgeLine = errors.New("ptx: invalid webtunnel bridge line")
+
+// ErrWebTunnelInvalidURL indicates that the WebTunnel URL is not valid.
+var ErrWebTunnelInvalidURL = errors.New("ptx: invalid webtunnel URL")
+
+// ErrWebTunnelUpgradeFailed indicates that the server did not accept the upgrade.
+var ErrWebTunnelUpgradeFailed = errors.New("ptx: webtunnel upgrade failed")
+
+// ParseWebTunnelBridgeLine parses a bridge line such as the following:
+//
+//	webtunnel [2001:db8::1]:443 FINGERPRINT url=https://example.com/secret ver=0.0.1
+//
+// where the leading "webtunnel" keyword is optional.
+func ParseWebTunnelBridgeLine(line string) (*WebTunnelDialer, error) {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && fields[0] == "webtunnel" {
+		fields = fields[1:]
+	}
+	if len(fields) < 3 {
+		return nil, ErrWebTunnelInvalidBridgeLine
+	}
+	d := &WebTunnelDialer{
+		Address:     fields[0],
+		Fingerprint: fields[1],
+	}
+	for _, field := range fields[2:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			return nil, ErrWebTunnelInvalidBridgeLine
+		}
+		switch key {
+		case "url":
+			d.URL = value
+		case "ver":
+			d.Version = value
+		default:
+			// ignore unknown parameters
+		}
+	}
+	if _, err := d.parseURL(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DialContext establishes a connection with the given WebTunnel bridge. The
+// context argument allows to interrupt this operation midway.
+func (d *WebTunnelDialer) DialContext(ctx context.Context) (net.Conn, error) {
+	URL, err := d.parseURL()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.underlyingDialer().DialContext(ctx, "tcp", webTunnelEndpoint(URL))
+	if err != nil {
+		return nil, err
+	}
+	tlsConn, err := d.handshake(ctx, conn, URL)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader, err := d.upgrade(ctx, tlsConn, URL)
+	if err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+	return &webTunnelConn{Conn: tlsConn, reader: reader}, nil
+}
+
+// parseURL parses and validates d.URL.
+func (d *WebTunnelDialer) parseURL() (*url.URL, error) {
+	URL, err := url.Parse(d.URL)
+	if err != nil || URL.Scheme != "https" || URL.Hostname() == "" {
+		return nil, ErrWebTunnelInvalidURL
+	}
+	return URL, nil
+}
+
+// webTunnelEndpoint returns the endpoint to connect to for the given URL.
+func webTunnelEndpoint(URL *url.URL) string {
+	port := URL.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(URL.Hostname(), port)
+}
+
+// underlyingDialer returns a suitable SimpleDialer.
+func (d *WebTunnelDialer) underlyingDialer() model.SimpleDialer {
+	if d.UnderlyingDialer != nil {
+		return d.UnderlyingDialer
+	}
+	return netxlite.NewDialerWithStdlibResolver(model.DiscardLogger)
+}
+
+// handshake performs the TLS handshake.
+func (d *WebTunnelDialer) handshake(ctx context.Context, conn net.Conn, URL *url.URL) (net.Conn, error) {
+	serverName := d.ServerName
+	if serverName == "" {
+		serverName = URL.Hostname()
+	}
+	config := &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		RootCAs:    d.RootCAs,
+		ServerName: serverName,
+	}
+	netx := &netxlite.Netx{}
+	thx := netx.NewTLSHandshakerStdlib(model.DiscardLogger)
+	return thx.Handshake(ctx, conn, config)
+}
+
+// upgrade sends the upgrade request and reads the response. On success, it
+// returns the reader to use for reading the rest of the stream.
+func (d *WebTunnelDialer) upgrade(ctx context.Context, conn net.Conn, URL *url.URL) (*bufio.Reader, error) {
+	// make sure the context can interrupt the I/O below
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0)) // unblock any pending I/O
+	})
+	defer func() {
+		if stop() {
+			conn.SetDeadline(time.Time{})
+		}
+	}()
+
+	req, err := d.newUpgradeRequest(URL)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close() // a 101 response has no body
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("%w: %s", ErrWebTunnelUpgradeFailed, resp.Status)
+	}
+	return reader, nil
+}
+
+// newUpgradeRequest creates the HTTP request asking the server to upgrade
+// the connection. We mimic a WebSocket upgrade like the reference client.
+func (d *WebTunnelDialer) newUpgradeRequest(URL *url.URL) (*http.Request, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", URL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	return req, nil
+}
+
+// webTunnelConn is the connection returned by WebTunnelDialer. We need to
+// read from the bufio.Reader because it may contain buffered data.
+type webTunnelConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read implements net.Conn.
+func (c *webTunnelConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// AsBridgeArgument returns the argument to be passed to
+// the tor command line to declare this bridge.
+func (d *WebTunnelDialer) AsBridgeArgument() string {
+	out := fmt.Sprintf("webtunnel %s %s url=%s", d.Address, d.Fingerprint, d.URL)
+	if d.Version != "" {
+		out += fmt.Sprintf(" ver=%s", d.Version)
+	}
+	return out
+}
+
+// Name returns the pluggable transport name.
+func (d *WebTunnelDialer) Name() string {
+	return "webtunnel"
+}
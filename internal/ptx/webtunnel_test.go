@@ -0,0 +1,170 @@
+package ptx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// newWebTunnelServer creates a server that accepts the upgrade on /secret
+// and then echoes back whatever it receives.
+func newWebTunnelServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/secret" || r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		conn, bufrw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		bufrw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+		bufrw.WriteString("Connection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		bufrw.Flush()
+		io.Copy(conn, bufrw)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestWebTunnelDialer(srv *httptest.Server, path string) *WebTunnelDialer {
+	return &WebTunnelDialer{
+		Address:     "[2001:db8::1]:443",
+		Fingerprint: "0123456789ABCDEF0123456789ABCDEF01234567",
+		RootCAs:     srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+		ServerName:  "example.com",
+		URL:         srv.URL + path,
+	}
+}
+
+func TestWebTunnelDialerWorks(t *testing.T) {
+	srv := newWebTunnelServer(t)
+	d := newTestWebTunnelDialer(srv, "/secret")
+	conn, err := d.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("antani")); err != nil {
+		t.Fatal(err)
+	}
+	buffer := make([]byte, 6)
+	if _, err := io.ReadFull(conn, buffer); err != nil {
+		t.Fatal(err)
+	}
+	if string(buffer) != "antani" {
+		t.Fatal("unexpected data", string(buffer))
+	}
+}
+
+func TestWebTunnelDialerWithUpgradeFailure(t *testing.T) {
+	srv := newWebTunnelServer(t)
+	d := newTestWebTunnelDialer(srv, "/invalid")
+	conn, err := d.DialContext(context.Background())
+	if !errors.Is(err, ErrWebTunnelUpgradeFailed) {
+		t.Fatal("unexpected error", err)
+	}
+	if conn != nil {
+		t.Fatal("expected nil conn")
+	}
+}
+
+func TestWebTunnelDialerWithTLSFailure(t *testing.T) {
+	srv := newWebTunnelServer(t)
+	d := newTestWebTunnelDialer(srv, "/secret")
+	d.RootCAs = nil // the server certificate is not trusted
+	conn, err := d.DialContext(context.Background())
+	if err == nil || err.Error() != netxlite.FailureSSLUnknownAuthority {
+		t.Fatal("unexpected error", err)
+	}
+	if conn != nil {
+		t.Fatal("expected nil conn")
+	}
+}
+
+func TestWebTunnelDialerWithDialFailure(t *testing.T) {
+	expected := errors.New("mocked error")
+	d := &WebTunnelDialer{
+		URL: "https://example.com/secret",
+		UnderlyingDialer: &mocks.Dialer{
+			MockDialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				if address != "example.com:443" {
+					t.Error("unexpected address", address)
+				}
+				return nil, expected
+			},
+		},
+	}
+	conn, err := d.DialContext(context.Background())
+	if !errors.Is(err, expected) {
+		t.Fatal("unexpected error", err)
+	}
+	if conn != nil {
+		t.Fatal("expected nil conn")
+	}
+}
+
+func TestWebTunnelDialerWithInvalidURL(t *testing.T) {
+	for _, URL := range []string{"", "http://example.com/secret", "https:///secret", "\t"} {
+		d := &WebTunnelDialer{URL: URL}
+		conn, err := d.DialContext(context.Background())
+		if !errors.Is(err, ErrWebTunnelInvalidURL) {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	}
+}
+
+func TestParseWebTunnelBridgeLine(t *testing.T) {
+	type testcase struct {
+		line   string
+		expect string
+		err    error
+	}
+
+	testcases := []testcase{{
+		line:   "webtunnel [2001:db8::1]:443 FP url=https://example.com/secret ver=0.0.1",
+		expect: "webtunnel [2001:db8::1]:443 FP url=https://example.com/secret ver=0.0.1",
+	}, {
+		line:   "[2001:db8::1]:443 FP url=https://example.com/secret antani=1",
+		expect: "webtunnel [2001:db8::1]:443 FP url=https://example.com/secret",
+	}, {
+		line: "webtunnel [2001:db8::1]:443 FP",
+		err:  ErrWebTunnelInvalidBridgeLine,
+	}, {
+		line: "webtunnel [2001:db8::1]:443 FP https://example.com/secret",
+		err:  ErrWebTunnelInvalidBridgeLine,
+	}, {
+		line: "webtunnel [2001:db8::1]:443 FP ver=0.0.1",
+		err:  ErrWebTunnelInvalidURL,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.line, func(t *testing.T) {
+			d, err := ParseWebTunnelBridgeLine(tc.line)
+			if !errors.Is(err, tc.err) {
+				t.Fatal("unexpected error", err)
+			}
+			if err != nil {
+				return
+			}
+			if d.Name() != "webtunnel" {
+				t.Fatal("invalid value returned by d.Name")
+			}
+			if d.AsBridgeArgument() != tc.expect {
+				t.Fatal("unexpected bridge argument", d.AsBridgeArgument())
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package registry
+
+//
+// Registers the `dsl' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/dsl"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	AllExperiments["dsl"] = &Factory{
+		build: func(config interface{}) model.ExperimentMeasurer {
+			return dsl.NewExperimentMeasurer(
+				*config.(*dsl.Config),
+			)
+		},
+		config:           &dsl.Config{},
+		enabledByDefault: true,
+		inputPolicy:      model.InputNone,
+	}
+}
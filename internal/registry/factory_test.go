@@ -423,6 +423,10 @@ func TestNewFactory(t *testing.T) {
 			enabledByDefault: true,
 			inputPolicy:      model.InputOrStaticDefault,
 		},
+		"dsl": {
+			enabledByDefault: true,
+			inputPolicy:      model.InputNone,
+		},
 		"echcheck": {
 			// Note: echcheck is not enabled by default because we just introduced it
 			// into 3.19.0-alpha, which makes it a relatively new experiment.
@@ -530,6 +534,10 @@ func TestNewFactory(t *testing.T) {
 			//enabledByDefault: false,
 			inputPolicy: model.InputNone,
 		},
+		"torwebtunnel": {
+			//enabledByDefault: false,
+			inputPolicy: model.InputNone,
+		},
 		"urlgetter": {
 			enabledByDefault: true,
 			inputPolicy:      model.InputStrictlyRequired,
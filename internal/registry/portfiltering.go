@@ -13,10 +13,10 @@ func init() {
 	AllExperiments["portfiltering"] = &Factory{
 		build: func(config any) model.ExperimentMeasurer {
 			return portfiltering.NewExperimentMeasurer(
-				config.(portfiltering.Config),
+				*config.(*portfiltering.Config),
 			)
 		},
-		config:           portfiltering.Config{},
+		config:           &portfiltering.Config{},
 		enabledByDefault: true,
 		interruptible:    false,
 		inputPolicy:      model.InputNone,
@@ -0,0 +1,23 @@
+package registry
+
+//
+// Registers the `torwebtunnel' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/torwebtunnel"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	AllExperiments["torwebtunnel"] = &Factory{
+		build: func(config interface{}) model.ExperimentMeasurer {
+			return torwebtunnel.NewExperimentMeasurer(
+				*config.(*torwebtunnel.Config),
+			)
+		},
+		config:           &torwebtunnel.Config{},
+		enabledByDefault: false,
+		inputPolicy:      model.InputNone,
+	}
+}
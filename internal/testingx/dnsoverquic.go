@@ -0,0 +1,140 @@
+package testingx
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/quic-go/quic-go"
+)
+
+// DNSOverQUICListener is a DNS-over-QUIC (RFC9250) listener. The zero value of
+// this struct is invalid, please use [MustNewDNSOverQUICListener].
+type DNSOverQUICListener struct {
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	listener  *quic.Listener
+	pconn     net.PacketConn
+	rtx       DNSRoundTripper
+	wg        sync.WaitGroup
+}
+
+// MustNewDNSOverQUICListener creates a new [DNSOverQUICListener] using the given
+// [*net.UDPAddr], [DNSOverUDPUnderlyingListener], [*tls.Config], and [DNSRoundTripper].
+//
+// When the config does not contain any ALPN, we configure "doq".
+func MustNewDNSOverQUICListener(addr *net.UDPAddr, dul DNSOverUDPUnderlyingListener,
+	config *tls.Config, rtx DNSRoundTripper) *DNSOverQUICListener {
+	pconn := runtimex.Try1(dul.ListenUDP("udp", addr))
+	config = config.Clone()
+	if len(config.NextProtos) <= 0 {
+		config.NextProtos = []string{"doq"}
+	}
+	listener := runtimex.Try1(quic.Listen(pconn, config, &quic.Config{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	dl := &DNSOverQUICListener{
+		cancel:    cancel,
+		closeOnce: sync.Once{},
+		listener:  listener,
+		pconn:     pconn,
+		rtx:       rtx,
+		wg:        sync.WaitGroup{},
+	}
+	dl.wg.Add(1)
+	go dl.mainloop(ctx)
+	return dl
+}
+
+// LocalAddr returns the listener address.
+func (dl *DNSOverQUICListener) LocalAddr() net.Addr {
+	return dl.pconn.LocalAddr()
+}
+
+// Close implements io.Closer.
+func (dl *DNSOverQUICListener) Close() (err error) {
+	dl.closeOnce.Do(func() {
+		// cancel the context to interrupt the round tripper and the conns
+		dl.cancel()
+
+		// close the listener to interrupt Accept
+		err = dl.listener.Close()
+
+		// close the underlying conn, which the listener does not own
+		dl.pconn.Close()
+
+		// wait for the background goroutines to join
+		dl.wg.Wait()
+	})
+	return err
+}
+
+func (dl *DNSOverQUICListener) mainloop(ctx context.Context) {
+	// synchronize with Close
+	defer dl.wg.Done()
+
+	for {
+		qconn, err := dl.listener.Accept(ctx)
+		if err != nil {
+			return
+		}
+		dl.wg.Add(1)
+		go dl.handleConn(ctx, qconn)
+	}
+}
+
+func (dl *DNSOverQUICListener) handleConn(ctx context.Context, qconn quic.Connection) {
+	// synchronize with Close
+	defer dl.wg.Done()
+
+	// The error code zero is DOQ_NO_ERROR according to RFC9250 Sect. 4.3.
+	defer qconn.CloseWithError(0, "")
+
+	for {
+		stream, err := qconn.AcceptStream(ctx)
+		if err != nil {
+			return
+		}
+		dl.handleStream(ctx, stream)
+	}
+}
+
+// errDNSOverQUICInvalidMessage indicates we received an invalid message.
+var errDNSOverQUICInvalidMessage = errors.New("testingx: invalid DNS-over-QUIC message")
+
+func (dl *DNSOverQUICListener) handleStream(ctx context.Context, stream quic.Stream) error {
+	defer stream.Close()
+
+	// read the length-prefixed query
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(stream, header); err != nil {
+		return err
+	}
+	rawReq := make([]byte, binary.BigEndian.Uint16(header))
+	if _, err := io.ReadFull(stream, rawReq); err != nil {
+		return err
+	}
+	if len(rawReq) < 2 {
+		return errDNSOverQUICInvalidMessage
+	}
+
+	// perform the round trip
+	rawResp, err := dl.rtx.RoundTrip(ctx, rawReq)
+	if err != nil {
+		return err
+	}
+	if len(rawResp) < 2 || len(rawResp) > 0xffff {
+		return errDNSOverQUICInvalidMessage
+	}
+
+	// write the length-prefixed response
+	buf := make([]byte, 2, 2+len(rawResp))
+	binary.BigEndian.PutUint16(buf, uint16(len(rawResp)))
+	buf = append(buf, rawResp...)
+	_, err = stream.Write(buf)
+	return err
+}
@@ -0,0 +1,116 @@
+package testingx
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/netem"
+	"github.com/quic-go/quic-go"
+)
+
+func TestDNSOverQUICListener(t *testing.T) {
+	serverCA := netem.MustNewCA()
+	serverCert := serverCA.MustNewTLSCertificate("dns.example.com")
+
+	// roundTrip sends a DNS-over-QUIC query for example.com to the given
+	// listener and returns the raw response or an error.
+	roundTrip := func(listener *DNSOverQUICListener) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		qconn, err := quic.DialAddr(ctx, listener.LocalAddr().String(), &tls.Config{
+			NextProtos: []string{"doq"},
+			RootCAs:    serverCA.DefaultCertPool(),
+			ServerName: "dns.example.com",
+		}, &quic.Config{})
+		if err != nil {
+			return nil, err
+		}
+		defer qconn.CloseWithError(0, "")
+		stream, err := qconn.OpenStreamSync(ctx)
+		if err != nil {
+			return nil, err
+		}
+		query := &dns.Msg{}
+		query.SetQuestion("example.com.", dns.TypeA)
+		query.Id = 0
+		rawQuery, err := query.Pack()
+		if err != nil {
+			return nil, err
+		}
+		buf := binary.BigEndian.AppendUint16(nil, uint16(len(rawQuery)))
+		buf = append(buf, rawQuery...)
+		if _, err := stream.Write(buf); err != nil {
+			return nil, err
+		}
+		if err := stream.Close(); err != nil {
+			return nil, err
+		}
+		return io.ReadAll(stream)
+	}
+
+	newListener := func(rtx DNSRoundTripper) *DNSOverQUICListener {
+		return MustNewDNSOverQUICListener(
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+			&DNSOverUDPListenerStdlib{},
+			&tls.Config{Certificates: []tls.Certificate{*serverCert}},
+			rtx,
+		)
+	}
+
+	t.Run("when querying for an existing domain", func(t *testing.T) {
+		config := netem.NewDNSConfig()
+		config.AddRecord("example.com", "", "93.184.216.34")
+		listener := newListener(NewDNSRoundTripperWithDNSConfig(config))
+		defer listener.Close()
+
+		rawResp, err := roundTrip(listener)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rawResp) < 2 || int(binary.BigEndian.Uint16(rawResp)) != len(rawResp)-2 {
+			t.Fatal("invalid length-prefixed response")
+		}
+		resp := &dns.Msg{}
+		if err := resp.Unpack(rawResp[2:]); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Id != 0 {
+			t.Fatal("expected zero message ID")
+		}
+		if len(resp.Answer) != 1 {
+			t.Fatal("expected one answer")
+		}
+	})
+
+	t.Run("when the round tripper fails", func(t *testing.T) {
+		listener := newListener(DNSRoundTripperFunc(func(ctx context.Context, req []byte) ([]byte, error) {
+			return nil, errors.New("mocked error")
+		}))
+		defer listener.Close()
+
+		rawResp, err := roundTrip(listener)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rawResp) != 0 {
+			t.Fatal("expected empty response")
+		}
+	})
+
+	t.Run("we can call Close multiple times", func(t *testing.T) {
+		listener := newListener(NewDNSRoundTripperNXDOMAIN())
+		if err := listener.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := listener.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -52,6 +52,10 @@ type Config struct {
 	// executing. When not set, we execute `tor`.
 	TorBinary string
 
+	// WebTunnelBridge is the bridge line to be used by the
+	// torwebtunnel tunnel, which fails when it's empty.
+	WebTunnelBridge string
+
 	// testExecabsLookPath allows us to mock exeabs.LookPath
 	testExecabsLookPath func(name string) (string, error)
 
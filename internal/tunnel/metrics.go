@@ -0,0 +1,30 @@
+package tunnel
+
+//
+// Metrics definitions
+//
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// metricBootstrapCount counts the tunnel bootstraps by tunnel name and result, where
+	// the result is either "success" or "failure".
+	metricBootstrapCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ooniprobe_tunnel_bootstrap_count",
+		Help: "Total number of tunnel bootstraps by tunnel name and result",
+	}, []string{"tunnel", "result"})
+
+	// metricBootstrapDurationSeconds summarizes the time to bootstrap a tunnel.
+	metricBootstrapDurationSeconds = promauto.NewSummaryVec(prometheus.SummaryOpts{
+		Name: "ooniprobe_tunnel_bootstrap_duration_seconds",
+		Help: "Summarizes the time to successfully bootstrap a tunnel (in seconds)",
+		Objectives: map[float64]float64{
+			0.5:  0.010, // 0.490 <= φ <= 0.510
+			0.9:  0.010, // 0.899 <= φ <= 0.901
+			0.99: 0.001, // 0.989 <= φ <= 0.991
+		},
+	}, []string{"tunnel"})
+)
@@ -54,12 +54,12 @@ func torsfStart(ctx context.Context, config *Config) (Tunnel, DebugInfo, error)
 	return tsft, debugInfo, nil
 }
 
-func (c *Config) sfNewPTXListener(ctx context.Context, sfdialer *ptx.SnowflakeDialer) (out torsfPTXListener) {
+func (c *Config) sfNewPTXListener(ctx context.Context, ptdialer ptx.PTDialer) (out torsfPTXListener) {
 	out = &ptx.Listener{
 		ExperimentByteCounter: nil,
 		ListenSocks:           c.testSfListenSocks,
 		Logger:                c.logger(),
-		PTDialer:              sfdialer,
+		PTDialer:              ptdialer,
 		SessionByteCounter:    bytecounter.ContextSessionByteCounter(ctx),
 	}
 	if c.testSfWrapPTXListener != nil {
@@ -0,0 +1,60 @@
+package tunnel
+
+//
+// torwebtunnel: Tor+WebTunnel tunnel
+//
+
+import (
+	"context"
+
+	"github.com/ooni/probe-cli/v3/internal/ptx"
+)
+
+// torwebtunnelStart starts the torwebtunnel (tor+webtunnel) tunnel
+func torwebtunnelStart(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
+	config.logger().Infof("tunnel: starting webtunnel")
+	if err := ctx.Err(); err != nil {
+		return nil, DebugInfo{}, err
+	}
+
+	// 1. start a listener using webtunnel
+	wtdialer, err := newWebTunnelDialer(config)
+	if err != nil {
+		return nil, DebugInfo{}, err
+	}
+	ptl := config.sfNewPTXListener(ctx, wtdialer)
+	if err := ptl.Start(); err != nil {
+		return nil, DebugInfo{}, err
+	}
+
+	// 2. append arguments to the configuration
+	extraArguments := []string{
+		"UseBridges", "1",
+		"ClientTransportPlugin", ptl.AsClientTransportPluginArgument(),
+		"Bridge", wtdialer.AsBridgeArgument(),
+	}
+	config.TorArgs = append(config.TorArgs, extraArguments...)
+
+	// 3. start tor as we would normally do
+	torTunnel, debugInfo, err := config.sfTorStart(ctx, config)
+	debugInfo.Name = "torwebtunnel"
+	if err != nil {
+		ptl.Stop()
+		return nil, debugInfo, err
+	}
+
+	// 4. wrap the tunnel and the listener
+	tt := &torsfTunnel{
+		torTunnel:  torTunnel,
+		sfListener: ptl,
+	}
+	return tt, debugInfo, nil
+}
+
+// newWebTunnelDialer returns the correct webtunnel dialer.
+func newWebTunnelDialer(config *Config) (*ptx.WebTunnelDialer, error) {
+	if config.WebTunnelBridge == "" {
+		return nil, ErrEmptyWebTunnelBridge
+	}
+	return ptx.ParseWebTunnelBridgeLine(config.WebTunnelBridge)
+}
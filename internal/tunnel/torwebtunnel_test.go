@@ -0,0 +1,169 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/mocks"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/ptx"
+)
+
+// testWebTunnelBridge is the bridge line we use for testing.
+const testWebTunnelBridge = "webtunnel [2001:db8::1]:443 0123456789ABCDEF0123456789ABCDEF01234567 url=https://example.com/secret"
+
+func Test_torwebtunnelStart(t *testing.T) {
+	t.Run("with empty bridge", func(t *testing.T) {
+		ctx := context.Background()
+		config := &Config{
+			Name:      "torwebtunnel",
+			Session:   &MockableSession{},
+			TunnelDir: filepath.Join(os.TempDir(), "torwebtunnel-xx"),
+			Logger:    model.DiscardLogger,
+		}
+		tun, debugInfo, err := torwebtunnelStart(ctx, config)
+		if !errors.Is(err, ErrEmptyWebTunnelBridge) {
+			t.Fatal("unexpected err", err)
+		}
+		if tun != nil {
+			t.Fatal("expected nil tun")
+		}
+		if diff := cmp.Diff(DebugInfo{}, debugInfo); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with invalid bridge", func(t *testing.T) {
+		ctx := context.Background()
+		config := &Config{
+			Name:            "torwebtunnel",
+			Session:         &MockableSession{},
+			TunnelDir:       filepath.Join(os.TempDir(), "torwebtunnel-xx"),
+			Logger:          model.DiscardLogger,
+			WebTunnelBridge: "webtunnel antani",
+		}
+		tun, _, err := torwebtunnelStart(ctx, config)
+		if !errors.Is(err, ptx.ErrWebTunnelInvalidBridgeLine) {
+			t.Fatal("unexpected err", err)
+		}
+		if tun != nil {
+			t.Fatal("expected nil tun")
+		}
+	})
+
+	t.Run("ptl.Start fails", func(t *testing.T) {
+		ctx := context.Background()
+		expected := errors.New("mocked error")
+		config := &Config{
+			Name:      "torwebtunnel",
+			Session:   &MockableSession{},
+			TunnelDir: filepath.Join(os.TempDir(), "torwebtunnel-xx"),
+			Logger:    model.DiscardLogger,
+			testSfListenSocks: func(network, laddr string) (ptx.SocksListener, error) {
+				return nil, expected
+			},
+			WebTunnelBridge: testWebTunnelBridge,
+		}
+		tun, _, err := torwebtunnelStart(ctx, config)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if tun != nil {
+			t.Fatal("expected nil tun")
+		}
+	})
+
+	t.Run("torStart fails", func(t *testing.T) {
+		ctx := context.Background()
+		stopCounter := &atomic.Int64{}
+		expected := errors.New("expected err")
+		config := &Config{
+			Name:      "torwebtunnel",
+			Session:   &MockableSession{},
+			TunnelDir: filepath.Join(os.TempDir(), "torwebtunnel-xx"),
+			Logger:    model.DiscardLogger,
+			testSfWrapPTXListener: func(tp torsfPTXListener) torsfPTXListener {
+				return &torsfPTXListenerWrapper{
+					torsfPTXListener: tp,
+					counter:          stopCounter,
+				}
+			},
+			testSfTorStart: func(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
+				return nil, DebugInfo{}, expected
+			},
+			WebTunnelBridge: testWebTunnelBridge,
+		}
+		expectDebugInfo := DebugInfo{
+			Name: "torwebtunnel",
+		}
+		tun, debugInfo, err := torwebtunnelStart(ctx, config)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if tun != nil {
+			t.Fatal("expected nil tun")
+		}
+		if diff := cmp.Diff(expectDebugInfo, debugInfo); diff != "" {
+			t.Fatal(diff)
+		}
+		if stopCounter.Load() != 1 {
+			t.Fatal("did not call stop")
+		}
+	})
+
+	t.Run("on success", func(t *testing.T) {
+		ctx := context.Background()
+		config := &Config{
+			Name:      "torwebtunnel",
+			Session:   &MockableSession{},
+			TunnelDir: filepath.Join(os.TempDir(), "torwebtunnel-xx"),
+			Logger:    model.DiscardLogger,
+			testSfTorStart: func(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
+				tun := &fakeTunnel{
+					addr: &mocks.Addr{
+						MockString: func() string {
+							return "127.0.0.1:5555"
+						},
+					},
+					bootstrapTime: 123,
+					listener: &mocks.Listener{
+						MockClose: func() error {
+							return nil
+						},
+					},
+					once: sync.Once{},
+				}
+				return tun, DebugInfo{}, nil
+			},
+			WebTunnelBridge: testWebTunnelBridge,
+		}
+		tun, debugInfo, err := torwebtunnelStart(ctx, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if debugInfo.Name != "torwebtunnel" {
+			t.Fatal("unexpected debug info name", debugInfo.Name)
+		}
+		args := strings.Join(config.TorArgs, " ")
+		if !strings.Contains(args, "Bridge "+testWebTunnelBridge) {
+			t.Fatal("unexpected tor args", args)
+		}
+		if !strings.Contains(args, "ClientTransportPlugin webtunnel socks5 127.0.0.1:") {
+			t.Fatal("unexpected tor args", args)
+		}
+		if tun.BootstrapTime() != 123 {
+			t.Fatal("invalid bootstrap time")
+		}
+		if tun.SOCKS5ProxyURL().String() != "socks5://127.0.0.1:5555" {
+			t.Fatal("invalid socks5 proxy URL")
+		}
+		tun.Stop()
+	})
+}
@@ -74,6 +74,9 @@ var ErrEmptyTunnelDir = errors.New("TunnelDir is empty")
 // is not supported by this package.
 var ErrUnsupportedTunnelName = errors.New("unsupported tunnel name")
 
+// ErrEmptyWebTunnelBridge indicates that config.WebTunnelBridge is empty.
+var ErrEmptyWebTunnelBridge = errors.New("WebTunnelBridge is empty")
+
 // DebugInfo contains information useful to debug issues
 // when starting up a given tunnel fails.
 type DebugInfo struct {
@@ -107,6 +110,9 @@ type DebugInfo struct {
 // case, fetching the Psiphon configuration from the backend may
 // fail when the backend is not reachable.
 //
+// The "torwebtunnel" tunnel runs tor using the WebTunnel pluggable
+// transport and requires you to set config.WebTunnelBridge.
+//
 // The "fake" tunnel is a fake tunnel that just exposes a
 // SOCKS5 proxy and then connects directly to server. We use
 // this special kind of tunnel to implement tests.
@@ -119,6 +125,18 @@ type DebugInfo struct {
 //
 // 3. nil on success, an error on failure.
 func Start(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
+	tun, debugInfo, err := start(ctx, config)
+	if err != nil {
+		metricBootstrapCount.WithLabelValues(config.Name, "failure").Inc()
+		return nil, debugInfo, err
+	}
+	metricBootstrapCount.WithLabelValues(config.Name, "success").Inc()
+	metricBootstrapDurationSeconds.WithLabelValues(config.Name).Observe(tun.BootstrapTime().Seconds())
+	return tun, debugInfo, nil
+}
+
+// start is the internal implementation of [Start].
+func start(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
 	switch config.Name {
 	case "fake":
 		return fakeStart(ctx, config)
@@ -126,6 +144,8 @@ func Start(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
 		return psiphonStart(ctx, config)
 	case "torsf":
 		return torsfStart(ctx, config)
+	case "torwebtunnel":
+		return torwebtunnelStart(ctx, config)
 	case "tor":
 		return torStart(ctx, config)
 	default:
@@ -0,0 +1,44 @@
+package webconnectivityqa
+
+import (
+	"github.com/apex/log"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+)
+
+// addressFamilyBlockingAAAAQueries is the case where the censor drops the AAAA queries
+// for the target domain, which we see on some mobile networks, while A queries and
+// IPv4 endpoints work as intended.
+//
+// Note: we cannot emulate blocking IPv6 endpoints because netem only supports IPv4
+// and only answers to A queries, so we test such a case using the minipipeline (see the
+// blockedipv6endpoints manual test case).
+func addressFamilyBlockingAAAAQueries() *TestCase {
+	return &TestCase{
+		Name:  "addressFamilyBlockingAAAAQueries",
+		Flags: 0,
+		Input: "https://www.example.com/",
+		Configure: func(env *netemx.QAEnv) {
+			env.DPIEngine().AddRule(&netemx.DPIDropDNSQueryForQueryType{
+				Domain:    "www.example.com",
+				Logger:    log.Log,
+				QueryType: dns.TypeAAAA,
+			})
+		},
+		ExpectErr: false,
+		ExpectTestKeys: &TestKeys{
+			DNSConsistency:  "consistent",
+			BodyLengthMatch: true,
+			BodyProportion:  1,
+			StatusCodeMatch: true,
+			HeadersMatch:    true,
+			TitleMatch:      true,
+			XStatus:         1,  // StatusSuccessSecure
+			XDNSFlags:       2,  // AnalysisDNSFlagUnexpectedFailure
+			XBlockingFlags:  33, // AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagSuccess
+			XFamilyFlags:    2,  // AnalysisFamilyFlagIPv6SelectiveInterference
+			Accessible:      true,
+			Blocking:        false,
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package webconnectivityqa
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestAddressFamilyBlockingAAAAQueries(t *testing.T) {
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	tc := addressFamilyBlockingAAAAQueries()
+	tc.Configure(env)
+
+	env.Do(func() {
+		netx := &netxlite.Netx{}
+		txp := netxlite.NewUnwrappedDNSOverUDPTransport(
+			netx.NewDialerWithoutResolver(log.Log), net.JoinHostPort(netemx.AddressDNSGoogle8888, "53"))
+		encoder := &netxlite.DNSEncoderMiekg{}
+
+		// the A query should work as intended
+		resp, err := txp.RoundTrip(context.Background(), encoder.Encode("www.example.com", dns.TypeA, false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs, err := resp.DecodeLookupHost()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addrs) != 1 || addrs[0] != netemx.AddressWwwExampleCom {
+			t.Fatal("unexpected addrs", addrs)
+		}
+
+		// the AAAA query should time out
+		resp, err = txp.RoundTrip(context.Background(), encoder.Encode("www.example.com", dns.TypeAAAA, false))
+		if err == nil || err.Error() != netxlite.FailureGenericTimeoutError {
+			t.Fatal("unexpected error", err)
+		}
+		if resp != nil {
+			t.Fatal("expected nil response")
+		}
+	})
+}
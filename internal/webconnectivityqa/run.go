@@ -15,7 +15,11 @@ import (
 // MeasureTestCase returns the JSON measurement produced by a [TestCase].
 func MeasureTestCase(measurer model.ExperimentMeasurer, tc *TestCase) (*model.Measurement, error) {
 	// configure the netemx scenario
-	env := netemx.MustNewScenario(netemx.InternetScenario)
+	scenario := tc.Scenario
+	if scenario == nil {
+		scenario = netemx.InternetScenario
+	}
+	env := netemx.MustNewScenario(scenario)
 	defer env.Close()
 	if tc.Configure != nil {
 		tc.Configure(env)
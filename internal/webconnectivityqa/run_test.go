@@ -3,12 +3,14 @@ package webconnectivityqa
 import (
 	"context"
 	"errors"
+	"net/http"
 	"strings"
 	"testing"
 
 	"github.com/ooni/probe-cli/v3/internal/mocks"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
 
 func TestRunTestCase(t *testing.T) {
@@ -208,7 +210,7 @@ func TestRunTestCase(t *testing.T) {
 				return "web_connectivity"
 			},
 			MockExperimentVersion: func() string {
-				return "0.5.28"
+				return "0.5.29"
 			},
 			MockRun: func(ctx context.Context, args *model.ExperimentArgs) error {
 				args.Measurement.TestKeys = &TestKeys{
@@ -244,7 +246,7 @@ func TestRunTestCase(t *testing.T) {
 				return "web_connectivity"
 			},
 			MockExperimentVersion: func() string {
-				return "0.5.28"
+				return "0.5.29"
 			},
 			MockRun: func(ctx context.Context, args *model.ExperimentArgs) error {
 				args.Measurement.TestKeys = &TestKeys{
@@ -282,7 +284,7 @@ func TestRunTestCase(t *testing.T) {
 				return "web_connectivity"
 			},
 			MockExperimentVersion: func() string {
-				return "0.5.28"
+				return "0.5.29"
 			},
 			MockRun: func(ctx context.Context, args *model.ExperimentArgs) error {
 				args.Measurement.TestKeys = &TestKeys{
@@ -320,7 +322,7 @@ func TestRunTestCase(t *testing.T) {
 				return "web_connectivity"
 			},
 			MockExperimentVersion: func() string {
-				return "0.5.28"
+				return "0.5.29"
 			},
 			MockRun: func(ctx context.Context, args *model.ExperimentArgs) error {
 				args.Measurement.TestKeys = &TestKeys{
@@ -390,7 +392,7 @@ func TestRunTestCase(t *testing.T) {
 				return "web_connectivity"
 			},
 			MockExperimentVersion: func() string {
-				return "0.5.28"
+				return "0.5.29"
 			},
 			MockRun: func(ctx context.Context, args *model.ExperimentArgs) error {
 				args.Measurement.TestKeys = &TestKeys{
@@ -408,4 +410,45 @@ func TestRunTestCase(t *testing.T) {
 			t.Fatal("did not call tc.Configure")
 		}
 	})
+
+	t.Run("tc.Scenario is used when it is not nil", func(t *testing.T) {
+		tc := &TestCase{
+			Name:     "",
+			Input:    "",
+			Scenario: []*netemx.ScenarioDomainAddresses{},
+			Configure: func(env *netemx.QAEnv) {
+				env.AddRecordToAllResolvers("www.example.com", "", netemx.AddressWwwExampleCom)
+			},
+			ExpectErr: false,
+			ExpectTestKeys: &TestKeys{
+				Accessible: true,
+				Blocking:   nil,
+			},
+		}
+		measurer := &mocks.ExperimentMeasurer{
+			MockExperimentName: func() string {
+				return "web_connectivity"
+			},
+			MockExperimentVersion: func() string {
+				return "0.5.29"
+			},
+			MockRun: func(ctx context.Context, args *model.ExperimentArgs) error {
+				// with an empty scenario, there is no API server to connect to
+				client := args.Session.DefaultHTTPClient()
+				req := runtimex.Try1(http.NewRequest("GET", "https://api.ooni.io/", nil))
+				if _, err := client.Do(req); err == nil {
+					return errors.New("expected an error here")
+				}
+				args.Measurement.TestKeys = &TestKeys{
+					Accessible: true,
+					Blocking:   nil,
+				}
+				return nil
+			},
+		}
+		err := RunTestCase(measurer, tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
 }
@@ -24,6 +24,10 @@ type TestCase struct {
 	// LongTest indicates that this is a long test.
 	LongTest bool
 
+	// Scenario is the OPTIONAL scenario to use. When not set, we
+	// use [netemx.InternetScenario] as the scenario.
+	Scenario []*netemx.ScenarioDomainAddresses
+
 	// Configure is an OPTIONAL hook for further configuring the scenario.
 	Configure func(env *netemx.QAEnv)
 
@@ -39,9 +43,23 @@ type TestCase struct {
 	Checkers []Checker
 }
 
+// NewTestCaseFromScenarioFile creates a [*TestCase] measuring the input
+// of the given [*netemx.ScenarioFile] using its scenario and DPI rules. The
+// returned test case does not contain any expectation.
+func NewTestCaseFromScenarioFile(sf *netemx.ScenarioFile) *TestCase {
+	return &TestCase{
+		Name:      sf.Name,
+		Input:     sf.Input,
+		Scenario:  sf.Scenario(),
+		Configure: sf.Configure,
+	}
+}
+
 // AllTestCases returns all the defined test cases.
 func AllTestCases() []*TestCase {
 	return []*TestCase{
+		addressFamilyBlockingAAAAQueries(),
+
 		badSSLWithUnknownAuthorityWithConsistentDNS(),
 		badSSLWithExpiredCertificate(),
 		badSSLWithWrongServerName(),
@@ -1,6 +1,10 @@
 package webconnectivityqa
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+)
 
 func TestAllTestCases(t *testing.T) {
 	t.Run("we have at least one test case to run", func(t *testing.T) {
@@ -9,3 +13,33 @@ func TestAllTestCases(t *testing.T) {
 		}
 	})
 }
+
+func TestNewTestCaseFromScenarioFile(t *testing.T) {
+	sf, err := netemx.ParseScenarioFile([]byte(`
+name: dnsBlockingOfExampleCom
+input: https://www.example.com/
+extends_internet_scenario: true
+dpi_rules:
+  - type: spoof_dns_response
+    domain: www.example.com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := NewTestCaseFromScenarioFile(sf)
+	if tc.Name != "dnsBlockingOfExampleCom" {
+		t.Fatal("unexpected name", tc.Name)
+	}
+	if tc.Input != "https://www.example.com/" {
+		t.Fatal("unexpected input", tc.Input)
+	}
+	if len(tc.Scenario) != len(netemx.InternetScenario) {
+		t.Fatal("unexpected scenario length", len(tc.Scenario))
+	}
+	if tc.Configure == nil {
+		t.Fatal("expected non-nil configure")
+	}
+	if tc.ExpectTestKeys != nil || tc.ExpectErr {
+		t.Fatal("expected no expectations")
+	}
+}
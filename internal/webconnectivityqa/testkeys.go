@@ -43,6 +43,7 @@ type TestKeys struct {
 	XDNSFlags      int64 `json:"x_dns_flags"`
 	XBlockingFlags int64 `json:"x_blocking_flags"`
 	XNullNullFlags int64 `json:"x_null_null_flags"`
+	XFamilyFlags   int64 `json:"x_family_flags"`
 
 	// Accessible indicates whether the URL was accessible.
 	Accessible any `json:"accessible"`
@@ -71,9 +72,9 @@ func compareTestKeys(expected, got *TestKeys) error {
 	switch got.XExperimentVersion {
 	case "0.4.3":
 		// ignore the fields that are specific to LTE
-		options = append(options, cmpopts.IgnoreFields(TestKeys{}, "XDNSFlags", "XBlockingFlags", "XNullNullFlags"))
+		options = append(options, cmpopts.IgnoreFields(TestKeys{}, "XDNSFlags", "XBlockingFlags", "XNullNullFlags", "XFamilyFlags"))
 
-	case "0.5.28":
+	case "0.5.29":
 		// ignore the fields that are specific to v0.4
 		options = append(options, cmpopts.IgnoreFields(TestKeys{}, "XStatus"))
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/x/dslvm"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 // NewMinimalRuntime creates a minimal [Runtime] implementation.
@@ -128,6 +129,11 @@ func (tx *minimalTrace) NewDialerWithoutResolver(dl model.DebugLogger, wrappers
 	return tx.netx.NewDialerWithoutResolver(dl, wrappers...)
 }
 
+// NewParallelDNSOverHTTPSResolver implements Trace.
+func (tx *minimalTrace) NewParallelDNSOverHTTPSResolver(logger model.DebugLogger, URL string) model.Resolver {
+	return tx.netx.NewParallelDNSOverHTTPSResolver(logger, URL)
+}
+
 // NewParallelUDPResolver implements Trace.
 func (tx *minimalTrace) NewParallelUDPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
 	return tx.netx.NewParallelUDPResolver(logger, dialer, address)
@@ -148,6 +154,11 @@ func (tx *minimalTrace) NewTLSHandshakerStdlib(dl model.DebugLogger) model.TLSHa
 	return tx.netx.NewTLSHandshakerStdlib(dl)
 }
 
+// NewTLSHandshakerUTLS implements Trace.
+func (tx *minimalTrace) NewTLSHandshakerUTLS(dl model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker {
+	return tx.netx.NewTLSHandshakerUTLS(dl, id)
+}
+
 // NewUDPListener implements Trace
 func (tx *minimalTrace) NewUDPListener() model.UDPListener {
 	return tx.netx.NewUDPListener()
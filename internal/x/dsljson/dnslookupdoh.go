@@ -0,0 +1,55 @@
+package dsljson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/ooni/probe-cli/v3/internal/x/dslvm"
+)
+
+type dnsLookupDoHValue struct {
+	Domain string   `json:"domain"`
+	Output string   `json:"output"`
+	Tags   []string `json:"tags"`
+	URL    string   `json:"url"`
+}
+
+func (lx *loader) onDNSLookupDoH(raw json.RawMessage) error {
+	// parse the raw value
+	var value dnsLookupDoHValue
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+
+	// make sure the value is valid
+	if value.Domain == "" {
+		return errors.New("dns_lookup_doh: empty domain")
+	}
+	URL, err := url.Parse(value.URL)
+	if err != nil {
+		return fmt.Errorf("dns_lookup_doh: invalid url: %w", err)
+	}
+	if URL.Scheme != "https" || URL.Host == "" {
+		return fmt.Errorf("dns_lookup_doh: expected an https URL with a host, got: %s", value.URL)
+	}
+
+	// create the required output registers
+	output, err := registerMakeOutput[string](lx, value.Output)
+	if err != nil {
+		return err
+	}
+
+	// instantiate the stage
+	sx := &dslvm.DNSLookupDoHStage{
+		Domain: value.Domain,
+		Output: output,
+		Tags:   value.Tags,
+		URL:    value.URL,
+	}
+
+	// remember the stage for later
+	lx.stages = append(lx.stages, sx)
+	return nil
+}
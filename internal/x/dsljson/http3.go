@@ -0,0 +1,87 @@
+package dsljson
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+
+	"github.com/ooni/probe-cli/v3/internal/x/dslvm"
+)
+
+type http3RoundTripValue struct {
+	Accept              string   `json:"accept"`
+	AcceptLanguage      string   `json:"accept_language"`
+	Host                string   `json:"host"`
+	Input               string   `json:"input"`
+	InsecureSkipVerify  bool     `json:"insecure_skip_verify"`
+	MaxBodySnapshotSize int64    `json:"max_body_snapshot_size"`
+	Method              string   `json:"method"`
+	Output              string   `json:"output"`
+	Referer             string   `json:"referer"`
+	RootCAs             []string `json:"root_cas"`
+	ServerName          string   `json:"server_name"`
+	Tags                []string `json:"tags"`
+	URLPath             string   `json:"url_path"`
+	UserAgent           string   `json:"user_agent"`
+}
+
+func (lx *loader) onHTTP3RoundTrip(raw json.RawMessage) error {
+	// parse the raw value
+	var value http3RoundTripValue
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+
+	// make sure the value is valid
+	if value.ServerName == "" {
+		return errors.New("http3_round_trip: empty server_name")
+	}
+
+	// create the required output registers
+	output, err := registerMakeOutput[dslvm.Done](lx, value.Output)
+	if err != nil {
+		return err
+	}
+
+	// make sure we register output as something to wait for
+	lx.toWait = append(lx.toWait, output)
+
+	// fetch the required input register
+	input, err := registerPopInput[string](lx, value.Input)
+	if err != nil {
+		return err
+	}
+
+	// create the X509 cert pool
+	var pool *x509.CertPool
+	for _, cert := range value.RootCAs {
+		if pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(cert)) {
+			return errors.New("cannot add PEM-encoded cert to X509 cert pool")
+		}
+	}
+
+	// instantiate the stage
+	sx := &dslvm.HTTP3RoundTripStage{
+		Accept:              value.Accept,
+		AcceptLanguage:      value.AcceptLanguage,
+		Host:                value.Host,
+		Input:               input,
+		InsecureSkipVerify:  value.InsecureSkipVerify,
+		MaxBodySnapshotSize: value.MaxBodySnapshotSize,
+		Method:              value.Method,
+		Output:              output,
+		Referer:             value.Referer,
+		RootCAs:             pool,
+		ServerName:          value.ServerName,
+		Tags:                value.Tags,
+		URLPath:             value.URLPath,
+		UserAgent:           value.UserAgent,
+	}
+
+	// remember the stage for later
+	lx.stages = append(lx.stages, sx)
+	return nil
+}
@@ -35,16 +35,19 @@ func newLoader() *loader {
 	}
 
 	lx.loaders["drop"] = lx.onDrop
+	lx.loaders["dns_lookup_doh"] = lx.onDNSLookupDoH
 	lx.loaders["dns_lookup_udp"] = lx.onDNSLookupUDP
 	lx.loaders["dedup_addrs"] = lx.onDedupAddrs
 	lx.loaders["getaddrinfo"] = lx.onGetaddrinfo
 	lx.loaders["http_round_trip"] = lx.onHTTPRoundTrip
+	lx.loaders["http3_round_trip"] = lx.onHTTP3RoundTrip
 	lx.loaders["make_endpoints"] = lx.onMakeEndpoints
 	lx.loaders["quic_handshake"] = lx.onQUICHandshake
 	lx.loaders["take_n"] = lx.onTakeN
 	lx.loaders["tcp_connect"] = lx.onTCPConnect
 	lx.loaders["tls_handshake"] = lx.onTLSHandshake
 	lx.loaders["tee_addrs"] = lx.onTeeAddrs
+	lx.loaders["utls_handshake"] = lx.onUTLSHandshake
 
 	return lx
 }
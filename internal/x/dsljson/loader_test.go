@@ -0,0 +1,119 @@
+package dsljson
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestLoaderValidation(t *testing.T) {
+	type testcase struct {
+		// name is the name of the test case
+		name string
+
+		// document is the DSL document to load
+		document string
+
+		// expectErr is a substring of the error we expect
+		expectErr string
+	}
+
+	cases := []testcase{{
+		name: "dns_lookup_doh with a non-https URL",
+		document: `{"stages": [{
+			"name": "dns_lookup_doh",
+			"value": {"domain": "www.example.com", "output": "addrs", "url": "http://dns.google/dns-query"}
+		}]}`,
+		expectErr: "dns_lookup_doh: expected an https URL with a host",
+	}, {
+		name: "dns_lookup_doh with an empty domain",
+		document: `{"stages": [{
+			"name": "dns_lookup_doh",
+			"value": {"output": "addrs", "url": "https://dns.google/dns-query"}
+		}]}`,
+		expectErr: "dns_lookup_doh: empty domain",
+	}, {
+		name: "utls_handshake with an unknown client_hello_id",
+		document: `{"stages": [{
+			"name": "getaddrinfo",
+			"value": {"domain": "www.example.com", "output": "addrs"}
+		}, {
+			"name": "make_endpoints",
+			"value": {"input": "addrs", "output": "endpoints", "port": "443"}
+		}, {
+			"name": "tcp_connect",
+			"value": {"input": "endpoints", "output": "tcp_conns"}
+		}, {
+			"name": "utls_handshake",
+			"value": {"client_hello_id": "antani", "input": "tcp_conns", "output": "tls_conns"}
+		}]}`,
+		expectErr: "utls_handshake: unknown client_hello_id: antani",
+	}, {
+		name: "utls_handshake with bad root_cas",
+		document: `{"stages": [{
+			"name": "getaddrinfo",
+			"value": {"domain": "www.example.com", "output": "addrs"}
+		}, {
+			"name": "make_endpoints",
+			"value": {"input": "addrs", "output": "endpoints", "port": "443"}
+		}, {
+			"name": "tcp_connect",
+			"value": {"input": "endpoints", "output": "tcp_conns"}
+		}, {
+			"name": "utls_handshake",
+			"value": {
+				"client_hello_id": "chrome",
+				"input": "tcp_conns",
+				"output": "tls_conns",
+				"root_cas": ["antani"]
+			}
+		}]}`,
+		expectErr: "cannot add PEM-encoded cert to X509 cert pool",
+	}, {
+		name: "http3_round_trip with an empty server_name",
+		document: `{"stages": [{
+			"name": "getaddrinfo",
+			"value": {"domain": "www.example.com", "output": "addrs"}
+		}, {
+			"name": "make_endpoints",
+			"value": {"input": "addrs", "output": "endpoints", "port": "443"}
+		}, {
+			"name": "http3_round_trip",
+			"value": {"input": "endpoints", "output": "done"}
+		}]}`,
+		expectErr: "http3_round_trip: empty server_name",
+	}, {
+		name: "http3_round_trip with bad root_cas",
+		document: `{"stages": [{
+			"name": "getaddrinfo",
+			"value": {"domain": "www.example.com", "output": "addrs"}
+		}, {
+			"name": "make_endpoints",
+			"value": {"input": "addrs", "output": "endpoints", "port": "443"}
+		}, {
+			"name": "http3_round_trip",
+			"value": {
+				"input": "endpoints",
+				"output": "done",
+				"root_cas": ["antani"],
+				"server_name": "www.example.com"
+			}
+		}]}`,
+		expectErr: "cannot add PEM-encoded cert to X509 cert pool",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var root RootNode
+			if err := json.Unmarshal([]byte(tc.document), &root); err != nil {
+				t.Fatal(err)
+			}
+			err := newLoader().load(model.DiscardLogger, &root)
+			if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package dsljson
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/netemx"
+	"github.com/ooni/probe-cli/v3/internal/x/dslengine"
+	"github.com/ooni/probe-cli/v3/internal/x/dslvm"
+)
+
+// runWithinQAEnv loads and runs the given document inside a [*netemx.QAEnv]
+// using the internet scenario and returns the collected observations.
+func runWithinQAEnv(t *testing.T, document string) *dslvm.Observations {
+	var root RootNode
+	if err := json.Unmarshal([]byte(document), &root); err != nil {
+		t.Fatal(err)
+	}
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+	var (
+		observations *dslvm.Observations
+		err          error
+	)
+	env.Do(func() {
+		rtx := dslengine.NewRuntimeMeasurexLite(log.Log, time.Now())
+		err = Run(context.Background(), rtx, &root)
+		observations = rtx.Observations()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return observations
+}
+
+func TestRunDNSLookupDoH(t *testing.T) {
+	observations := runWithinQAEnv(t, `{"stages": [{
+		"name": "dns_lookup_doh",
+		"value": {"domain": "www.example.com", "output": "addrs", "url": "https://dns.google/dns-query"}
+	}]}`)
+	var found bool
+	for _, query := range observations.Queries {
+		if query.Engine != "doh" || query.QueryType != "A" {
+			continue // e.g., the lookup of the DoH server domain
+		}
+		if query.Failure != nil {
+			t.Fatal("unexpected failure", *query.Failure)
+		}
+		for _, answer := range query.Answers {
+			found = found || answer.IPv4 == netemx.AddressWwwExampleCom
+		}
+	}
+	if !found {
+		t.Fatal("did not find the expected address using DoH")
+	}
+}
+
+func TestRunUTLSHandshake(t *testing.T) {
+	observations := runWithinQAEnv(t, `{"stages": [{
+		"name": "getaddrinfo",
+		"value": {"domain": "www.example.com", "output": "addrs"}
+	}, {
+		"name": "make_endpoints",
+		"value": {"input": "addrs", "output": "endpoints", "port": "443"}
+	}, {
+		"name": "tcp_connect",
+		"value": {"input": "endpoints", "output": "tcp_conns"}
+	}, {
+		"name": "utls_handshake",
+		"value": {
+			"client_hello_id": "chrome",
+			"input": "tcp_conns",
+			"output": "tls_conns",
+			"server_name": "www.example.com"
+		}
+	}]}`)
+	if len(observations.TLSHandshakes) != 1 {
+		t.Fatal("expected exactly one TLS handshake")
+	}
+	if failure := observations.TLSHandshakes[0].Failure; failure != nil {
+		t.Fatal("unexpected failure", *failure)
+	}
+}
+
+func TestRunHTTP3RoundTrip(t *testing.T) {
+	observations := runWithinQAEnv(t, `{"stages": [{
+		"name": "getaddrinfo",
+		"value": {"domain": "www.example.com", "output": "addrs"}
+	}, {
+		"name": "make_endpoints",
+		"value": {"input": "addrs", "output": "endpoints", "port": "443"}
+	}, {
+		"name": "http3_round_trip",
+		"value": {
+			"host": "www.example.com",
+			"input": "endpoints",
+			"method": "GET",
+			"output": "done",
+			"server_name": "www.example.com",
+			"url_path": "/"
+		}
+	}]}`)
+	if len(observations.QUICHandshakes) != 1 {
+		t.Fatal("expected exactly one QUIC handshake")
+	}
+	if failure := observations.QUICHandshakes[0].Failure; failure != nil {
+		t.Fatal("unexpected failure", *failure)
+	}
+	if len(observations.Requests) != 1 {
+		t.Fatal("expected exactly one request")
+	}
+	if failure := observations.Requests[0].Failure; failure != nil {
+		t.Fatal("unexpected failure", *failure)
+	}
+	if code := observations.Requests[0].Response.Code; code != 200 {
+		t.Fatal("unexpected status code", code)
+	}
+}
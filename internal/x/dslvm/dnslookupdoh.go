@@ -0,0 +1,81 @@
+package dslvm
+
+import (
+	"context"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+)
+
+// DNSLookupDoHStage is a [Stage] that resolves domain names using a DNS-over-HTTPS resolver.
+type DNSLookupDoHStage struct {
+	// Domain is the MANDATORY domain to resolve using this DNS resolver.
+	Domain string
+
+	// Output is the MANDATORY channel emitting IP addresses. We will close this
+	// channel when we have finished streaming the resolved addresses.
+	Output chan<- string
+
+	// Tags contains OPTIONAL tags for the DNS observations.
+	Tags []string
+
+	// URL is the MANDATORY DNS-over-HTTPS server URL (e.g., https://dns.google/dns-query).
+	URL string
+}
+
+var _ Stage = &DNSLookupDoHStage{}
+
+// Run resolves a Domain using the given DNS-over-HTTPS URL and streams the
+// results on Output, which is closed when we're done.
+//
+// This function honours the semaphore returned by the [Runtime] ActiveDNSLookups
+// method and waits until it's given the permission to start a lookup.
+func (sx *DNSLookupDoHStage) Run(ctx context.Context, rtx Runtime) {
+	// wait for permission to lookup and signal when done
+	rtx.ActiveDNSLookups().Wait()
+	defer rtx.ActiveDNSLookups().Signal()
+
+	// make sure we close output when done
+	defer close(sx.Output)
+
+	// create trace
+	trace := rtx.NewTrace(rtx.IDGenerator().Add(1), rtx.ZeroTime(), sx.Tags...)
+
+	// start operation logger
+	ol := logx.NewOperationLogger(
+		rtx.Logger(),
+		"[#%d] DNSLookup[%s] %s",
+		trace.Index(),
+		sx.URL,
+		sx.Domain,
+	)
+
+	// setup: note that the timeout is larger than for UDP because the
+	// DoH transport may need to establish a TLS connection first
+	const timeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// create the resolver and make sure we close its idle connections
+	resolver := trace.NewParallelDNSOverHTTPSResolver(rtx.Logger(), sx.URL)
+	defer resolver.CloseIdleConnections()
+
+	// lookup
+	addrs, err := resolver.LookupHost(ctx, sx.Domain)
+
+	// stop the operation logger
+	ol.Stop(err)
+
+	// save the observations
+	rtx.SaveObservations(maybeTraceToObservations(trace)...)
+
+	// handle error case
+	if err != nil {
+		return
+	}
+
+	// handle success
+	for _, addr := range addrs {
+		sx.Output <- addr
+	}
+}
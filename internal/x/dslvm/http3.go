@@ -0,0 +1,91 @@
+package dslvm
+
+import (
+	"context"
+	"crypto/x509"
+)
+
+// HTTP3RoundTripStage is a [Stage] that performs a QUIC handshake with each
+// endpoint using the "h3" ALPN followed by an HTTP/3 round trip. It is
+// equivalent to a [*QUICHandshakeStage] whose output is connected to the input
+// of a [*HTTPRoundTripStage], except that the ALPN is always "h3".
+type HTTP3RoundTripStage struct {
+	// Accept contains the OPTIONAL accept header.
+	Accept string
+
+	// AcceptLanguage contains the OPTIONAL accept-language header.
+	AcceptLanguage string
+
+	// Host contains the MANDATORY host header.
+	Host string
+
+	// Input contains the MANDATORY channel from which to read endpoints. We
+	// assume that this channel will be closed when done.
+	Input <-chan string
+
+	// InsecureSkipVerify OPTIONALLY skips QUIC verification.
+	InsecureSkipVerify bool
+
+	// MaxBodySnapshotSize is the OPTIONAL maximum body snapshot size.
+	MaxBodySnapshotSize int64
+
+	// Method contains the MANDATORY method.
+	Method string
+
+	// Output is the MANDATORY channel emitting [Done]. We will close this
+	// channel when the Input channel has been closed.
+	Output chan<- Done
+
+	// Referer contains the OPTIONAL referer header.
+	Referer string
+
+	// RootCAs OPTIONALLY configures alternative root CAs.
+	RootCAs *x509.CertPool
+
+	// ServerName is the MANDATORY server name.
+	ServerName string
+
+	// Tags contains OPTIONAL tags to add to the endpoint observations.
+	Tags []string
+
+	// URLPath contains the MANDATORY URL path.
+	URLPath string
+
+	// UserAgent contains the OPTIONAL user-agent header.
+	UserAgent string
+}
+
+var _ Stage = &HTTP3RoundTripStage{}
+
+// Run reads endpoints from Input, performs a QUIC handshake and an HTTP/3 round
+// trip with each of them, and emits [Done] on Output. The parallelism is controlled
+// by the [Runtime] ActiveConnections [Semaphore] as documented in the
+// [*QUICHandshakeStage] and [*HTTPRoundTripStage] Run methods.
+func (sx *HTTP3RoundTripStage) Run(ctx context.Context, rtx Runtime) {
+	conns := make(chan *QUICConnection)
+
+	handshaker := &QUICHandshakeStage{
+		Input:              sx.Input,
+		InsecureSkipVerify: sx.InsecureSkipVerify,
+		NextProtos:         []string{"h3"},
+		Output:             conns,
+		RootCAs:            sx.RootCAs,
+		ServerName:         sx.ServerName,
+		Tags:               sx.Tags,
+	}
+	go handshaker.Run(ctx, rtx)
+
+	roundTripper := &HTTPRoundTripStage[*QUICConnection]{
+		Accept:              sx.Accept,
+		AcceptLanguage:      sx.AcceptLanguage,
+		Host:                sx.Host,
+		Input:               conns,
+		MaxBodySnapshotSize: sx.MaxBodySnapshotSize,
+		Method:              sx.Method,
+		Output:              sx.Output,
+		Referer:             sx.Referer,
+		URLPath:             sx.URLPath,
+		UserAgent:           sx.UserAgent,
+	}
+	roundTripper.Run(ctx, rtx)
+}
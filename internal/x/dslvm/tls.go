@@ -133,6 +133,19 @@ func (sx *TLSHandshakeStage) handshake(ctx context.Context, rtx Runtime, tcpConn
 	// obtain the handshaker for use
 	handshaker := trace.NewTLSHandshakerStdlib(rtx.Logger())
 
+	// handshake and emit the conn on success
+	tlsHandshake(ctx, rtx, tcpConn, handshaker, config, ol, sx.Output)
+}
+
+// tlsHandshake performs the TLS handshake using the given handshaker, stops the
+// given operation logger, saves the observations and, on success, emits the
+// resulting [*TLSConnection] on output. This function is shared by the
+// [*TLSHandshakeStage] and the [*UTLSHandshakeStage].
+func tlsHandshake(ctx context.Context, rtx Runtime, tcpConn *TCPConnection, handshaker model.TLSHandshaker,
+	config *tls.Config, ol *logx.OperationLogger, output chan<- *TLSConnection) {
+	// keep using the same trace
+	trace := tcpConn.Trace()
+
 	// setup
 	const timeout = 10 * time.Second
 	ctx, cancel := context.WithTimeout(ctx, timeout)
@@ -155,7 +168,7 @@ func (sx *TLSHandshakeStage) handshake(ctx context.Context, rtx Runtime, tcpConn
 	}
 
 	// handle success
-	sx.Output <- &TLSConnection{
+	output <- &TLSConnection{
 		Conn: tlsConn,
 		tx:   trace,
 	}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 // Trace collects [*Observations] using tracing. Specific implementations
@@ -29,6 +30,9 @@ type Trace interface {
 	// model.MeasuringNetwork interface, but they're not used by this function.
 	NewDialerWithoutResolver(dl model.DebugLogger, wrappers ...model.DialerWrapper) model.Dialer
 
+	// NewParallelDNSOverHTTPSResolver returns a possibly-trace-ware parallel DoH resolver.
+	NewParallelDNSOverHTTPSResolver(logger model.DebugLogger, URL string) model.Resolver
+
 	// NewParallelUDPResolver returns a possibly-trace-ware parallel UDP resolver
 	NewParallelUDPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver
 
@@ -45,6 +49,10 @@ type Trace interface {
 	// except that it returns a model.TLSHandshaker that uses this trace.
 	NewTLSHandshakerStdlib(dl model.DebugLogger) model.TLSHandshaker
 
+	// NewTLSHandshakerUTLS is equivalent to netxlite.Netx.NewTLSHandshakerUTLS
+	// except that it returns a model.TLSHandshaker that uses this trace.
+	NewTLSHandshakerUTLS(dl model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker
+
 	// NetworkEvents returns all the network events collected so far.
 	NetworkEvents() (out []*model.ArchivalNetworkEvent)
 
@@ -0,0 +1,92 @@
+package dslvm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/logx"
+	utls "gitlab.com/yawning/utls.git"
+)
+
+// UTLSHandshakeStage is like [*TLSHandshakeStage] but uses uTLS to mimic
+// the ClientHello of a specific TLS client (e.g., a browser).
+type UTLSHandshakeStage struct {
+	// ClientHelloID is the MANDATORY ClientHello to mimic.
+	ClientHelloID *utls.ClientHelloID
+
+	// Input contains the MANDATORY channel from which to read [*TCPConnection]. We
+	// assume that this channel will be closed when done.
+	Input <-chan *TCPConnection
+
+	// InsecureSkipVerify OPTIONALLY skips TLS verification.
+	InsecureSkipVerify bool
+
+	// NextProtos OPTIONALLY configures the ALPN.
+	NextProtos []string
+
+	// Output is the MANDATORY channel emitting [*TLSConnection]. We will close this
+	// channel when the Input channel has been closed.
+	Output chan<- *TLSConnection
+
+	// RootCAs OPTIONALLY configures alternative root CAs.
+	RootCAs *x509.CertPool
+
+	// ServerName is the MANDATORY server name.
+	ServerName string
+}
+
+var _ Stage = &UTLSHandshakeStage{}
+
+// Run is like [*TLSHandshakeStage.Run] except that it uses uTLS.
+func (sx *UTLSHandshakeStage) Run(ctx context.Context, rtx Runtime) {
+	// make sure we close the output channel
+	defer close(sx.Output)
+
+	// track the number of running goroutines
+	waitGroup := &sync.WaitGroup{}
+
+	for tcpConn := range sx.Input {
+		// process connection in a background goroutine, which is fine
+		// because the previous step has acquired the semaphore.
+		waitGroup.Add(1)
+		go func(tcpConn *TCPConnection) {
+			defer waitGroup.Done()
+			sx.handshake(ctx, rtx, tcpConn)
+		}(tcpConn)
+	}
+
+	// wait for pending work to finish
+	waitGroup.Wait()
+}
+
+func (sx *UTLSHandshakeStage) handshake(ctx context.Context, rtx Runtime, tcpConn *TCPConnection) {
+	// keep using the same trace
+	trace := tcpConn.Trace()
+
+	// create a suitable TLS configuration
+	config := &tls.Config{
+		NextProtos:         sx.NextProtos,
+		InsecureSkipVerify: sx.InsecureSkipVerify,
+		RootCAs:            sx.RootCAs,
+		ServerName:         sx.ServerName,
+	}
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		rtx.Logger(),
+		"[#%d] TLSHandshake[utls:%s] with %s SNI=%s ALPN=%v",
+		trace.Index(),
+		sx.ClientHelloID.Str(),
+		tcpConn.RemoteAddress(),
+		config.ServerName,
+		config.NextProtos,
+	)
+
+	// obtain the handshaker for use
+	handshaker := trace.NewTLSHandshakerUTLS(rtx.Logger(), sx.ClientHelloID)
+
+	// handshake and emit the conn on success
+	tlsHandshake(ctx, rtx, tcpConn, handshaker, config, ol, sx.Output)
+}
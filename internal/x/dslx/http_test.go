@@ -385,6 +385,40 @@ func TestHTTPRequest(t *testing.T) {
 				t.Fatal("unexpected URL path", res.State.HTTPRequest.URL.Path)
 			}
 		})
+
+		t.Run("with max body snapshot size", func(t *testing.T) {
+			httpTransport := HTTPConnection{
+				Address:             "1.2.3.4:80",
+				MaxBodySnapshotSize: 4,
+				Network:             "tcp",
+				Scheme:              "http",
+				Trace:               trace,
+				Transport: &mocks.HTTPTransport{
+					MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+						resp := &http.Response{
+							Body: io.NopCloser(strings.NewReader("antani")),
+						}
+						return resp, nil
+					},
+					MockNetwork: func() string {
+						return "tcp"
+					},
+				},
+			}
+			rt := NewRuntimeMeasurexLite(model.DiscardLogger, time.Now())
+			httpRequest := HTTPRequest(rt)
+			res := httpRequest.Apply(context.Background(), NewMaybeWithValue(&httpTransport))
+			if res.Error != nil {
+				t.Fatal("unexpected error")
+			}
+			if string(res.State.HTTPResponseBodySnapshot) != "anta" {
+				t.Fatal("unexpected body snapshot", string(res.State.HTTPResponseBodySnapshot))
+			}
+			obs := rt.Observations()
+			if len(obs.Requests) != 1 || !obs.Requests[0].Response.BodyIsTruncated {
+				t.Fatal("expected a truncated body")
+			}
+		})
 	})
 }
 
@@ -31,6 +31,10 @@ type HTTPConnection struct {
 	// Domain is the OPTIONAL domain from which the address was resolved.
 	Domain string
 
+	// MaxBodySnapshotSize is the OPTIONAL maximum number of bytes of the response
+	// body to read and archive. When zero or negative, we use 1<<19 bytes.
+	MaxBodySnapshotSize int64
+
 	// Network is the MANDATORY network used by the underlying conn.
 	Network string
 
@@ -215,13 +219,19 @@ func httpNewURLHost(input *HTTPConnection, logger model.Logger) string {
 	}
 }
 
+// httpDefaultMaxBodySnapshotSize is the default value of [HTTPConnection] MaxBodySnapshotSize.
+const httpDefaultMaxBodySnapshotSize = 1 << 19
+
 // httpRoundTrip performs the actual HTTP round trip
 func httpRoundTrip(
 	ctx context.Context,
 	input *HTTPConnection,
 	req *http.Request,
 ) (*http.Response, []byte, []*Observations, error) {
-	const maxbody = 1 << 19 // TODO(https://github.com/ooni/probe/issues/2621): allow to configure this value
+	maxbody := input.MaxBodySnapshotSize
+	if maxbody <= 0 {
+		maxbody = httpDefaultMaxBodySnapshotSize
+	}
 	started := input.Trace.TimeSince(input.Trace.ZeroTime())
 
 	// manually create a single 1-length observations structure because
@@ -9,6 +9,10 @@ set -euxo pipefail
 	-destdir ./internal/minipipeline/testdata/webconnectivity/manual/8844 \
 	-measurement ./internal/minipipeline/testdata/webconnectivity/manual/8844/measurement.json
 
+./script/go.bash run ./internal/cmd/minipipeline \
+	-destdir ./internal/minipipeline/testdata/webconnectivity/manual/blockedipv6endpoints \
+	-measurement ./internal/minipipeline/testdata/webconnectivity/manual/blockedipv6endpoints/measurement.json
+
 ./script/go.bash run ./internal/cmd/minipipeline \
 	-destdir ./internal/minipipeline/testdata/webconnectivity/manual/dnsgoogle80 \
 	-measurement ./internal/minipipeline/testdata/webconnectivity/manual/dnsgoogle80/measurement.json